@@ -0,0 +1,336 @@
+// Package alert evaluates simple rules against a swarm's
+// events while it runs, so that unattended runs surface
+// problems as they happen rather than in the logs afterwards.
+//
+// Rules are usually read from a JSON file:
+//
+//	[
+//	  {"name": "errors", "metric": "error-rate", "above": 0.1, "window": "1m"},
+//	  {"name": "stalled", "metric": "join-stall", "window": "30s",
+//	   "webhook": "https://hooks.example.com/kahoot"}
+//	]
+//
+// An "error-rate" rule fires when more than the given fraction
+// of joins and answers failed during the window. A
+// "join-stall" rule fires when bots are waiting to join but
+// none has joined or failed for the whole window.
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// Metrics which rules may use.
+const (
+	ErrorRate = "error-rate"
+	JoinStall = "join-stall"
+)
+
+// CheckInterval is how often an Engine evaluates its rules.
+const CheckInterval = time.Second
+
+// A Duration is a time.Duration which is written as a string
+// such as "30s" in JSON.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON formats the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// A Rule describes a condition worth alerting on.
+type Rule struct {
+	Name   string   `json:"name"`
+	Metric string   `json:"metric"`
+	Window Duration `json:"window"`
+
+	// Above is the error rate, between 0 and 1, which an
+	// "error-rate" rule must exceed to fire.
+	Above float64 `json:"above"`
+
+	// Webhook, if set, receives each of the rule's alerts as
+	// a JSON POST.
+	Webhook string `json:"webhook,omitempty"`
+}
+
+// Validate checks that the rule can be evaluated.
+func (r *Rule) Validate() error {
+	if r.Window <= 0 {
+		return fmt.Errorf("rule %q: window must be positive", r.Name)
+	}
+	switch r.Metric {
+	case ErrorRate:
+		if r.Above < 0 || r.Above >= 1 {
+			return fmt.Errorf("rule %q: above must be at least 0 and less than 1", r.Name)
+		}
+	case JoinStall:
+	default:
+		return fmt.Errorf("rule %q: unknown metric %q", r.Name, r.Metric)
+	}
+	return nil
+}
+
+// ReadRules reads and validates a JSON array of rules.
+func ReadRules(path string) ([]Rule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parse alert rules: %s", err)
+	}
+	for i := range rules {
+		if rules[i].Name == "" {
+			rules[i].Name = rules[i].Metric
+		}
+		if err := rules[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// An Alert is sent when a rule starts firing, and again with
+// Firing unset once it stops.
+type Alert struct {
+	Rule    string    `json:"rule"`
+	Metric  string    `json:"metric"`
+	Firing  bool      `json:"firing"`
+	Value   float64   `json:"value"`
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+type outcome struct {
+	time   time.Time
+	failed bool
+}
+
+// An Engine evaluates rules against the events it watches.
+type Engine struct {
+	rules  []Rule
+	notify func(a *Alert)
+	client *http.Client
+
+	lock         sync.Mutex
+	outcomes     []outcome
+	pending      int
+	lastProgress time.Time
+	firing       map[string]bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewEngine creates an Engine and starts evaluating its
+// rules every CheckInterval.
+// The notify function is called for every alert, in addition
+// to any webhook the rule names, and may be called from
+// several goroutines at once.
+func NewEngine(rules []Rule, notify func(a *Alert)) *Engine {
+	e := &Engine{
+		rules:  rules,
+		notify: notify,
+		client: &http.Client{Timeout: kahoot.DefaultTimeout},
+		firing: map[string]bool{},
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go e.loop()
+	return e
+}
+
+// Watch feeds the events of a subscription to the engine,
+// returning once the subscription is closed. It may be
+// called for several subscriptions at once, such as the
+// swarm's Bus and each bot's Conn.
+func (e *Engine) Watch(sub *kahoot.Subscription) {
+	for event := range sub.C {
+		e.observe(event)
+	}
+}
+
+// Close stops evaluating rules.
+func (e *Engine) Close() {
+	close(e.stop)
+	<-e.done
+}
+
+func (e *Engine) loop() {
+	defer close(e.done)
+	ticker := time.NewTicker(CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.stop:
+			return
+		case now := <-ticker.C:
+			for _, a := range e.check(now) {
+				e.send(a)
+			}
+		}
+	}
+}
+
+func (e *Engine) observe(event kahoot.Event) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	switch event.Topic {
+	case kahoot.TopicConnection:
+		switch event.Type {
+		case "joining":
+			if n, ok := event.Data.(int); ok {
+				if e.pending == 0 {
+					e.lastProgress = event.Time
+				}
+				e.pending += n
+			}
+		case "joined":
+			e.joinProgress(event.Time)
+			e.outcomes = append(e.outcomes, outcome{time: event.Time})
+		}
+	case kahoot.TopicQuestion:
+		if event.Type == "answered" {
+			e.outcomes = append(e.outcomes, outcome{time: event.Time})
+		}
+	case kahoot.TopicError:
+		if event.Type == "join" {
+			e.joinProgress(event.Time)
+		}
+		e.outcomes = append(e.outcomes, outcome{time: event.Time, failed: true})
+	}
+}
+
+func (e *Engine) joinProgress(t time.Time) {
+	if e.pending > 0 {
+		e.pending--
+	}
+	e.lastProgress = t
+}
+
+// check evaluates every rule at a point in time and returns
+// the alerts for rules which started or stopped firing.
+func (e *Engine) check(now time.Time) []*Alert {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	var longest time.Duration
+	for _, r := range e.rules {
+		if w := time.Duration(r.Window); w > longest {
+			longest = w
+		}
+	}
+	for len(e.outcomes) > 0 && now.Sub(e.outcomes[0].time) > longest {
+		e.outcomes = e.outcomes[1:]
+	}
+
+	var alerts []*Alert
+	for _, r := range e.rules {
+		value, firing, msg := e.evaluate(&r, now)
+		if firing == e.firing[r.Name] {
+			continue
+		}
+		e.firing[r.Name] = firing
+		if !firing {
+			msg = "resolved: " + msg
+		}
+		alerts = append(alerts, &Alert{
+			Rule:    r.Name,
+			Metric:  r.Metric,
+			Firing:  firing,
+			Value:   value,
+			Time:    now,
+			Message: msg,
+		})
+	}
+	return alerts
+}
+
+func (e *Engine) evaluate(r *Rule, now time.Time) (value float64, firing bool, msg string) {
+	window := time.Duration(r.Window)
+	switch r.Metric {
+	case ErrorRate:
+		var total, failed int
+		for _, o := range e.outcomes {
+			if now.Sub(o.time) <= window {
+				total++
+				if o.failed {
+					failed++
+				}
+			}
+		}
+		if total > 0 {
+			value = float64(failed) / float64(total)
+		}
+		msg = fmt.Sprintf("%d of %d joins and answers failed in the last %s",
+			failed, total, window)
+		return value, failed > 0 && value > r.Above, msg
+	case JoinStall:
+		if e.pending == 0 {
+			return 0, false, "no bots are waiting to join"
+		}
+		stalled := now.Sub(e.lastProgress)
+		msg = fmt.Sprintf("%d bots waiting, none joined for %s",
+			e.pending, stalled.Truncate(time.Second))
+		return stalled.Seconds(), stalled >= window, msg
+	}
+	return 0, false, ""
+}
+
+func (e *Engine) send(a *Alert) {
+	if e.notify != nil {
+		e.notify(a)
+	}
+	for _, r := range e.rules {
+		if r.Name == a.Rule && r.Webhook != "" {
+			go func(url string) {
+				if err := e.post(url, a); err != nil && e.notify != nil {
+					e.notify(&Alert{
+						Rule:    a.Rule,
+						Metric:  a.Metric,
+						Firing:  a.Firing,
+						Time:    time.Now(),
+						Message: "webhook failed: " + err.Error(),
+					})
+				}
+			}(r.Webhook)
+		}
+	}
+}
+
+func (e *Engine) post(url string, a *Alert) error {
+	body, _ := json.Marshal(a)
+	resp, err := e.client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return errors.New("webhook responded " + resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestReadRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "alert")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "rules.json")
+	ioutil.WriteFile(path, []byte(`[{"metric": "join-stall", "window": "30s"}]`), 0644)
+	rules, err := ReadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].Name != JoinStall || time.Duration(rules[0].Window) != 30*time.Second {
+		t.Errorf("unexpected rules: %+v", rules)
+	}
+
+	ioutil.WriteFile(path, []byte(`[{"metric": "error-rate", "above": 2, "window": "1m"}]`), 0644)
+	if _, err := ReadRules(path); err == nil {
+		t.Error("expected an error for an out-of-range rate")
+	}
+	ioutil.WriteFile(path, []byte(`[{"metric": "bogus", "window": "1m"}]`), 0644)
+	if _, err := ReadRules(path); err == nil {
+		t.Error("expected an error for an unknown metric")
+	}
+}
+
+func TestErrorRate(t *testing.T) {
+	e := newTestEngine(Rule{Name: "errors", Metric: ErrorRate, Above: 0.1,
+		Window: Duration(time.Minute)})
+	start := time.Now()
+	for i := 0; i < 9; i++ {
+		e.observe(kahoot.Event{Topic: kahoot.TopicQuestion, Type: "answered", Time: start})
+	}
+	e.observe(kahoot.Event{Topic: kahoot.TopicError, Type: "send", Time: start,
+		Data: errors.New("x")})
+	if alerts := e.check(start); len(alerts) != 0 {
+		t.Fatalf("10%% should not fire: %v", alerts)
+	}
+	e.observe(kahoot.Event{Topic: kahoot.TopicError, Type: "send", Time: start,
+		Data: errors.New("x")})
+	alerts := e.check(start)
+	if len(alerts) != 1 || !alerts[0].Firing || alerts[0].Rule != "errors" {
+		t.Fatalf("expected a firing alert, got %v", alerts)
+	}
+	if alerts := e.check(start.Add(time.Second)); len(alerts) != 0 {
+		t.Fatalf("alert should only fire once: %v", alerts)
+	}
+	alerts = e.check(start.Add(2 * time.Minute))
+	if len(alerts) != 1 || alerts[0].Firing {
+		t.Fatalf("expected a resolved alert, got %v", alerts)
+	}
+}
+
+func TestJoinStall(t *testing.T) {
+	e := newTestEngine(Rule{Name: "stalled", Metric: JoinStall,
+		Window: Duration(30 * time.Second)})
+	start := time.Now()
+	e.observe(kahoot.Event{Topic: kahoot.TopicConnection, Type: "joining", Time: start, Data: 2})
+	e.observe(kahoot.Event{Topic: kahoot.TopicConnection, Type: "joined", Time: start.Add(time.Second)})
+	if alerts := e.check(start.Add(20 * time.Second)); len(alerts) != 0 {
+		t.Fatalf("unexpected alerts: %v", alerts)
+	}
+	alerts := e.check(start.Add(31 * time.Second))
+	if len(alerts) != 1 || !alerts[0].Firing {
+		t.Fatalf("expected a firing alert, got %v", alerts)
+	}
+	e.observe(kahoot.Event{Topic: kahoot.TopicError, Type: "join", Time: start.Add(40 * time.Second),
+		Data: errors.New("x")})
+	alerts = e.check(start.Add(41 * time.Second))
+	if len(alerts) != 1 || alerts[0].Firing {
+		t.Fatalf("expected a resolved alert, got %v", alerts)
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Alert, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a Alert
+		json.NewDecoder(r.Body).Decode(&a)
+		received <- a
+	}))
+	defer server.Close()
+
+	e := newTestEngine(Rule{Name: "stalled", Metric: JoinStall, Webhook: server.URL})
+	e.send(&Alert{Rule: "stalled", Firing: true, Message: "hello"})
+	select {
+	case a := <-received:
+		if a.Message != "hello" || !a.Firing {
+			t.Errorf("unexpected alert: %+v", a)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not called")
+	}
+}
+
+func newTestEngine(rules ...Rule) *Engine {
+	return &Engine{
+		rules:  rules,
+		client: http.DefaultClient,
+		firing: map[string]bool{},
+	}
+}
@@ -0,0 +1,52 @@
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+
+	l, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append("qa", "start", map[string]interface{}{"gamePin": "123", "count": 5})
+	l.Append("qa", "stop", nil)
+	l.Close()
+
+	// Reopening must continue the chain.
+	l, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.Append("ops", "start", map[string]interface{}{"gamePin": "456"})
+	l.Close()
+
+	last, err := Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Seq != 3 || last.Actor != "ops" {
+		t.Errorf("unexpected last entry: %+v", last)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	tampered := strings.Replace(string(data), `"gamePin":"123"`, `"gamePin":"999"`, 1)
+	ioutil.WriteFile(path, []byte(tampered), 0600)
+	if _, err := Verify(path); err == nil {
+		t.Error("expected tampering to be detected")
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("expected Open to refuse a tampered log")
+	}
+}
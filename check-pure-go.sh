@@ -0,0 +1,25 @@
+#!/bin/bash
+#
+# Checks that every tool still builds without cgo, so that it can be
+# cross-compiled for ARM boards such as the Raspberry Pi with nothing
+# but the Go toolchain.
+#
+# No package may use cgo, including the database drivers in
+# sqlsink/drivers; new dependencies must be pure Go.
+
+cd "$(dirname "$0")"
+status=0
+
+for pkg in $(CGO_ENABLED=1 go list -deps -f '{{if and .CgoFiles (not .Standard)}}{{.ImportPath}}{{end}}' ./...); do
+	echo "uses cgo: $pkg"
+	status=1
+done
+
+for target in linux/arm linux/arm64 linux/amd64; do
+	echo "Building for $target without cgo... Please wait"
+	if ! GOOS=${target%/*} GOARCH=${target#*/} GOARM=7 CGO_ENABLED=0 go build ./...; then
+		status=1
+	fi
+done
+
+exit $status
@@ -0,0 +1,176 @@
+// Package compare summarizes run workspaces and reports how
+// two runs differ.
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+// A Run holds the measurements of one run workspace.
+type Run struct {
+	Dir string
+
+	Joined int
+	Failed int
+
+	// JoinLatencies are in milliseconds.
+	JoinLatencies []float64
+
+	// FinalScores holds each bot's last reported total score.
+	FinalScores []float64
+
+	Answers int
+	Correct int
+}
+
+// LoadRun reads a run's summary and recordings.
+func LoadRun(dir string) (*Run, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		return nil, err
+	}
+	var summary struct {
+		Joined        int       `json:"joined"`
+		Failed        int       `json:"failed"`
+		JoinLatencies []float64 `json:"joinLatenciesMs"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return nil, fmt.Errorf("parse summary: %s", err)
+	}
+	run := &Run{
+		Dir:           dir,
+		Joined:        summary.Joined,
+		Failed:        summary.Failed,
+		JoinLatencies: summary.JoinLatencies,
+	}
+
+	recordings, err := workspace.ReadRecordings(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, events := range recordings {
+		var final *float64
+		for _, e := range events {
+			if e.Topic != kahoot.TopicResult || e.Type != "result" {
+				continue
+			}
+			var result kahoot.QuizResult
+			if json.Unmarshal(e.Data, &result) != nil {
+				continue
+			}
+			run.Answers++
+			if result.IsCorrect {
+				run.Correct++
+			}
+			score := result.TotalScore
+			final = &score
+		}
+		if final != nil {
+			run.FinalScores = append(run.FinalScores, *final)
+		}
+	}
+	return run, nil
+}
+
+// JoinRate returns the fraction of bots which joined.
+func (r *Run) JoinRate() float64 {
+	if r.Joined+r.Failed == 0 {
+		return math.NaN()
+	}
+	return float64(r.Joined) / float64(r.Joined+r.Failed)
+}
+
+// Accuracy returns the fraction of results which were
+// correct.
+func (r *Run) Accuracy() float64 {
+	if r.Answers == 0 {
+		return math.NaN()
+	}
+	return float64(r.Correct) / float64(r.Answers)
+}
+
+// A Metric is one row of a comparison.
+type Metric struct {
+	Name string
+	A, B float64
+}
+
+// Delta returns B - A.
+func (m Metric) Delta() float64 {
+	return m.B - m.A
+}
+
+// Compare measures the same metrics for two runs.
+func Compare(a, b *Run) []Metric {
+	metrics := []Metric{
+		{"bots joined", float64(a.Joined), float64(b.Joined)},
+		{"bots failed", float64(a.Failed), float64(b.Failed)},
+		{"join success rate", a.JoinRate(), b.JoinRate()},
+	}
+	for _, p := range []float64{50, 90, 99} {
+		metrics = append(metrics, Metric{
+			fmt.Sprintf("join latency p%g (ms)", p),
+			Percentile(a.JoinLatencies, p),
+			Percentile(b.JoinLatencies, p),
+		})
+	}
+	metrics = append(metrics, Metric{"answer accuracy", a.Accuracy(), b.Accuracy()})
+	for _, p := range []float64{10, 50, 90} {
+		metrics = append(metrics, Metric{
+			fmt.Sprintf("final score p%g", p),
+			Percentile(a.FinalScores, p),
+			Percentile(b.FinalScores, p),
+		})
+	}
+	return metrics
+}
+
+// Percentile returns the p-th percentile of values using
+// linear interpolation, or NaN if there are no values.
+func Percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	sorted := append([]float64{}, values...)
+	sort.Float64s(sorted)
+	pos := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(pos))
+	upper := int(math.Ceil(pos))
+	frac := pos - float64(lower)
+	return sorted[lower]*(1-frac) + sorted[upper]*frac
+}
+
+// WriteTable prints metrics as an aligned table.
+func WriteTable(w io.Writer, a, b string, metrics []Metric) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "metric\t%s\t%s\tdelta\t\n", a, b)
+	for _, m := range metrics {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", m.Name, format(m.A), format(m.B),
+			formatDelta(m.Delta()))
+	}
+	return tw.Flush()
+}
+
+func format(x float64) string {
+	if math.IsNaN(x) {
+		return "-"
+	}
+	return fmt.Sprintf("%.4g", x)
+}
+
+func formatDelta(x float64) string {
+	if math.IsNaN(x) {
+		return "-"
+	}
+	return fmt.Sprintf("%+.4g", x)
+}
@@ -0,0 +1,59 @@
+package compare
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+func TestPercentile(t *testing.T) {
+	values := []float64{40, 10, 30, 20}
+	for _, c := range []struct{ p, expected float64 }{{0, 10}, {50, 25}, {100, 40}} {
+		if actual := Percentile(values, c.p); actual != c.expected {
+			t.Errorf("p%g: expected %g but got %g", c.p, c.expected, actual)
+		}
+	}
+	if !math.IsNaN(Percentile(nil, 50)) {
+		t.Error("expected NaN for no values")
+	}
+}
+
+func TestLoadRun(t *testing.T) {
+	root, err := ioutil.TempDir("", "compare")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	ws, err := workspace.Create(root, "flood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	ws.WriteSummary(map[string]interface{}{"joined": 3, "failed": 1, "joinLatenciesMs": []float64{100, 200, 300}})
+	bus := kahoot.NewBus()
+	sub := bus.Subscribe()
+	bus.Publish(kahoot.TopicResult, "result", &kahoot.QuizResult{IsCorrect: true, TotalScore: 900})
+	bus.Publish(kahoot.TopicResult, "result", &kahoot.QuizResult{TotalScore: 900})
+	bus.Close()
+	ws.Record("alex", sub)
+
+	run, err := LoadRun(ws.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.JoinRate() != 0.75 || run.Accuracy() != 0.5 || len(run.FinalScores) != 1 {
+		t.Errorf("unexpected run: %+v", run)
+	}
+
+	var buf bytes.Buffer
+	WriteTable(&buf, "a", "b", Compare(run, &Run{}))
+	if !strings.Contains(buf.String(), "join latency p50 (ms)") {
+		t.Errorf("unexpected table:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,137 @@
+// Package control exposes a tiny HTTP API for driving a swarm
+// live, designed to be bound to Stream Deck buttons.
+//
+// Every endpoint accepts both GET and POST, since most button
+// integrations can only open a URL:
+//
+//	/next-strategy   switch all bots to the next strategy
+//	/answer-now      make waiting bots answer immediately
+//	/pause           stop bots from answering
+//	/resume          let paused bots answer again
+//	/leave?bots=SEL  make bots leave the game
+//	/tag?tag=T       tag bots, for later selectors to use
+//	/add-bots?n=N    join N more bots (10 by default)
+//	/add-10-bots     join 10 more bots
+//	/trace?bot=NAME  toggle wire tracing for a bot (or set it
+//	                 with on=true or on=false)
+//
+// /answer-now, /pause, /resume, and /tag apply to every bot,
+// or to those picked by a bots parameter such as
+// bots=tag:loud,0-9,alex* (see swarm.ParseSelector). /leave
+// needs the parameter, so that one mistyped URL cannot empty
+// the game; use bots=all to make every bot leave.
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+const defaultAddCount = 10
+
+// maxAddCount limits how many bots a single request may add.
+const maxAddCount = 100
+
+// A Handler serves control requests for a swarm.
+type Handler struct {
+	Swarm *swarm.Swarm
+
+	// Prefix is used to name bots which are added.
+	Prefix string
+}
+
+type response struct {
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Strategy string `json:"strategy,omitempty"`
+	Bots     int    `json:"bots"`
+	Tracing  *bool  `json:"tracing,omitempty"`
+
+	// Selected is how many bots a command applied to.
+	Selected *int `json:"selected,omitempty"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var err error
+	var tracing *bool
+	var selected *int
+	sel, err := swarm.ParseSelector(r.FormValue("bots"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	count := func(n int) {
+		selected = &n
+	}
+	switch r.URL.Path {
+	case "/next-strategy":
+		h.Swarm.NextStrategy()
+	case "/answer-now":
+		count(h.Swarm.AnswerNowSelected(sel))
+	case "/pause":
+		count(h.Swarm.Pause(sel, true))
+	case "/resume":
+		count(h.Swarm.Pause(sel, false))
+	case "/leave":
+		if r.FormValue("bots") == "" {
+			http.Error(w, "missing bots parameter", http.StatusBadRequest)
+			return
+		}
+		count(h.Swarm.Leave(sel))
+	case "/tag":
+		tag := r.FormValue("tag")
+		if tag == "" {
+			http.Error(w, "missing tag", http.StatusBadRequest)
+			return
+		}
+		count(h.Swarm.Tag(sel, tag))
+	case "/add-10-bots":
+		err = h.Swarm.AddBots(defaultAddCount, h.Prefix)
+	case "/add-bots":
+		n := defaultAddCount
+		if nStr := r.FormValue("n"); nStr != "" {
+			n, err = strconv.Atoi(nStr)
+			if err != nil || n < 1 || n > maxAddCount {
+				http.Error(w, "invalid bot count", http.StatusBadRequest)
+				return
+			}
+		}
+		err = h.Swarm.AddBots(n, h.Prefix)
+	case "/trace":
+		bot := r.FormValue("bot")
+		on := !h.Swarm.Tracing(bot)
+		if onStr := r.FormValue("on"); onStr != "" {
+			on, err = strconv.ParseBool(onStr)
+			if err != nil {
+				http.Error(w, "invalid on value", http.StatusBadRequest)
+				return
+			}
+		}
+		err = h.Swarm.SetTracing(bot, on)
+		actual := h.Swarm.Tracing(bot)
+		tracing = &actual
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	resp := response{OK: err == nil, Strategy: h.Swarm.Strategy(), Tracing: tracing, Selected: selected}
+	if err != nil {
+		resp.Error = err.Error()
+		w.WriteHeader(http.StatusBadGateway)
+	}
+	for _, bot := range h.Swarm.Bots() {
+		if bot.Err == nil {
+			resp.Bots++
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,64 @@
+package control
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+func TestNextStrategy(t *testing.T) {
+	h := &Handler{Swarm: swarm.New("123", swarm.Options{}), Prefix: "bot"}
+	for _, expected := range []string{"random", "idle"} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/next-strategy", nil))
+		var resp response
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if !resp.OK || resp.Strategy != expected {
+			t.Errorf("expected strategy %s but got %+v", expected, resp)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/add-bots?n=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected bad request but got %d", rec.Code)
+	}
+}
+
+func TestTraceUnknownBot(t *testing.T) {
+	h := &Handler{Swarm: swarm.New("123", swarm.Options{}), Prefix: "bot"}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/trace?bot=nobody", nil))
+	var resp response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.OK || resp.Tracing == nil || *resp.Tracing {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	h := &Handler{Swarm: swarm.New("123", swarm.Options{}), Prefix: "bot"}
+	for _, c := range []struct {
+		url  string
+		code int
+	}{
+		{"/pause?bots=tag:", http.StatusBadRequest},
+		{"/leave", http.StatusBadRequest},
+		{"/tag?bots=0-3", http.StatusBadRequest},
+		{"/pause?bots=tag:loud,0-3,alex*", http.StatusOK},
+		{"/leave?bots=all", http.StatusOK},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", c.url, nil))
+		if rec.Code != c.code {
+			t.Errorf("%s: expected status %d but got %d", c.url, c.code, rec.Code)
+		}
+	}
+}
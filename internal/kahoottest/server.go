@@ -0,0 +1,590 @@
+// Package kahoottest runs a fake Kahoot server for tests.
+//
+// The server answers reserve requests with a challenge and an
+// encoded session token, speaks enough Bayeux over WebSocket
+// for clients to handshake, subscribe, and log in, and plays a
+// scripted quiz to whoever has logged in. It records logins
+// and answers so that tests can check what a client sent.
+//
+// The package does not depend on the kahoot package, so that
+// kahoot's own tests can use it. Point the client at the
+// server with ReserveURL, DialAddr, and CometdURL.
+package kahoottest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SelfTestChallenge is a challenge in the shape the real
+// server sends. Its answer is SelfTestMask.
+const SelfTestChallenge = "decode.call(this, 'Kahoot'); function decode(message) " +
+	"{var offset = 3 * (2 + 1); if (this.angular.isObject(offset)) " +
+	"{console.log(\"Offset derived as: {\", offset, \"}\");}" +
+	"return _.replace(message, /./g, function(char, position) " +
+	"{return String.fromCharCode((((char.charCodeAt(0) * position) + offset) % 77) + 48);});}"
+
+// SelfTestMask is the answer to SelfTestChallenge.
+const SelfTestMask = "9MoRtb"
+
+// Player channel message ids the server sends.
+const (
+	questionIntroId   = 1
+	questionAnswersId = 2
+	gameOverId        = 3
+	revealAnswerId    = 8
+	kickedId          = 10
+	quizEndId         = 13
+	recoveryDataId    = 17
+)
+
+// Controller message ids the server understands.
+const (
+	requestRecoveryId = 16
+	answerId          = 45
+)
+
+// A Question is one question of the scripted quiz.
+type Question struct {
+	// NumAnswers is the number of choices. It defaults to 4.
+	NumAnswers int
+
+	// Correct is the index of the correct choice.
+	Correct int
+
+	// Type is the gameBlockType sent with the question, such
+	// as "quiz" or "survey". It defaults to "quiz".
+	Type string
+
+	// Intro is how long the question is introduced before it
+	// accepts answers, and Time how long it accepts them. The
+	// question closes early once every player has answered.
+	Intro time.Duration
+	Time  time.Duration
+}
+
+// Config configures a Server. The zero value serves one game
+// with the self-test challenge and no questions.
+type Config struct {
+	// Pin is the only game pin the server knows, or empty to
+	// accept any pin.
+	Pin string
+
+	// Challenge is sent with every reserve response, and Mask
+	// is its answer, with which the session token is encoded.
+	// They default to SelfTestChallenge and SelfTestMask.
+	Challenge string
+	Mask      string
+
+	// Token is the session token, "token" by default.
+	Token string
+
+	// Reserve fields describing the game.
+	TwoFactorAuth bool
+	Namerator     bool
+	GameMode      string
+
+	// LoginError, if set, is the error code every login is
+	// refused with.
+	LoginError string
+
+	// Questions are played by Start.
+	Questions []Question
+}
+
+// An Answer is one answer a player sent.
+type Answer struct {
+	Nickname string
+	Question int
+	Choice   int
+}
+
+// A Server is a fake Kahoot server, listening on a local port.
+type Server struct {
+	config Config
+	http   *httptest.Server
+
+	lock        sync.Mutex
+	reserves    int
+	connections int
+	players     []*player
+	logins      []string
+	answers     []Answer
+
+	// state is the recovery state: 0 in the lobby, 2 while a
+	// question is introduced, 3 while it accepts answers, 4
+	// once its result is shown, and 5 once the quiz is over.
+	state    int
+	question int
+	answered chan struct{}
+}
+
+type player struct {
+	ws        *websocket.Conn
+	writeLock sync.Mutex
+	cid       string
+	nickname  string
+
+	// choices maps question indices to the player's answer.
+	choices map[int]int
+}
+
+// NewServer starts a server with the given configuration.
+// Close it when the test is done.
+func NewServer(config Config) *Server {
+	if config.Challenge == "" {
+		config.Challenge = SelfTestChallenge
+		if config.Mask == "" {
+			config.Mask = SelfTestMask
+		}
+	}
+	if config.Token == "" {
+		config.Token = "token"
+	}
+	s := &Server{config: config, question: -1}
+	s.http = httptest.NewServer(s)
+	return s
+}
+
+// Close stops the server and drops every connection.
+func (s *Server) Close() {
+	s.lock.Lock()
+	players := s.players
+	s.lock.Unlock()
+	for _, p := range players {
+		p.ws.Close()
+	}
+	s.http.Close()
+}
+
+// ReserveURL is the URL to reserve sessions at, to which the
+// game pin is appended.
+func (s *Server) ReserveURL() string {
+	return s.http.URL + "/reserve/session/"
+}
+
+// DialAddr is the host and port to dial.
+func (s *Server) DialAddr() string {
+	return strings.TrimPrefix(s.http.URL, "http://")
+}
+
+// CometdURL is the WebSocket URL, to which the game pin and
+// session token are appended.
+func (s *Server) CometdURL() string {
+	return "ws://" + s.DialAddr() + "/cometd/"
+}
+
+// Reserves returns the number of reserve requests served.
+func (s *Server) Reserves() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.reserves
+}
+
+// Connections returns the number of WebSocket connections
+// accepted.
+func (s *Server) Connections() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.connections
+}
+
+// Logins returns the nicknames of every login, in order.
+func (s *Server) Logins() []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, s.logins...)
+}
+
+// Answers returns every answer received, in order.
+func (s *Server) Answers() []Answer {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]Answer{}, s.answers...)
+}
+
+// ServeHTTP serves reserve requests and WebSocket connections.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, "/reserve/session/") {
+		s.serveReserve(w, strings.TrimPrefix(r.URL.Path, "/reserve/session/"))
+	} else if strings.HasPrefix(r.URL.Path, "/cometd/") {
+		s.serveCometd(w, r)
+	} else {
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveReserve(w http.ResponseWriter, pin string) {
+	s.lock.Lock()
+	s.reserves++
+	s.lock.Unlock()
+	if s.config.Pin != "" && pin != s.config.Pin {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("Not found"))
+		return
+	}
+	token := []byte(s.config.Token)
+	for i := range token {
+		if s.config.Mask != "" {
+			token[i] ^= s.config.Mask[i%len(s.config.Mask)]
+		}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"challenge":     s.config.Challenge,
+		"twoFactorAuth": s.config.TwoFactorAuth,
+		"namerator":     s.config.Namerator,
+		"gameMode":      s.config.GameMode,
+	})
+	w.Header().Set("X-Kahoot-Session-Token", base64.StdEncoding.EncodeToString(token))
+	w.Write(body)
+}
+
+func (s *Server) serveCometd(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/cometd/"), "/")
+	if len(parts) != 2 || parts[1] != s.config.Token ||
+		(s.config.Pin != "" && parts[0] != s.config.Pin) {
+		http.Error(w, "bad session", http.StatusForbidden)
+		return
+	}
+	upgrader := websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	s.lock.Lock()
+	s.connections++
+	p := &player{
+		ws:      ws,
+		cid:     strconv.Itoa(s.connections),
+		choices: map[int]int{},
+	}
+	s.players = append(s.players, p)
+	s.lock.Unlock()
+	defer s.remove(p)
+
+	for {
+		var msgs []map[string]interface{}
+		if ws.ReadJSON(&msgs) != nil {
+			return
+		}
+		for _, msg := range msgs {
+			s.handle(p, msg)
+		}
+	}
+}
+
+func (s *Server) remove(p *player) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for i, x := range s.players {
+		if x == p {
+			s.players = append(s.players[:i], s.players[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *Server) handle(p *player, msg map[string]interface{}) {
+	channel, _ := msg["channel"].(string)
+	switch channel {
+	case "/meta/handshake":
+		p.write(map[string]interface{}{"channel": channel, "successful": true,
+			"clientId": "client" + p.cid})
+	case "/meta/subscribe":
+		p.write(map[string]interface{}{"channel": channel, "successful": true,
+			"subscription": msg["subscription"]})
+	case "/meta/connect", "/meta/disconnect":
+		p.write(map[string]interface{}{"channel": channel, "successful": true})
+	case "/service/controller":
+		data, _ := msg["data"].(map[string]interface{})
+		p.write(map[string]interface{}{"channel": channel, "successful": true})
+		if data["type"] == "login" {
+			s.login(p, data)
+		} else if id, _ := data["id"].(float64); id == answerId {
+			s.answer(p, data)
+		} else if id == requestRecoveryId {
+			s.recover(p)
+		}
+	}
+}
+
+func (s *Server) login(p *player, data map[string]interface{}) {
+	name, _ := data["name"].(string)
+	s.lock.Lock()
+	s.logins = append(s.logins, name)
+	p.nickname = name
+	s.lock.Unlock()
+	resp := map[string]interface{}{"type": "loginResponse", "cid": p.cid}
+	if s.config.LoginError != "" {
+		resp["error"] = s.config.LoginError
+		resp["description"] = "refused by kahoottest"
+	}
+	p.write(map[string]interface{}{"channel": "/service/controller", "data": resp})
+}
+
+func (s *Server) answer(p *player, data map[string]interface{}) {
+	var content struct {
+		Choice interface{} `json:"choice"`
+	}
+	str, _ := data["content"].(string)
+	if json.Unmarshal([]byte(str), &content) != nil {
+		return
+	}
+	choice, ok := content.Choice.(float64)
+	if !ok {
+		choice = -1
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.state != 3 {
+		return
+	}
+	if _, ok := p.choices[s.question]; ok {
+		return
+	}
+	p.choices[s.question] = int(choice)
+	s.answers = append(s.answers, Answer{Nickname: p.nickname, Question: s.question, Choice: int(choice)})
+	if s.answered != nil && s.allAnswered() {
+		close(s.answered)
+		s.answered = nil
+	}
+}
+
+// allAnswered reports whether every logged-in player answered
+// the current question. The caller holds the lock.
+func (s *Server) allAnswered() bool {
+	for _, p := range s.players {
+		if _, ok := p.choices[s.question]; !ok && p.nickname != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) recover(p *player) {
+	s.lock.Lock()
+	state, question := s.state, s.question
+	s.lock.Unlock()
+	data := map[string]interface{}{}
+	if question >= 0 && question < len(s.config.Questions) {
+		data = s.questionContent(question)
+	}
+	p.send(recoveryDataId, map[string]interface{}{"state": state, "data": data})
+}
+
+// Start plays the scripted questions to every player which has
+// logged in, then ends the quiz. It returns once the game is
+// over.
+func (s *Server) Start() {
+	for i, q := range s.config.Questions {
+		s.setState(2, i)
+		s.Broadcast(questionIntroId, s.questionContent(i))
+		time.Sleep(q.Intro)
+
+		answered := make(chan struct{})
+		s.lock.Lock()
+		s.state = 3
+		s.answered = answered
+		if s.allAnswered() {
+			close(answered)
+			s.answered = nil
+		}
+		s.lock.Unlock()
+		s.Broadcast(questionAnswersId, s.questionContent(i))
+		select {
+		case <-answered:
+		case <-time.After(q.Time):
+		}
+
+		s.setState(4, i)
+		s.reveal(i)
+	}
+	s.setState(5, len(s.config.Questions)-1)
+	s.end()
+	s.Broadcast(gameOverId, map[string]interface{}{})
+}
+
+func (s *Server) setState(state, question int) {
+	s.lock.Lock()
+	s.state, s.question = state, question
+	s.answered = nil
+	s.lock.Unlock()
+}
+
+func (s *Server) questionContent(index int) map[string]interface{} {
+	var counts []interface{}
+	for _, q := range s.config.Questions {
+		counts = append(counts, numAnswers(q))
+	}
+	q := s.config.Questions[index]
+	answerMap := map[string]interface{}{}
+	for i := 0; i < numAnswers(q); i++ {
+		answerMap[strconv.Itoa(i)] = i
+	}
+	blockType := q.Type
+	if blockType == "" {
+		blockType = "quiz"
+	}
+	return map[string]interface{}{
+		"questionIndex":       index,
+		"quizQuestionAnswers": counts,
+		"answerMap":           answerMap,
+		"gameBlockType":       blockType,
+		"timeAvailable":       q.Time / time.Millisecond,
+	}
+}
+
+func numAnswers(q Question) int {
+	if q.NumAnswers <= 0 {
+		return 4
+	}
+	return q.NumAnswers
+}
+
+// reveal tells each player how it did on a question.
+func (s *Server) reveal(index int) {
+	correct := s.config.Questions[index].Correct
+	for _, p := range s.loggedIn() {
+		s.lock.Lock()
+		choice, ok := p.choices[index]
+		if !ok {
+			choice = -1
+		}
+		score := s.score(p)
+		s.lock.Unlock()
+		p.send(revealAnswerId, map[string]interface{}{
+			"choice":     choice,
+			"isCorrect":  choice == correct,
+			"points":     pointsFor(choice == correct),
+			"totalScore": score,
+			"rank":       1,
+		})
+	}
+}
+
+// end sends each player its final standing.
+func (s *Server) end() {
+	for _, p := range s.loggedIn() {
+		s.lock.Lock()
+		var correct int
+		for i, q := range s.config.Questions {
+			if choice, ok := p.choices[i]; ok && choice == q.Correct {
+				correct++
+			}
+		}
+		score := s.score(p)
+		s.lock.Unlock()
+		p.send(quizEndId, map[string]interface{}{
+			"rank":           1,
+			"totalScore":     score,
+			"correctCount":   correct,
+			"incorrectCount": len(s.config.Questions) - correct,
+		})
+	}
+}
+
+// score is a player's total so far. The caller holds the lock.
+func (s *Server) score(p *player) int {
+	var total int
+	for i, q := range s.config.Questions {
+		if choice, ok := p.choices[i]; ok {
+			total += pointsFor(choice == q.Correct)
+		}
+	}
+	return total
+}
+
+func pointsFor(correct bool) int {
+	if correct {
+		return 1000
+	}
+	return 0
+}
+
+// Broadcast sends a player channel message with the given id
+// and content to every player which has logged in.
+func (s *Server) Broadcast(id int, content interface{}) {
+	for _, p := range s.loggedIn() {
+		p.send(id, content)
+	}
+}
+
+// Publish sends a message with the given data on any channel
+// to every player which has logged in, whether or not it
+// subscribed to the channel.
+func (s *Server) Publish(channel string, data map[string]interface{}) {
+	for _, p := range s.loggedIn() {
+		p.write(map[string]interface{}{"channel": channel, "data": data})
+	}
+}
+
+// Kick removes a player from the game with the given kick
+// code. It reports whether the player was found.
+func (s *Server) Kick(nickname string, code int) bool {
+	p := s.find(nickname)
+	if p == nil {
+		return false
+	}
+	p.send(kickedId, map[string]interface{}{"kickCode": code})
+	return true
+}
+
+// Drop closes a player's connection without a word, as when
+// the network fails. It reports whether the player was found.
+func (s *Server) Drop(nickname string) bool {
+	p := s.find(nickname)
+	if p == nil {
+		return false
+	}
+	p.ws.Close()
+	return true
+}
+
+func (s *Server) find(nickname string) *player {
+	for _, p := range s.loggedIn() {
+		if p.nickname == nickname {
+			return p
+		}
+	}
+	return nil
+}
+
+func (s *Server) loggedIn() []*player {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var res []*player
+	for _, p := range s.players {
+		if p.nickname != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+func (p *player) send(id int, content interface{}) {
+	encoded, _ := json.Marshal(content)
+	p.write(map[string]interface{}{
+		"channel": "/service/player",
+		"data": map[string]interface{}{
+			"id":      id,
+			"type":    "message",
+			"content": string(encoded),
+		},
+	})
+}
+
+func (p *player) write(msg map[string]interface{}) {
+	p.writeLock.Lock()
+	defer p.writeLock.Unlock()
+	p.ws.WriteJSON([]map[string]interface{}{msg})
+}
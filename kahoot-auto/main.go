@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"strconv"
 	"syscall"
 
 	"github.com/howeyc/gopass"
 	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/tokenstore"
 )
 
 // ParseQuizInformation parses quiz information
@@ -17,12 +19,11 @@ import (
 // default answer number, and default answer color.
 func ParseQuizInformation(data *kahoot.QuizInfo) [][]string {
 	var results [][]string
-	colormap := map[int]string{0: "red", 1: "blue", 2: "yellow", 3: "blue"}
 	for _, value := range data.Questions {
 		var questiondata []string
 		for i, choice := range value.Choices {
 			if choice.Correct == true {
-				questiondata = append(questiondata, value.Question, choice.Answer, strconv.Itoa(i), colormap[i])
+				questiondata = append(questiondata, value.Question, choice.Answer, strconv.Itoa(i), kahoot.ChoiceStyleAt(i).Color)
 				break
 			}
 		}
@@ -38,6 +39,37 @@ func Prompt(question string) string {
 	return response
 }
 
+// creatorToken returns a stored access token for the
+// account, logging in and storing a new one if necessary.
+func creatorToken(email string) (string, error) {
+	storePath := filepath.Join(os.Getenv("HOME"), ".kahoot-hack", "tokens.json")
+	store, err := tokenstore.Default(storePath, func() (string, error) {
+		fmt.Print("token store passphrase > ")
+		pass, err := gopass.GetPasswdMasked()
+		return string(pass), err
+	})
+	if err != nil {
+		return "", err
+	}
+	if token, err := store.Load(email); err == nil && !token.Expired() {
+		return token.Value, nil
+	}
+
+	fmt.Print("password > ")
+	password, err := gopass.GetPasswdMasked()
+	if err != nil {
+		return "", err
+	}
+	value, expires, err := kahoot.AccessTokenExpiry(email, string(password))
+	if err != nil {
+		return "", err
+	}
+	if err := store.Save(email, &tokenstore.Token{Value: value, Expires: expires}); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: could not save token:", err)
+	}
+	return value, nil
+}
+
 func main() {
 	argnum := len(os.Args)
 	if argnum != 5 && argnum != 4 {
@@ -54,12 +86,7 @@ func main() {
 	} else {
 		email = os.Args[4]
 	}
-	fmt.Print("password > ")
-	password, err := gopass.GetPasswdMasked()
-	if err != nil {
-		panic(err)
-	}
-	token, err := kahoot.AccessToken(email, string(password))
+	token, err := creatorToken(email)
 	if err != nil {
 		panic(err)
 	}
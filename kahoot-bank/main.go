@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/howeyc/gopass"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/questionbank"
+	"github.com/unixpickle/kahoot-hack/tokenstore"
+)
+
+func main() {
+	bankPath := flag.String("bank", questionbank.DefaultPath, "question bank file")
+	tag := flag.String("tag", "", "only list or export questions with this tag")
+	flag.Usage = usage
+	flag.Parse()
+	args := flag.Args()
+	if len(args) < 1 {
+		usage()
+	}
+
+	bank, err := questionbank.Load(*bankPath)
+	if err != nil {
+		die(err)
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			usage()
+		}
+		for _, dir := range args[1:] {
+			added, err := bank.AddRun(dir)
+			if err != nil {
+				die(err)
+			}
+			fmt.Printf("%s: %d new questions\n", dir, added)
+		}
+	case "list":
+		for _, q := range bank.Tagged(*tag) {
+			list(q)
+		}
+		return
+	case "tag":
+		if len(args) < 3 {
+			usage()
+		}
+		q := find(bank, args[1])
+		for _, t := range args[2:] {
+			q.AddTag(t)
+		}
+	case "text":
+		if len(args) != 3 {
+			usage()
+		}
+		find(bank, args[1]).Text = args[2]
+	case "choice":
+		if len(args) != 4 {
+			usage()
+		}
+		q := find(bank, args[1])
+		var index int
+		if _, err := fmt.Sscan(args[2], &index); err != nil || index < 1 || index > q.NumAnswers {
+			die("choice must be a number from 1 to " + fmt.Sprint(q.NumAnswers))
+		}
+		q.Choices[index-1] = args[3]
+	case "export":
+		if len(args) != 3 {
+			usage()
+		}
+		export(bank, *tag, args[1], args[2])
+		return
+	default:
+		usage()
+	}
+	if err := bank.Save(*bankPath); err != nil {
+		die(err)
+	}
+}
+
+func list(q *questionbank.Question) {
+	text := q.Text
+	if text == "" {
+		text = "(no text)"
+	}
+	fmt.Printf("%s  %s", q.Key, text)
+	if len(q.Tags) > 0 {
+		fmt.Printf("  [%s]", strings.Join(q.Tags, ", "))
+	}
+	fmt.Printf("  seen %d times\n", len(q.Sightings))
+	for i, c := range q.Choices {
+		mark := " "
+		for _, correct := range q.Correct {
+			if correct == i {
+				mark = "*"
+			}
+		}
+		if c == "" {
+			c = "?"
+		}
+		fmt.Printf("    %s %d. %s\n", mark, i+1, c)
+	}
+}
+
+func find(bank *questionbank.Bank, key string) *questionbank.Question {
+	q := bank.Find(key)
+	if q == nil {
+		die("no single question matches " + key)
+	}
+	return q
+}
+
+func export(bank *questionbank.Bank, tag, title, email string) {
+	questions := bank.Tagged(tag)
+	if len(questions) == 0 {
+		die("no questions to export")
+	}
+	for _, q := range questions {
+		if !q.Known() {
+			fmt.Fprintln(os.Stderr, "warning: question", q.Key, "has unknown answers; placeholders are used")
+		}
+	}
+	quiz := questionbank.Quiz(title, questions)
+	if email == "-" {
+		data, _ := json.MarshalIndent(quiz, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	token, err := creatorToken(email)
+	if err != nil {
+		die(err)
+	}
+	saved, err := kahoot.CreateQuiz(token, quiz)
+	if err != nil {
+		die(err)
+	}
+	fmt.Println("created quiz", saved.Uuid)
+}
+
+// creatorToken returns a stored access token for the
+// account, logging in and storing a new one if necessary.
+func creatorToken(email string) (string, error) {
+	storePath := filepath.Join(os.Getenv("HOME"), ".kahoot-hack", "tokens.json")
+	store, err := tokenstore.Default(storePath, func() (string, error) {
+		fmt.Print("token store passphrase > ")
+		pass, err := gopass.GetPasswdMasked()
+		return string(pass), err
+	})
+	if err != nil {
+		return "", err
+	}
+	if token, err := store.Load(email); err == nil && !token.Expired() {
+		return token.Value, nil
+	}
+
+	fmt.Print("password > ")
+	password, err := gopass.GetPasswdMasked()
+	if err != nil {
+		return "", err
+	}
+	value, expires, err := kahoot.AccessTokenExpiry(email, string(password))
+	if err != nil {
+		return "", err
+	}
+	if err := store.Save(email, &tokenstore.Token{Value: value, Expires: expires}); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: could not save token:", err)
+	}
+	return value, nil
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: bank [-bank file] add <run directory> [run directory ...]")
+	fmt.Fprintln(os.Stderr, "       bank [-bank file] [-tag tag] list")
+	fmt.Fprintln(os.Stderr, "       bank [-bank file] tag <key> <tag> [tag ...]")
+	fmt.Fprintln(os.Stderr, "       bank [-bank file] text <key> <question text>")
+	fmt.Fprintln(os.Stderr, "       bank [-bank file] choice <key> <1-4> <answer text>")
+	fmt.Fprintln(os.Stderr, "       bank [-bank file] [-tag tag] export <title> <email | ->")
+	os.Exit(1)
+}
+
+func die(err interface{}) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func main() {
+	jsonOutput := flag.Bool("json", false, "print the game's metadata as JSON")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: check [-json] <game pin>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	info, err := kahoot.LookupGameInfo(flag.Arg(0))
+	if errors.Is(err, kahoot.ErrGameNotFound) {
+		fmt.Println("No game with pin", flag.Arg(0))
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "check failed:", err)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		data, _ := json.MarshalIndent(map[string]interface{}{
+			"pin":      info.Pin,
+			"exists":   true,
+			"info":     info,
+			"warnings": info.Warnings(),
+		}, "", "  ")
+		fmt.Println(string(data))
+		return
+	}
+	locked := "unknown"
+	if info.Locked != nil {
+		locked = fmt.Sprint(*info.Locked)
+	}
+	fmt.Println("Game", info.Pin, "exists.")
+	fmt.Println("  locked:        ", locked)
+	fmt.Println("  two-factor:    ", info.TwoFactorAuth)
+	fmt.Println("  namerator:     ", info.Namerator)
+	fmt.Println("  game mode:     ", info.GameMode)
+	for _, w := range info.Warnings() {
+		fmt.Println("Warning:", w)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/unixpickle/kahoot-hack/compare"
+)
+
+func main() {
+	if len(os.Args) != 3 {
+		fmt.Fprintln(os.Stderr, "Usage: compare <run directory A> <run directory B>")
+		os.Exit(1)
+	}
+	var runs [2]*compare.Run
+	for i, dir := range os.Args[1:] {
+		run, err := compare.LoadRun(dir)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, dir+":", err)
+			os.Exit(1)
+		}
+		runs[i] = run
+	}
+	compare.WriteTable(os.Stdout, "A", "B", compare.Compare(runs[0], runs[1]))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/unixpickle/kahoot-hack/parquetexport"
+)
+
+func main() {
+	out := flag.String("out", "dataset", "directory of the Parquet dataset")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: export [-out dir] <run directory> [run directory ...]")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	for _, dir := range flag.Args() {
+		if err := parquetexport.ExportRun(dir, *out); err != nil {
+			fmt.Fprintln(os.Stderr, dir+":", err)
+			os.Exit(1)
+		}
+		fmt.Println("exported", dir)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/manifest"
+	"github.com/unixpickle/kahoot-hack/netem"
+	"github.com/unixpickle/kahoot-hack/swarm"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+// config holds kahoot-flood's flags.
+type config struct {
+	presetName  string
+	ordered     bool
+	concurrency int
+	delay       time.Duration
+	strategy    string
+	fixedAnswer int
+	bandwidth   int
+	network     string
+
+	overlayAddr   string
+	questionsPath string
+	translateTo   string
+	translator    string
+
+	answerDelay    time.Duration
+	personasPath   string
+	personaDelay   time.Duration
+	race           bool
+	reserveRetries int
+	reserveBackoff time.Duration
+	reconnect      int
+	depart         string
+	strict         bool
+	lateJoin       string
+	lobbyTimeout   time.Duration
+	lastMoment     time.Duration
+
+	controlAddr  string
+	telemetryURL string
+	manifestURL  string
+	protocolDir  string
+	maxRequests  int
+	maxBots      int
+	maxAnswers   int
+	remoteEval   string
+	reserveRate  float64
+	loginRate    float64
+	rateBurst    int
+	httpTimeout  time.Duration
+
+	sqlDriver  string
+	sqlDSN     string
+	traceBots  string
+	duration   time.Duration
+	alertRules string
+	plugins    stringList
+
+	workspaceRoot string
+	seed          int64
+	rerun         string
+	version       bool
+}
+
+// defineFlags registers the flags on the default FlagSet.
+func defineFlags() *config {
+	c := &config{}
+	flag.StringVar(&c.presetName, "preset", "", "named preset to start from")
+	flag.BoolVar(&c.ordered, "ordered", false, "join in roster order, one login at a time")
+	flag.IntVar(&c.concurrency, "concurrency", ConcurrencyCount, "number of bots connecting at once")
+	flag.DurationVar(&c.delay, "delay", 0, "pause between starting each bot")
+	flag.StringVar(&c.strategy, "strategy", "idle", "answer strategy (idle, random, fixed, correct, wrong, or human)")
+	flag.IntVar(&c.fixedAnswer, "answer", 0, "answer index for the fixed strategy")
+	flag.IntVar(&c.bandwidth, "bandwidth", 0, "per-bot bandwidth limit in bytes/sec (0 for none)")
+	flag.StringVar(&c.network, "network", "", "network profile to emulate per bot (3g, edge, hotel-wifi)")
+	flag.StringVar(&c.overlayAddr, "overlay", "", "address to serve overlay snapshots on (e.g. localhost:8090)")
+	flag.StringVar(&c.questionsPath, "questions", "", "quiz JSON file with the question texts to show in the overlay and the answers for the correct strategy")
+	flag.StringVar(&c.translateTo, "translate-to", "", "language code to translate overlay question texts into (e.g. de)")
+	flag.StringVar(&c.translator, "translator", "deepl", `translation provider: "deepl" or a command reading lines on stdin`)
+	flag.DurationVar(&c.answerDelay, "answer-delay", 0, "time bots wait before answering")
+	flag.StringVar(&c.personasPath, "personas", "", "JSON file keeping each nickname's answer delay and random seed across runs")
+	flag.DurationVar(&c.personaDelay, "persona-delay", swarm.DefaultPersonaDelay, "longest extra answer delay given to a new persona")
+	flag.BoolVar(&c.race, "race", false, "answer as fast as possible and report the latencies achieved")
+	flag.IntVar(&c.reserveRetries, "reserve-retries", 0, "times to retry reserving a bot's session after a temporary failure")
+	flag.DurationVar(&c.reserveBackoff, "reserve-backoff", kahoot.DefaultBackoff, "delay before the first reservation retry, doubled for each one after it")
+	flag.IntVar(&c.reconnect, "reconnect", 0, "times a bot tries to reconnect and log in again after its connection drops")
+	flag.StringVar(&c.depart, "depart", "", `shares of the bots which leave after given questions, e.g. "20%@3,10%@5"`)
+	flag.BoolVar(&c.strict, "strict", false, "stop the run on the first message the bots do not understand, dumping it (for protocol checks in CI)")
+	flag.StringVar(&c.lateJoin, "late-join", "", `numbers of bots which join after given questions, e.g. "10@3,5@6:straggler"`)
+	flag.DurationVar(&c.lobbyTimeout, "lobby-timeout", 0, "leave if the host has not started the game after this long (0 for no limit)")
+	flag.DurationVar(&c.lastMoment, "last-moment", 0, "answer this long plus the round trip before each question closes (0 to answer right away)")
+	flag.StringVar(&c.controlAddr, "control", "", "address to serve live control commands on (e.g. localhost:8091)")
+	flag.StringVar(&c.telemetryURL, "telemetry", "", "opt-in endpoint for anonymized samples of unrecognized protocol traffic")
+	flag.StringVar(&c.manifestURL, "manifest", "", "URL of a signed protocol manifest to load at startup")
+	flag.StringVar(&c.protocolDir, "protocol-dir", "", "directory with a protocol.json overriding the built-in protocol")
+	flag.IntVar(&c.maxRequests, "max-requests-per-hour", 0, "budget for HTTP requests and connections per hour (0 for none)")
+	flag.IntVar(&c.maxBots, "max-bots", 0, "budget for concurrently connected bots (0 for none)")
+	flag.IntVar(&c.maxAnswers, "max-answers-per-minute", 0, "budget for answer messages per minute (0 for none)")
+	flag.StringVar(&c.remoteEval, "remote-eval", "", "send challenges no local solver knows to this evaluation service, e.g. "+kahoot.SafevalURL+" (off by default)")
+	flag.Float64Var(&c.reserveRate, "reserve-rate", kahoot.DefaultRateLimit.ReservesPerSecond, "session reservations per second, shared by every bot (0 for no limit)")
+	flag.Float64Var(&c.loginRate, "login-rate", kahoot.DefaultRateLimit.LoginsPerSecond, "logins per second, shared by every bot (0 for no limit)")
+	flag.IntVar(&c.rateBurst, "rate-burst", kahoot.DefaultRateLimit.Burst, "reservations or logins which may go at once after a quiet spell")
+	flag.StringVar(&c.sqlDriver, "sql-driver", "sqlite", "database driver for -sql (sqlite or postgres)")
+	flag.StringVar(&c.sqlDSN, "sql", "", "database to store events and results in (e.g. runs.db)")
+	flag.StringVar(&c.traceBots, "trace-bots", "", "comma-separated bots whose tracing SIGUSR1 toggles (default all)")
+	flag.DurationVar(&c.duration, "duration", 0, "leave the game and exit after this long (0 for no limit)")
+	flag.StringVar(&c.alertRules, "alerts", "", "JSON file of alert rules to evaluate during the run")
+	flag.DurationVar(&c.httpTimeout, "http-timeout", kahoot.DefaultTimeout, "timeout for each HTTP request and connection attempt")
+	flag.Var(&c.plugins, "plugin", "plugin binary to load (may be repeated)")
+	flag.StringVar(&c.workspaceRoot, "workspace", workspace.DefaultRoot, "directory for run artifacts")
+	flag.Int64Var(&c.seed, "seed", 0, "seed for the bots' random choices and personas (0 picks one; the run's manifest records it)")
+	flag.StringVar(&c.rerun, "rerun", "", "start a run again from its manifest.json or workspace directory, ignoring other arguments")
+	flag.BoolVar(&c.version, "version", false, "print the version, protocol, and challenge solvers, then exit")
+	flag.Usage = usage
+	return c
+}
+
+// configureKahoot applies the flags which configure the kahoot
+// package as a whole.
+func (c *config) configureKahoot() error {
+	kahoot.HTTPClient.Timeout = c.httpTimeout
+	kahoot.SetBudget(kahoot.Budget{
+		RequestsPerHour:  c.maxRequests,
+		MaxConns:         c.maxBots,
+		AnswersPerMinute: c.maxAnswers,
+	})
+	if c.remoteEval != "" {
+		kahoot.SetEvaluators(append(kahoot.DefaultEvaluators(), kahoot.RemoteEvaluator{URL: c.remoteEval})...)
+	}
+	kahoot.SetRateLimit(kahoot.RateLimit{
+		ReservesPerSecond: c.reserveRate,
+		LoginsPerSecond:   c.loginRate,
+		Burst:             c.rateBurst,
+	})
+	if c.protocolDir != "" {
+		if err := kahoot.LoadProtocolDir(c.protocolDir); err != nil {
+			return fmt.Errorf("failed to load protocol: %s", err)
+		}
+	}
+	if c.manifestURL != "" {
+		if err := manifest.Load(c.manifestURL); err != nil {
+			fmt.Fprintln(os.Stderr, "using built-in protocol definitions:", err)
+		}
+	}
+	return nil
+}
+
+// buildPreset turns the flags and arguments into the preset to
+// run, along with the quiz given by -questions, if any.
+func (c *config) buildPreset(args []string) (swarm.Preset, *kahoot.QuizInfo, error) {
+	preset := swarm.Preset{Name: "custom"}
+	if c.presetName != "" {
+		var err error
+		preset, err = swarm.LookupPreset(c.presetName)
+		if err != nil {
+			return preset, nil, err
+		}
+	} else {
+		preset.Options.Concurrency = c.concurrency
+		preset.Strategy = c.strategy
+	}
+	if c.network != "" {
+		profile, err := netem.LookupProfile(c.network)
+		if err != nil {
+			return preset, nil, err
+		}
+		preset.Options.Network = profile
+	}
+	if c.depart != "" {
+		departures, err := swarm.ParseDepartures(c.depart)
+		if err != nil {
+			return preset, nil, err
+		}
+		preset.Options.Departures = departures
+	}
+	if c.lateJoin != "" {
+		lateJoins, err := swarm.ParseLateJoins(c.lateJoin)
+		if err != nil {
+			return preset, nil, err
+		}
+		preset.Options.LateJoins = lateJoins
+	}
+	if c.strict {
+		preset.Options.Strict = true
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "ordered":
+			preset.Options.Ordered = c.ordered
+		case "concurrency":
+			preset.Options.Concurrency = c.concurrency
+		case "delay":
+			preset.Options.JoinDelay = c.delay
+		case "strategy":
+			preset.Strategy = c.strategy
+		case "answer-delay":
+			preset.Options.AnswerDelay = c.answerDelay
+		case "last-moment":
+			preset.Options.LastMoment = c.lastMoment
+		case "reserve-retries":
+			preset.Options.Token.Retries = c.reserveRetries
+		case "reserve-backoff":
+			preset.Options.Token.Backoff = c.reserveBackoff
+		case "reconnect":
+			preset.Options.Reconnect = c.reconnect
+		case "lobby-timeout":
+			preset.Options.LobbyTimeout = c.lobbyTimeout
+		case "race":
+			preset.Options.Race = c.race
+		case "bandwidth":
+			preset.Options.Network.ReadBytesPerSec = c.bandwidth
+			preset.Options.Network.WriteBytesPerSec = c.bandwidth
+		}
+	})
+	if len(args) > 1 {
+		names, err := nicknames(args)
+		if err != nil {
+			return preset, nil, err
+		}
+		preset.Nicknames = names
+	}
+	var quiz *kahoot.QuizInfo
+	if c.questionsPath != "" {
+		var err error
+		if quiz, err = kahoot.ReadQuizFile(c.questionsPath); err != nil {
+			return preset, nil, fmt.Errorf("failed to load questions: %s", err)
+		}
+	}
+	if preset.Options.Strategies == nil {
+		preset.Options.Strategies = map[string]func(*swarm.Bot, *kahoot.QuizAction) int{}
+	}
+	preset.Options.Strategies["fixed"] = swarm.Adapt(kahoot.AlwaysIndex(c.fixedAnswer))
+	preset.Options.Strategies["human"] = swarm.Adapt(kahoot.DefaultHumanLike(quiz))
+	if quiz != nil {
+		preset.Options.Strategies["correct"] = swarm.Adapt(kahoot.CorrectStrategy(quiz))
+		preset.Options.Strategies["wrong"] = swarm.Adapt(kahoot.WrongOnPurpose(quiz))
+	} else if preset.Strategy == "correct" || preset.Strategy == "wrong" {
+		return preset, nil, fmt.Errorf("the %s strategy needs -questions", preset.Strategy)
+	}
+	if err := preset.Validate(); err != nil {
+		return preset, nil, err
+	}
+	if c.personasPath != "" {
+		personas, err := swarm.LoadPersonas(c.personasPath, preset.NicknameList(), c.personaDelay)
+		if err != nil {
+			return preset, nil, fmt.Errorf("failed to load personas: %s", err)
+		}
+		preset.Options.Personas = personas
+	}
+	preset.Options.Seed = c.seed
+	return preset, quiz, nil
+}
+
+// inputFiles lists the files a run reads its setup from.
+func (c *config) inputFiles(args []string) []string {
+	var res []string
+	if len(args) == 2 {
+		res = append(res, args[1])
+	}
+	for _, path := range []string{c.questionsPath, c.personasPath, c.alertRules} {
+		if path != "" {
+			res = append(res, path)
+		}
+	}
+	if c.protocolDir != "" {
+		res = append(res, filepath.Join(c.protocolDir, "protocol.json"))
+	}
+	return append(res, c.plugins...)
+}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
@@ -1,88 +1,358 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
+	"math/rand"
 	"os"
 	"os/signal"
 	"strconv"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
+	"github.com/unixpickle/kahoot-hack/control"
 	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/plugins"
+	_ "github.com/unixpickle/kahoot-hack/sqlsink/drivers"
+	"github.com/unixpickle/kahoot-hack/swarm"
+	"github.com/unixpickle/kahoot-hack/telemetry"
+	"github.com/unixpickle/kahoot-hack/workspace"
 )
 
 const ConcurrencyCount = 4
 
+// teardownGrace is how long leaving may take after -duration
+// runs out before the process exits regardless.
+const teardownGrace = 30 * time.Second
+
 func main() {
-	if len(os.Args) != 3 && len(os.Args) != 4 {
-		fmt.Fprintln(os.Stderr, "Usage: flood <game pin> <nickname prefix> <count>")
-		fmt.Fprintln(os.Stderr, "       flood <game pin> <name_list.txt>")
-		os.Exit(1)
-	}
-
-	gamePin := os.Args[1]
-
-	var dieLock sync.Mutex
-	connChan := make(chan *kahoot.Conn)
-	for i := 0; i < ConcurrencyCount; i++ {
-		go func() {
-			for {
-				conn, err := kahoot.NewConn(gamePin)
-				if err != nil {
-					dieLock.Lock()
-					fmt.Fprintln(os.Stderr, "failed to connect:", err)
-					os.Exit(1)
-					dieLock.Unlock()
-				}
-				connChan <- conn
+	os.Exit(run())
+}
+
+// run runs kahoot-flood and returns its exit status. Returning
+// instead of exiting lets the deferred cleanup, such as writing
+// the run's summary, happen on every path.
+func run() int {
+	c := defineFlags()
+	flag.Parse()
+	if c.version {
+		fmt.Print(kahoot.Version())
+		return 0
+	}
+	runArgs := os.Args[1:]
+	var rerunOf *workspace.Manifest
+	if c.rerun != "" {
+		if flag.NFlag() > 1 || flag.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, "-rerun takes no other arguments")
+			return 1
+		}
+		m, err := workspace.ReadManifest(c.rerun)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to read manifest:", err)
+			return 1
+		}
+		runArgs = m.RerunArgs()
+		if err := flag.CommandLine.Parse(runArgs); err != nil {
+			return 1
+		}
+		rerunOf = m
+	}
+	if c.seed == 0 {
+		c.seed = time.Now().UnixNano()
+	}
+	// Each bot draws its choices from its own source seeded
+	// from this (see swarm.Options.Seed); the shared source
+	// only serves draws made in one place, such as choosing
+	// who departs.
+	rand.Seed(c.seed)
+	args := flag.Args()
+
+	if len(args) < 1 || len(args) > 3 || (len(args) == 1 && c.presetName == "") {
+		usage()
+		return 1
+	}
+
+	gamePin := args[0]
+
+	if err := c.configureKahoot(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if c.telemetryURL != "" {
+		reporter := telemetry.NewReporter(c.telemetryURL)
+		defer reporter.Close()
+		kahoot.SetReporter(reporter)
+	}
+	loaded, err := c.loadPlugins(gamePin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	var pluginSinks []plugins.Sink
+	for _, p := range loaded {
+		defer p.Close()
+		if p.Sink != nil {
+			pluginSinks = append(pluginSinks, p.Sink)
+		}
+	}
+
+	preset, quiz, err := c.buildPreset(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	ws, err := workspace.Create(c.workspaceRoot, "flood")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to create workspace:", err)
+		return 1
+	}
+	defer ws.Close()
+	ws.SaveConfig(map[string]interface{}{"gamePin": gamePin, "preset": preset})
+	writeManifest(ws, runArgs, c.seed, rerunOf, c.inputFiles(args))
+	summary := &runSummary{GamePin: gamePin, Started: time.Now()}
+	defer func() {
+		summary.Ended = time.Now()
+		ws.WriteSummary(summary)
+	}()
+
+	sink, err := c.openSink(ws, gamePin)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	saveToWorkspace(&preset.Options, ws)
+	s := swarm.New(gamePin, preset.Options)
+	defer s.Close()
+	alerts, err := c.startAlerts(ws, s)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := c.startOverlay(s, quiz); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	var recording recorders
+	var teardownOnce sync.Once
+	teardown := func() {
+		teardownOnce.Do(func() {
+			if alerts != nil {
+				alerts.Close()
+			}
+			s.Close()
+			// Closing the swarm closed the bots' Buses, so the
+			// recorders only have their buffered events left.
+			select {
+			case <-recording.done():
+			case <-time.After(teardownGrace):
+				fmt.Fprintln(os.Stderr, "recording events took too long; some may be missing")
+			}
+			report := s.Report()
+			fmt.Println()
+			report.WriteText(os.Stdout)
+			if err := ws.WriteReport(report); err != nil {
+				fmt.Fprintln(os.Stderr, "failed to write report:", err)
 			}
-		}()
+		})
+	}
+	timeUp := make(chan struct{})
+	if c.duration > 0 {
+		time.AfterFunc(c.duration, func() {
+			fmt.Println("Time limit reached; leaving the game.")
+			close(timeUp)
+			// Cuts joining short if it is still going.
+			teardown()
+		})
+		time.AfterFunc(c.duration+teardownGrace, func() {
+			fmt.Fprintln(os.Stderr, "leaving took too long; exiting anyway")
+			os.Exit(1)
+		})
 	}
 
-	for _, nickname := range nicknames() {
-		conn := <-connChan
-		defer conn.GracefulClose()
-		conn.Login(nickname)
+	s.Join(preset.NicknameList())
+	for _, bot := range s.Bots() {
+		if bot.Err != nil {
+			fmt.Fprintln(os.Stderr, "failed to join as", bot.Nickname+":", bot.Err)
+			ws.Logger().Println("failed to join as", bot.Nickname+":", bot.Err)
+			summary.Failed++
+		} else {
+			ws.Logger().Println("joined as", bot.Nickname)
+			summary.Joined++
+			summary.JoinLatencies = append(summary.JoinLatencies,
+				bot.JoinTime.Seconds()*1000)
+			recording.record(bot, ws, sink, pluginSinks, alerts)
+		}
+	}
+
+	s.SetStrategy(preset.Strategy)
+	var strictFailed <-chan error
+	if c.strict {
+		strictFailed = watchStrict(s)
 	}
+	var lobbyTimedOut <-chan struct{}
+	if preset.Options.LobbyTimeout > 0 {
+		lobbyTimedOut = watchLobby(s, ws, preset.Options.LobbyTimeout)
+	}
+	s.Play()
 
+	if c.controlAddr != "" {
+		prefix := preset.Prefix
+		if prefix == "" {
+			prefix = "bot"
+		}
+		handler := &control.Handler{Swarm: s, Prefix: prefix}
+		serveLocal("control", c.controlAddr, handler)
+	}
+
+	fmt.Println("Saving artifacts to", ws.Dir)
 	fmt.Println("Kill this process to deauthenticate.")
+	if c.duration > 0 {
+		fmt.Println("Leaving automatically after", c.duration)
+	}
+	toggleTracingOnSignal(s, ws, c.traceBots)
+
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	status := 0
+	select {
+	case <-sigChan:
+	case <-timeUp:
+	case <-lobbyTimedOut:
+	case err := <-strictFailed:
+		fmt.Fprintln(os.Stderr, "strict mode:", err)
+		ws.Logger().Println("strict mode:", err)
+		status = 1
+	}
+	teardown()
+	return status
 }
 
-func nicknames() []string {
-	if len(os.Args) == 4 {
-		count, err := strconv.Atoi(os.Args[3])
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "invalid count:", os.Args[3])
-			os.Exit(1)
+// watchStrict returns a channel which receives the first
+// strict mode failure of any bot.
+func watchStrict(s *swarm.Swarm) <-chan error {
+	failed := make(chan error, 1)
+	sub := s.Events().Subscribe(kahoot.TopicError)
+	go func() {
+		defer sub.Close()
+		for e := range sub.C {
+			if err, ok := e.Data.(error); ok && e.Type == "strict" {
+				failed <- err
+				return
+			}
 		}
-		base := os.Args[2]
-		res := make([]string, count)
-		for x := 0; x < count; x++ {
-			res[x] = base + strconv.Itoa(x+1)
+	}()
+	return failed
+}
+
+// watchLobby returns a channel which is closed if the host
+// does not start the game within the swarm's lobby timeout.
+func watchLobby(s *swarm.Swarm, ws *workspace.Workspace, timeout time.Duration) <-chan struct{} {
+	timedOut := make(chan struct{})
+	sub := s.Events().Subscribe(kahoot.TopicConnection)
+	go func() {
+		defer sub.Close()
+		for e := range sub.C {
+			if e.Type == "lobbyTimeout" {
+				fmt.Println("The host never started the game; leaving.")
+				ws.Logger().Println("host never started the game within", timeout)
+				close(timedOut)
+				return
+			}
 		}
-		return res
-	}
+	}()
+	return timedOut
+}
 
-	contents, err := ioutil.ReadFile(os.Args[2])
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
-	}
+// toggleTracingOnSignal toggles tracing for the given bots, or
+// every bot, each time the process receives SIGUSR1.
+func toggleTracingOnSignal(s *swarm.Swarm, ws *workspace.Workspace, traceBots string) {
+	traceChan := make(chan os.Signal, 1)
+	signal.Notify(traceChan, syscall.SIGUSR1)
+	go func() {
+		var names []string
+		if traceBots != "" {
+			names = strings.Split(traceBots, ",")
+		}
+		for range traceChan {
+			if err := s.ToggleTracing(names...); err != nil {
+				fmt.Fprintln(os.Stderr, "toggle tracing:", err)
+			}
+			ws.Logger().Println("toggled tracing for", len(names), "bots (0 means all)")
+		}
+	}()
+}
+
+type runSummary struct {
+	GamePin string    `json:"gamePin"`
+	Joined  int       `json:"joined"`
+	Failed  int       `json:"failed"`
+	Started time.Time `json:"started"`
+	Ended   time.Time `json:"ended"`
 
-	res := strings.Split(string(contents), "\n")
-	for i := 0; i < len(res); i++ {
-		res[i] = strings.TrimSpace(res[i])
-		if len(res[i]) == 0 {
-			res[i] = res[len(res)-1]
-			res = res[:len(res)-1]
-			i--
+	JoinLatencies []float64 `json:"joinLatenciesMs"`
+}
+
+// writeManifest saves how to start the run again, warning
+// about anything which differs from the run it repeats.
+func writeManifest(ws *workspace.Workspace, args []string, seed int64, rerunOf *workspace.Manifest,
+	inputs []string) {
+	if rerunOf != nil {
+		for _, diff := range rerunOf.Differences() {
+			fmt.Fprintln(os.Stderr, "warning: rerun differs from the original:", diff)
+			ws.Logger().Println("rerun differs from the original:", diff)
 		}
 	}
+	m := workspace.NewManifest("kahoot-flood", args, seed)
+	flag.VisitAll(func(f *flag.Flag) {
+		m.Flags[f.Name] = f.Value.String()
+	})
+	for _, path := range inputs {
+		if err := m.AddFile(path); err != nil {
+			ws.Logger().Println("manifest:", err)
+		}
+	}
+	if err := ws.WriteManifest(m); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to write manifest:", err)
+		return
+	}
+	ws.Logger().Println("rerun with: kahoot-flood -rerun", ws.Path(workspace.ManifestFile))
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: flood [flags] <game pin> <nickname prefix> <count>")
+	fmt.Fprintln(os.Stderr, "       flood [flags] <game pin> <name_list.txt>")
+	fmt.Fprintln(os.Stderr, "       flood -preset <name> [flags] <game pin>")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Presets:", swarm.PresetNames())
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
 
-	return res
+// nicknames returns the bots' nicknames given on the command
+// line, as a prefix and count or a roster file.
+func nicknames(args []string) ([]string, error) {
+	if len(args) == 3 {
+		count, err := strconv.Atoi(args[2])
+		if err != nil {
+			return nil, errors.New("invalid count: " + args[2])
+		}
+		base := args[1]
+		res := make([]string, count)
+		for x := 0; x < count; x++ {
+			// A prefix such as "team{n}-bot" places the number.
+			if strings.Contains(base, "{n}") {
+				res[x] = strings.ReplaceAll(base, "{n}", strconv.Itoa(x+1))
+			} else {
+				res[x] = base + strconv.Itoa(x+1)
+			}
+		}
+		return res, nil
+	}
+	return swarm.ReadRoster(args[1])
 }
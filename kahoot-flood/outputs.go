@@ -0,0 +1,212 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/unixpickle/kahoot-hack/alert"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/overlay"
+	"github.com/unixpickle/kahoot-hack/plugins"
+	"github.com/unixpickle/kahoot-hack/sqlsink"
+	"github.com/unixpickle/kahoot-hack/swarm"
+	"github.com/unixpickle/kahoot-hack/translate"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+// loadPlugins loads the -plugin binaries and registers what
+// they provide. The caller must close the plugins.
+func (c *config) loadPlugins(gamePin string) ([]*plugins.Plugin, error) {
+	var res []*plugins.Plugin
+	for _, path := range c.plugins {
+		p, err := plugins.Load(path)
+		if err != nil {
+			for _, loaded := range res {
+				loaded.Close()
+			}
+			return nil, fmt.Errorf("failed to load plugin: %s", err)
+		}
+		p.Register(gamePin)
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+// openSink opens the -sql database, if one is given.
+func (c *config) openSink(ws *workspace.Workspace, gamePin string) (*sqlsink.Sink, error) {
+	if c.sqlDSN == "" {
+		return nil, nil
+	}
+	dialect, err := sqlsink.LookupDialect(c.sqlDriver)
+	if err == nil {
+		var db *sql.DB
+		if db, err = sql.Open(c.sqlDriver, c.sqlDSN); err == nil {
+			var sink *sqlsink.Sink
+			if sink, err = sqlsink.New(db, dialect, filepath.Base(ws.Dir), gamePin); err == nil {
+				return sink, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to open database: %s", err)
+}
+
+// saveToWorkspace makes the swarm write crash reports and
+// traces into the run's workspace.
+func saveToWorkspace(opts *swarm.Options, ws *workspace.Workspace) {
+	opts.OnCrash = func(bot *swarm.Bot, crash *swarm.Crash) {
+		fmt.Fprintln(os.Stderr, "bot", bot.Nickname, "crashed:", crash.Value)
+		ws.Logger().Println("bot", bot.Nickname, "crashed:", crash.Value)
+		ws.WriteCrashReport(bot.Nickname, crash.Report(bot))
+	}
+	opts.TraceWriter = func(bot *swarm.Bot) (io.WriteCloser, error) {
+		return ws.CreateTrace(bot.Nickname)
+	}
+}
+
+// startAlerts evaluates the -alerts rules against the swarm's
+// events, if there are any.
+func (c *config) startAlerts(ws *workspace.Workspace, s *swarm.Swarm) (*alert.Engine, error) {
+	if c.alertRules == "" {
+		return nil, nil
+	}
+	rules, err := alert.ReadRules(c.alertRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read alert rules: %s", err)
+	}
+	alerts := alert.NewEngine(rules, func(a *alert.Alert) {
+		fmt.Fprintln(os.Stderr, "ALERT", a.Rule+":", a.Message)
+		ws.Logger().Println("alert", a.Rule+":", a.Message)
+	})
+	go alerts.Watch(s.Events().Subscribe())
+	return alerts, nil
+}
+
+// startOverlay serves the -overlay snapshots, if asked to.
+func (c *config) startOverlay(s *swarm.Swarm, quiz *kahoot.QuizInfo) error {
+	if c.overlayAddr == "" {
+		return nil
+	}
+	server := overlay.NewServer()
+	if quiz != nil {
+		if err := loadOverlayTexts(server, quiz, c.translator, c.translateTo); err != nil {
+			return fmt.Errorf("failed to load questions: %s", err)
+		}
+	}
+	go server.Watch(s.Events())
+	serveLocal("overlay", c.overlayAddr, server)
+	return nil
+}
+
+// loadOverlayTexts shows a quiz's question texts in the
+// overlay, translated if a target language is given.
+func loadOverlayTexts(server *overlay.Server, quiz *kahoot.QuizInfo, translator, target string) error {
+	for _, q := range quiz.Questions {
+		server.Texts = append(server.Texts, q.Question)
+	}
+	if target == "" {
+		return nil
+	}
+	provider, err := translate.New(translator)
+	if err != nil {
+		return err
+	}
+	translated, err := translate.Quiz(provider, quiz, target)
+	if err != nil {
+		return err
+	}
+	for _, q := range translated.Questions {
+		server.Translations = append(server.Translations, q.Question)
+	}
+	return nil
+}
+
+// serveLocal serves a local endpoint in the background.
+// If the port is taken, for instance by another run on the
+// same machine, a free port on the same host is used instead.
+func serveLocal(name, addr string, handler http.Handler) {
+	listener, err := net.Listen("tcp", addr)
+	if errors.Is(err, syscall.EADDRINUSE) {
+		host, _, splitErr := net.SplitHostPort(addr)
+		if splitErr != nil {
+			fmt.Fprintln(os.Stderr, name, "server:", err)
+			return
+		}
+		listener, err = net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, name, "server:", err)
+			return
+		}
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, name, "server:", err)
+		return
+	}
+	fmt.Println("Serving", name, "on", listener.Addr())
+	go func() {
+		if err := http.Serve(listener, handler); err != nil {
+			fmt.Fprintln(os.Stderr, name, "server:", err)
+		}
+	}()
+}
+
+// recorders tracks the goroutines which save the bots' events,
+// so that the process can wait for them to write what they
+// have buffered before it exits.
+type recorders struct {
+	wg sync.WaitGroup
+}
+
+// record saves a joined bot's events in the workspace, the
+// database, and the plugins' sinks, and feeds its errors to the
+// alerts.
+func (r *recorders) record(bot *swarm.Bot, ws *workspace.Workspace, sink *sqlsink.Sink,
+	pluginSinks []plugins.Sink, alerts *alert.Engine) {
+	nickname := bot.Nickname
+	r.start(bot.Conn.Events().Subscribe(), func(sub *kahoot.Subscription) {
+		ws.Record(nickname, sub)
+	})
+	if sink != nil {
+		r.start(bot.Conn.Events().Subscribe(), func(sub *kahoot.Subscription) {
+			sink.Record(nickname, sub)
+		})
+	}
+	for _, pluginSink := range pluginSinks {
+		pluginSink := pluginSink
+		r.start(bot.Conn.Events().Subscribe(), func(sub *kahoot.Subscription) {
+			for e := range sub.C {
+				pluginSink.Record(nickname, e)
+			}
+		})
+	}
+	if alerts != nil {
+		go alerts.Watch(bot.Conn.Events().Subscribe(kahoot.TopicError))
+	}
+}
+
+// start runs record in the background until it returns, which
+// it should once sub is closed.
+func (r *recorders) start(sub *kahoot.Subscription, record func(*kahoot.Subscription)) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		record(sub)
+	}()
+}
+
+// done returns a channel which is closed once every recorder
+// has seen its subscription close.
+func (r *recorders) done() <-chan struct{} {
+	ch := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
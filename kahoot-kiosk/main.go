@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+const DefaultConfig = "/etc/kahoot-kiosk.json"
+
+// retryDelay is how long to wait before joining again after
+// a failed join or a disconnect. At boot, the network or the
+// game may simply not be up yet.
+const retryDelay = 10 * time.Second
+
+type config struct {
+	GamePin     string `json:"gamePin"`
+	Nickname    string `json:"nickname"`
+	Strategy    string `json:"strategy"`
+	AnswerDelay string `json:"answerDelay"`
+
+	answerDelay time.Duration
+}
+
+func readConfig(path string) (*config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &config{Strategy: "random"}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse config: %s", err)
+	}
+	if c.GamePin == "" || c.Nickname == "" {
+		return nil, errors.New("config needs a gamePin and a nickname")
+	}
+	if _, ok := swarm.Strategies[c.Strategy]; !ok {
+		return nil, errors.New("unknown strategy: " + c.Strategy)
+	}
+	if c.AnswerDelay != "" {
+		if c.answerDelay, err = time.ParseDuration(c.AnswerDelay); err != nil {
+			return nil, fmt.Errorf("parse answerDelay: %s", err)
+		}
+	}
+	return c, nil
+}
+
+func main() {
+	configPath := flag.String("config", DefaultConfig, "JSON file with the game pin, nickname, and strategy")
+	noClear := flag.Bool("no-clear", false, "append status lines instead of redrawing the screen")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: kiosk [-config file] [-no-clear]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, `The config file looks like {"gamePin": "123456", "nickname": "demo",`)
+		fmt.Fprintln(os.Stderr, `"strategy": "random", "answerDelay": "2s"}.`)
+		fmt.Fprintln(os.Stderr)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	c, err := readConfig(*configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	screen := &screen{
+		out:      os.Stdout,
+		redraw:   !*noClear,
+		gamePin:  c.GamePin,
+		nickname: c.Nickname,
+		strategy: c.Strategy,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	for {
+		screen.setStatus("joining the game...")
+		s := swarm.New(c.GamePin, swarm.Options{Concurrency: 1, AnswerDelay: c.answerDelay})
+		if err := s.Join([]string{c.Nickname}); err != nil {
+			screen.setStatus(fmt.Sprintf("could not join (%s); retrying in %s", err, retryDelay))
+			select {
+			case <-time.After(retryDelay):
+				continue
+			case <-stop:
+				return
+			}
+		}
+		s.SetStrategy(c.Strategy)
+		ended := watch(screen, s)
+		s.Play()
+		screen.setStatus("joined; waiting for the game to start")
+
+		select {
+		case kicked := <-ended:
+			s.Close()
+			if kicked {
+				// The host does not want this kiosk in the game.
+				screen.setStatus("kicked by the host; not rejoining")
+				<-stop
+				return
+			}
+			screen.setStatus(fmt.Sprintf("disconnected; rejoining in %s", retryDelay))
+			select {
+			case <-time.After(retryDelay):
+			case <-stop:
+				return
+			}
+		case <-stop:
+			screen.setStatus("leaving the game")
+			s.Close()
+			return
+		}
+	}
+}
+
+// watch updates the screen from the swarm's events, and from
+// those of its only bot. Once the bot disconnects, the
+// returned channel receives whether it was kicked.
+func watch(screen *screen, s *swarm.Swarm) <-chan bool {
+	bot := s.Bots()[0]
+	ended := make(chan bool, 1)
+	botSub := bot.Conn.Events().Subscribe()
+	swarmSub := s.Events().Subscribe(kahoot.TopicQuestion)
+	go func() {
+		defer swarmSub.Close()
+		defer botSub.Close()
+		var kicked bool
+		swarmEvents := swarmSub.C
+		for {
+			select {
+			case e, ok := <-swarmEvents:
+				if !ok {
+					// The swarm closed; the bot's events
+					// say how it ended.
+					swarmEvents = nil
+					continue
+				}
+				switch data := e.Data.(type) {
+				case *kahoot.QuizAction:
+					screen.setQuestion(data)
+				case *swarm.Answer:
+					screen.setAnswer(data)
+				}
+			case e, ok := <-botSub.C:
+				if !ok || e.Type == "closed" {
+					ended <- kicked
+					return
+				}
+				switch e.Type {
+				case "result":
+					if result, ok := e.Data.(*kahoot.QuizResult); ok {
+						screen.setResult(result)
+					}
+				case "kicked":
+					kicked = true
+				}
+			}
+		}
+	}()
+	return ended
+}
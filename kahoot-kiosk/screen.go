@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// clearScreen moves the cursor home and clears the terminal,
+// which works on the Linux console as well as in terminals.
+const clearScreen = "\033[H\033[2J"
+
+// A screen shows the kiosk's state on a small attached
+// display, redrawing everything on each change.
+type screen struct {
+	out    io.Writer
+	redraw bool
+
+	gamePin  string
+	nickname string
+	strategy string
+
+	lock     sync.Mutex
+	status   string
+	question string
+	answer   string
+	result   string
+}
+
+func (s *screen) setStatus(status string) {
+	s.update(func() {
+		s.status = status
+	})
+}
+
+func (s *screen) setQuestion(action *kahoot.QuizAction) {
+	s.update(func() {
+		if action.Type == kahoot.QuestionIntro {
+			s.question = fmt.Sprintf("question %d: get ready", action.Index+1)
+			s.answer = ""
+		} else {
+			s.question = fmt.Sprintf("question %d: %d answers", action.Index+1, action.NumAnswers)
+		}
+	})
+}
+
+func (s *screen) setAnswer(answer *swarm.Answer) {
+	s.update(func() {
+		s.answer = "answered " + kahoot.ChoiceStyleAt(answer.Choice).String()
+	})
+}
+
+func (s *screen) setResult(result *kahoot.QuizResult) {
+	s.update(func() {
+		verdict := "wrong"
+		if result.IsCorrect {
+			verdict = "correct"
+		}
+		s.result = fmt.Sprintf("question %d %s, +%.0f points, total %.0f, rank %d",
+			result.Index+1, verdict, result.Points, result.TotalScore, result.Rank)
+	})
+}
+
+func (s *screen) update(f func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	f()
+	if !s.redraw {
+		fmt.Fprintln(s.out, time.Now().Format("15:04:05"), s.line())
+		return
+	}
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "KAHOOT KIOSK\n\npin       %s\nnickname  %s\nstrategy  %s\n\n",
+		s.gamePin, s.nickname, s.strategy)
+	fmt.Fprintf(&b, "%s\n", s.status)
+	for _, line := range []string{s.question, s.answer, s.result} {
+		if line != "" {
+			fmt.Fprintf(&b, "%s\n", line)
+		}
+	}
+	io.WriteString(s.out, b.String())
+}
+
+// line summarizes the screen for the log-style output of
+// -no-clear.
+func (s *screen) line() string {
+	var parts []string
+	for _, part := range []string{s.status, s.question, s.answer, s.result} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/manifest"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "keygen":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			die(err)
+		}
+		fmt.Println("public: ", base64.StdEncoding.EncodeToString(pub))
+		fmt.Println("private:", base64.StdEncoding.EncodeToString(priv))
+	case "defaults":
+		data, _ := json.MarshalIndent(kahoot.DefaultProtocol, "", "  ")
+		fmt.Println(string(data))
+	case "sign":
+		if len(os.Args) != 4 && len(os.Args) != 5 {
+			usage()
+		}
+		validFor := defaultValidity
+		if len(os.Args) == 5 {
+			var err error
+			if validFor, err = time.ParseDuration(os.Args[4]); err != nil || validFor <= 0 {
+				die("invalid validity: " + os.Args[4])
+			}
+		}
+		sign(os.Args[2], os.Args[3], validFor)
+	default:
+		usage()
+	}
+}
+
+// defaultValidity is how long a signed manifest is trusted
+// unless the sign command is given a duration.
+const defaultValidity = 30 * 24 * time.Hour
+
+func sign(protocolPath, keyPath string, validFor time.Duration) {
+	protoData, err := ioutil.ReadFile(protocolPath)
+	if err != nil {
+		die(err)
+	}
+	var p kahoot.Protocol
+	if err := json.Unmarshal(protoData, &p); err != nil {
+		die(err)
+	}
+	if err := kahoot.SetProtocol(&p); err != nil {
+		die(err)
+	}
+	keyData, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		die(err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(keyData)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		die("invalid private key")
+	}
+	env, err := manifest.Sign(&p, time.Now().Add(validFor), ed25519.PrivateKey(key))
+	if err != nil {
+		die(err)
+	}
+	json.NewEncoder(os.Stdout).Encode(env)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: manifest keygen")
+	fmt.Fprintln(os.Stderr, "       manifest defaults")
+	fmt.Fprintln(os.Stderr, "       manifest sign <protocol.json> <private_key_file> [valid_for, e.g. 720h]")
+	os.Exit(1)
+}
+
+func die(err interface{}) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
@@ -1,33 +1,100 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/unixpickle/kahoot-hack/kahoot"
 )
 
 func main() {
-	if len(os.Args) != 3 {
-		fmt.Fprintln(os.Stderr, "Usage: play <game pin> <nickname>")
+	pin := flag.String("pin", "", "game pin, instead of the first argument")
+	name := flag.String("name", "", "nickname, instead of the second argument")
+	quizPath := flag.String("quiz", "", "quiz JSON file with the question and choice texts to show")
+	translateTo := flag.String("translate-to", "", "language code to also show the texts in (e.g. de)")
+	translator := flag.String("translator", "deepl", `translation provider: "deepl" or a command reading lines on stdin`)
+	strategyName := flag.String("strategy", "", "answer automatically with a strategy ("+
+		strings.Join(kahoot.AnswerStrategyNames(), ", ")+") instead of asking")
+	fixedAnswer := flag.Int("answer", 0, "answer index for the fixed strategy")
+	speak := flag.String("speak", "", `text-to-speech command which reads announcements on stdin (e.g. "espeak")`)
+	version := flag.Bool("version", false, "print the version, protocol, and challenge solvers, then exit")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: play [-quiz file] [-translate-to lang] [-speak command] [-strategy name] <game pin> <nickname>")
+		fmt.Fprintln(os.Stderr, "       play [flags] -pin <game pin> -name <nickname>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if *version {
+		fmt.Print(kahoot.Version())
+		return
+	}
+	args := flag.Args()
+	if *pin != "" {
+		args = append([]string{*pin}, args...)
+	}
+	if *name != "" {
+		args = append(args, *name)
+	}
+	if len(args) != 2 {
+		flag.Usage()
 		os.Exit(1)
 	}
 
-	gamePin := os.Args[1]
-	nickname := os.Args[2]
+	gamePin := args[0]
+	nickname := args[1]
+
+	var texts *quizTexts
+	if *quizPath != "" {
+		var err error
+		texts, err = loadQuizTexts(*quizPath, *translator, *translateTo)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to load quiz:", err)
+			os.Exit(1)
+		}
+	}
+
+	var strategy kahoot.AnswerStrategy
+	if *strategyName != "" {
+		var quiz *kahoot.QuizInfo
+		if texts != nil {
+			quiz = texts.quiz
+		}
+		var err error
+		if strategy, err = kahoot.NewStrategy(*strategyName, quiz, *fixedAnswer); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
 
 	conn, err := kahoot.NewConn(gamePin)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "failed to connect:", err)
 		os.Exit(1)
 	}
-	if err := conn.Login(nickname); err != nil {
+	if conn.Namerator() {
+		nickname, err = conn.LoginGenerated()
+		if err == nil {
+			fmt.Println("This game generates nicknames; you are", nickname)
+		}
+	} else {
+		err = conn.Login(nickname)
+	}
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "failed to login:", err)
 		os.Exit(1)
 	}
+	if conn.TeamMode() {
+		if err := conn.JoinTeam(nil); err != nil {
+			fmt.Fprintln(os.Stderr, "failed to join a team:", err)
+			os.Exit(1)
+		}
+	}
 
 	closed := make(chan bool, 1)
 	closed <- false
@@ -40,6 +107,7 @@ func main() {
 		conn.GracefulClose()
 	}()
 
+	speaker := newSpeaker(*speak)
 	quiz := kahoot.NewQuiz(conn)
 	for {
 		action, err := quiz.Receive()
@@ -50,10 +118,26 @@ func main() {
 			os.Exit(1)
 		}
 		if action.Type == kahoot.QuestionIntro {
+			texts.printQuestion(action.Index)
+			speaker.say(texts.spokenQuestion(action.Index))
 			fmt.Println("Awaiting answers...")
 		} else if action.Type == kahoot.QuestionAnswers {
-			fmt.Print("Answer (0 through " + strconv.Itoa(action.NumAnswers-1) + "): ")
-			answer := readNumberInput()
+			texts.printChoices(action)
+			speaker.say(texts.spokenChoices(action))
+			var answer int
+			if strategy != nil {
+				choice := strategy.ChooseAnswer(action, conn.GameState())
+				if choice.Choice < 0 {
+					fmt.Println("Skipping this question.")
+					continue
+				}
+				time.Sleep(time.Until(choice.At(action, conn.RTT())))
+				fmt.Println("Answering", choice.Choice+1)
+				answer = action.QuizChoice(choice.Choice)
+			} else {
+				fmt.Print("Answer (1 through " + strconv.Itoa(action.NumAnswers) + "): ")
+				answer = action.QuizChoice(readChoiceInput(action.NumAnswers) - 1)
+			}
 			if err := quiz.Send(answer); err != nil {
 				fmt.Fprintln(os.Stderr, "Could not answer:", err)
 				os.Exit(1)
@@ -62,7 +146,9 @@ func main() {
 	}
 }
 
-func readNumberInput() int {
+// readChoiceInput reads a choice numbered from 1 to count, as
+// shown on screen. With an unknown count, any number is taken.
+func readChoiceInput(count int) int {
 	for {
 		var buffer string
 		for {
@@ -77,12 +163,15 @@ func readNumberInput() int {
 			}
 			buffer += string(rune(buf[0]))
 		}
-		res, err := strconv.Atoi(buffer)
-		if err != nil {
-			fmt.Println("please enter a number")
+		res, err := strconv.Atoi(strings.TrimSpace(buffer))
+		if err != nil || res < 1 || (count > 0 && res > count) {
+			if count > 0 {
+				fmt.Println("please enter a number from 1 to " + strconv.Itoa(count))
+			} else {
+				fmt.Println("please enter a positive number")
+			}
 			continue
-		} else {
-			return res
 		}
+		return res
 	}
 }
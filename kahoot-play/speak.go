@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// A speaker pipes announcements, one at a time, to the
+// standard input of a text-to-speech command. A nil *speaker
+// says nothing.
+type speaker struct {
+	name  string
+	args  []string
+	queue chan string
+}
+
+func newSpeaker(command string) *speaker {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return nil
+	}
+	s := &speaker{name: fields[0], args: fields[1:], queue: make(chan string, 16)}
+	go s.loop()
+	return s
+}
+
+// say queues an announcement without waiting for it, so that
+// the player can answer while it is still being read out.
+func (s *speaker) say(text string) {
+	if s == nil {
+		return
+	}
+	select {
+	case s.queue <- text:
+	default:
+		// The command cannot keep up; stale questions are
+		// not worth hearing.
+	}
+}
+
+func (s *speaker) loop() {
+	for text := range s.queue {
+		cmd := exec.Command(s.name, s.args...)
+		cmd.Stdin = strings.NewReader(text + "\n")
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintln(os.Stderr, "text-to-speech failed:", err)
+		}
+	}
+}
+
+// spokenQuestion is the announcement of a new question. If a
+// translation is known, it is read instead of the original.
+func (q *quizTexts) spokenQuestion(index int) string {
+	res := fmt.Sprintf("Question %d.", index+1)
+	question, translated := q.question(index)
+	if translated != nil {
+		question = translated
+	}
+	if question != nil {
+		res += " " + question.Question
+	}
+	return res
+}
+
+// spokenChoices is the announcement that answering is open.
+// Each choice is described by its shape on the host's screen.
+func (q *quizTexts) spokenChoices(action *kahoot.QuizAction) string {
+	question, translated := q.question(action.Index)
+	if translated != nil {
+		question = translated
+	}
+	var parts []string
+	if question != nil && len(question.Choices) == action.NumAnswers {
+		for screen := range question.Choices {
+			c := question.Choices[action.QuizChoice(screen)]
+			style := kahoot.ChoiceStyleAt(screen)
+			parts = append(parts, fmt.Sprintf("%d, %s: %s.", screen+1, style, c.Answer))
+		}
+	}
+	parts = append(parts, fmt.Sprintf("Answer with a number from 1 to %d.", action.NumAnswers))
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/translate"
+)
+
+// quizTexts holds the texts of a quiz known ahead of time,
+// and optionally their translations. A nil *quizTexts prints
+// nothing.
+type quizTexts struct {
+	quiz       *kahoot.QuizInfo
+	translated *kahoot.QuizInfo
+}
+
+func loadQuizTexts(path, translator, target string) (*quizTexts, error) {
+	quiz, err := kahoot.ReadQuizFile(path)
+	if err != nil {
+		return nil, err
+	}
+	res := &quizTexts{quiz: quiz}
+	if target != "" {
+		provider, err := translate.New(translator)
+		if err != nil {
+			return nil, err
+		}
+		res.translated, err = translate.Quiz(provider, quiz, target)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (q *quizTexts) question(index int) (*kahoot.QuizQuestion, *kahoot.QuizQuestion) {
+	if q == nil || index < 0 || index >= len(q.quiz.Questions) {
+		return nil, nil
+	}
+	if q.translated == nil {
+		return &q.quiz.Questions[index], nil
+	}
+	return &q.quiz.Questions[index], &q.translated.Questions[index]
+}
+
+func (q *quizTexts) printQuestion(index int) {
+	question, translated := q.question(index)
+	if question == nil {
+		return
+	}
+	fmt.Printf("Question %d: %s\n", index+1, question.Question)
+	if translated != nil {
+		fmt.Printf("            %s\n", translated.Question)
+	}
+}
+
+// printChoices lists the choices in the order they are shown
+// on screen, numbered from 1 like the keys which answer them.
+func (q *quizTexts) printChoices(action *kahoot.QuizAction) {
+	question, translated := q.question(action.Index)
+	if question == nil || len(question.Choices) != action.NumAnswers {
+		return
+	}
+	for screen := range question.Choices {
+		i := action.QuizChoice(screen)
+		fmt.Printf("  %d. %s", screen+1, question.Choices[i].Answer)
+		if translated != nil {
+			fmt.Printf(" (%s)", translated.Choices[i].Answer)
+		}
+		fmt.Println()
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -71,19 +72,23 @@ func nicknames() []string {
 	return res
 }
 
+const maxResponseSize = 1 << 16
+
+var client = &http.Client{Timeout: kahoot.DefaultTimeout}
+
 func sanitizeName(name string) (string, error) {
 	for interval := 16; interval > 1; interval-- {
 		attempt := boldify(name, interval)
 		testURL := "https://play.kahoot.it/profanity/profanities/nickname/" +
 			url.QueryEscape(attempt)
-		resp, err := http.Get(testURL)
+		resp, err := client.Get(testURL)
 		if resp != nil {
 			defer resp.Body.Close()
 		}
 		if err != nil {
 			return "", err
 		}
-		contents, err := ioutil.ReadAll(resp.Body)
+		contents, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
 		if err != nil {
 			return "", err
 		}
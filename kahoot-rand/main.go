@@ -3,18 +3,17 @@ package main
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"math/rand"
 	"os"
 	"os/signal"
 	"strconv"
-	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
 )
 
 var wg sync.WaitGroup
@@ -95,7 +94,7 @@ func launchConnection(gamePin string, nickname string) {
 		if action.Type == kahoot.QuestionAnswers {
 			atomic.StoreUint32(&AnswerCount, uint32(action.NumAnswers))
 			answer := rand.Intn(action.NumAnswers)
-			quiz.Send(action.AnswerMap[answer])
+			quiz.Send(action.QuizChoice(answer))
 			StatisticsChan <- answer
 		}
 	}
@@ -122,17 +121,13 @@ func printStatistics(clientCount int) {
 
 func readNicknames() (<-chan string, error) {
 	if len(os.Args) == 3 {
-		contents, err := ioutil.ReadFile(os.Args[2])
+		names, err := swarm.ReadRoster(os.Args[2])
 		if err != nil {
 			return nil, err
 		}
-		nameLines := strings.Split(string(contents), "\n")
-		res := make(chan string, len(nameLines))
-		for _, line := range nameLines {
-			nickname := strings.TrimSpace(line)
-			if len(nickname) != 0 {
-				res <- nickname
-			}
+		res := make(chan string, len(names))
+		for _, nickname := range names {
+			res <- nickname
 		}
 		close(res)
 		return res, nil
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/unixpickle/kahoot-hack/reconcile"
+)
+
+func main() {
+	tolerance := flag.Duration("tolerance", reconcile.DefaultTolerance,
+		"largest answer time difference which is not reported")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: reconcile [-tolerance 1s] <host report.csv> <run directory>")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	host, err := reconcile.ReadHostReportFile(flag.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, flag.Arg(0)+":", err)
+		os.Exit(1)
+	}
+	bots, err := reconcile.ReadBotAnswers(flag.Arg(1))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, flag.Arg(1)+":", err)
+		os.Exit(1)
+	}
+	report := reconcile.Reconcile(bots, host, *tolerance)
+	report.WriteText(os.Stdout)
+	if len(report.Discrepancies()) > 0 {
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,59 @@
+package kahoot
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// AbandonTimeout is how long a connection may go without
+// hearing from the server before the game is considered
+// abandoned. It is well above the long-poll timeout which the
+// handshake asks for.
+var AbandonTimeout = 2 * time.Minute
+
+// GameAbandoned is published as an "abandoned" connection event
+// when the game seems to have ended without a quiz-end message,
+// for instance because the host went away. The connection may
+// still be open; it is up to the caller to close it.
+type GameAbandoned struct {
+	Reason string `json:"reason"`
+
+	// Silence is how long nothing had been heard from the
+	// server, if that is the reason.
+	Silence time.Duration `json:"silence,omitempty"`
+}
+
+func (c *Conn) heard(now time.Time) {
+	atomic.StoreInt64(&c.lastHeard, now.UnixNano())
+}
+
+// checkSilence reports the game abandoned if the server has
+// been silent for longer than AbandonTimeout.
+func (c *Conn) checkSilence(now time.Time) {
+	last := atomic.LoadInt64(&c.lastHeard)
+	if last == 0 {
+		return
+	}
+	if silence := now.Sub(time.Unix(0, last)); silence > AbandonTimeout {
+		c.abandon(&GameAbandoned{Reason: "server stopped responding", Silence: silence})
+	}
+}
+
+// abandon publishes the event once per connection.
+func (c *Conn) abandon(a *GameAbandoned) {
+	if atomic.CompareAndSwapInt32(&c.abandoned, 0, 1) {
+		c.events.Publish(TopicConnection, "abandoned", a)
+	}
+}
+
+// abandonAdvice reports whether a /meta/connect reply tells the
+// client to stop reconnecting, which the server does once the
+// game is gone.
+func abandonAdvice(msg Message) bool {
+	advice, ok := msg["advice"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	reconnect, _ := advice["reconnect"].(string)
+	return reconnect == "none"
+}
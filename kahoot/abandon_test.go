@@ -0,0 +1,42 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAbandon(t *testing.T) {
+	c := &Conn{events: NewBus()}
+	sub := c.events.Subscribe(TopicConnection)
+	defer sub.Close()
+
+	now := time.Now()
+	c.checkSilence(now)
+	c.heard(now)
+	c.checkSilence(now.Add(AbandonTimeout / 2))
+	c.checkSilence(now.Add(AbandonTimeout + time.Second))
+	c.checkSilence(now.Add(2 * AbandonTimeout))
+
+	e := <-sub.C
+	a, ok := e.Data.(*GameAbandoned)
+	if e.Type != "abandoned" || !ok || a.Silence != AbandonTimeout+time.Second {
+		t.Fatalf("unexpected event %+v", e)
+	}
+	select {
+	case e := <-sub.C:
+		t.Errorf("expected a single event but also got %+v", e)
+	default:
+	}
+	if _, ok := decodeGameEvent(e).(*GameAbandoned); !ok {
+		t.Error("abandoned events should decode to GameAbandoned")
+	}
+}
+
+func TestAbandonAdvice(t *testing.T) {
+	if !abandonAdvice(Message{"advice": map[string]interface{}{"reconnect": "none"}}) {
+		t.Error("reconnect none should mean abandoned")
+	}
+	if abandonAdvice(Message{"advice": map[string]interface{}{"reconnect": "retry"}}) || abandonAdvice(Message{}) {
+		t.Error("only reconnect none should mean abandoned")
+	}
+}
@@ -0,0 +1,128 @@
+package kahoot
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// ErrQuizMismatch is returned by AutoPlay when the game's
+// questions do not have the numbers of choices of the quiz it
+// was given.
+var ErrQuizMismatch = errors.New("game does not match the quiz")
+
+// AutoPlayOptions configures Conn.AutoPlay.
+type AutoPlayOptions struct {
+	// Quiz, if non-nil, is used instead of fetching the public
+	// quiz, e.g. a quiz from QuizInformation.
+	Quiz *QuizInfo
+
+	// Each answer waits a random time between MinDelay and
+	// MaxDelay after the question opens, but never so long
+	// that it would arrive after the question closes.
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+// AutoPlay answers every question of the game with the
+// correct answer from the quiz with the given id, until
+// receiving fails, and returns that error. Questions without a
+// correct answer, such as polls, get a random one.
+func (c *Conn) AutoPlay(quizID string, opts AutoPlayOptions) error {
+	quizInfo := opts.Quiz
+	if quizInfo == nil {
+		var err error
+		if quizInfo, err = PublicQuiz(quizID); err != nil {
+			return err
+		}
+	}
+	quiz := NewQuiz(c)
+	for {
+		action, err := quiz.Receive()
+		if err != nil {
+			return err
+		}
+		if action.Type != QuestionAnswers {
+			continue
+		}
+		if !quizInfo.Matches(action.AnswerCounts) {
+			c.events.Publish(TopicError, "autoplay", ErrQuizMismatch)
+			return ErrQuizMismatch
+		}
+		time.Sleep(time.Until(autoPlayTime(action, c.RTT(), opts)))
+		if err := quiz.Send(correctChoice(quizInfo, action)); err != nil {
+			return err
+		}
+	}
+}
+
+// Matches reports whether the quiz's questions have the given
+// numbers of choices, as listed in QuizAction.AnswerCounts.
+// Every quiz matches empty answerCounts.
+func (quiz *QuizInfo) Matches(answerCounts []int) bool {
+	if len(answerCounts) == 0 {
+		return true
+	} else if len(quiz.Questions) != len(answerCounts) {
+		return false
+	}
+	for i, q := range quiz.Questions {
+		if len(q.Choices) != answerCounts[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// CorrectChoice returns the index of the first correct choice
+// of a question, or false if it has none.
+func (quiz *QuizInfo) CorrectChoice(question int) (int, bool) {
+	if question >= 0 && question < len(quiz.Questions) {
+		for i, c := range quiz.Questions[question].Choices {
+			if c.Correct {
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// CorrectScreenChoice returns where the first correct choice
+// of the action's question is shown on screen, or false if it
+// has none. When the game shows the choice texts, they are
+// matched against the quiz instead of trusting AnswerMap, so
+// that shuffled answers are found either way.
+func (quiz *QuizInfo) CorrectScreenChoice(action *QuizAction) (int, bool) {
+	choice, ok := quiz.CorrectChoice(action.Index)
+	if !ok {
+		return 0, false
+	}
+	correct := strings.TrimSpace(quiz.Questions[action.Index].Choices[choice].Answer)
+	for screen, text := range action.Choices {
+		if correct != "" && strings.EqualFold(strings.TrimSpace(text), correct) {
+			return screen, true
+		}
+	}
+	return action.ScreenChoice(choice), true
+}
+
+// correctChoice returns the quiz's index of the first correct
+// choice of the action's question, or a random one if none is.
+func correctChoice(quiz *QuizInfo, action *QuizAction) int {
+	if screen, ok := quiz.CorrectScreenChoice(action); ok {
+		return action.QuizChoice(screen)
+	}
+	if action.NumAnswers <= 0 {
+		return 0
+	}
+	return rand.Intn(action.NumAnswers)
+}
+
+// autoPlayTime picks when to answer the action.
+func autoPlayTime(action *QuizAction, rtt time.Duration, opts AutoPlayOptions) time.Time {
+	delay := opts.MinDelay
+	if opts.MaxDelay > opts.MinDelay {
+		delay += time.Duration(rand.Int63n(int64(opts.MaxDelay - opts.MinDelay)))
+	}
+	return Answer{Delay: delay}.At(action, rtt)
+}
@@ -0,0 +1,67 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCorrectChoice(t *testing.T) {
+	quiz := &QuizInfo{Questions: []QuizQuestion{
+		{Choices: []QuizChoice{{Answer: "a"}, {Answer: "b"}, {Answer: "c", Correct: true}}},
+		{Choices: []QuizChoice{{Answer: "yes"}, {Answer: "no"}}},
+	}}
+	if !quiz.Matches([]int{3, 2}) || quiz.Matches([]int{3, 3}) || quiz.Matches([]int{3}) || !quiz.Matches(nil) {
+		t.Error("unexpected Matches results")
+	}
+	if c := correctChoice(quiz, &QuizAction{Index: 0, NumAnswers: 3}); c != 2 {
+		t.Errorf("expected choice 2 but got %d", c)
+	}
+	for i := 0; i < 10; i++ {
+		if c := correctChoice(quiz, &QuizAction{Index: 1, NumAnswers: 2}); c < 0 || c > 1 {
+			t.Fatalf("poll answer %d out of range", c)
+		}
+	}
+}
+
+func TestAutoPlayTime(t *testing.T) {
+	received := time.Now()
+	opts := AutoPlayOptions{MinDelay: time.Second, MaxDelay: 3 * time.Second}
+	action := &QuizAction{Received: received, TimeLeft: 20 * time.Second}
+	for i := 0; i < 20; i++ {
+		at := autoPlayTime(action, 0, opts)
+		if at.Before(received.Add(time.Second)) || !at.Before(received.Add(3*time.Second)) {
+			t.Fatalf("answer time %v outside of the delay range", at.Sub(received))
+		}
+	}
+	short := &QuizAction{Received: received, TimeLeft: 2 * time.Second}
+	opts.MinDelay = 5 * time.Second
+	opts.MaxDelay = 5 * time.Second
+	if at := autoPlayTime(short, 100*time.Millisecond, opts); !at.Equal(received.Add(1900 * time.Millisecond)) {
+		t.Errorf("expected the answer before the deadline, got %v", at.Sub(received))
+	}
+}
+
+func TestCorrectScreenChoice(t *testing.T) {
+	quiz := &QuizInfo{Questions: []QuizQuestion{
+		{Choices: []QuizChoice{{Answer: "a"}, {Answer: "b"}, {Answer: "c", Correct: true}}},
+	}}
+	shuffled := &QuizAction{NumAnswers: 3, AnswerMap: map[int]int{0: 2, 1: 0, 2: 1}}
+	if screen, ok := quiz.CorrectScreenChoice(shuffled); !ok || screen != 0 {
+		t.Errorf("expected screen choice 0 but got %d", screen)
+	}
+	if c := correctChoice(quiz, shuffled); c != 2 {
+		t.Errorf("expected quiz choice 2 but got %d", c)
+	}
+
+	// The texts win over a map which says nothing was moved.
+	shown := &QuizAction{NumAnswers: 3, AnswerMap: map[int]int{0: 0, 1: 1, 2: 2},
+		Choices: []string{"b", " C ", "a"}}
+	if screen, ok := quiz.CorrectScreenChoice(shown); !ok || screen != 1 {
+		t.Errorf("expected screen choice 1 but got %d", screen)
+	}
+
+	unmapped := &QuizAction{NumAnswers: 3}
+	if unmapped.QuizChoice(2) != 2 || unmapped.ScreenChoice(1) != 1 {
+		t.Error("a missing AnswerMap should be the identity")
+	}
+}
@@ -0,0 +1,128 @@
+package kahoot
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// A Budget caps how much traffic this process may generate,
+// so that a misconfigured script fails loudly instead of
+// hammering the servers. Zero fields are unlimited.
+//
+// The budget is shared by every Conn, Quiz and HTTP helper in
+// the package.
+type Budget struct {
+	// RequestsPerHour limits HTTP requests and new
+	// connections over any one-hour window.
+	RequestsPerHour int `json:"requestsPerHour,omitempty"`
+
+	// MaxConns limits the number of open connections, which
+	// is the number of concurrent bots.
+	MaxConns int `json:"maxConns,omitempty"`
+
+	// AnswersPerMinute limits answer messages over any
+	// one-minute window.
+	AnswersPerMinute int `json:"answersPerMinute,omitempty"`
+}
+
+// A BudgetError is returned when an operation would exceed
+// the budget. The operation is not performed.
+type BudgetError struct {
+	Limit string
+	Max   int
+}
+
+func (b *BudgetError) Error() string {
+	return fmt.Sprintf("budget exceeded: at most %d %s", b.Max, b.Limit)
+}
+
+type budgetState struct {
+	lock     sync.Mutex
+	budget   Budget
+	requests []time.Time
+	answers  []time.Time
+	conns    int
+}
+
+var currentBudget budgetState
+
+// SetBudget replaces the budget. Usage already counted in the
+// current windows still applies.
+// In a demo build, the budget never exceeds the demo limits.
+func SetBudget(b Budget) {
+	if DemoMode {
+		b = demoBudget(b)
+	}
+	currentBudget.lock.Lock()
+	defer currentBudget.lock.Unlock()
+	currentBudget.budget = b
+}
+
+// CurrentBudget returns the budget set by SetBudget.
+func CurrentBudget() Budget {
+	currentBudget.lock.Lock()
+	defer currentBudget.lock.Unlock()
+	return currentBudget.budget
+}
+
+// TakeRequest counts one HTTP request against the budget, for
+// requests made outside the package. It returns a *BudgetError
+// if the request should not be made.
+func TakeRequest() error {
+	return currentBudget.takeRequest()
+}
+
+func (b *budgetState) takeRequest() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var ok bool
+	b.requests, ok = takeWindow(b.requests, time.Hour, b.budget.RequestsPerHour)
+	if !ok {
+		return &BudgetError{Limit: "requests per hour", Max: b.budget.RequestsPerHour}
+	}
+	return nil
+}
+
+func (b *budgetState) takeAnswer() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	var ok bool
+	b.answers, ok = takeWindow(b.answers, time.Minute, b.budget.AnswersPerMinute)
+	if !ok {
+		return &BudgetError{Limit: "answer messages per minute", Max: b.budget.AnswersPerMinute}
+	}
+	return nil
+}
+
+func (b *budgetState) takeConn() error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.budget.MaxConns > 0 && b.conns >= b.budget.MaxConns {
+		return &BudgetError{Limit: "concurrent bots", Max: b.budget.MaxConns}
+	}
+	b.conns++
+	return nil
+}
+
+func (b *budgetState) releaseConn() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.conns--
+}
+
+// takeWindow records a use in a sliding window of times,
+// unless max uses already happened within the window.
+func takeWindow(times []time.Time, window time.Duration, max int) ([]time.Time, bool) {
+	if max <= 0 {
+		return times, true
+	}
+	cutoff := time.Now().Add(-window)
+	for len(times) > 0 && !times[0].After(cutoff) {
+		times = times[1:]
+	}
+	if len(times) >= max {
+		return times, false
+	}
+	return append(times, time.Now()), true
+}
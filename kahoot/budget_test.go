@@ -0,0 +1,43 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBudget(t *testing.T) {
+	b := &budgetState{budget: Budget{RequestsPerHour: 2, MaxConns: 1}}
+	for i := 0; i < 2; i++ {
+		if err := b.takeRequest(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err, ok := b.takeRequest().(*BudgetError); !ok || err.Max != 2 {
+		t.Errorf("expected budget error but got %v", err)
+	}
+	if err := b.takeAnswer(); err != nil {
+		t.Error("unlimited answers should not fail:", err)
+	}
+
+	if err := b.takeConn(); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.takeConn(); err == nil {
+		t.Error("expected second connection to exceed the budget")
+	}
+	b.releaseConn()
+	if err := b.takeConn(); err != nil {
+		t.Error("released connection should free a slot:", err)
+	}
+}
+
+func TestTakeWindow(t *testing.T) {
+	old := []time.Time{time.Now().Add(-2 * time.Minute), time.Now()}
+	times, ok := takeWindow(old, time.Minute, 2)
+	if !ok || len(times) != 2 {
+		t.Errorf("expected expired use to be dropped, got %v %v", times, ok)
+	}
+	if _, ok := takeWindow(times, time.Minute, 2); ok {
+		t.Error("expected window to be full")
+	}
+}
@@ -0,0 +1,180 @@
+package kahoot
+
+import (
+	"sync"
+	"time"
+)
+
+const subscriptionBufferSize = 64
+
+// A Topic is a category of events published on a Bus.
+type Topic string
+
+const (
+	TopicConnection Topic = "connection"
+	TopicQuestion   Topic = "question"
+	TopicResult     Topic = "result"
+	TopicError      Topic = "error"
+)
+
+// An Event is a single notification published on a Bus.
+type Event struct {
+	Topic Topic
+	Type  string
+	Time  time.Time
+
+	// Seq increases by one for every event published
+	// on the Bus, regardless of topic.
+	Seq uint64
+
+	Data interface{}
+}
+
+// A Bus delivers events to any number of independent
+// subscribers.
+//
+// Publishing never blocks; if a subscriber is not keeping
+// up, events are dropped for that subscriber only.
+//
+// A Bus also keeps its newest events, so that consumers which
+// attach late, such as a dashboard opened mid-game, can catch
+// up with what they missed.
+type Bus struct {
+	lock    sync.Mutex
+	seq     uint64
+	subs    map[*Subscription]struct{}
+	history *eventRing
+	closed  bool
+}
+
+// NewBus creates an empty Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subs:    map[*Subscription]struct{}{},
+		history: newEventRing(DefaultHistorySize),
+	}
+}
+
+// SetHistorySize changes how many events the Bus keeps,
+// forgetting those it has kept so far. A size of 0 keeps none.
+func (b *Bus) SetHistorySize(size int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.history = newEventRing(size)
+}
+
+// History returns the events the Bus has kept, oldest first.
+// They remain available after the Bus is closed.
+func (b *Bus) History() History {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.history.history()
+}
+
+// Subscribe creates a Subscription for the given topics.
+// If no topics are given, the Subscription receives every
+// event.
+func (b *Bus) Subscribe(topics ...Topic) *Subscription {
+	_, s := b.Replay(topics...)
+	return s
+}
+
+// Replay is like Subscribe, but also returns the kept events in
+// the given topics. No event is in both: the Subscription gets
+// exactly those published after the History.
+func (b *Bus) Replay(topics ...Topic) (History, *Subscription) {
+	ch := make(chan Event, subscriptionBufferSize)
+	s := &Subscription{C: ch, ch: ch, bus: b}
+	if len(topics) > 0 {
+		s.topics = map[Topic]bool{}
+		for _, t := range topics {
+			s.topics[t] = true
+		}
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		close(ch)
+	} else {
+		b.subs[s] = struct{}{}
+	}
+	history := b.history.history()
+	if len(topics) > 0 {
+		history = history.OfTopic(topics...)
+	}
+	return history, s
+}
+
+// Publish sends an event to every interested subscriber.
+// It returns the event that was published.
+func (b *Bus) Publish(topic Topic, typeStr string, data interface{}) Event {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.seq++
+	e := Event{
+		Topic: topic,
+		Type:  typeStr,
+		Time:  time.Now(),
+		Seq:   b.seq,
+		Data:  data,
+	}
+	if b.closed {
+		return e
+	}
+	b.history.add(e)
+	for s := range b.subs {
+		if s.topics != nil && !s.topics[topic] {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			s.dropped++
+		}
+	}
+	return e
+}
+
+// Close closes every subscription.
+// Events published after Close are discarded.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		return
+	}
+	b.closed = true
+	for s := range b.subs {
+		close(s.ch)
+	}
+	b.subs = nil
+}
+
+// A Subscription receives events from a Bus.
+type Subscription struct {
+	// C is closed when the Subscription or its Bus is closed.
+	C <-chan Event
+
+	ch      chan Event
+	bus     *Bus
+	topics  map[Topic]bool
+	dropped int
+}
+
+// Dropped returns the number of events which were not
+// delivered because the subscriber was not keeping up.
+func (s *Subscription) Dropped() int {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	return s.dropped
+}
+
+// Close unsubscribes from the Bus and closes s.C.
+func (s *Subscription) Close() {
+	s.bus.lock.Lock()
+	defer s.bus.lock.Unlock()
+	if _, ok := s.bus.subs[s]; ok {
+		delete(s.bus.subs, s)
+		close(s.ch)
+	}
+}
@@ -0,0 +1,101 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBusTopics(t *testing.T) {
+	b := NewBus()
+	all := b.Subscribe()
+	questions := b.Subscribe(TopicQuestion)
+
+	b.Publish(TopicConnection, "connected", nil)
+	b.Publish(TopicQuestion, "intro", 3)
+	b.Close()
+
+	var allTypes []string
+	for e := range all.C {
+		allTypes = append(allTypes, e.Type)
+	}
+	if len(allTypes) != 2 || allTypes[0] != "connected" || allTypes[1] != "intro" {
+		t.Errorf("unexpected events: %v", allTypes)
+	}
+
+	e, ok := <-questions.C
+	if !ok || e.Type != "intro" || e.Data != 3 || e.Seq != 2 {
+		t.Errorf("unexpected event: %+v", e)
+	}
+	if _, ok := <-questions.C; ok {
+		t.Error("expected closed subscription")
+	}
+}
+
+func TestBusDrops(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe(TopicError)
+	for i := 0; i < subscriptionBufferSize+5; i++ {
+		b.Publish(TopicError, "receive", nil)
+	}
+	if s.Dropped() != 5 {
+		t.Errorf("expected 5 drops but got %d", s.Dropped())
+	}
+	s.Close()
+	s.Close()
+}
+
+func TestBusHistory(t *testing.T) {
+	b := NewBus()
+	b.SetHistorySize(3)
+	b.Publish(TopicConnection, "connected", nil)
+	b.Publish(TopicQuestion, "intro", 1)
+	middle := time.Now()
+	b.Publish(TopicQuestion, "answers", 1)
+	b.Publish(TopicResult, "result", nil)
+	b.Close()
+	b.Publish(TopicError, "late", nil)
+
+	h := b.History()
+	if len(h) != 3 || h[0].Type != "intro" || h[2].Type != "result" {
+		t.Fatalf("unexpected history: %v", h)
+	}
+	if q := h.Since(middle).OfTopic(TopicQuestion); len(q) != 1 || q[0].Type != "answers" {
+		t.Errorf("unexpected questions since %v: %v", middle, q)
+	}
+	if r := h.OfType("result", "connected"); len(r) != 1 || r[0].Seq != 4 {
+		t.Errorf("unexpected results: %v", r)
+	}
+	if u := h.Until(middle).After(1); len(u) != 1 || u[0].Type != "intro" {
+		t.Errorf("unexpected early events: %v", u)
+	}
+	if e, ok := h.OfTopic(TopicError).Last(); ok {
+		t.Errorf("unexpected error event: %v", e)
+	}
+}
+
+func TestBusReplay(t *testing.T) {
+	b := NewBus()
+	b.Publish(TopicQuestion, "intro", 1)
+	b.Publish(TopicError, "receive", nil)
+	h, s := b.Replay(TopicQuestion)
+	b.Publish(TopicQuestion, "answers", 1)
+	b.Close()
+
+	if len(h) != 1 || h[0].Type != "intro" {
+		t.Errorf("unexpected history: %v", h)
+	}
+	var types []string
+	for e := range s.C {
+		types = append(types, e.Type)
+	}
+	if len(types) != 1 || types[0] != "answers" {
+		t.Errorf("unexpected events: %v", types)
+	}
+
+	b = NewBus()
+	b.SetHistorySize(0)
+	b.Publish(TopicQuestion, "intro", 1)
+	if h := b.History(); len(h) != 0 {
+		t.Errorf("expected no history but got %v", h)
+	}
+}
@@ -0,0 +1,72 @@
+// Package challenge solves the challenges which the reserve
+// endpoint sends with each session token. A challenge is a
+// short JavaScript program whose result is the mask that
+// decodes the token.
+//
+// Solvers are tried in the order they were registered. The
+// package registers a solver for the decode function Kahoot
+// has used since 2016; new challenge formats can be supported
+// by registering another solver, without touching the code
+// which reserves sessions.
+package challenge
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// ErrUnrecognized is returned, possibly wrapped, when no
+// solver knows a challenge's shape.
+var ErrUnrecognized = errors.New("challenge not recognized")
+
+// A Solver computes the mask for a challenge. It returns an
+// error wrapping ErrUnrecognized if the challenge is not in a
+// shape it knows.
+type Solver func(challenge string) (string, error)
+
+// Decode applies the challenge's decode function to message,
+// like String.fromCharCode(((c * i) + offset) % 77 + 48) for
+// every character c at position i.
+func Decode(message string, offset float64) string {
+	var res []rune
+	for i, x := range []rune(message) {
+		n := math.Mod(float64(x)*float64(i)+offset, 77) + 48
+		res = append(res, rune(uint16(n)))
+	}
+	return string(res)
+}
+
+// PatternSolver returns a Solver for challenges matching any of
+// the patterns. Each pattern must capture the message first and
+// the offset expression second.
+func PatternSolver(patterns []*regexp.Regexp) Solver {
+	return func(ch string) (string, error) {
+		for _, expr := range patterns {
+			submatch := expr.FindStringSubmatch(ch)
+			if len(submatch) < 3 {
+				continue
+			}
+			if offset, err := Eval(submatch[2]); err == nil {
+				return Decode(submatch[1], offset), nil
+			}
+		}
+		return "", ErrUnrecognized
+	}
+}
+
+// decodeRegexp matches every known shape of the decode
+// function: the message may be passed directly (as in 2016) or
+// through decode.call(this, ...), and the offset may be logged
+// or checked before it is used, but not reassigned.
+var decodeRegexp = regexp.MustCompile(`^decode(?:\.call\(this,\s*|\()'([a-zA-Z0-9]*)'\);\s*` +
+	`function decode\(message\)\s*\{var offset = ([0-9\+\*\(\)\s]*);[^=]*` +
+	`return _\.replace\(message, /\./g, function\(char, position\) ` +
+	`\{return String\.fromCharCode\(\(\(\(char\.charCodeAt\(0\) \* position\) \+ offset\) % 77\) \+ 48\);\}\);\}$`)
+
+// SolveDecode solves challenges built on the decode function,
+// in any of the shapes Kahoot has sent.
+func SolveDecode(ch string) (string, error) {
+	return PatternSolver([]*regexp.Regexp{decodeRegexp})(strings.TrimSpace(ch))
+}
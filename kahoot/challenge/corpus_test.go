@@ -0,0 +1,77 @@
+package challenge
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+// A corpusEntry is a challenge with its expected mask, or an
+// empty mask if no built-in solver should recognize it.
+type corpusEntry struct {
+	Name      string `json:"name"`
+	Source    string `json:"source"`
+	Challenge string `json:"challenge"`
+	Mask      string `json:"mask"`
+}
+
+func readCorpus(t *testing.T) []corpusEntry {
+	data, err := ioutil.ReadFile("testdata/corpus.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []corpusEntry
+	if err := json.Unmarshal(data, &res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestCorpus(t *testing.T) {
+	for _, entry := range readCorpus(t) {
+		t.Run(entry.Name, func(t *testing.T) {
+			mask, name, err := Solve(entry.Challenge)
+			if entry.Mask == "" {
+				if !errors.Is(err, ErrUnrecognized) {
+					t.Errorf("expected the challenge to go unrecognized, got %q from %s", mask, name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			} else if mask != entry.Mask {
+				t.Errorf("%s: expected %q got %q", name, entry.Mask, mask)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer Unregister("test")
+	ch := "offset(42)"
+	if _, _, err := Solve(ch); err == nil {
+		t.Fatal("expected no solver to know the challenge")
+	}
+	Register("test", func(ch string) (string, error) {
+		if ch != "offset(42)" {
+			return "", ErrUnrecognized
+		}
+		return "mask", nil
+	})
+	if mask, name, err := Solve(ch); err != nil || mask != "mask" || name != "test" {
+		t.Errorf("unexpected result %q %q %v", mask, name, err)
+	}
+	Register("test", func(string) (string, error) { return "", ErrUnrecognized })
+	names := Names()
+	if len(names) != 2 || names[0] != "decode" || names[1] != "test" {
+		t.Errorf("unexpected names %v", names)
+	}
+	if _, _, err := Solve(ch); err == nil {
+		t.Error("expected the replaced solver to be used")
+	}
+	Unregister("test")
+	if len(Names()) != 1 {
+		t.Errorf("unexpected names %v", Names())
+	}
+}
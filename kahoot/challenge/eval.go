@@ -1,4 +1,4 @@
-package kahoot
+package challenge
 
 import (
 	"regexp"
@@ -8,12 +8,16 @@ import (
 
 var simpleExprRegexp = regexp.MustCompile(`\(([0-9\+\*\s]*)\)`)
 
-// eval evaluates a mathematical expression, such as:
+// Eval evaluates a challenge's offset expression, such as:
 //
-//     ((76 * 21) * (((81 + 4) * 55) + 10))
+//	((76 * 21) * (((81 + 4) * 55) + 10))
 //
-// This is necessary to obtain session tokens.
-func eval(expr string) (int64, error) {
+// The challenge is really computed by JavaScript, so Eval
+// follows JavaScript's number semantics: every value is a
+// float64, operations happen in the same order, and large
+// products are rounded rather than overflowing. Below 2^53
+// this is exact integer arithmetic.
+func Eval(expr string) (float64, error) {
 	for {
 		// Evaluate a simple sub-expression.
 		match := simpleExprRegexp.FindStringSubmatch(expr)
@@ -25,7 +29,7 @@ func eval(expr string) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		valStr := strconv.FormatInt(val, 10)
+		valStr := strconv.FormatFloat(val, 'f', -1, 64)
 		expr = strings.Replace(expr, match[0], valStr, 1)
 	}
 	return evalSimple(expr)
@@ -34,14 +38,13 @@ func eval(expr string) (int64, error) {
 // evalSimple evaluates an expression with no nested
 // parentheses, like
 //
-//     23 + 64 + 35 * 35
-//
-func evalSimple(expr string) (int64, error) {
-	var sum int64
+//	23 + 64 + 35 * 35
+func evalSimple(expr string) (float64, error) {
+	var sum float64
 	for _, sumTerm := range strings.Split(expr, "+") {
-		product := int64(1)
+		product := float64(1)
 		for _, numStr := range strings.Split(sumTerm, "*") {
-			num, err := strconv.ParseInt(strings.TrimSpace(numStr), 10, 64)
+			num, err := strconv.ParseFloat(strings.TrimSpace(numStr), 64)
 			if err != nil {
 				return 0, err
 			}
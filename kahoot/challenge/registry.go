@@ -0,0 +1,69 @@
+package challenge
+
+import (
+	"fmt"
+	"sync"
+)
+
+type registered struct {
+	name   string
+	solver Solver
+}
+
+var registryLock sync.Mutex
+var registry = []registered{{"decode", SolveDecode}}
+
+// Register adds a solver under a name, to be tried after those
+// registered before it. Registering a name again replaces that
+// solver in place.
+func Register(name string, solver Solver) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for i, r := range registry {
+		if r.name == name {
+			registry[i].solver = solver
+			return
+		}
+	}
+	registry = append(registry, registered{name, solver})
+}
+
+// Unregister removes the solver with the given name, if there
+// is one.
+func Unregister(name string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for i, r := range registry {
+		if r.name == name {
+			registry = append(registry[:i:i], registry[i+1:]...)
+			return
+		}
+	}
+}
+
+// Names returns the names of the registered solvers, in the
+// order they are tried.
+func Names() []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	var res []string
+	for _, r := range registry {
+		res = append(res, r.name)
+	}
+	return res
+}
+
+// Solve tries each registered solver in turn and returns the
+// first mask found, with the name of the solver which found
+// it. If every solver fails, the error wraps ErrUnrecognized.
+func Solve(ch string) (mask, name string, err error) {
+	registryLock.Lock()
+	solvers := append([]registered{}, registry...)
+	registryLock.Unlock()
+	for _, r := range solvers {
+		if mask, err := r.solver(ch); err == nil {
+			return mask, r.name, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w by any of %d solvers", ErrUnrecognized, len(solvers))
+}
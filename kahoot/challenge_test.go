@@ -0,0 +1,77 @@
+package kahoot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot/challenge"
+)
+
+func TestLargeOperands(t *testing.T) {
+	// Expected masks are from running the challenge in node.
+	cases := []struct {
+		message, offset, mask string
+	}{
+		{"Kahoot", "(99999 * 99999 * 99999 * 99999)", "666666"},
+		{"abcXYZ09", "(4294967296 * 4294967296 + 7)", "@@@@@@@@"},
+		{"q", "123456789012 * 987654321098 + 5", "W"},
+		{"longerMessage123", "((76 * 21) * (((81 + 4) * 55) + 10)) * 9007199254740993",
+			">>>>>>>>>>>>>>>>"},
+	}
+	for _, c := range cases {
+		challenge := strings.Replace(strings.Replace(selfTestChallenge, "'Kahoot'",
+			"'"+c.message+"'", 1), "3 * (2 + 1)", c.offset, 1)
+		mask, ok := solveChallengeLocally(challenge)
+		if !ok {
+			t.Errorf("%s: challenge not recognized", c.offset)
+		} else if string(mask) != c.mask {
+			t.Errorf("%s: expected %q got %q", c.offset, c.mask, mask)
+		}
+	}
+}
+
+func TestRegisteredSolver(t *testing.T) {
+	// The 2016 shape no longer matches the protocol's patterns,
+	// but the challenge package still knows it.
+	ch := "decode('Kahoot'); function decode(message) {var offset = 3 * (2 + 1); " +
+		"console.log(\"Offset derived as:\", offset); " +
+		"return _.replace(message, /./g, function(char, position) " +
+		"{return String.fromCharCode((((char.charCodeAt(0) * position) + offset) % 77) + 48);});}"
+	if _, ok := solveChallengeLocally(ch); ok {
+		t.Fatal("challenge should not match the protocol's patterns")
+	}
+	mask, err := computeChallenge(context.Background(), nil, ch)
+	if err != nil || string(mask) != selfTestAnswer {
+		t.Errorf("expected %q, got %q (%v)", selfTestAnswer, mask, err)
+	}
+
+	defer challenge.Unregister("test")
+	challenge.Register("test", func(ch string) (string, error) {
+		if ch != "new format" {
+			return "", challenge.ErrUnrecognized
+		}
+		return "abc", nil
+	})
+	if mask, err := computeChallenge(context.Background(), nil, "new format"); err != nil || string(mask) != "abc" {
+		t.Errorf("expected the registered solver's mask, got %q (%v)", mask, err)
+	}
+}
+
+func TestEmptyMask(t *testing.T) {
+	defer challenge.Unregister("test")
+	challenge.Register("test", func(ch string) (string, error) {
+		if ch != "empty" {
+			return "", challenge.ErrUnrecognized
+		}
+		return "", nil
+	})
+	if _, err := computeChallenge(context.Background(), nil, "empty"); err == nil {
+		t.Error("an empty mask should not solve the challenge")
+	}
+	_, err := decipherToken(context.Background(), nil, "dG9rZW4=", "empty")
+	if !errors.Is(err, ErrChallengeUnsolved) {
+		t.Errorf("expected ErrChallengeUnsolved, got %v", err)
+	}
+}
@@ -1,12 +1,16 @@
 package kahoot
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"net/url"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -20,46 +24,174 @@ const incomingBufferSize = 16
 type Message map[string]interface{}
 
 type Conn struct {
-	ws *websocket.Conn
-
+	// lastHeard, keepAlive, abandoned, kicked and state are
+	// accessed atomically. The 64-bit fields come first so
+	// that they are aligned.
+	lastHeard int64
+
+	// keepAlive is the heartbeat interval the server advised,
+	// in nanoseconds, or 0 for the default.
+	keepAlive int64
+
+	// ws, clientId and closing are guarded by wsLock. ws and
+	// clientId change when the connection is re-established.
+	wsLock   sync.Mutex
+	ws       *websocket.Conn
 	clientId string
-	gameId   string
+	closing  bool
+
+	// strictErr is the message a strict Conn failed on. It is
+	// guarded by wsLock.
+	strictErr *ProtocolError
+
+	gameId    string
+	namerator bool
+	teamMode  bool
+	opts      ConnOptions
+
+	// player and team are guarded by playerLock, since a
+	// reconnect logs in again from another goroutine.
+	playerLock sync.Mutex
+	player     Player
+	team       []string
 
 	channelsLock sync.RWMutex
 	incoming     map[string]chan Message
 	outgoing     chan Message
 
-	closed chan struct{}
+	closed       chan struct{}
+	stop         chan struct{}
+	shutdownOnce sync.Once
+
+	events *Bus
+
+	traceLock   sync.Mutex
+	traceWriter io.Writer
+
+	rttLock sync.Mutex
+	rtt     time.Duration
+
+	gameLock sync.Mutex
+	game     GameState
+
+	// twoFactorStart is only used by Quiz.Receive.
+	twoFactorStart time.Time
+
+	abandoned int32
+	kicked    int32
+	state     int32
+}
+
+// Connection states, as stored in Conn.state.
+const (
+	connConnecting int32 = iota
+	connLive
+	connReconnecting
+)
+
+// ConnOptions customizes how a Conn reaches the server.
+type ConnOptions struct {
+	// WrapConn, if non-nil, wraps the network connection before
+	// the WebSocket handshake, for instance to shape traffic.
+	WrapConn func(net.Conn) net.Conn
+
+	// SessionToken, if set, is a deciphered session token,
+	// e.g. from GameSessionTokenContext, to connect with
+	// instead of reserving a new session.
+	SessionToken string
+
+	// HTTPClient, if set, replaces the package's HTTPClient
+	// for reserving the session and evaluating its challenge.
+	HTTPClient *http.Client
+
+	// Proxy, if set, is an HTTP proxy which the WebSocket
+	// connection tunnels through with CONNECT. Unless
+	// HTTPClient is set, the session requests use it too.
+	Proxy *url.URL
+
+	// Header lists extra headers to send with the session
+	// requests and the WebSocket handshake.
+	Header http.Header
+
+	// Timeout, if set, bounds dialing the game and the
+	// WebSocket handshake instead of HTTPClient's Timeout.
+	Timeout time.Duration
+
+	// Stage, if set, is told how long each stage of joining
+	// took, including Login and two-factor on the new Conn.
+	Stage StageFunc
+
+	// Token configures retries of the session reservation.
+	// It is unused if SessionToken is set.
+	Token TokenOptions
+
+	// Reconnect, if set, is how many times in a row the Conn
+	// tries to re-establish itself after the connection drops:
+	// reserving a new session, dialing, handshaking, and
+	// logging in again with the same nickname, with the
+	// backoff of Token between attempts. It publishes a
+	// "reconnecting" connection event with the attempt number
+	// before each attempt and "reconnected" once one succeeds;
+	// "closed" only comes once every attempt failed.
+	// Kicked and abandoned connections are not re-established.
+	Reconnect int
+
+	// Strict makes the Conn fail on the first message it does
+	// not understand, for validating the protocol in CI: a
+	// message on a channel it did not subscribe to, a player
+	// message with an unknown id, or a question, result or
+	// recovery state it cannot parse. It publishes a "strict"
+	// error event with a *ProtocolError, closes, and every
+	// Receive returns that error from then on. By default such
+	// messages are skipped.
+	Strict bool
 }
 
 // NewConn connects to the kahoot server and performs a handshake
 // using a given game pin.
 func NewConn(gameId string) (*Conn, error) {
-	token, err := gameSessionToken(gameId)
-	if err != nil {
-		return nil, errors.New("failed to create session: " + err.Error())
-	}
+	return NewConnOptions(gameId, nil)
+}
 
-	conn, err := net.Dial("tcp", "kahoot.it:443")
-	if err != nil {
-		return nil, err
+// NewConnOptions is like NewConn, but with custom options.
+// A nil opts is equivalent to an empty ConnOptions.
+func NewConnOptions(gameId string, opts *ConnOptions) (*Conn, error) {
+	if opts == nil {
+		opts = &ConnOptions{}
 	}
 
-	url, err := url.Parse("wss://kahoot.it/cometd/" + gameId + "/" + token)
-	if err != nil {
+	proto := protocol()
+
+	if err := currentBudget.takeConn(); err != nil {
 		return nil, err
 	}
-	reqHeader := http.Header{}
-	reqHeader.Set("Origin", "https://kahoot.it")
-	reqHeader.Set("Cookie", "no.mobitroll.session="+gameId)
-	ws, _, err := websocket.NewClient(conn, url, reqHeader, 100, 100)
+	token := opts.SessionToken
+	if token == "" {
+		var err error
+		token, err = sessionToken(context.Background(), opts, gameId)
+		if err != nil {
+			currentBudget.releaseConn()
+			return nil, errors.New("failed to create session: " + err.Error())
+		}
+	}
+	start := time.Now()
+	c, err := connect(gameId, token, proto, opts)
+	opts.Stage.since(StageHandshake, start, err)
+	return c, err
+}
+
+// connect dials the game and performs the Bayeux handshake.
+func connect(gameId, token string, proto *compiledProtocol, opts *ConnOptions) (*Conn, error) {
+	ws, err := dialGame(context.Background(), gameId, token, proto, opts)
 	if err != nil {
+		currentBudget.releaseConn()
 		return nil, err
 	}
 
 	c := &Conn{
 		ws:     ws,
 		gameId: gameId,
+		opts:   *opts,
 		incoming: map[string]chan Message{
 			"/meta/connect":    make(chan Message, incomingBufferSize),
 			"/meta/disconnect": make(chan Message, incomingBufferSize),
@@ -68,39 +200,126 @@ func NewConn(gameId string) (*Conn, error) {
 		},
 		outgoing: make(chan Message),
 		closed:   make(chan struct{}),
+		stop:     make(chan struct{}),
+		events:   NewBus(),
+		game:     GameState{Question: -1},
+	}
+	if info, ok := gameInfoCache.load(gameId); ok {
+		c.namerator = info.Namerator
+		c.teamMode = info.GameMode == gameModeTeam
 	}
 
-	go c.readLoop()
+	go c.readLoop(ws, make(chan struct{}))
 	go c.writeLoop()
 
-	err = c.Send("/meta/handshake", Message{
+	if err := c.handshake(proto); err != nil {
+		c.Close()
+		return nil, err
+	}
+	atomic.StoreInt32(&c.state, connLive)
+
+	go c.keepAliveLoop()
+
+	c.events.Publish(TopicConnection, "connected", gameId)
+
+	return c, nil
+}
+
+// dialGame opens the game's WebSocket. If ctx ends first, the
+// dial is abandoned.
+func dialGame(ctx context.Context, gameId, token string, proto *compiledProtocol,
+	opts *ConnOptions) (*websocket.Conn, error) {
+	if err := currentBudget.takeRequest(); err != nil {
+		return nil, err
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = dialTimeout()
+	}
+	var conn net.Conn
+	var err error
+	if opts.Proxy != nil {
+		conn, err = dialProxy(ctx, opts.Proxy, proto.DialAddr, timeout)
+	} else {
+		dialer := net.Dialer{Timeout: timeout}
+		conn, err = dialer.DialContext(ctx, "tcp", proto.DialAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer closeOnCancel(ctx, conn)()
+	if opts.WrapConn != nil {
+		conn = opts.WrapConn(conn)
+	}
+
+	url, err := url.Parse(proto.CometdURL + gameId + "/" + token)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reqHeader := http.Header{}
+	for k, v := range opts.Header {
+		reqHeader[k] = v
+	}
+	reqHeader.Set("Origin", proto.Origin)
+	reqHeader.Set("Cookie", "no.mobitroll.session="+gameId)
+	conn.SetDeadline(time.Now().Add(timeout))
+	ws, _, err := websocket.NewClient(conn, url, reqHeader, 100, 100)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	ws.SetReadLimit(MaxResponseSize)
+	return ws, nil
+}
+
+// handshake performs the Bayeux handshake on a freshly dialed
+// WebSocket: it obtains a client id, subscribes to the game's
+// channels, and connects.
+func (c *Conn) handshake(proto *compiledProtocol) error {
+	timeout := c.opts.Timeout
+	if timeout <= 0 {
+		timeout = dialTimeout()
+	}
+	err := c.Send("/meta/handshake", Message{
 		"version":                  "1.0",
 		"minimumVersion":           "1.0",
 		"supportedConnectionTypes": []string{"websocket", "long-polling"},
 		"advice":                   map[string]int{"timeout": 60000, "interval": 0},
 	})
 	if err != nil {
-		c.Close()
-		return nil, err
+		return err
 	}
 
-	response, err := c.Receive("/meta/handshake")
+	response, err := c.receiveWithin("/meta/handshake", timeout)
 	if err != nil {
-		c.Close()
-		return nil, err
+		return err
 	}
 
-	if clientId, ok := response["clientId"].(string); !ok {
-		c.Close()
-		return nil, errors.New("invalid handshake response")
-	} else {
-		c.clientId = clientId
+	clientId, ok := response["clientId"].(string)
+	if !ok {
+		return errors.New("invalid handshake response")
 	}
+	c.wsLock.Lock()
+	c.clientId = clientId
+	c.wsLock.Unlock()
 
-	for _, service := range []string{"controller", "player", "status"} {
-		if err := c.Subscribe("/service/" + service); err != nil {
-			c.Close()
-			return nil, err
+	for _, channel := range []string{proto.Channels.Controller, proto.Channels.Player,
+		proto.Channels.Status} {
+		c.channelsLock.Lock()
+		if c.incoming == nil {
+			c.channelsLock.Unlock()
+			return ErrConnClosed
+		} else if _, ok := c.incoming[channel]; !ok {
+			c.incoming[channel] = make(chan Message, incomingBufferSize)
+		}
+		c.channelsLock.Unlock()
+		if err := c.subscribe(channel, timeout); err != nil {
+			return err
 		}
 	}
 
@@ -109,59 +328,159 @@ func NewConn(gameId string) (*Conn, error) {
 		"advice":         map[string]int{"timeout": 0},
 	})
 	if err != nil {
-		c.Close()
-		return nil, err
+		return err
 	}
-	connResp, err := c.Receive("/meta/connect")
+	connResp, err := c.receiveWithin("/meta/connect", timeout)
 	if err != nil {
-		c.Close()
-		return nil, err
+		return err
 	}
 	if success, ok := connResp["successful"].(bool); !ok || !success {
-		c.Close()
-		return nil, errors.New("did not receive successful response")
+		return errors.New("did not receive successful response")
 	}
+	return nil
+}
 
-	go c.keepAliveLoop()
-
-	return c, nil
+// Events returns the Bus on which the connection and any
+// Quiz built on it publish their events. The Bus is closed
+// once the connection has shut down for good, after the
+// "closed" event.
+func (c *Conn) Events() *Bus {
+	return c.events
 }
 
-// Login tells the server our nickname.
+// Login tells the server our nickname and waits for it to be
+// accepted. If the server refuses it, the error is a
+// *LoginError; see ErrDuplicateName, ErrGameLocked and
+// ErrNameBlocked.
+// A demo build ignores the nickname and picks a demo one; see
+// Player for the name actually used.
 func (c *Conn) Login(nickname string) error {
-	m := Message{
-		"data": Message{
-			"type":   "login",
-			"gameid": c.gameId,
-			"host":   "kahoot.it",
-			"name":   nickname,
-		},
+	if err := currentRateLimit.waitLogin(c.stop); err != nil {
+		return err
+	}
+	start := time.Now()
+	err := c.login(nickname, 0)
+	c.opts.Stage.since(StageLogin, start, err)
+	return err
+}
+
+// login is Login, but gives up after timeout, unless it is 0.
+func (c *Conn) login(nickname string, timeout time.Duration) error {
+	if DemoMode {
+		nickname = demoNickname()
 	}
-	if err := c.Send("/service/controller", m); err != nil {
+	proto := protocol()
+	data := proto.template("login")
+	data["gameid"] = c.gameId
+	data["host"] = proto.Host
+	data["name"] = nickname
+	sent := time.Now()
+	if err := c.send(proto.Channels.Controller, Message{"data": data}); err != nil {
 		return err
 	}
 
+	c.channelsLock.RLock()
+	controller, status := c.incoming[proto.Channels.Controller], c.incoming[proto.Channels.Status]
+	c.channelsLock.RUnlock()
+	if controller == nil {
+		return ErrConnClosed
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
 	for {
-		resp, err := c.Receive("/service/controller")
-		if err != nil {
-			return err
-		} else if data, ok := resp["data"].(map[string]interface{}); !ok {
-			continue
-		} else if typeStr, ok := data["type"].(string); !ok || typeStr != "loginResponse" {
+		var resp Message
+		channel := proto.Channels.Controller
+		select {
+		case resp = <-controller:
+		case resp = <-status:
+			channel = proto.Channels.Status
+		case <-expired:
+			return errors.New("timed out waiting for the login response")
+		}
+		if resp == nil {
+			return ErrConnClosed
+		}
+		done, err := loginOutcome(channel, resp)
+		if !done {
 			continue
-		} else {
-			return nil
+		} else if err != nil {
+			if loginErr, ok := err.(*LoginError); ok {
+				loginErr.Pin = c.gameId
+			}
+			c.events.Publish(TopicError, "login", err)
+			return err
 		}
+		data := resp["data"].(map[string]interface{})
+		c.observeRTT(time.Since(sent))
+		player := Player{Cid: parseCid(data["cid"]), Nickname: nickname}
+		c.playerLock.Lock()
+		c.player = player
+		c.playerLock.Unlock()
+		c.events.Publish(TopicConnection, "login", player)
+		return nil
+	}
+}
+
+// Namerator reports whether the game only accepts generated
+// nicknames, as far as the reserve response said. Use
+// LoginGenerated to join such a game.
+func (c *Conn) Namerator() bool {
+	return c.namerator
+}
+
+// Player returns the identity which the server assigned to
+// us when we logged in.
+// The Cid is empty before Login succeeds.
+func (c *Conn) Player() Player {
+	c.playerLock.Lock()
+	defer c.playerLock.Unlock()
+	return c.player
+}
+
+// RTT returns a smoothed estimate of how long the server
+// takes to acknowledge a controller message, measured on
+// logins and answers. It is zero until the first one is
+// acknowledged.
+func (c *Conn) RTT() time.Duration {
+	c.rttLock.Lock()
+	defer c.rttLock.Unlock()
+	return c.rtt
+}
+
+// observeRTT folds a measured round trip into the estimate,
+// weighting it by 1/8 as TCP does.
+func (c *Conn) observeRTT(d time.Duration) {
+	c.rttLock.Lock()
+	defer c.rttLock.Unlock()
+	if c.rtt == 0 {
+		c.rtt = d
+	} else {
+		c.rtt += (d - c.rtt) / 8
 	}
 }
 
 // Close terminates the connection, waiting synchronously for the
 // incoming channels to close.
 func (c *Conn) Close() {
-	c.ws.Close()
+	c.wsLock.Lock()
+	if !c.closing {
+		c.closing = true
+		close(c.stop)
+	}
+	ws := c.ws
+	c.wsLock.Unlock()
+	ws.Close()
 	<-c.closed
 }
 
+func (c *Conn) isClosing() bool {
+	c.wsLock.Lock()
+	defer c.wsLock.Unlock()
+	return c.closing
+}
+
 // GracefulClose closes the connection gracefully, telling the other end that
 // we are disconnecting.
 func (c *Conn) GracefulClose() {
@@ -169,11 +488,21 @@ func (c *Conn) GracefulClose() {
 	if c.Send("/meta/disconnect", Message{}) != nil {
 		return
 	}
-	c.Receive("/meta/disconnect")
+	c.receiveWithin("/meta/disconnect", dialTimeout())
 }
 
 // Send transmits a message to the server over a channel.
+// A demo build refuses raw messages on the controller channel.
 func (c *Conn) Send(channel string, m Message) error {
+	if DemoMode && channel == protocol().Channels.Controller {
+		// Only the messages which Login and Quiz build
+		// themselves may reach the game.
+		return ErrDemoRestricted
+	}
+	return c.send(channel, m)
+}
+
+func (c *Conn) send(channel string, m Message) error {
 	packet := Message{}
 	for k, v := range m {
 		packet[k] = v
@@ -201,8 +530,14 @@ func (c *Conn) Subscribe(name string) error {
 	}
 	c.incoming[name] = make(chan Message, incomingBufferSize)
 	c.channelsLock.Unlock()
+	return c.subscribe(name, 0)
+}
+
+// subscribe asks the server for a channel's messages, giving
+// up after timeout unless it is 0.
+func (c *Conn) subscribe(name string, timeout time.Duration) error {
 	c.Send("/meta/subscribe", Message{"subscription": name})
-	nextMsg, err := c.Receive("/meta/subscribe")
+	nextMsg, err := c.receiveWithin("/meta/subscribe", timeout)
 	if err != nil {
 		return err
 	} else if success, ok := nextMsg["successful"].(bool); !ok || !success {
@@ -214,25 +549,67 @@ func (c *Conn) Subscribe(name string) error {
 // Receive returns the next message on a given channel.
 // You must Subscribe() to the channel before Receiving on it.
 func (c *Conn) Receive(channel string) (Message, error) {
+	return c.receiveWithin(channel, 0)
+}
+
+// receiveWithin is Receive, but gives up after timeout unless
+// it is 0.
+func (c *Conn) receiveWithin(channel string, timeout time.Duration) (Message, error) {
 	c.channelsLock.RLock()
 	if c.incoming == nil {
 		c.channelsLock.RUnlock()
-		return nil, ErrConnClosed
+		return nil, c.closedErr()
 	}
 	ch, ok := c.incoming[channel]
 	c.channelsLock.RUnlock()
 	if !ok {
 		return nil, ErrNotSubscribed
 	}
-	if res := <-ch; res != nil {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+	select {
+	case res := <-ch:
+		if res == nil {
+			return nil, c.closedErr()
+		}
 		return res, nil
-	} else {
-		return nil, ErrConnClosed
+	case <-expired:
+		return nil, errors.New("timed out waiting for " + channel)
 	}
 }
 
-func (c *Conn) readLoop() {
-	defer func() {
+// readLoop reads from one WebSocket until it fails, then
+// either re-establishes the connection or shuts it down. done
+// is closed once reading stopped.
+func (c *Conn) readLoop(ws *websocket.Conn, done chan struct{}) {
+	panicked, err := c.read(ws)
+	ws.Close()
+	close(done)
+	if err != nil {
+		c.events.Publish(TopicError, "read", err)
+	}
+	if atomic.LoadInt32(&c.state) == connReconnecting {
+		// The reconnect in progress decides.
+		return
+	}
+	if err != nil && !panicked && c.shouldReconnect() {
+		if atomic.CompareAndSwapInt32(&c.state, connLive, connReconnecting) {
+			go c.reconnect()
+			return
+		} else if atomic.LoadInt32(&c.state) == connReconnecting {
+			return
+		}
+	}
+	c.shutdown()
+}
+
+// shutdown closes the incoming channels, which ends every
+// pending Receive, publishes the "closed" event, and closes the
+// Bus, which ends every Subscription.
+func (c *Conn) shutdown() {
+	c.shutdownOnce.Do(func() {
 		c.channelsLock.Lock()
 		defer c.channelsLock.Unlock()
 		for _, ch := range c.incoming {
@@ -240,28 +617,57 @@ func (c *Conn) readLoop() {
 		}
 		c.incoming = nil
 		close(c.closed)
+		currentBudget.releaseConn()
+		c.events.Publish(TopicConnection, "closed", c.gameId)
+		c.events.Close()
+	})
+}
+
+func (c *Conn) read(ws *websocket.Conn) (panicked bool, err error) {
+	defer func() {
+		// A malformed message should only take down this
+		// connection, not the whole program.
+		if r := recover(); r != nil {
+			c.events.Publish(TopicError, "panic", fmt.Errorf("read loop: %v", r))
+			panicked, err = true, nil
+		}
 	}()
 	for {
 		var msgs []Message
-		err := c.ws.ReadJSON(&msgs)
-		if err != nil {
-			return
+		if err := ws.ReadJSON(&msgs); err != nil {
+			return false, err
 		}
+		c.trace("in", msgs)
+		c.heard(time.Now())
 		for _, msg := range msgs {
-			if chName, ok := msg["channel"].(string); !ok {
-				return
-			} else {
-				c.channelsLock.RLock()
-				ch, ok := c.incoming[chName]
-				c.channelsLock.RUnlock()
-				if ok {
-					// NOTE: the select allows us to drop packets from channels
-					// that nobody cares about (e.g. /meta/connect).
-					select {
-					case ch <- msg:
-					default:
-					}
+			chName, ok := msg["channel"].(string)
+			if !ok {
+				return false, nil
+			}
+			if chName == "/meta/connect" {
+				if abandonAdvice(msg) {
+					c.abandon(&GameAbandoned{Reason: "server advised not to reconnect"})
 				}
+				c.followAdvice(msg)
+			}
+			c.channelsLock.RLock()
+			ch, ok := c.incoming[chName]
+			closed := c.incoming == nil
+			c.channelsLock.RUnlock()
+			if !ok && !closed {
+				if err := c.unrecognized(&ProtocolError{Channel: chName, Problem: "unknown channel"}, msg); err != nil {
+					return false, err
+				}
+			} else if ok {
+				// NOTE: the select allows us to drop packets from channels
+				// that nobody cares about (e.g. /meta/connect).
+				select {
+				case ch <- msg:
+				default:
+				}
+			}
+			if chName == "/meta/connect" && c.opts.Reconnect > 0 && handshakeAdvice(msg) {
+				return false, errHandshakeAdvised
 			}
 		}
 	}
@@ -272,14 +678,20 @@ func (c *Conn) writeLoop() {
 	for {
 		select {
 		case msg := <-c.outgoing:
+			c.wsLock.Lock()
+			ws, clientId := c.ws, c.clientId
+			c.wsLock.Unlock()
 			id++
 			msg["id"] = strconv.Itoa(id)
 			if msg["channel"] != "/meta/handshake" {
-				msg["clientId"] = c.clientId
+				msg["clientId"] = clientId
 			}
-			if c.ws.WriteJSON([]Message{msg}) != nil {
-				c.ws.Close()
-				return
+			msgs := []Message{msg}
+			c.trace("out", msgs)
+			if ws.WriteJSON(msgs) != nil {
+				// Reading fails as well, which ends or
+				// re-establishes the connection.
+				ws.Close()
 			}
 		case <-c.closed:
 			return
@@ -289,12 +701,16 @@ func (c *Conn) writeLoop() {
 
 func (c *Conn) keepAliveLoop() {
 	for {
-		delay := time.After(time.Second * 5)
+		delay := time.After(c.keepAliveInterval())
 		select {
 		case <-delay:
 		case <-c.closed:
 			return
 		}
+		if atomic.LoadInt32(&c.state) != connLive {
+			continue
+		}
+		c.checkSilence(time.Now())
 		c.Send("/meta/connect", Message{"connectionType": "websocket"})
 	}
 }
@@ -0,0 +1,21 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestObserveRTT(t *testing.T) {
+	c := &Conn{}
+	if c.RTT() != 0 {
+		t.Fatal("expected no estimate before any measurement")
+	}
+	c.observeRTT(80 * time.Millisecond)
+	if c.RTT() != 80*time.Millisecond {
+		t.Errorf("first measurement should be taken as is, got %v", c.RTT())
+	}
+	c.observeRTT(160 * time.Millisecond)
+	if c.RTT() != 90*time.Millisecond {
+		t.Errorf("expected 90ms but got %v", c.RTT())
+	}
+}
@@ -0,0 +1,122 @@
+package kahoot
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultRetryAfter is how long the creator API is left alone
+// after it rate limits us without saying for how long.
+const DefaultRetryAfter = time.Minute
+
+// A RateLimitError is returned when the creator API has asked
+// us to back off. Until then, creator API calls fail with it
+// without making a request.
+type RateLimitError struct {
+	Until time.Time
+}
+
+func (r *RateLimitError) Error() string {
+	return fmt.Sprintf("creator API rate limited for another %s",
+		time.Until(r.Until).Round(time.Second))
+}
+
+// A cachedQuiz is a quiz with the validators to revalidate it.
+type cachedQuiz struct {
+	info         QuizInfo
+	etag         string
+	lastModified string
+}
+
+type creatorState struct {
+	lock    sync.Mutex
+	retryAt time.Time
+	quizzes map[string]*cachedQuiz
+}
+
+var creatorAPI = &creatorState{quizzes: map[string]*cachedQuiz{}}
+
+// do sends a creator API request, unless the API asked us to
+// back off. A 429 or 503 response becomes a RateLimitError.
+func (c *creatorState) do(req *http.Request) (*http.Response, error) {
+	c.lock.Lock()
+	retryAt := c.retryAt
+	c.lock.Unlock()
+	if time.Now().Before(retryAt) {
+		return nil, &RateLimitError{Until: retryAt}
+	}
+	if err := currentBudget.takeRequest(); err != nil {
+		return nil, err
+	}
+	resp, err := HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		resp.Body.Close()
+		until := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		c.lock.Lock()
+		if until.After(c.retryAt) {
+			c.retryAt = until
+		}
+		c.lock.Unlock()
+		return nil, &RateLimitError{Until: until}
+	}
+	return resp, nil
+}
+
+// retryAfter parses a Retry-After header, which is either a
+// number of seconds or an HTTP date.
+func retryAfter(header string, now time.Time) time.Time {
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return t
+	}
+	return now.Add(DefaultRetryAfter)
+}
+
+// conditional adds the validators of a cached quiz to a
+// request, returning the cached quiz if there is one.
+func (c *creatorState) conditional(req *http.Request, quizid string) *cachedQuiz {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	cached, ok := c.quizzes[quizid]
+	if !ok {
+		return nil
+	}
+	if cached.etag != "" {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	if cached.lastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.lastModified)
+	}
+	return cached
+}
+
+// storeQuiz caches a quiz if the response can be revalidated.
+func (c *creatorState) storeQuiz(quizid string, info *QuizInfo, header http.Header) {
+	etag, lastModified := header.Get("ETag"), header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.quizzes[quizid] = &cachedQuiz{info: *copyQuiz(info), etag: etag, lastModified: lastModified}
+}
+
+// copyQuiz copies a quiz deeply enough that changing the
+// copy's questions does not touch the original.
+func copyQuiz(info *QuizInfo) *QuizInfo {
+	res := *info
+	res.Questions = make([]QuizQuestion, len(info.Questions))
+	for i, q := range info.Questions {
+		q.Choices = append([]QuizChoice{}, q.Choices...)
+		res.Questions[i] = q
+	}
+	return &res
+}
@@ -0,0 +1,89 @@
+package kahoot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func withCreatorServer(t *testing.T, handler http.HandlerFunc) func() {
+	server := httptest.NewServer(handler)
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.CreatorURL = server.URL + "/rest/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	oldAPI := creatorAPI
+	creatorAPI = &creatorState{quizzes: map[string]*cachedQuiz{}}
+	return func() {
+		creatorAPI = oldAPI
+		SetProtocol(&old)
+		server.Close()
+	}
+}
+
+func TestQuizInformationCache(t *testing.T) {
+	var hits, full int
+	defer withCreatorServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"title":"Capitals","questions":[{"question":"France?"}]}`))
+	})()
+
+	for i := 0; i < 3; i++ {
+		info, err := QuizInformation("token", "abc")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Title != "Capitals" || len(info.Questions) != 1 {
+			t.Fatalf("unexpected quiz %+v", info)
+		}
+		info.Questions[0].Question = "changed"
+	}
+	if hits != 3 || full != 1 {
+		t.Errorf("expected 3 requests and 1 download, got %d and %d", hits, full)
+	}
+}
+
+func TestCreatorRetryAfter(t *testing.T) {
+	var hits int
+	defer withCreatorServer(t, func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Retry-After", "120")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})()
+
+	for i := 0; i < 2; i++ {
+		_, err := QuizInformation("token", "abc")
+		rateErr, ok := err.(*RateLimitError)
+		if !ok {
+			t.Fatalf("expected a rate limit error but got %v", err)
+		}
+		if wait := time.Until(rateErr.Until); wait < time.Minute || wait > 2*time.Minute {
+			t.Errorf("unexpected wait %v", wait)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("expected requests to stop after the first 429, got %d", hits)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	if at := retryAfter("30", now); !at.Equal(now.Add(30 * time.Second)) {
+		t.Errorf("unexpected time %v", at)
+	}
+	if at := retryAfter("Wed, 01 Jan 2020 00:05:00 GMT", now); !at.Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("unexpected time %v", at)
+	}
+	if at := retryAfter("", now); !at.Equal(now.Add(DefaultRetryAfter)) {
+		t.Errorf("unexpected time %v", at)
+	}
+}
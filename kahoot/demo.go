@@ -0,0 +1,61 @@
+package kahoot
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// Limits enforced by a demo build, which is built with
+// "go build -tags demo" and checked through DemoMode. A demo
+// binary can be handed to students: whatever flags they pass,
+// it joins at most a few bots with harmless names, and each
+// bot answers each question at most once.
+const (
+	DemoMaxBots          = 5
+	DemoAnswersPerMinute = 30
+
+	// DemoSuffix is appended to every nickname, so the host
+	// can tell demo bots apart from students.
+	DemoSuffix = "-demo"
+)
+
+// ErrDemoRestricted is returned for operations which a demo
+// build does not allow.
+var ErrDemoRestricted = errors.New("not allowed in the demo build")
+
+var demoNames = []string{"Apple", "Birch", "Clover", "Daisy", "Ember"}
+
+var demoLogins uint32
+
+// demoNickname returns the next of the fixed demo nicknames.
+func demoNickname() string {
+	n := atomic.AddUint32(&demoLogins, 1) - 1
+	return demoNames[int(n)%len(demoNames)] + DemoSuffix
+}
+
+// IsDemoNickname reports whether a nickname is one that a
+// demo build would use.
+func IsDemoNickname(name string) bool {
+	base := strings.TrimSuffix(name, DemoSuffix)
+	if base == name {
+		return false
+	}
+	for _, n := range demoNames {
+		if n == base {
+			return true
+		}
+	}
+	return false
+}
+
+// demoBudget tightens a budget to the demo limits.
+func demoBudget(b Budget) Budget {
+	if b.MaxConns <= 0 || b.MaxConns > DemoMaxBots {
+		b.MaxConns = DemoMaxBots
+	}
+	if b.AnswersPerMinute <= 0 || b.AnswersPerMinute > DemoAnswersPerMinute {
+		b.AnswersPerMinute = DemoAnswersPerMinute
+	}
+	return b
+}
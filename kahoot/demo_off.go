@@ -0,0 +1,7 @@
+//go:build !demo
+// +build !demo
+
+package kahoot
+
+// DemoMode is true in a demo build.
+const DemoMode = false
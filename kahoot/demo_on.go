@@ -0,0 +1,11 @@
+//go:build demo
+// +build demo
+
+package kahoot
+
+// DemoMode is true in a demo build.
+const DemoMode = true
+
+func init() {
+	currentBudget.budget = demoBudget(currentBudget.budget)
+}
@@ -0,0 +1,33 @@
+package kahoot
+
+import "testing"
+
+func TestDemoBudget(t *testing.T) {
+	b := demoBudget(Budget{})
+	if b.MaxConns != DemoMaxBots || b.AnswersPerMinute != DemoAnswersPerMinute {
+		t.Errorf("unlimited budget should get the demo limits, got %+v", b)
+	}
+	b = demoBudget(Budget{MaxConns: 100, AnswersPerMinute: 2, RequestsPerHour: 7})
+	if b.MaxConns != DemoMaxBots || b.AnswersPerMinute != 2 || b.RequestsPerHour != 7 {
+		t.Errorf("unexpected budget %+v", b)
+	}
+}
+
+func TestDemoNickname(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < len(demoNames); i++ {
+		name := demoNickname()
+		if !IsDemoNickname(name) {
+			t.Errorf("%q should be a demo nickname", name)
+		}
+		seen[name] = true
+	}
+	if len(seen) != len(demoNames) {
+		t.Errorf("expected %d distinct names, got %v", len(demoNames), seen)
+	}
+	for _, name := range []string{"Apple", "alex-demo", "bot1"} {
+		if IsDemoNickname(name) {
+			t.Errorf("%q should not be a demo nickname", name)
+		}
+	}
+}
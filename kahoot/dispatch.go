@@ -0,0 +1,193 @@
+package kahoot
+
+import "sync"
+
+// A GameEvent is one of QuestionReady, QuestionStart,
+// QuestionEnd, QuizEnd, GameOver, Kicked, Feedback,
+// GameAbandoned, or StateChanged.
+type GameEvent interface {
+	gameEvent()
+}
+
+// QuestionReady announces the next question, before answers
+// may be sent.
+type QuestionReady struct{ *QuizAction }
+
+// QuestionStart means answers to the question may now be
+// sent.
+type QuestionStart struct{ *QuizAction }
+
+// QuestionEnd carries the player's result once a question
+// closes.
+type QuestionEnd struct{ *QuizResult }
+
+// GameOver means the host ended the game.
+type GameOver struct{}
+
+// Kicked means the host removed the player from the game.
+type Kicked struct {
+	// Code is the server's kick code.
+	Code int
+}
+
+// Feedback means the host asks players to rate the quiz.
+type Feedback struct{}
+
+// StateChanged follows each of the other events which changed
+// the Conn's GameState, and carries the new state.
+type StateChanged struct{ *GameState }
+
+func (QuestionReady) gameEvent()  {}
+func (QuestionStart) gameEvent()  {}
+func (QuestionEnd) gameEvent()    {}
+func (*QuizEnd) gameEvent()       {}
+func (GameOver) gameEvent()       {}
+func (Kicked) gameEvent()         {}
+func (Feedback) gameEvent()       {}
+func (*GameAbandoned) gameEvent() {}
+func (StateChanged) gameEvent()   {}
+
+// A Dispatcher decodes a Quiz's messages into GameEvents and
+// passes them to the registered handlers, in order, so that a
+// bot can be written as a type switch without knowing the wire
+// format.
+//
+// Handlers run on the goroutine calling Run. Since the
+// underlying Bus drops events for slow subscribers, a handler
+// which blocks for long may miss events.
+type Dispatcher struct {
+	quiz *Quiz
+	sub  *Subscription
+
+	lock     sync.Mutex
+	handlers []func(GameEvent)
+	channels []chan GameEvent
+}
+
+// NewDispatcher creates a Dispatcher for the quiz. It starts
+// collecting events right away, though none are delivered
+// before Run is called.
+func NewDispatcher(q *Quiz) *Dispatcher {
+	return &Dispatcher{
+		quiz: q,
+		sub:  q.conn.events.Subscribe(TopicQuestion, TopicResult, TopicConnection),
+	}
+}
+
+// Handle registers f to be called with every GameEvent.
+func (d *Dispatcher) Handle(f func(GameEvent)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.handlers = append(d.handlers, f)
+}
+
+// Events returns a channel which receives every GameEvent and
+// is closed when Run returns. If the channel's reader falls
+// far behind, Run waits for it.
+func (d *Dispatcher) Events() <-chan GameEvent {
+	ch := make(chan GameEvent, subscriptionBufferSize)
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.channels = append(d.channels, ch)
+	return ch
+}
+
+// Run receives from the quiz and dispatches its events until
+// receiving fails, for instance because the connection closed,
+// and returns that error. Nothing else may call the quiz's
+// Receive while Run is running.
+func (d *Dispatcher) Run() error {
+	defer d.sub.Close()
+	errc := make(chan error, 1)
+	go func() {
+		for {
+			if _, err := d.quiz.Receive(); err != nil {
+				errc <- err
+				return
+			}
+		}
+	}()
+	events := d.sub.C
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				events = nil
+			} else if ge := decodeGameEvent(e); ge != nil {
+				d.dispatch(ge)
+			}
+		case err := <-errc:
+			d.drain(events)
+			d.lock.Lock()
+			for _, ch := range d.channels {
+				close(ch)
+			}
+			d.channels = nil
+			d.lock.Unlock()
+			return err
+		}
+	}
+}
+
+// drain dispatches the events already published, such as
+// those of the messages received just before the failure.
+func (d *Dispatcher) drain(events <-chan Event) {
+	for events != nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			} else if ge := decodeGameEvent(e); ge != nil {
+				d.dispatch(ge)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(e GameEvent) {
+	d.lock.Lock()
+	handlers := append([]func(GameEvent){}, d.handlers...)
+	channels := append([]chan GameEvent{}, d.channels...)
+	d.lock.Unlock()
+	for _, h := range handlers {
+		h(e)
+	}
+	for _, ch := range channels {
+		ch <- e
+	}
+}
+
+// decodeGameEvent returns the GameEvent for a Bus event, or
+// nil if there is none.
+func decodeGameEvent(e Event) GameEvent {
+	switch data := e.Data.(type) {
+	case *QuizAction:
+		if e.Type == "intro" {
+			return QuestionReady{data}
+		} else if e.Type == "answers" {
+			return QuestionStart{data}
+		}
+	case *QuizResult:
+		if e.Type == "result" {
+			return QuestionEnd{data}
+		}
+	case *QuizEnd:
+		return data
+	case *GameAbandoned:
+		return data
+	case *GameState:
+		return StateChanged{data}
+	}
+	switch {
+	case e.Topic == TopicResult && e.Type == "gameOver":
+		return GameOver{}
+	case e.Topic == TopicConnection && e.Type == "feedback":
+		return Feedback{}
+	case e.Topic == TopicConnection && e.Type == "kicked":
+		code, _ := e.Data.(float64)
+		return Kicked{Code: int(code)}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package kahoot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeGameEvent(t *testing.T) {
+	action := &QuizAction{Index: 2, NumAnswers: 4}
+	result := &QuizResult{Index: 2, IsCorrect: true}
+	end := &QuizEnd{Rank: 3}
+	cases := []struct {
+		event    Event
+		expected GameEvent
+	}{
+		{Event{Topic: TopicQuestion, Type: "intro", Data: action}, QuestionReady{action}},
+		{Event{Topic: TopicQuestion, Type: "answers", Data: action}, QuestionStart{action}},
+		{Event{Topic: TopicQuestion, Type: "gap", Data: &Gap{From: 1, To: 2}}, nil},
+		{Event{Topic: TopicResult, Type: "result", Data: result}, QuestionEnd{result}},
+		{Event{Topic: TopicResult, Type: "quizEnd", Data: end}, end},
+		{Event{Topic: TopicResult, Type: "gameOver"}, GameOver{}},
+		{Event{Topic: TopicConnection, Type: "kicked", Data: 1.0}, Kicked{Code: 1}},
+		{Event{Topic: TopicConnection, Type: "feedback"}, Feedback{}},
+		{Event{Topic: TopicConnection, Type: "login", Data: Player{}}, nil},
+	}
+	for i, c := range cases {
+		if actual := decodeGameEvent(c.event); !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("case %d: expected %#v but got %#v", i, c.expected, actual)
+		}
+	}
+	if ev := decodeGameEvent(cases[0].event).(QuestionReady); ev.Index != 2 {
+		t.Error("QuestionReady should expose the action's fields")
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	d := &Dispatcher{}
+	var handled []GameEvent
+	d.Handle(func(e GameEvent) { handled = append(handled, e) })
+	ch := d.Events()
+	d.dispatch(GameOver{})
+	if len(handled) != 1 || handled[0] != (GameOver{}) {
+		t.Errorf("unexpected handled events %v", handled)
+	}
+	if e := <-ch; e != (GameOver{}) {
+		t.Errorf("unexpected channel event %v", e)
+	}
+}
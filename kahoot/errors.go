@@ -0,0 +1,101 @@
+package kahoot
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Reasons a session could not be reserved. The errors of
+// reserving a session are *SessionErrors which wrap them, so
+// they can be checked with errors.Is.
+var (
+	ErrGameNotFound      = errors.New("game pin not found")
+	ErrChallengeUnsolved = errors.New("failed to defeat challenge")
+	ErrRateLimited       = errors.New("rate limited")
+	ErrSessionRefused    = errors.New("session request refused")
+)
+
+// ErrKicked is wrapped by the *KickError which Quiz.Receive
+// returns once the host kicked the player.
+var ErrKicked = errors.New("kicked from the game")
+
+// ErrUnrecognized is wrapped by the *ProtocolError which a
+// strict Conn fails with; see ConnOptions.Strict.
+var ErrUnrecognized = errors.New("unrecognized message")
+
+// A ProtocolError describes the message which a strict Conn
+// did not understand.
+type ProtocolError struct {
+	Channel string
+
+	// Id is the message id on the player channel, or 0.
+	Id int
+
+	// Problem says what was not understood, such as "unknown
+	// channel" or "unparsed question".
+	Problem string
+
+	// Message is the whole message as JSON.
+	Message string
+}
+
+func (p *ProtocolError) Error() string {
+	msg := fmt.Sprintf("%s: %s on %s", ErrUnrecognized, p.Problem, p.Channel)
+	if p.Id != 0 {
+		msg += fmt.Sprintf(" (id %d)", p.Id)
+	}
+	return msg + ": " + p.Message
+}
+
+func (p *ProtocolError) Unwrap() error {
+	return ErrUnrecognized
+}
+
+// A SessionError is returned when a session for a game could
+// not be reserved.
+type SessionError struct {
+	Pin string
+
+	// Status is the HTTP status of the reserve response, or 0
+	// if the response was fine but its challenge was not.
+	Status int
+
+	// Reason is ErrGameNotFound, ErrChallengeUnsolved,
+	// ErrRateLimited, ErrSessionRefused for any other 4xx
+	// status, or nil for a server error.
+	Reason error
+
+	retryAfter time.Duration
+}
+
+func (s *SessionError) Error() string {
+	msg := "reserve session"
+	if s.Reason != nil {
+		msg = s.Reason.Error()
+	}
+	msg += ": " + s.Pin
+	if s.Status != 0 {
+		msg += fmt.Sprintf(" (HTTP %d)", s.Status)
+	}
+	return msg
+}
+
+func (s *SessionError) Unwrap() error {
+	return s.Reason
+}
+
+// A KickError is returned by Quiz.Receive after the host
+// kicked the player.
+type KickError struct {
+	Pin  string
+	Code int
+}
+
+func (k *KickError) Error() string {
+	return fmt.Sprintf("%s: %s (code %d)", ErrKicked, k.Pin, k.Code)
+}
+
+func (k *KickError) Unwrap() error {
+	return ErrKicked
+}
@@ -0,0 +1,17 @@
+package kahoot
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSessionError(t *testing.T) {
+	err := error(&SessionError{Pin: "123456", Status: 404, Reason: ErrGameNotFound})
+	if !errors.Is(err, ErrGameNotFound) || err.Error() != "game pin not found: 123456 (HTTP 404)" {
+		t.Errorf("unexpected error %v", err)
+	}
+	err = &KickError{Pin: "123456", Code: 1}
+	if !errors.Is(err, ErrKicked) || err.Error() != "kicked from the game: 123456 (code 1)" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package kahoot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/unixpickle/kahoot-hack/kahoot/challenge"
+)
+
+// SafevalURL is the evaluation service which the tools used
+// to send every unrecognized challenge to. It is only used
+// if a RemoteEvaluator is installed with SetEvaluators.
+const SafevalURL = "http://safeval.pw/eval"
+
+// An Evaluator computes the mask for a session's challenge.
+//
+// Challenges go through the evaluators given to SetEvaluators
+// in order, until one succeeds. By default only local ones are
+// used, so challenges never leave the machine.
+type Evaluator interface {
+	// Name identifies the evaluator in Version.
+	Name() string
+
+	// Evaluate returns the challenge's mask, or an error if
+	// it cannot. It should give up once ctx is done.
+	Evaluate(ctx context.Context, challenge string) (string, error)
+}
+
+// PatternEvaluator solves challenges with the protocol's
+// patterns and then the solvers registered with the challenge
+// package. It never runs the challenge's code.
+var PatternEvaluator Evaluator = patternEvaluator{}
+
+type patternEvaluator struct{}
+
+func (patternEvaluator) Name() string {
+	return fmt.Sprintf("patterns (%d)", len(protocol().challengeRegexps))
+}
+
+func (patternEvaluator) Evaluate(ctx context.Context, ch string) (string, error) {
+	if mask, ok := solveChallengeLocally(ch); ok {
+		return string(mask), nil
+	}
+	mask, _, err := challenge.Solve(ch)
+	return mask, err
+}
+
+// funcEvaluator adapts the solvers of the embedded JavaScript
+// engine and SetChallengeSolver.
+type funcEvaluator struct {
+	name  string
+	solve func(challenge string) (string, error)
+}
+
+func (f funcEvaluator) Name() string {
+	return f.name
+}
+
+func (f funcEvaluator) Evaluate(ctx context.Context, ch string) (string, error) {
+	return f.solve(ch)
+}
+
+// A RemoteEvaluator sends challenges to an evaluation service
+// which runs them and replies with the result, such as
+// SafevalURL. The challenge is sent as the code parameter of
+// a GET request, through the HTTP client of the connection
+// reserving the session.
+//
+// Since this shares every challenge with a third party, it is
+// never used unless it is passed to SetEvaluators.
+type RemoteEvaluator struct {
+	// URL is the service's address. It defaults to SafevalURL.
+	URL string
+}
+
+func (r RemoteEvaluator) url() string {
+	if r.URL == "" {
+		return SafevalURL
+	}
+	return r.URL
+}
+
+// Name returns the service's host.
+func (r RemoteEvaluator) Name() string {
+	if u, err := url.Parse(r.url()); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return r.url()
+}
+
+// Evaluate asks the service for the challenge's mask. The
+// request counts against the budget.
+func (r RemoteEvaluator) Evaluate(ctx context.Context, ch string) (string, error) {
+	evalURL, err := url.Parse(r.url())
+	if err != nil {
+		return "", err
+	}
+	query := evalURL.Query()
+	query.Set("code", ch)
+	evalURL.RawQuery = query.Encode()
+	if err := currentBudget.takeRequest(); err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", evalURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := httpConfigFrom(ctx).do(req)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("server failed to evaluate: " + ch)
+	}
+	mask, err := readBody(resp.Body)
+	return string(mask), err
+}
+
+// DefaultEvaluators returns the evaluators used unless
+// SetEvaluators is called: PatternEvaluator, then the embedded
+// JavaScript engine in builds with the goja tag.
+func DefaultEvaluators() []Evaluator {
+	res := []Evaluator{PatternEvaluator}
+	if jsSolver != nil {
+		res = append(res, funcEvaluator{"goja", jsSolver})
+	}
+	return res
+}
+
+var evaluatorLock sync.Mutex
+var evaluators []Evaluator
+
+// SetEvaluators replaces the evaluators challenges go through.
+// Calling it with no evaluators restores DefaultEvaluators.
+//
+// To fall back to safeval.pw as older releases did, use
+//
+//	kahoot.SetEvaluators(append(kahoot.DefaultEvaluators(), kahoot.RemoteEvaluator{})...)
+func SetEvaluators(evals ...Evaluator) {
+	evaluatorLock.Lock()
+	defer evaluatorLock.Unlock()
+	evaluators = append([]Evaluator{}, evals...)
+}
+
+// currentEvaluators returns the evaluators to try in order.
+// The solver from SetChallengeSolver goes before the first
+// RemoteEvaluator, so that it is still tried before sending a
+// challenge away.
+func currentEvaluators() []Evaluator {
+	evaluatorLock.Lock()
+	res := append([]Evaluator{}, evaluators...)
+	evaluatorLock.Unlock()
+	if len(res) == 0 {
+		res = DefaultEvaluators()
+	}
+	solver := currentSolver()
+	if solver == nil {
+		return res
+	}
+	external := funcEvaluator{"external", solver}
+	for i, e := range res {
+		if isRemote(e) {
+			return append(res[:i:i], append([]Evaluator{external}, res[i:]...)...)
+		}
+	}
+	return append(res, external)
+}
+
+func isRemote(e Evaluator) bool {
+	switch e.(type) {
+	case RemoteEvaluator, *RemoteEvaluator:
+		return true
+	}
+	return false
+}
+
+type httpConfigKey struct{}
+
+// withHTTPConfig makes client available to the evaluators.
+func withHTTPConfig(ctx context.Context, client *httpConfig) context.Context {
+	return context.WithValue(ctx, httpConfigKey{}, client)
+}
+
+func httpConfigFrom(ctx context.Context) *httpConfig {
+	client, _ := ctx.Value(httpConfigKey{}).(*httpConfig)
+	return client
+}
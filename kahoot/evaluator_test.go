@@ -0,0 +1,54 @@
+package kahoot
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestRemoteEvaluator(t *testing.T) {
+	var lock sync.Mutex
+	var received []string
+	requests := func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return append([]string{}, received...)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		received = append(received, r.URL.Query().Get("code"))
+		lock.Unlock()
+		w.Write([]byte("remote"))
+	}))
+	defer server.Close()
+
+	if _, err := computeChallenge(context.Background(), nil, "unknown"); err == nil {
+		t.Fatal("expected no default evaluator to solve the challenge")
+	}
+
+	defer SetEvaluators()
+	SetEvaluators(append(DefaultEvaluators(), RemoteEvaluator{URL: server.URL + "/eval"})...)
+	mask, err := computeChallenge(context.Background(), nil, "unknown")
+	if err != nil || string(mask) != "remote" {
+		t.Fatalf("unexpected result %q, %v", mask, err)
+	}
+	if r := requests(); len(r) != 1 || r[0] != "unknown" {
+		t.Errorf("unexpected requests %v", r)
+	}
+
+	defer SetChallengeSolver(nil)
+	SetChallengeSolver(func(ch string) (string, error) {
+		return "external", nil
+	})
+	if mask, _ := computeChallenge(context.Background(), nil, "unknown"); string(mask) != "external" {
+		t.Errorf("expected the external solver before the remote one, got %q", mask)
+	}
+	if mask, _ := computeChallenge(context.Background(), nil, selfTestChallenge); string(mask) != selfTestAnswer {
+		t.Errorf("expected the patterns first, got %q", mask)
+	}
+	if r := requests(); len(r) != 1 {
+		t.Errorf("unexpected requests %v", r)
+	}
+}
@@ -0,0 +1,93 @@
+package kahoot
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GameInfoTTL is how long LookupGameInfo reuses a pin's
+// metadata. Pins are recycled once games end, so entries do
+// not live forever.
+var GameInfoTTL = 10 * time.Minute
+
+// GameInfo is the metadata the reserve endpoint sends along
+// with a game's session challenge.
+type GameInfo struct {
+	Pin string `json:"-"`
+
+	TwoFactorAuth bool   `json:"twoFactorAuth"`
+	Namerator     bool   `json:"namerator"`
+	GameMode      string `json:"gameMode"`
+
+	// Locked is only set if the server says whether the
+	// lobby is locked; not every server version does.
+	Locked *bool `json:"locked,omitempty"`
+
+	// Fetched is when the metadata was received.
+	Fetched time.Time `json:"-"`
+}
+
+// Warnings lists the game settings which stop bots from
+// joining or playing normally.
+func (g *GameInfo) Warnings() []string {
+	var res []string
+	if g.Locked != nil && *g.Locked {
+		res = append(res, "the lobby is locked, so nobody can join")
+	}
+	if g.TwoFactorAuth {
+		res = append(res, "two-factor auth is on, so bots must enter the code shown on the host's screen")
+	}
+	if g.Namerator {
+		res = append(res, "the namerator is on, so bots get generated names instead of their nicknames")
+	}
+	if g.GameMode == "team" {
+		res = append(res, "team mode is on, so each bot joins as a team of one")
+	}
+	return res
+}
+
+type gameInfoStore struct {
+	lock  sync.Mutex
+	infos map[string]*GameInfo
+}
+
+var gameInfoCache = &gameInfoStore{infos: map[string]*GameInfo{}}
+
+func (g *gameInfoStore) store(info *GameInfo) {
+	copied := *info
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.infos[info.Pin] = &copied
+}
+
+func (g *gameInfoStore) load(pin string) (*GameInfo, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	info, ok := g.infos[pin]
+	if !ok || time.Since(info.Fetched) > GameInfoTTL {
+		return nil, false
+	}
+	copied := *info
+	return &copied, true
+}
+
+// LookupGameInfo returns a game's metadata without joining
+// it. The result is cached per pin, and joining a game also
+// fills the cache.
+func LookupGameInfo(gamePin string) (*GameInfo, error) {
+	gamePin = strings.TrimSpace(gamePin)
+	if info, ok := gameInfoCache.load(gamePin); ok {
+		return info, nil
+	}
+	if err := currentRateLimit.waitReserve(context.Background()); err != nil {
+		return nil, err
+	}
+	res, err := reserve(context.Background(), nil, gamePin)
+	if err != nil {
+		return nil, err
+	}
+	info := res.GameInfo
+	return &info, nil
+}
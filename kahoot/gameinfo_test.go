@@ -0,0 +1,51 @@
+package kahoot
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLookupGameInfo(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if !strings.HasSuffix(r.URL.Path, "/123456") {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"twoFactorAuth":true,"namerator":false,"gameMode":"team","challenge":"x"}`))
+	}))
+	defer server.Close()
+
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.URL + "/reserve/session/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProtocol(&old)
+
+	for i := 0; i < 2; i++ {
+		info, err := LookupGameInfo("123456")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !info.TwoFactorAuth || info.Namerator || info.GameMode != "team" || info.Pin != "123456" {
+			t.Errorf("unexpected info: %+v", info)
+		}
+	}
+	info, _ := LookupGameInfo("123456")
+	if w := info.Warnings(); len(w) != 2 {
+		t.Errorf("expected two-factor and team warnings but got %v", w)
+	}
+	if hits != 1 {
+		t.Errorf("expected one request but got %d", hits)
+	}
+
+	if _, err := LookupGameInfo("999999"); !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("expected not-found error but got %v", err)
+	}
+}
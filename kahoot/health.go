@@ -0,0 +1,40 @@
+package kahoot
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+// selfTestChallenge has the same shape as the challenges sent
+// by the reserve endpoint, so solving it exercises the same
+// code path as joining a game.
+const selfTestChallenge = "decode.call(this, 'Kahoot'); function decode(message) " +
+	"{var offset = 3 * (2 + 1); if (this.angular.isObject(offset)) " +
+	"{console.log(\"Offset derived as: {\", offset, \"}\");}" +
+	"return _.replace(message, /./g, function(char, position) " +
+	"{return String.fromCharCode((((char.charCodeAt(0) * position) + offset) % 77) + 48);});}"
+
+const selfTestAnswer = "9MoRtb"
+
+// CheckService reports whether new games could be joined
+// right now: the game server must accept TCP connections, and
+// the challenge solver must work without any remote help.
+func CheckService(timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", protocol().DialAddr, timeout)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	return checkSolver()
+}
+
+func checkSolver() error {
+	mask, ok := solveChallengeLocally(selfTestChallenge)
+	if !ok {
+		return errors.New("challenge solver does not match protocol")
+	} else if string(mask) != selfTestAnswer {
+		return errors.New("challenge solver returned " + string(mask))
+	}
+	return nil
+}
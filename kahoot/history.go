@@ -0,0 +1,105 @@
+package kahoot
+
+import "time"
+
+// DefaultHistorySize is how many events a Bus keeps for
+// History and Replay, unless SetHistorySize is called.
+const DefaultHistorySize = 256
+
+// A History is a list of past events, oldest first.
+//
+// Its methods narrow it down, so queries can be chained:
+//
+//	conn.Events().History().Since(t).OfTopic(TopicQuestion)
+type History []Event
+
+// Since returns the events published at or after t.
+func (h History) Since(t time.Time) History {
+	return h.filter(func(e Event) bool { return !e.Time.Before(t) })
+}
+
+// Until returns the events published before t.
+func (h History) Until(t time.Time) History {
+	return h.filter(func(e Event) bool { return e.Time.Before(t) })
+}
+
+// After returns the events with a sequence number above seq,
+// which is how a consumer picks up where it left off.
+func (h History) After(seq uint64) History {
+	return h.filter(func(e Event) bool { return e.Seq > seq })
+}
+
+// OfTopic returns the events in any of the topics.
+func (h History) OfTopic(topics ...Topic) History {
+	return h.filter(func(e Event) bool {
+		for _, t := range topics {
+			if e.Topic == t {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// OfType returns the events of any of the types, such as
+// "intro" or "result".
+func (h History) OfType(types ...string) History {
+	return h.filter(func(e Event) bool {
+		for _, t := range types {
+			if e.Type == t {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Last returns the newest event, if there is one.
+func (h History) Last() (Event, bool) {
+	if len(h) == 0 {
+		return Event{}, false
+	}
+	return h[len(h)-1], true
+}
+
+func (h History) filter(f func(Event) bool) History {
+	var res History
+	for _, e := range h {
+		if f(e) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// An eventRing holds the newest events published on a Bus.
+type eventRing struct {
+	events []Event
+	next   int
+	full   bool
+}
+
+func newEventRing(size int) *eventRing {
+	if size <= 0 {
+		return &eventRing{}
+	}
+	return &eventRing{events: make([]Event, size)}
+}
+
+func (r *eventRing) add(e Event) {
+	if len(r.events) == 0 {
+		return
+	}
+	r.events[r.next] = e
+	r.next = (r.next + 1) % len(r.events)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *eventRing) history() History {
+	if !r.full {
+		return append(History{}, r.events[:r.next]...)
+	}
+	return append(append(History{}, r.events[r.next:]...), r.events[:r.next]...)
+}
@@ -0,0 +1,158 @@
+package kahoot
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultTimeout bounds every HTTP request, dial and
+// WebSocket handshake unless HTTPClient is replaced.
+const DefaultTimeout = 15 * time.Second
+
+// HTTPClient is used for every HTTP request the package
+// makes. Its Timeout also bounds connecting to a game.
+// Replace it before making any connections to change the
+// timeout or transport.
+var HTTPClient = &http.Client{Timeout: DefaultTimeout}
+
+// MaxResponseSize bounds how many bytes are read from any
+// HTTP response body or WebSocket message.
+var MaxResponseSize int64 = 1 << 20
+
+var errResponseTooLarge = errors.New("response too large")
+
+// readBody reads a response body, failing once more than
+// MaxResponseSize bytes arrive.
+func readBody(r io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(r, MaxResponseSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > MaxResponseSize {
+		return nil, errResponseTooLarge
+	}
+	return data, nil
+}
+
+func dialTimeout() time.Duration {
+	if HTTPClient.Timeout > 0 {
+		return HTTPClient.Timeout
+	}
+	return DefaultTimeout
+}
+
+// httpConfig is the HTTP setup of one connection's session
+// requests. A nil *httpConfig uses HTTPClient.
+type httpConfig struct {
+	client *http.Client
+	header http.Header
+}
+
+func (c *httpConfig) do(req *http.Request) (*http.Response, error) {
+	if c == nil {
+		return HTTPClient.Do(req)
+	}
+	for k, v := range c.header {
+		req.Header[k] = v
+	}
+	client := c.client
+	if client == nil {
+		client = HTTPClient
+	}
+	return client.Do(req)
+}
+
+func (opts *ConnOptions) httpConfig() *httpConfig {
+	if opts.HTTPClient == nil && opts.Proxy == nil && opts.Header == nil {
+		return nil
+	}
+	c := &httpConfig{client: opts.HTTPClient, header: opts.Header}
+	if c.client == nil && opts.Proxy != nil {
+		c.client = &http.Client{
+			Timeout:   HTTPClient.Timeout,
+			Transport: &http.Transport{Proxy: http.ProxyURL(opts.Proxy)},
+		}
+	}
+	return c
+}
+
+// dialProxy opens a tunnel to addr through an HTTP proxy,
+// unless ctx ends first.
+func dialProxy(ctx context.Context, proxy *url.URL, addr string, timeout time.Duration) (net.Conn, error) {
+	proxyAddr := proxy.Host
+	if proxy.Port() == "" {
+		proxyAddr = net.JoinHostPort(proxy.Hostname(), "80")
+	}
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, err
+	}
+	defer closeOnCancel(ctx, conn)()
+	conn.SetDeadline(time.Now().Add(timeout))
+	req := &http.Request{
+		Method: "CONNECT",
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if proxy.User != nil {
+		password, _ := proxy.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(proxy.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %s", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy: %s", resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// A bufferedConn reads the bytes the proxy sent right after
+// its response before reading from the connection.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+// closeOnCancel makes conn's pending reads and writes fail if
+// ctx ends before the returned function is called.
+func closeOnCancel(ctx context.Context, conn net.Conn) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	return func() { close(done) }
+}
@@ -5,7 +5,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"time"
 )
 
 // QuizChoice represents a possible answer for a QuizQuestion.
@@ -35,6 +39,22 @@ type QuizQuestion struct {
 	Choices         []QuizChoice `json:"choices"`
 	Resources       string       `json:"resources"`
 	Type            string       `json:"type"`
+
+	// PointsMultiplier is set by newer quizzes; see
+	// Multiplier.
+	PointsMultiplier *int `json:"pointsMultiplier,omitempty"`
+}
+
+// Multiplier returns 2 for a double points question, 0 for
+// one worth no points, and 1 otherwise. Quizzes which predate
+// double points only say whether a question gives points.
+func (q *QuizQuestion) Multiplier() int {
+	if q.PointsMultiplier != nil {
+		return *q.PointsMultiplier
+	} else if q.Points {
+		return 1
+	}
+	return 0
 }
 
 // QuizMetadata stores metadata about a quiz.
@@ -102,49 +122,133 @@ type QuizInfo struct {
 // AccessToken returns an access token from the
 // kahoot rest api.
 func AccessToken(email, password string) (string, error) {
-	client := &http.Client{}
+	token, _, err := AccessTokenExpiry(email, password)
+	return token, err
+}
+
+// AccessTokenExpiry is like AccessToken, but it also returns
+// the time at which the token expires.
+func AccessTokenExpiry(email, password string) (string, time.Time, error) {
 	rawauth := map[string]string{"username": email, "password": password, "grant_type": "password"}
 	authentication, err := json.Marshal(rawauth)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
-	request, err := http.NewRequest("POST", "https://create.kahoot.it/rest/authenticate", bytes.NewReader(authentication))
+	request, err := http.NewRequest("POST", protocol().CreatorURL+"authenticate", bytes.NewReader(authentication))
 	request.Header.Add("content-type", "application/json")
-	response, err := client.Do(request)
+	response, err := creatorAPI.do(request)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	defer response.Body.Close()
 	receivedtoken := &token{}
-	err = json.NewDecoder(response.Body).Decode(receivedtoken)
+	err = json.NewDecoder(io.LimitReader(response.Body, MaxResponseSize)).Decode(receivedtoken)
 	if err != nil {
-		return "", err
+		return "", time.Time{}, err
 	}
 	if receivedtoken.User.Activated == false {
-		return "", errors.New("401 unauthorized error:email or password is incorrect")
+		return "", time.Time{}, errors.New("401 unauthorized error:email or password is incorrect")
 	}
-	return receivedtoken.AccessToken, nil
+	expires := time.Unix(0, receivedtoken.Expires*int64(time.Millisecond))
+	return receivedtoken.AccessToken, expires, nil
 }
 
 // QuizInformation returns all quiz information for a
 // specific kahoot id.
+// Quizzes are cached, and fetching one again only downloads it
+// if it changed since.
 func QuizInformation(token, quizid string) (*QuizInfo, error) {
-	client := &http.Client{}
-	request, err := http.NewRequest("GET", fmt.Sprintf("https://create.kahoot.it/rest/kahoots/%s", quizid), nil)
+	request, err := http.NewRequest("GET", fmt.Sprintf("%skahoots/%s", protocol().CreatorURL, quizid), nil)
 	if err != nil {
 		return nil, err
 	}
 	request.Header.Add("content-type", "application/json")
 	request.Header.Add("authorization", token)
-	response, err := client.Do(request)
+	cached := creatorAPI.conditional(request, quizid)
+	response, err := creatorAPI.do(request)
 	if err != nil {
 		return nil, err
 	}
 	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		return copyQuiz(&cached.info), nil
+	}
 	kahootquiz := &QuizInfo{}
-	err = json.NewDecoder(response.Body).Decode(kahootquiz)
+	err = json.NewDecoder(io.LimitReader(response.Body, MaxResponseSize)).Decode(kahootquiz)
 	if err != nil {
 		return nil, err
 	}
+	if response.StatusCode == http.StatusOK {
+		creatorAPI.storeQuiz(quizid, kahootquiz, response.Header)
+	}
 	return kahootquiz, nil
 }
+
+// PublicQuiz fetches a public quiz, including its answers,
+// without a login.
+func PublicQuiz(uuid string) (*QuizInfo, error) {
+	playURL := protocol().PlayURL
+	if playURL == "" {
+		return nil, errors.New("protocol has no play endpoint")
+	}
+	if err := currentBudget.takeRequest(); err != nil {
+		return nil, err
+	}
+	response, err := HTTPClient.Get(playURL + "kahoots/" + url.PathEscape(uuid))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch quiz %s: %s", uuid, response.Status)
+	}
+	quiz := &QuizInfo{}
+	if err := json.NewDecoder(io.LimitReader(response.Body, MaxResponseSize)).Decode(quiz); err != nil {
+		return nil, fmt.Errorf("fetch quiz %s: %s", uuid, err)
+	}
+	return quiz, nil
+}
+
+// CreateQuiz saves a new quiz in the creator account which
+// the token belongs to, and returns the quiz as saved, with
+// its new Uuid.
+func CreateQuiz(token string, quiz *QuizInfo) (*QuizInfo, error) {
+	body, err := json.Marshal(quiz)
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequest("POST", protocol().CreatorURL+"kahoots", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("content-type", "application/json")
+	request.Header.Add("authorization", token)
+	response, err := creatorAPI.do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK && response.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("create quiz: %s", response.Status)
+	}
+	saved := &QuizInfo{}
+	err = json.NewDecoder(io.LimitReader(response.Body, MaxResponseSize)).Decode(saved)
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// ReadQuizFile reads a quiz saved as JSON, for instance by
+// kahoot-bank's export.
+func ReadQuizFile(path string) (*QuizInfo, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	quiz := &QuizInfo{}
+	if err := json.Unmarshal(data, quiz); err != nil {
+		return nil, fmt.Errorf("parse %s: %s", path, err)
+	}
+	return quiz, nil
+}
@@ -0,0 +1,50 @@
+//go:build goja
+// +build goja
+
+package kahoot
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// jsTimeout bounds how long a challenge script may run.
+const jsTimeout = time.Second
+
+// challengePrelude stubs out the browser globals a challenge
+// script uses.
+const challengePrelude = `
+var _ = {replace: function(s, re, f) { return String(s).replace(re, f); }};
+var angular = {};
+["isArray", "isObject", "isString", "isDate", "isNumber", "isElement"].forEach(function(name) {
+	angular[name] = function() { return false; };
+});
+`
+
+func init() {
+	jsSolver = solveChallengeJS
+}
+
+// solveChallengeJS runs a challenge script in an embedded
+// JavaScript engine, so that challenges which the built-in
+// patterns do not recognize are still solved locally.
+func solveChallengeJS(ch string) (string, error) {
+	vm := goja.New()
+	vm.Set("console", map[string]interface{}{"log": func(...interface{}) {}})
+	timer := time.AfterFunc(jsTimeout, func() {
+		vm.Interrupt("challenge took longer than " + jsTimeout.String())
+	})
+	defer timer.Stop()
+
+	// The completion value of the script is the value of its
+	// first statement, decode.call(...).
+	v, err := vm.RunString(challengePrelude + ch)
+	if err != nil {
+		return "", err
+	} else if v == nil || v.String() == "undefined" {
+		return "", errors.New("challenge script produced no value")
+	}
+	return v.String(), nil
+}
@@ -0,0 +1,48 @@
+//go:build goja
+// +build goja
+
+package kahoot
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSolveChallengeJS(t *testing.T) {
+	// A loop-based shape which the built-in patterns do not
+	// recognize.
+	ch := "decode.call(this, 'abc'); function decode(message) {var offset = 0; " +
+		"for (var i = 0; i < 3; i++) { offset += i * 7; } var out = ''; " +
+		"for (var j = 0; j < message.length; j++) { " +
+		"out += String.fromCharCode(((message.charCodeAt(j) * j) + offset) % 77 + 48); } return out;}"
+	if _, ok := solveChallengeLocally(ch); ok {
+		t.Fatal("challenge should not match the built-in patterns")
+	}
+	mask, err := solveChallengeJS(ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected []rune
+	for i, x := range "abc" {
+		expected = append(expected, rune((int(x)*i+21)%77+48))
+	}
+	if mask != string(expected) {
+		t.Errorf("expected %q but got %q", string(expected), mask)
+	}
+	if m, err := computeChallenge(context.Background(), nil, ch); err != nil || string(m) != mask {
+		t.Errorf("computeChallenge should use the engine, got %q %v", m, err)
+	}
+}
+
+func TestSolveChallengeJSTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := solveChallengeJS("while (true) {}")
+	if err == nil || !strings.Contains(err.Error(), "longer than") {
+		t.Errorf("expected a timeout error but got %v", err)
+	}
+	if time.Since(start) > 5*jsTimeout {
+		t.Error("script was not interrupted in time")
+	}
+}
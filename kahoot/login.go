@@ -0,0 +1,186 @@
+package kahoot
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Reasons the server gives for refusing a login. A refused
+// Login returns a *LoginError which wraps one of them, so they
+// can be checked with errors.Is.
+var (
+	ErrDuplicateName = errors.New("nickname already taken")
+	ErrGameLocked    = errors.New("game is locked")
+	ErrNameBlocked   = errors.New("nickname not allowed")
+)
+
+// A LoginError is returned when the server refuses a login.
+type LoginError struct {
+	Pin string
+
+	// Code and Description are what the server sent.
+	Code        string
+	Description string
+
+	// Reason is ErrDuplicateName, ErrGameLocked,
+	// ErrNameBlocked, or nil for reasons not known here.
+	Reason error
+}
+
+func (l *LoginError) Error() string {
+	msg := "login refused"
+	if l.Reason != nil {
+		msg += ": " + l.Reason.Error()
+	}
+	if l.Code != "" {
+		msg += " (" + l.Code
+		if l.Description != "" {
+			msg += ": " + l.Description
+		}
+		msg += ")"
+	}
+	return msg
+}
+
+func (l *LoginError) Unwrap() error {
+	return l.Reason
+}
+
+// loginOutcome interprets a message received while waiting
+// for a login to be confirmed. It returns done once the login
+// succeeded or failed, with the failure as err.
+func loginOutcome(channel string, msg Message) (done bool, err error) {
+	data, ok := msg["data"].(map[string]interface{})
+	if !ok {
+		return false, nil
+	}
+	typeStr, _ := data["type"].(string)
+	if channel == protocol().Channels.Status {
+		if status, _ := data["status"].(string); typeStr == "status" && strings.EqualFold(status, "locked") {
+			return true, &LoginError{Code: status, Reason: ErrGameLocked}
+		}
+		return false, nil
+	}
+	if typeStr != "loginResponse" {
+		return false, nil
+	}
+	code, _ := data["error"].(string)
+	if code == "" {
+		return true, nil
+	}
+	description, _ := data["description"].(string)
+	return true, &LoginError{
+		Code:        code,
+		Description: description,
+		Reason:      loginErrorReason(code, description),
+	}
+}
+
+// loginErrorReason maps the server's error codes, and the
+// descriptions which accompany its generic codes, to reasons.
+func loginErrorReason(code, description string) error {
+	switch strings.ToUpper(code) {
+	case "DUPLICATE_NAME", "NAME_TAKEN":
+		return ErrDuplicateName
+	case "LOCKED", "GAME_LOCKED":
+		return ErrGameLocked
+	case "NAME_BLOCKED", "INAPPROPRIATE_NAME", "NICKNAME_NOT_ALLOWED":
+		return ErrNameBlocked
+	}
+	d := strings.ToLower(description)
+	switch {
+	case strings.Contains(d, "duplicate") || strings.Contains(d, "taken"):
+		return ErrDuplicateName
+	case strings.Contains(d, "locked"):
+		return ErrGameLocked
+	case strings.Contains(d, "inappropriate") || strings.Contains(d, "not allowed") ||
+		strings.Contains(d, "blocked"):
+		return ErrNameBlocked
+	}
+	return nil
+}
+
+// LoginGenerated asks the namerator for a nickname and logs in
+// with it, for games which reject names of the player's own
+// choosing. It returns the nickname the server accepted.
+//
+// The namerator is asked with the Conn's HTTPClient, Proxy and
+// Header, within its Timeout if one is set; the request is
+// abandoned if the Conn closes.
+func (c *Conn) LoginGenerated() (string, error) {
+	ctx, cancel := c.requestContext()
+	start := time.Now()
+	nickname, err := generateNickname(ctx, &c.opts)
+	cancel()
+	c.opts.Stage.since(StageNamerator, start, err)
+	if err != nil {
+		return "", err
+	}
+	if err := c.Login(nickname); err != nil {
+		return "", err
+	}
+	return c.Player().Nickname, nil
+}
+
+// requestContext is the context for an HTTP request made on
+// the Conn's behalf. It ends when the Conn closes, or after
+// the Conn's Timeout if one is set.
+func (c *Conn) requestContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.closed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if c.opts.Timeout <= 0 {
+		return ctx, cancel
+	}
+	timed, cancelTimed := context.WithTimeout(ctx, c.opts.Timeout)
+	return timed, func() {
+		cancelTimed()
+		cancel()
+	}
+}
+
+// generateNickname fetches a nickname from the namerator.
+func generateNickname(ctx context.Context, opts *ConnOptions) (string, error) {
+	nameratorURL := protocol().NameratorURL
+	if nameratorURL == "" {
+		return "", errors.New("protocol has no namerator endpoint")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", nameratorURL, nil)
+	if err != nil {
+		return "", err
+	}
+	if err := currentBudget.takeRequest(); err != nil {
+		return "", err
+	}
+	resp, err := opts.httpConfig().do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := readBody(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("namerator: %s", resp.Status)
+	}
+	var res struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", fmt.Errorf("parse namerator response: %s", err)
+	} else if res.Name == "" {
+		return "", errors.New("namerator returned no name")
+	}
+	return res.Name, nil
+}
@@ -0,0 +1,86 @@
+package kahoot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLoginOutcome(t *testing.T) {
+	controller := protocol().Channels.Controller
+	status := protocol().Channels.Status
+	cases := []struct {
+		channel string
+		data    map[string]interface{}
+		done    bool
+		reason  error
+	}{
+		{controller, map[string]interface{}{"type": "loginResponse", "cid": "12"}, true, nil},
+		{controller, map[string]interface{}{"type": "other"}, false, nil},
+		{controller, map[string]interface{}{"type": "loginResponse", "error": "USER_INPUT",
+			"description": "Duplicate name"}, true, ErrDuplicateName},
+		{controller, map[string]interface{}{"type": "loginResponse", "error": "NAME_BLOCKED"},
+			true, ErrNameBlocked},
+		{status, map[string]interface{}{"type": "status", "status": "LOCKED"}, true, ErrGameLocked},
+		{status, map[string]interface{}{"type": "status", "status": "ACTIVE"}, false, nil},
+	}
+	for i, c := range cases {
+		done, err := loginOutcome(c.channel, Message{"data": c.data})
+		if done != c.done {
+			t.Errorf("case %d: expected done=%v", i, c.done)
+		}
+		if c.reason == nil {
+			if err != nil {
+				t.Errorf("case %d: unexpected error %v", i, err)
+			}
+		} else if !errors.Is(err, c.reason) {
+			t.Errorf("case %d: expected %v but got %v", i, c.reason, err)
+		}
+	}
+
+	_, err := loginOutcome(controller, Message{"data": map[string]interface{}{
+		"type": "loginResponse", "error": "SOMETHING_NEW"}})
+	if le, ok := err.(*LoginError); !ok || le.Reason != nil || le.Code != "SOMETHING_NEW" {
+		t.Errorf("unknown codes should still be login errors, got %v", err)
+	}
+}
+
+func TestGenerateNickname(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "yes" {
+			http.Error(w, "missing header", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"name":"Sneaky Otter"}`))
+	}))
+	defer server.Close()
+	old := CurrentProtocol()
+	defer SetProtocol(&old)
+	p := CurrentProtocol()
+	p.NameratorURL = server.URL
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &ConnOptions{Header: http.Header{"X-Test": {"yes"}}}
+	name, err := generateNickname(context.Background(), opts)
+	if err != nil {
+		t.Fatal(err)
+	} else if name != "Sneaky Otter" {
+		t.Errorf("unexpected name: %q", name)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := generateNickname(ctx, opts); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the canceled context to stop the request but got %v", err)
+	}
+
+	p.NameratorURL = ""
+	SetProtocol(&p)
+	if _, err := generateNickname(context.Background(), opts); err == nil {
+		t.Error("expected an error without a namerator endpoint")
+	}
+}
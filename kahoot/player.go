@@ -0,0 +1,90 @@
+package kahoot
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// A Player identifies a participant in a game.
+//
+// The Cid is assigned by the server when the player logs in,
+// and it is what the host's reports use to key players.
+type Player struct {
+	Cid      string `json:"cid"`
+	Nickname string `json:"nickname"`
+}
+
+// A PlayerMap maps between cids and nicknames.
+// It is safe to use from multiple goroutines.
+type PlayerMap struct {
+	lock       sync.RWMutex
+	byCid      map[string]Player
+	byNickname map[string]Player
+}
+
+// NewPlayerMap creates an empty PlayerMap.
+func NewPlayerMap() *PlayerMap {
+	return &PlayerMap{
+		byCid:      map[string]Player{},
+		byNickname: map[string]Player{},
+	}
+}
+
+// Add records a player, replacing any previous player with
+// the same cid or nickname.
+func (p *PlayerMap) Add(player Player) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if old, ok := p.byCid[player.Cid]; ok {
+		delete(p.byNickname, old.Nickname)
+	}
+	if old, ok := p.byNickname[player.Nickname]; ok {
+		delete(p.byCid, old.Cid)
+	}
+	p.byCid[player.Cid] = player
+	p.byNickname[player.Nickname] = player
+}
+
+// Nickname looks up the nickname for a cid.
+func (p *PlayerMap) Nickname(cid string) (string, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	player, ok := p.byCid[cid]
+	return player.Nickname, ok
+}
+
+// Cid looks up the cid for a nickname.
+func (p *PlayerMap) Cid(nickname string) (string, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	player, ok := p.byNickname[nickname]
+	return player.Cid, ok
+}
+
+// Players returns every player, sorted by cid.
+func (p *PlayerMap) Players() []Player {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	res := make([]Player, 0, len(p.byCid))
+	for _, player := range p.byCid {
+		res = append(res, player)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Cid < res[j].Cid
+	})
+	return res
+}
+
+// parseCid converts the cid of a loginResponse to a string.
+// Older servers send a number, newer ones a string.
+func parseCid(cid interface{}) string {
+	switch cid := cid.(type) {
+	case float64:
+		return strconv.FormatInt(int64(cid), 10)
+	case string:
+		return cid
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,23 @@
+package kahoot
+
+import "testing"
+
+func TestPlayerMap(t *testing.T) {
+	m := NewPlayerMap()
+	m.Add(Player{Cid: parseCid(float64(-2026719275)), Nickname: "alex"})
+	m.Add(Player{Cid: parseCid("404836752"), Nickname: "bob"})
+	m.Add(Player{Cid: "404836752", Nickname: "bobby"})
+
+	if cid, ok := m.Cid("alex"); !ok || cid != "-2026719275" {
+		t.Errorf("bad cid for alex: %s", cid)
+	}
+	if _, ok := m.Cid("bob"); ok {
+		t.Error("renamed player should be forgotten")
+	}
+	if name, ok := m.Nickname("404836752"); !ok || name != "bobby" {
+		t.Errorf("bad nickname: %s", name)
+	}
+	if players := m.Players(); len(players) != 2 || players[0].Nickname != "alex" {
+		t.Errorf("bad players: %v", players)
+	}
+}
@@ -0,0 +1,183 @@
+package kahoot
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// A Protocol holds the details of the kahoot protocol which
+// tend to change: endpoints, challenge formats, and which
+// message ids are understood.
+type Protocol struct {
+	Version string `json:"version"`
+
+	// KnownGood is the last date on which the protocol was
+	// checked against the live servers.
+	KnownGood string `json:"knownGood,omitempty"`
+
+	// Host is the value of the "host" field in messages.
+	Host       string `json:"host"`
+	DialAddr   string `json:"dialAddr"`
+	Origin     string `json:"origin"`
+	ReserveURL string `json:"reserveURL"`
+	CometdURL  string `json:"cometdURL"`
+
+	// CreatorURL is the base URL of the creator REST API.
+	CreatorURL string `json:"creatorURL"`
+
+	// PlayURL is the base URL of the REST API which serves
+	// public quizzes without a login. It is optional.
+	PlayURL string `json:"playURL,omitempty"`
+
+	// NameratorURL hands out the generated nicknames which
+	// games with the namerator on require. It is optional.
+	NameratorURL string `json:"nameratorURL,omitempty"`
+
+	Channels Channels `json:"channels"`
+
+	// Templates are the constant parts of outgoing messages,
+	// keyed by "login", "message", and "answerContent".
+	Templates map[string]Message `json:"templates"`
+
+	// ChallengePatterns are regular expressions for challenge
+	// scripts which can be solved locally. Each must have two
+	// groups: the message to decode and the offset expression.
+	ChallengePatterns []string `json:"challengePatterns"`
+
+	// KnownPlayerIds are the /service/player message ids which
+	// should not be reported as unrecognized.
+	KnownPlayerIds []int `json:"knownPlayerIds"`
+}
+
+//go:embed protocol.json
+var protocolJSON []byte
+
+// DefaultProtocol is the protocol embedded in the package.
+var DefaultProtocol = mustParseProtocol(protocolJSON)
+
+// Channels are the names of the service channels.
+type Channels struct {
+	Controller string `json:"controller"`
+	Player     string `json:"player"`
+	Status     string `json:"status"`
+}
+
+type compiledProtocol struct {
+	Protocol
+	challengeRegexps []*regexp.Regexp
+	knownPlayerIds   map[int]bool
+}
+
+var (
+	protocolLock    sync.RWMutex
+	currentProtocol = mustCompileProtocol(&DefaultProtocol)
+)
+
+// SetProtocol replaces the protocol used by new connections.
+// It fails without changing anything if p is invalid.
+func SetProtocol(p *Protocol) error {
+	compiled, err := compileProtocol(p)
+	if err != nil {
+		return err
+	}
+	protocolLock.Lock()
+	currentProtocol = compiled
+	protocolLock.Unlock()
+	return nil
+}
+
+// CurrentProtocol returns a copy of the protocol in use.
+func CurrentProtocol() Protocol {
+	return protocol().Protocol
+}
+
+func protocol() *compiledProtocol {
+	protocolLock.RLock()
+	defer protocolLock.RUnlock()
+	return currentProtocol
+}
+
+func compileProtocol(p *Protocol) (*compiledProtocol, error) {
+	if p.Host == "" || p.DialAddr == "" || p.ReserveURL == "" || p.CometdURL == "" ||
+		p.CreatorURL == "" {
+		return nil, errors.New("protocol " + p.Version + ": missing endpoint")
+	}
+	if p.Channels.Controller == "" || p.Channels.Player == "" || p.Channels.Status == "" {
+		return nil, errors.New("protocol " + p.Version + ": missing channel name")
+	}
+	for _, name := range []string{"login", "message", "answerContent"} {
+		if _, ok := p.Templates[name]; !ok {
+			return nil, errors.New("protocol " + p.Version + ": missing template: " + name)
+		}
+	}
+	res := &compiledProtocol{Protocol: *p, knownPlayerIds: map[int]bool{}}
+	for _, pattern := range p.ChallengePatterns {
+		expr, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("protocol %s: %s", p.Version, err)
+		} else if expr.NumSubexp() != 2 {
+			return nil, fmt.Errorf("protocol %s: challenge pattern needs 2 groups", p.Version)
+		}
+		res.challengeRegexps = append(res.challengeRegexps, expr)
+	}
+	for _, id := range p.KnownPlayerIds {
+		res.knownPlayerIds[id] = true
+	}
+	return res, nil
+}
+
+// LoadProtocolDir overrides the default protocol with the
+// fields of dir/protocol.json. Fields which the file omits
+// keep their default values.
+func LoadProtocolDir(dir string) error {
+	data, err := ioutil.ReadFile(filepath.Join(dir, "protocol.json"))
+	if err != nil {
+		return err
+	}
+	p := copyProtocol(&DefaultProtocol)
+	if err := json.Unmarshal(data, p); err != nil {
+		return errors.New("protocol override: " + err.Error())
+	}
+	return SetProtocol(p)
+}
+
+// template returns a fresh copy of a message template which
+// the caller may modify.
+func (c *compiledProtocol) template(name string) Message {
+	var res Message
+	data, _ := json.Marshal(c.Templates[name])
+	json.Unmarshal(data, &res)
+	if res == nil {
+		res = Message{}
+	}
+	return res
+}
+
+func copyProtocol(p *Protocol) *Protocol {
+	var res Protocol
+	data, _ := json.Marshal(p)
+	json.Unmarshal(data, &res)
+	return &res
+}
+
+func mustParseProtocol(data []byte) Protocol {
+	var p Protocol
+	if err := json.Unmarshal(data, &p); err != nil {
+		panic("embedded protocol: " + err.Error())
+	}
+	return p
+}
+
+func mustCompileProtocol(p *Protocol) *compiledProtocol {
+	res, err := compileProtocol(p)
+	if err != nil {
+		panic(err)
+	}
+	return res
+}
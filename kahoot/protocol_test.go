@@ -0,0 +1,40 @@
+package kahoot
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadProtocolDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "protocol")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetProtocol(&DefaultProtocol)
+
+	override := `{"version": "test", "channels": {"controller": "/service/ctl",
+		"player": "/service/player", "status": "/service/status"}}`
+	err = ioutil.WriteFile(filepath.Join(dir, "protocol.json"), []byte(override), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadProtocolDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	p := CurrentProtocol()
+	if p.Version != "test" || p.Channels.Controller != "/service/ctl" {
+		t.Errorf("override not applied: %+v", p)
+	}
+	if p.Host != DefaultProtocol.Host || len(p.ChallengePatterns) != 1 {
+		t.Errorf("defaults not kept: %+v", p)
+	}
+
+	tmpl := protocol().template("answerContent")
+	tmpl["choice"] = 3
+	if _, ok := protocol().template("answerContent")["choice"]; ok {
+		t.Error("templates must be copied")
+	}
+}
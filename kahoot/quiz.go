@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"strconv"
+	"sync/atomic"
+	"time"
 )
 
 type QuizActionType int
@@ -13,115 +15,541 @@ const (
 	QuestionAnswers
 )
 
+const gameOverId = 3
+const revealAnswerId = 8
+const kickedId = 10
+const feedbackId = 12
+const quizEndId = 13
+const recoveryDataId = 17
+
 type QuizAction struct {
 	Type       QuizActionType
 	NumAnswers int
 	Index      int
-	AnswerMap  map[int]int
+
+	// AnswerMap maps each on-screen choice to the quiz's
+	// choice, which is what Quiz.Send takes. It differs from
+	// the identity when the host shuffles answers. See
+	// QuizChoice and ScreenChoice.
+	AnswerMap map[int]int
+
+	// Choices are the on-screen choice texts, in screen
+	// order, in games which show them on players' devices.
+	Choices []string
+
+	// AnswerCounts is the number of choices of every question
+	// in the quiz, which the server sends along with each
+	// question. It helps identify the quiz being played.
+	AnswerCounts []int
+
+	// Received is when the action arrived.
+	Received time.Time
+
+	// TimeLeft is how long the question remains open, if the
+	// server said so. It is zero otherwise.
+	TimeLeft time.Duration
+
+	// PointsMultiplier is 2 for a double points question, 0
+	// for one worth no points, and 1 otherwise.
+	PointsMultiplier int
+
+	// QuestionType is the kind of question, such as "quiz",
+	// "survey", or "jumble", if the server said.
+	QuestionType string
+}
+
+// Deadline returns the time at which the question closes, or
+// the zero time if it is not known.
+func (q *QuizAction) Deadline() time.Time {
+	if q.TimeLeft == 0 {
+		return time.Time{}
+	}
+	return q.Received.Add(q.TimeLeft)
+}
+
+// QuizChoice returns the quiz's choice for an on-screen one,
+// ready for Quiz.Send.
+func (q *QuizAction) QuizChoice(screen int) int {
+	if original, ok := q.AnswerMap[screen]; ok {
+		return original
+	}
+	return screen
+}
+
+// ScreenChoice returns where the quiz's choice is shown on
+// screen.
+func (q *QuizAction) ScreenChoice(original int) int {
+	for screen, o := range q.AnswerMap {
+		if o == original {
+			return screen
+		}
+	}
+	return original
+}
+
+// QuizResult is the outcome of a question, as revealed by the
+// server once the question ends.
+type QuizResult struct {
+	Choice     int     `json:"choice"`
+	IsCorrect  bool    `json:"isCorrect"`
+	Text       string  `json:"text"`
+	Points     float64 `json:"points"`
+	TotalScore float64 `json:"totalScore"`
+	Rank       int     `json:"rank"`
+
+	// Streak is the player's answer streak after this
+	// question, and StreakBonus the points it added, if the
+	// server reported them.
+	Streak      int     `json:"streak"`
+	StreakBonus float64 `json:"streakBonus"`
+
+	// Index is the question index which the result is for.
+	Index int `json:"-"`
+
+	// Player is the player which the result is for.
+	Player Player `json:"-"`
+}
+
+// QuizEnd is a player's standing once the quiz is over.
+type QuizEnd struct {
+	Rank           int     `json:"rank"`
+	TotalScore     float64 `json:"totalScore"`
+	CorrectCount   int     `json:"correctCount"`
+	IncorrectCount int     `json:"incorrectCount"`
+
+	// Player is the player which the standing is for.
+	Player Player `json:"-"`
+}
+
+// A SentAnswer is an answer which Send delivered. It is
+// published as a "sent" question event once the server
+// acknowledges it, or as "unacked" if it was written but the
+// acknowledgement failed.
+type SentAnswer struct {
+	Index int `json:"index"`
+
+	// Choice is the answer index, or -1 for answers which are
+	// not a single choice. Those are given as Value.
+	Choice int         `json:"choice"`
+	Value  interface{} `json:"value,omitempty"`
+
+	Sent time.Time `json:"sent"`
 }
 
 type Quiz struct {
 	conn *Conn
+
+	lastIndex  int
+	lastResult int
+
+	// answered is the last question index answered by Send,
+	// which a demo build answers only once.
+	answered int
+
+	prepared map[int]Message
 }
 
 func NewQuiz(c *Conn) *Quiz {
-	return &Quiz{c}
+	return &Quiz{conn: c, lastIndex: -1, lastResult: -1, answered: -1}
 }
 
 // Receive receives the next QuizAction.
 // This may be a QuestionIntro, indicating a new question is starting,
 // or QuestionAnswers, indicating that the user may now submit an answer.
+// Once the host kicks the player, it returns a *KickError.
 func (q *Quiz) Receive() (*QuizAction, error) {
-PacketLoop:
 	for {
-		packet, err := q.conn.Receive("/service/player")
+		packet, err := q.conn.Receive(protocol().Channels.Player)
 		if err != nil {
+			q.conn.events.Publish(TopicError, "receive", err)
 			return nil, err
 		}
 		var content Message
+		var id float64
+		var problem string
 		if data, ok := packet["data"].(map[string]interface{}); !ok {
+			problem = "message without data"
+		} else if id, ok = data["id"].(float64); !ok {
+			problem = "message without an id"
+		} else if contentStr, ok := data["content"].(string); !ok {
+			problem = "message without content"
+		} else if json.Unmarshal([]byte(contentStr), &content) != nil {
+			problem = "content is not a JSON object"
+		} else if !protocol().knownPlayerIds[int(id)] {
+			report(&Sample{
+				Kind:    "message",
+				Channel: protocol().Channels.Player,
+				Id:      int(id),
+				Content: contentStr,
+			})
+			problem = "unknown message id"
+		}
+		if problem != "" {
+			if err := q.unrecognized(packet, int(id), problem); err != nil {
+				return nil, err
+			}
 			continue
-		} else if id, ok := data["id"].(float64); !ok {
+		}
+		if id == revealAnswerId {
+			if result, err := parseQuizResult(content); err == nil {
+				result.Index = q.lastIndex
+				result.Player = q.conn.Player()
+				q.lastResult = q.lastIndex
+				q.conn.events.Publish(TopicResult, "result", result)
+				q.conn.updateGame(func(g *GameState) { g.observeResult(result) })
+			} else if err := q.unrecognized(packet, int(id), "unparsed result"); err != nil {
+				return nil, err
+			}
 			continue
-		} else if contentStr, ok := data["content"].(string); !ok {
+		} else if id == kickedId {
+			if code, ok := content["kickCode"]; ok {
+				atomic.StoreInt32(&q.conn.kicked, 1)
+				q.conn.events.Publish(TopicConnection, "kicked", code)
+				n, _ := code.(float64)
+				return nil, &KickError{Pin: q.conn.gameId, Code: int(n)}
+			} else if err := q.unrecognized(packet, int(id), "kick without a code"); err != nil {
+				return nil, err
+			}
 			continue
-		} else if json.Unmarshal([]byte(contentStr), &content) != nil {
+		} else if id == quizEndId {
+			var end QuizEnd
+			if decodeContent(content, &end) == nil {
+				end.Player = q.conn.Player()
+				q.conn.events.Publish(TopicResult, "quizEnd", &end)
+				q.conn.updateGame(func(g *GameState) { g.observeEnd(&end) })
+			} else if err := q.unrecognized(packet, int(id), "unparsed quiz end"); err != nil {
+				return nil, err
+			}
 			continue
-		} else if numArray, ok := content["quizQuestionAnswers"].([]interface{}); !ok {
+		} else if id == gameOverId {
+			q.conn.events.Publish(TopicResult, "gameOver", nil)
+			q.conn.updateGame(func(g *GameState) { g.Phase = PhaseOver })
 			continue
-		} else if questionIndex, ok := content["questionIndex"].(float64); !ok {
+		} else if id == feedbackId {
+			q.conn.events.Publish(TopicConnection, "feedback", nil)
 			continue
-		} else if int(questionIndex) >= len(numArray) || int(questionIndex) < 0 {
+		} else if name, ok := twoFactorEvents[int(id)]; ok {
+			q.conn.twoFactorStage(int(id))
+			q.conn.events.Publish(TopicConnection, name, nil)
 			continue
-		} else if numAnswers, ok := numArray[int(questionIndex)].(float64); !ok {
+		} else if id == teamAcceptedId {
+			q.conn.events.Publish(TopicConnection, "teamAccepted", nil)
 			continue
-		} else if answerMap, ok := content["answerMap"].(map[string]interface{}); !ok {
+		} else if id == recoveryDataId {
+			if action, ok := q.handleRecovery(content); action != nil {
+				return action, nil
+			} else if !ok {
+				if err := q.unrecognized(packet, int(id), "unparsed recovery state"); err != nil {
+					return nil, err
+				}
+			}
 			continue
+		}
+		var t QuizActionType
+		if id == 1 {
+			t = QuestionIntro
+		} else if id == 2 {
+			t = QuestionAnswers
 		} else {
-			var t QuizActionType
-			if id == 1 {
-				t = QuestionIntro
-			} else if id == 2 {
-				t = QuestionAnswers
-			} else {
-				continue
-			}
+			continue
+		}
+		if action := parseAction(t, content); action != nil {
+			q.deliver(action)
+			return action, nil
+		} else if err := q.unrecognized(packet, int(id), "unparsed question"); err != nil {
+			return nil, err
+		}
+	}
+}
 
-			intAnswerMap := map[int]int{}
-			for key, val := range answerMap {
-				intKey, err := strconv.Atoi(key)
-				if err != nil {
-					continue PacketLoop
-				}
-				if num, ok := val.(float64); !ok {
-					continue PacketLoop
-				} else {
-					intAnswerMap[intKey] = int(num)
-				}
-			}
+// unrecognized fails a strict Conn on a player message, and
+// closes it; see ConnOptions.Strict. It returns nil unless the
+// Conn is strict.
+func (q *Quiz) unrecognized(packet Message, id int, problem string) error {
+	err := q.conn.unrecognized(&ProtocolError{
+		Channel: protocol().Channels.Player,
+		Id:      id,
+		Problem: problem,
+	}, packet)
+	if err != nil {
+		q.conn.Close()
+	}
+	return err
+}
+
+// parseAction decodes the content of a question message, or
+// returns nil if it does not describe a question.
+func parseAction(t QuizActionType, content Message) *QuizAction {
+	numArray, ok := content["quizQuestionAnswers"].([]interface{})
+	if !ok {
+		return nil
+	}
+	questionIndex, ok := content["questionIndex"].(float64)
+	if !ok || int(questionIndex) >= len(numArray) || int(questionIndex) < 0 {
+		return nil
+	}
+	numAnswers, ok := numArray[int(questionIndex)].(float64)
+	if !ok {
+		return nil
+	}
+	answerMap, ok := content["answerMap"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	intAnswerMap := map[int]int{}
+	for key, val := range answerMap {
+		intKey, err := strconv.Atoi(key)
+		if err != nil {
+			return nil
+		}
+		num, ok := val.(float64)
+		if !ok {
+			return nil
+		}
+		intAnswerMap[intKey] = int(num)
+	}
+
+	action := &QuizAction{
+		Type:       t,
+		NumAnswers: int(numAnswers),
+		Index:      int(questionIndex),
+		AnswerMap:  intAnswerMap,
+		Received:   time.Now(),
 
-			return &QuizAction{
-				Type:       t,
-				NumAnswers: int(numAnswers),
-				Index:      int(questionIndex),
-				AnswerMap:  intAnswerMap,
-			}, nil
+		PointsMultiplier: pointsMultiplier(content),
+		QuestionType:     questionType(content),
+	}
+	for _, n := range numArray {
+		count, _ := n.(float64)
+		action.AnswerCounts = append(action.AnswerCounts, int(count))
+	}
+	action.Choices = choiceTexts(content["choices"])
+	for _, key := range []string{"timeLeft", "timeAvailable"} {
+		if ms, ok := content[key].(float64); ok {
+			action.TimeLeft = time.Duration(ms) * time.Millisecond
+			break
 		}
 	}
+	return action
+}
+
+// deliver records and publishes an action which Receive is
+// about to return.
+func (q *Quiz) deliver(action *QuizAction) {
+	q.trackGaps(action)
+	if action.Type == QuestionIntro {
+		q.conn.events.Publish(TopicQuestion, "intro", action)
+	} else {
+		q.conn.events.Publish(TopicQuestion, "answers", action)
+	}
+	q.conn.updateGame(func(g *GameState) { g.observeAction(action) })
 }
 
 // Send responds to a server's QuestionAnswers action with an answer index.
+// A demo build fails with ErrDemoRestricted on a second answer
+// to the same question.
 func (q *Quiz) Send(index int) error {
-	content := Message{
-		"choice": index,
-		"meta": Message{
-			"lag": 22,
-			"device": Message{
-				"userAgent": "hack",
-				"screen": Message{
-					"width":  1337,
-					"height": 1337,
-				},
-			},
-		},
+	message, ok := q.prepared[index]
+	if !ok {
+		message = q.answerMessage(map[string]interface{}{"choice": index})
 	}
-	encodedContent, _ := json.Marshal(content)
-	message := Message{
-		"data": Message{
-			"id":      45,
-			"type":    "message",
-			"gameid":  q.conn.gameId,
-			"host":    "kahoot.it",
-			"content": string(encodedContent),
-		},
-	}
-	if err := q.conn.Send("/service/controller", message); err != nil {
+	return q.send(message, &SentAnswer{Index: q.lastIndex, Choice: index})
+}
+
+// SendText answers an open-ended question.
+func (q *Quiz) SendText(text string) error {
+	message := q.answerMessage(map[string]interface{}{"type": "open_ended", "text": text})
+	return q.send(message, &SentAnswer{Index: q.lastIndex, Choice: -1, Value: text})
+}
+
+// SendJumble answers a jumble or puzzle question with the
+// choice indices in the order they should be placed.
+func (q *Quiz) SendJumble(order []int) error {
+	message := q.answerMessage(map[string]interface{}{"type": "jumble", "choice": order})
+	return q.send(message, &SentAnswer{Index: q.lastIndex, Choice: -1, Value: order})
+}
+
+// SendSlider answers a slider question with a value on its
+// scale.
+func (q *Quiz) SendSlider(value float64) error {
+	message := q.answerMessage(map[string]interface{}{"type": "slider", "choice": value})
+	return q.send(message, &SentAnswer{Index: q.lastIndex, Choice: -1, Value: value})
+}
+
+func (q *Quiz) send(message Message, sent *SentAnswer) error {
+	if DemoMode {
+		if q.lastIndex >= 0 && q.answered == q.lastIndex {
+			q.conn.events.Publish(TopicError, "send", ErrDemoRestricted)
+			return ErrDemoRestricted
+		}
+		q.answered = q.lastIndex
+	}
+	if err := currentBudget.takeAnswer(); err != nil {
+		q.conn.events.Publish(TopicError, "send", err)
 		return err
 	}
-	if controllerMsg, err := q.conn.Receive("/service/controller"); err != nil {
+	proto := protocol()
+	sent.Sent = time.Now()
+	if err := q.conn.send(proto.Channels.Controller, message); err != nil {
+		q.conn.events.Publish(TopicError, "send", err)
+		return err
+	}
+	if controllerMsg, err := q.conn.Receive(proto.Channels.Controller); err != nil {
+		q.conn.events.Publish(TopicQuestion, "unacked", sent)
+		q.conn.events.Publish(TopicError, "send", err)
 		return err
 	} else if success, ok := controllerMsg["successful"].(bool); !ok || !success {
-		return errors.New("did not receive successful response")
+		err := errors.New("did not receive successful response")
+		q.conn.events.Publish(TopicQuestion, "unacked", sent)
+		q.conn.events.Publish(TopicError, "send", err)
+		return err
 	}
+	q.conn.observeRTT(time.Since(sent.Sent))
+	q.conn.events.Publish(TopicQuestion, "sent", sent)
 	return nil
 }
+
+// Prepare encodes the answer messages for choices 0 through
+// numAnswers-1 ahead of time, so that a later Send of one of
+// them only has to write it.
+func (q *Quiz) Prepare(numAnswers int) {
+	if q.prepared == nil {
+		q.prepared = map[int]Message{}
+	}
+	for i := 0; i < numAnswers; i++ {
+		if _, ok := q.prepared[i]; !ok {
+			q.prepared[i] = q.answerMessage(map[string]interface{}{"choice": i})
+		}
+	}
+}
+
+// answerMessage builds an answer message whose content is
+// the answer template with fields added.
+func (q *Quiz) answerMessage(fields map[string]interface{}) Message {
+	proto := protocol()
+	content := proto.template("answerContent")
+	for k, v := range fields {
+		content[k] = v
+	}
+	encodedContent, _ := json.Marshal(content)
+	data := proto.template("message")
+	data["id"] = 45
+	data["gameid"] = q.conn.gameId
+	data["host"] = proto.Host
+	data["content"] = string(encodedContent)
+	return Message{"data": data}
+}
+
+// parseQuizResult decodes the content of a reveal-answer
+// message.
+func parseQuizResult(content Message) (*QuizResult, error) {
+	var res QuizResult
+	if err := decodeContent(content, &res); err != nil {
+		return nil, err
+	}
+	var points struct {
+		PointsData struct {
+			AnswerStreakPoints struct {
+				StreakLevel int     `json:"streakLevel"`
+				StreakBonus float64 `json:"streakBonus"`
+			} `json:"answerStreakPoints"`
+		} `json:"pointsData"`
+	}
+	if decodeContent(content, &points) == nil {
+		res.Streak = points.PointsData.AnswerStreakPoints.StreakLevel
+		res.StreakBonus = points.PointsData.AnswerStreakPoints.StreakBonus
+	}
+	return &res, nil
+}
+
+// questionType reads the kind of question from a question
+// message.
+func questionType(content Message) string {
+	for _, key := range []string{"gameBlockType", "type"} {
+		if s, ok := content[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Kinds of answer a question takes; see AnswerKind.
+const (
+	// AnswerChoice questions are answered with Send.
+	AnswerChoice = "choice"
+
+	// AnswerText questions are answered with SendText.
+	AnswerText = "text"
+
+	// AnswerOrder questions are answered with SendJumble.
+	AnswerOrder = "order"
+
+	// AnswerValue questions are answered with SendSlider.
+	AnswerValue = "value"
+
+	// AnswerNone blocks, such as content slides, take no
+	// answer.
+	AnswerNone = "none"
+)
+
+// answerKinds maps the question types the package knows to
+// the kind of answer they take.
+var answerKinds = map[string]string{
+	"":              AnswerChoice,
+	"quiz":          AnswerChoice,
+	"survey":        AnswerChoice,
+	"jumble":        AnswerOrder,
+	"puzzle":        AnswerOrder,
+	"open_ended":    AnswerText,
+	"word_cloud":    AnswerText,
+	"brainstorming": AnswerText,
+	"slider":        AnswerValue,
+	"content":       AnswerNone,
+}
+
+// AnswerKind returns the kind of answer a QuizAction's
+// QuestionType takes, or "" if the type is unknown. Questions
+// which do not name their type are multiple choice.
+func AnswerKind(questionType string) string {
+	return answerKinds[questionType]
+}
+
+// choiceTexts reads the on-screen choices of a question
+// message, which may be strings or objects with an answer.
+func choiceTexts(choices interface{}) []string {
+	list, ok := choices.([]interface{})
+	if !ok {
+		return nil
+	}
+	res := make([]string, len(list))
+	for i, c := range list {
+		switch c := c.(type) {
+		case string:
+			res[i] = c
+		case map[string]interface{}:
+			res[i], _ = c["answer"].(string)
+		}
+	}
+	return res
+}
+
+// pointsMultiplier reads a question's points modifier from
+// the message which introduces it.
+func pointsMultiplier(content Message) int {
+	if m, ok := content["pointsMultiplier"].(float64); ok {
+		return int(m)
+	} else if points, ok := content["points"].(bool); ok && !points {
+		return 0
+	}
+	return 1
+}
+
+// decodeContent decodes the content of a player message into
+// the struct v.
+func decodeContent(content Message, v interface{}) error {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,103 @@
+package kahoot
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/internal/kahoottest"
+)
+
+func TestAnswerMessage(t *testing.T) {
+	q := NewQuiz(&Conn{gameId: "123456"})
+	message := q.answerMessage(map[string]interface{}{"type": "jumble", "choice": []int{2, 0, 1}})
+	data := message["data"].(Message)
+	if data["id"] != 45 || data["gameid"] != "123456" || data["type"] != "message" {
+		t.Errorf("unexpected data %v", data)
+	}
+	var content struct {
+		Type   string
+		Choice []int
+		Meta   map[string]interface{}
+	}
+	if err := json.Unmarshal([]byte(data["content"].(string)), &content); err != nil {
+		t.Fatal(err)
+	}
+	if content.Type != "jumble" || !reflect.DeepEqual(content.Choice, []int{2, 0, 1}) || content.Meta == nil {
+		t.Errorf("unexpected content %+v", content)
+	}
+
+	q.Prepare(2)
+	if len(q.prepared) != 2 {
+		t.Fatalf("expected 2 prepared answers, got %d", len(q.prepared))
+	}
+	if !reflect.DeepEqual(q.prepared[1], q.answerMessage(map[string]interface{}{"choice": 1})) {
+		t.Error("prepared answer differs from an unprepared one")
+	}
+}
+
+func TestChoiceTexts(t *testing.T) {
+	var content Message
+	json.Unmarshal([]byte(`{"choices": ["x", {"answer": "y"}, 3]}`), &content)
+	if texts := choiceTexts(content["choices"]); !reflect.DeepEqual(texts, []string{"x", "y", ""}) {
+		t.Errorf("unexpected texts %q", texts)
+	}
+	if choiceTexts(nil) != nil {
+		t.Error("expected no texts")
+	}
+}
+
+func TestPlayOffline(t *testing.T) {
+	server := kahoottest.NewServer(kahoottest.Config{Questions: []kahoottest.Question{
+		{Correct: 1, Time: 5 * time.Second},
+		{Correct: 2, NumAnswers: 3, Type: "survey", Time: 5 * time.Second},
+	}})
+	defer server.Close()
+	defer useServer(t, server)()
+
+	c, err := NewConn("123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sub := c.Events().Subscribe(TopicResult)
+	defer sub.Close()
+	if err := c.Login("bot"); err != nil {
+		t.Fatal(err)
+	}
+	go c.PlayStrategy(AlwaysIndex(1))
+	go server.Start()
+
+	var results []*QuizResult
+	timeout := time.After(10 * time.Second)
+	for {
+		select {
+		case e := <-sub.C:
+			if result, ok := e.Data.(*QuizResult); ok {
+				results = append(results, result)
+			}
+			if e.Type != "quizEnd" {
+				continue
+			}
+		case <-timeout:
+			t.Fatalf("the quiz did not end, results were %v", results)
+		}
+		break
+	}
+	c.Close()
+	for range sub.C {
+		// The Bus closes once the connection has shut down.
+	}
+	if len(results) != 2 || !results[0].IsCorrect || results[1].IsCorrect || results[1].Index != 1 {
+		t.Errorf("unexpected results %+v", results)
+	}
+	expected := []kahoottest.Answer{{Nickname: "bot", Question: 0, Choice: 1},
+		{Nickname: "bot", Question: 1, Choice: 1}}
+	if answers := server.Answers(); !reflect.DeepEqual(answers, expected) {
+		t.Errorf("unexpected answers %+v", answers)
+	}
+	if state := c.GameState(); state.Phase != PhaseOver || state.Correct != 1 || state.Incorrect != 1 {
+		t.Errorf("unexpected state %+v", state)
+	}
+}
@@ -0,0 +1,144 @@
+package kahoot
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// A RateLimit paces reserve requests and logins, which the
+// server throttles per address: when many bots reserve
+// sessions at once, it starts refusing the address for a
+// while. Unlike a Budget, which fails operations beyond it, a
+// RateLimit makes them wait their turn.
+//
+// The limit is shared by every Conn in the package. Zero
+// rates are unlimited.
+type RateLimit struct {
+	// ReservesPerSecond paces session reservations, including
+	// retries and the lookups of LookupGameInfo.
+	ReservesPerSecond float64 `json:"reservesPerSecond,omitempty"`
+
+	// LoginsPerSecond paces logins, including those after a
+	// reconnect.
+	LoginsPerSecond float64 `json:"loginsPerSecond,omitempty"`
+
+	// Burst is how many of each may go at once after a quiet
+	// spell. It is at least 1.
+	Burst int `json:"burst,omitempty"`
+}
+
+// DefaultRateLimit is the rate limit until SetRateLimit is
+// called.
+var DefaultRateLimit = RateLimit{ReservesPerSecond: 10, LoginsPerSecond: 10, Burst: 10}
+
+// A tokenBucket holds up to burst tokens and gains rate of
+// them per second. Each operation takes one, waiting until one
+// is there.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// take takes a token, possibly going into debt, and returns
+// how long to wait until the token is really there.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+type rateLimitState struct {
+	lock    sync.Mutex
+	limit   RateLimit
+	reserve *tokenBucket
+	login   *tokenBucket
+}
+
+var currentRateLimit = newRateLimitState(DefaultRateLimit)
+
+func newRateLimitState(r RateLimit) *rateLimitState {
+	return &rateLimitState{
+		limit:   r,
+		reserve: newTokenBucket(r.ReservesPerSecond, r.Burst),
+		login:   newTokenBucket(r.LoginsPerSecond, r.Burst),
+	}
+}
+
+// SetRateLimit replaces the rate limit. Operations already
+// waiting keep their turn.
+func SetRateLimit(r RateLimit) {
+	state := newRateLimitState(r)
+	currentRateLimit.lock.Lock()
+	defer currentRateLimit.lock.Unlock()
+	currentRateLimit.limit = r
+	currentRateLimit.reserve = state.reserve
+	currentRateLimit.login = state.login
+}
+
+// CurrentRateLimit returns the rate limit in use.
+func CurrentRateLimit() RateLimit {
+	currentRateLimit.lock.Lock()
+	defer currentRateLimit.lock.Unlock()
+	return currentRateLimit.limit
+}
+
+// waitReserve waits for a reservation's turn, or until ctx is
+// done, in which case it returns ctx's error.
+func (r *rateLimitState) waitReserve(ctx context.Context) error {
+	if !r.wait(func() *tokenBucket { return r.reserve }, ctx.Done()) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// waitLogin waits for a login's turn, or until stop is closed.
+func (r *rateLimitState) waitLogin(stop <-chan struct{}) error {
+	if !r.wait(func() *tokenBucket { return r.login }, stop) {
+		return ErrConnClosed
+	}
+	return nil
+}
+
+// wait takes a token from the bucket, waiting for it unless
+// stop is closed first. A turn which is not waited out is
+// given back.
+func (r *rateLimitState) wait(bucket func() *tokenBucket, stop <-chan struct{}) bool {
+	r.lock.Lock()
+	b := bucket()
+	d := b.take(time.Now())
+	r.lock.Unlock()
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-stop:
+		r.lock.Lock()
+		b.tokens++
+		r.lock.Unlock()
+		return false
+	}
+}
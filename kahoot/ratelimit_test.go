@@ -0,0 +1,62 @@
+package kahoot
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	b := newTokenBucket(2, 2)
+	b.last = now
+	for i := 0; i < 2; i++ {
+		if d := b.take(now); d != 0 {
+			t.Errorf("take %d: expected no wait, got %s", i, d)
+		}
+	}
+	if d := b.take(now); d != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms, got %s", d)
+	}
+	if d := b.take(now); d != time.Second {
+		t.Errorf("expected to wait 1s, got %s", d)
+	}
+	if d := b.take(now.Add(10 * time.Second)); d != 0 {
+		t.Errorf("expected the bucket to refill, got %s", d)
+	}
+	if b.tokens != 1 {
+		t.Errorf("expected the burst to cap the tokens, got %g", b.tokens)
+	}
+	if d := newTokenBucket(0, 0).take(now); d != 0 {
+		t.Errorf("a zero rate should be unlimited, got %s", d)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	r := newRateLimitState(RateLimit{ReservesPerSecond: 1})
+	if err := r.waitReserve(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := r.waitReserve(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline to end the wait, got %v", err)
+	}
+	if r.reserve.tokens < -0.1 {
+		t.Errorf("the abandoned turn was not given back: %g tokens", r.reserve.tokens)
+	}
+
+	stop := make(chan struct{})
+	close(stop)
+	if err := r.waitLogin(stop); err != nil {
+		t.Errorf("logins are unlimited, got %v", err)
+	}
+}
+
+func TestSetRateLimit(t *testing.T) {
+	defer SetRateLimit(CurrentRateLimit())
+	SetRateLimit(RateLimit{LoginsPerSecond: 3})
+	if CurrentRateLimit().LoginsPerSecond != 3 {
+		t.Errorf("unexpected rate limit %+v", CurrentRateLimit())
+	}
+}
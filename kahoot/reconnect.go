@@ -0,0 +1,173 @@
+package kahoot
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// defaultKeepAlive is the heartbeat interval unless the server
+// advises one.
+const defaultKeepAlive = 5 * time.Second
+
+// errHandshakeAdvised ends a connection whose server asked
+// for a new handshake, so that it is re-established.
+var errHandshakeAdvised = errors.New("server asked for a new handshake")
+
+// followAdvice adopts the heartbeat interval of a /meta/connect
+// reply.
+func (c *Conn) followAdvice(msg Message) {
+	advice, ok := msg["advice"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	if ms, ok := advice["interval"].(float64); ok && ms > 0 {
+		atomic.StoreInt64(&c.keepAlive, int64(time.Duration(ms)*time.Millisecond))
+	}
+}
+
+func (c *Conn) keepAliveInterval() time.Duration {
+	if d := time.Duration(atomic.LoadInt64(&c.keepAlive)); d > 0 {
+		return d
+	}
+	return defaultKeepAlive
+}
+
+// handshakeAdvice reports whether a /meta/connect reply tells
+// the client that its session is gone and it must handshake
+// again.
+func handshakeAdvice(msg Message) bool {
+	advice, ok := msg["advice"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	reconnect, _ := advice["reconnect"].(string)
+	return reconnect == "handshake"
+}
+
+// shouldReconnect reports whether a dropped connection should
+// be re-established.
+func (c *Conn) shouldReconnect() bool {
+	return c.opts.Reconnect > 0 && !c.isClosing() && c.strictError() == nil &&
+		atomic.LoadInt32(&c.kicked) == 0 && atomic.LoadInt32(&c.abandoned) == 0
+}
+
+// reconnect re-establishes a dropped connection, or shuts it
+// down once every attempt failed. The connection is in the
+// reconnecting state while it runs.
+func (c *Conn) reconnect() {
+	ctx, cancel := c.stopContext()
+	defer cancel()
+	for attempt := 1; attempt <= c.opts.Reconnect && c.shouldReconnect(); attempt++ {
+		c.events.Publish(TopicConnection, "reconnecting", attempt)
+		done, err := c.redial(ctx)
+		if err == nil {
+			atomic.StoreInt32(&c.state, connLive)
+			select {
+			case <-done:
+				// It dropped again before going live. If its
+				// read loop did not notice, keep trying here.
+				if !atomic.CompareAndSwapInt32(&c.state, connLive, connReconnecting) {
+					return
+				}
+				err = ErrConnClosed
+			default:
+				c.events.Publish(TopicConnection, "reconnected", c.gameId)
+				return
+			}
+		}
+		c.events.Publish(TopicError, "reconnect", err)
+		if attempt < c.opts.Reconnect {
+			select {
+			case <-time.After(c.opts.Token.delay(attempt-1, err)):
+			case <-c.stop:
+			}
+		}
+	}
+	c.shutdown()
+}
+
+// stopContext returns a context which ends once the Conn is
+// closed, so that reconnecting gives up right away.
+func (c *Conn) stopContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, cancel
+}
+
+// redial reserves a new session, dials it, handshakes, and
+// logs in as before, giving up when ctx ends. The returned
+// channel is closed once the new WebSocket stops being read.
+func (c *Conn) redial(ctx context.Context) (<-chan struct{}, error) {
+	proto := protocol()
+	token, err := sessionToken(ctx, &c.opts, c.gameId)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	ws, err := dialGame(ctx, c.gameId, token, proto, &c.opts)
+	if err != nil {
+		c.opts.Stage.since(StageHandshake, start, err)
+		return nil, err
+	}
+	c.wsLock.Lock()
+	if c.closing {
+		c.wsLock.Unlock()
+		ws.Close()
+		return nil, ErrConnClosed
+	}
+	c.ws = ws
+	c.wsLock.Unlock()
+
+	c.drainMeta()
+	done := make(chan struct{})
+	go c.readLoop(ws, done)
+	err = c.handshake(proto)
+	c.opts.Stage.since(StageHandshake, start, err)
+	if err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	if nickname := c.Player().Nickname; nickname != "" {
+		if err := currentRateLimit.waitLogin(c.stop); err != nil {
+			ws.Close()
+			return nil, err
+		}
+		start = time.Now()
+		err = c.login(nickname, dialTimeout())
+		c.opts.Stage.since(StageLogin, start, err)
+		if err != nil {
+			ws.Close()
+			return nil, err
+		}
+	}
+	if team := c.teamMembers(); team != nil {
+		if err := c.sendTeam(team); err != nil {
+			ws.Close()
+			return nil, err
+		}
+	}
+	return done, nil
+}
+
+// drainMeta drops replies from the previous WebSocket, so that
+// the handshake only sees its own.
+func (c *Conn) drainMeta() {
+	c.channelsLock.RLock()
+	defer c.channelsLock.RUnlock()
+	for _, name := range []string{"/meta/connect", "/meta/disconnect", "/meta/handshake",
+		"/meta/subscribe"} {
+		ch := c.incoming[name]
+		for len(ch) > 0 {
+			<-ch
+		}
+	}
+}
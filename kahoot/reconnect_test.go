@@ -0,0 +1,152 @@
+package kahoot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/internal/kahoottest"
+)
+
+// useServer points the protocol at a fake server until the
+// returned function is called.
+func useServer(t *testing.T, server *kahoottest.Server) func() {
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.ReserveURL()
+	p.DialAddr = server.DialAddr()
+	p.CometdURL = server.CometdURL()
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	return func() { SetProtocol(&old) }
+}
+
+func TestReconnect(t *testing.T) {
+	server := kahoottest.NewServer(kahoottest.Config{})
+	defer server.Close()
+	defer useServer(t, server)()
+
+	c, err := NewConnOptions("123456", &ConnOptions{
+		Reconnect: 2,
+		Token:     TokenOptions{Backoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	sub := c.Events().Subscribe(TopicConnection)
+	defer sub.Close()
+	if err := c.Login("bot"); err != nil {
+		t.Fatal(err)
+	}
+	if !server.Drop("bot") {
+		t.Fatal("the server did not see the login")
+	}
+
+	var seen []string
+	timeout := time.After(5 * time.Second)
+	for len(seen) == 0 || seen[len(seen)-1] != "reconnected" {
+		select {
+		case e := <-sub.C:
+			if e.Type != "login" {
+				seen = append(seen, e.Type)
+			}
+			if e.Type == "closed" {
+				t.Fatalf("connection closed instead of reconnecting: %v", seen)
+			}
+		case <-timeout:
+			t.Fatalf("no reconnect, events were %v", seen)
+		}
+	}
+	if seen[0] != "reconnecting" {
+		t.Errorf("unexpected events %v", seen)
+	}
+	logins := server.Logins()
+	if server.Connections() != 2 || len(logins) != 2 || logins[1] != "bot" {
+		t.Errorf("expected a second login as bot, got %d connections and logins %v",
+			server.Connections(), logins)
+	}
+}
+
+func TestReconnectNotAfterKick(t *testing.T) {
+	c := &Conn{opts: ConnOptions{Reconnect: 3}}
+	if !c.shouldReconnect() {
+		t.Fatal("expected a reconnect")
+	}
+	c.kicked = 1
+	if c.shouldReconnect() {
+		t.Error("kicked connections should stay closed")
+	}
+}
+
+func TestConnectAdvice(t *testing.T) {
+	c := &Conn{}
+	if c.keepAliveInterval() != defaultKeepAlive {
+		t.Error("expected the default interval")
+	}
+	c.followAdvice(Message{"advice": map[string]interface{}{"interval": 2000.0, "reconnect": "handshake"}})
+	if c.keepAliveInterval() != 2*time.Second {
+		t.Errorf("unexpected interval %s", c.keepAliveInterval())
+	}
+	if !handshakeAdvice(Message{"advice": map[string]interface{}{"reconnect": "handshake"}}) ||
+		handshakeAdvice(Message{"advice": map[string]interface{}{"reconnect": "retry"}}) {
+		t.Error("unexpected handshake advice")
+	}
+}
+
+func TestCloseDuringReconnect(t *testing.T) {
+	server := kahoottest.NewServer(kahoottest.Config{})
+	defer server.Close()
+	defer useServer(t, server)()
+
+	c, err := NewConnOptions("123456", &ConnOptions{
+		Reconnect: 2,
+		Token:     TokenOptions{Backoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := c.Events().Subscribe(TopicConnection)
+	defer sub.Close()
+	if err := c.Login("bot"); err != nil {
+		t.Fatal(err)
+	}
+
+	hung := make(chan struct{})
+	defer close(hung)
+	reserve := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-hung:
+		case <-r.Context().Done():
+		}
+	}))
+	defer reserve.Close()
+	p := CurrentProtocol()
+	p.ReserveURL = reserve.URL + "/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Drop("bot")
+	timeout := time.After(5 * time.Second)
+	for reconnecting := false; !reconnecting; {
+		select {
+		case e := <-sub.C:
+			reconnecting = e.Type == "reconnecting"
+		case <-timeout:
+			t.Fatal("no reconnect")
+		}
+	}
+	closed := make(chan struct{})
+	go func() {
+		c.Close()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Close waited for the reservation")
+	}
+}
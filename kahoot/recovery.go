@@ -0,0 +1,121 @@
+package kahoot
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const requestRecoveryId = 16
+
+// A Gap is published when a Quiz notices that it missed events,
+// typically because the connection dropped for a while.
+// The questions with indices in [From, To) were missed.
+type Gap struct {
+	From int
+	To   int
+}
+
+// RecoveryState is the game state which the server sends
+// in response to Quiz.Recover.
+type RecoveryState struct {
+	State               int     `json:"state"`
+	Data                Message `json:"data"`
+	WasControllerKicked bool    `json:"wasControllerKicked"`
+	DidControllerLeave  bool    `json:"didControllerLeave"`
+}
+
+// QuestionIndex returns the question index encoded in the
+// recovery data, if there is one.
+func (r *RecoveryState) QuestionIndex() (int, bool) {
+	if idx, ok := r.Data["questionIndex"].(float64); ok {
+		return int(idx), true
+	}
+	return 0, false
+}
+
+// Recover asks the server to re-send the current game state.
+// The response is published as a "recovered" event on the
+// connection topic, and any questions which were skipped are
+// reported as Gap events.
+func (q *Quiz) Recover() error {
+	proto := protocol()
+	data := proto.template("message")
+	data["id"] = requestRecoveryId
+	data["gameid"] = q.conn.gameId
+	data["host"] = proto.Host
+	data["content"] = ""
+	return q.conn.send(proto.Channels.Controller, Message{"data": data})
+}
+
+// Phase returns the game phase the recovery state describes.
+// Players who join a game which has already started learn its
+// progress this way.
+func (r *RecoveryState) Phase() GamePhase {
+	switch r.State {
+	case 0, 1:
+		return PhaseLobby
+	case 2:
+		return PhaseIntro
+	case 3:
+		return PhaseAnswering
+	case 4:
+		return PhaseResult
+	}
+	return PhaseOver
+}
+
+// handleRecovery publishes a recovery state and brings the game
+// state up to date. If a question is in progress, it returns
+// the question as an action so that Receive can hand it on to
+// a player who joined late. It reports whether the content
+// was a recovery state.
+func (q *Quiz) handleRecovery(content Message) (*QuizAction, bool) {
+	data, err := json.Marshal(content)
+	if err != nil {
+		return nil, false
+	}
+	var state RecoveryState
+	if json.Unmarshal(data, &state) != nil {
+		return nil, false
+	}
+	var action *QuizAction
+	phase := state.Phase()
+	if phase == PhaseIntro {
+		action = parseAction(QuestionIntro, state.Data)
+	} else if phase == PhaseAnswering {
+		action = parseAction(QuestionAnswers, state.Data)
+	}
+	q.conn.events.Publish(TopicConnection, "recovered", &state)
+	if action != nil {
+		q.deliver(action)
+		return action, true
+	}
+	if idx, ok := state.QuestionIndex(); ok {
+		q.trackGaps(&QuizAction{Type: QuestionIntro, Index: idx})
+	}
+	q.conn.updateGame(func(g *GameState) {
+		if phase > g.Phase || phase == PhaseLobby && g.Question < 0 {
+			g.Phase = phase
+		}
+		if idx, ok := state.QuestionIndex(); ok && idx > g.Question {
+			g.Question = idx
+			g.Deadline = time.Time{}
+		}
+	})
+	return nil, true
+}
+
+// trackGaps publishes Gap events for any questions or
+// results that were skipped before the given action.
+func (q *Quiz) trackGaps(action *QuizAction) {
+	if action.Index > q.lastIndex+1 {
+		q.conn.events.Publish(TopicQuestion, "gap", &Gap{From: q.lastIndex + 1, To: action.Index})
+	}
+	if action.Index > q.lastIndex && q.lastIndex >= 0 && q.lastResult+1 < action.Index {
+		q.conn.events.Publish(TopicResult, "gap", &Gap{From: q.lastResult + 1, To: action.Index})
+		q.lastResult = action.Index - 1
+	}
+	if action.Index > q.lastIndex {
+		q.lastIndex = action.Index
+	}
+}
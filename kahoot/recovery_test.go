@@ -0,0 +1,68 @@
+package kahoot
+
+import "testing"
+
+func TestTrackGaps(t *testing.T) {
+	c := &Conn{events: NewBus()}
+	sub := c.events.Subscribe()
+	q := NewQuiz(c)
+
+	q.trackGaps(&QuizAction{Type: QuestionIntro, Index: 0})
+	q.trackGaps(&QuizAction{Type: QuestionAnswers, Index: 0})
+	q.trackGaps(&QuizAction{Type: QuestionIntro, Index: 3})
+	c.events.Close()
+
+	var gaps []Event
+	for e := range sub.C {
+		gaps = append(gaps, e)
+	}
+	if len(gaps) != 2 {
+		t.Fatalf("expected 2 gaps but got %d", len(gaps))
+	}
+	if gaps[0].Topic != TopicQuestion || *gaps[0].Data.(*Gap) != (Gap{From: 1, To: 3}) {
+		t.Errorf("bad question gap: %+v", gaps[0].Data)
+	}
+	if gaps[1].Topic != TopicResult || *gaps[1].Data.(*Gap) != (Gap{From: 0, To: 3}) {
+		t.Errorf("bad result gap: %+v", gaps[1].Data)
+	}
+}
+
+func TestRecoveryAction(t *testing.T) {
+	c := &Conn{events: NewBus(), game: GameState{Question: -1}}
+	sub := c.events.Subscribe(TopicQuestion)
+	defer sub.Close()
+	q := NewQuiz(c)
+
+	action, _ := q.handleRecovery(Message{
+		"state": 3.0,
+		"data": map[string]interface{}{
+			"questionIndex":       2.0,
+			"quizQuestionAnswers": []interface{}{4.0, 4.0, 2.0},
+			"answerMap":           map[string]interface{}{"0": 1.0, "1": 0.0},
+			"timeLeft":            5000.0,
+		},
+	})
+	if action == nil {
+		t.Fatal("expected an action for a question in progress")
+	}
+	if action.Type != QuestionAnswers || action.Index != 2 || action.NumAnswers != 2 {
+		t.Errorf("unexpected action %+v", action)
+	}
+	if e := <-sub.C; e.Type != "gap" {
+		t.Errorf("expected a gap first, got %+v", e)
+	}
+	if e := <-sub.C; e.Type != "answers" || e.Data != action {
+		t.Errorf("expected the answers event, got %+v", e)
+	}
+	state := c.GameState()
+	if state.Phase != PhaseAnswering || state.Question != 2 || state.TimeLeft() <= 0 {
+		t.Errorf("unexpected state %+v", state)
+	}
+
+	if action, ok := q.handleRecovery(Message{"state": 4.0, "data": map[string]interface{}{"questionIndex": 2.0}}); action != nil || !ok {
+		t.Error("expected no action once the result is shown")
+	}
+	if state := c.GameState(); state.Phase != PhaseResult || state.Question != 2 {
+		t.Errorf("unexpected state %+v", state)
+	}
+}
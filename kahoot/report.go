@@ -0,0 +1,52 @@
+package kahoot
+
+import (
+	"sync"
+	"time"
+)
+
+// A Sample is a piece of protocol traffic that this package
+// did not recognize.
+type Sample struct {
+	// Kind is "challenge" or "message".
+	Kind string `json:"kind"`
+
+	Channel string `json:"channel,omitempty"`
+	Id      int    `json:"id,omitempty"`
+
+	// Content is the raw challenge or message content.
+	// Reporters must anonymize it before sending it anywhere.
+	Content string `json:"content"`
+
+	Time time.Time `json:"time"`
+}
+
+// A Reporter is notified of unrecognized protocol traffic,
+// which helps track changes to the protocol.
+type Reporter interface {
+	Report(s *Sample)
+}
+
+var (
+	reporterLock sync.RWMutex
+	reporter     Reporter
+)
+
+// SetReporter installs a Reporter for unrecognized traffic.
+// No reporting happens unless a Reporter is installed.
+// Pass nil to stop reporting.
+func SetReporter(r Reporter) {
+	reporterLock.Lock()
+	defer reporterLock.Unlock()
+	reporter = r
+}
+
+func report(s *Sample) {
+	reporterLock.RLock()
+	r := reporter
+	reporterLock.RUnlock()
+	if r != nil {
+		s.Time = time.Now()
+		r.Report(s)
+	}
+}
@@ -0,0 +1,42 @@
+package kahoot
+
+import (
+	"math"
+	"time"
+)
+
+// Scoring constants of a standard question.
+const (
+	// MaxPoints is what a correct answer given instantly
+	// earns; one given as the time runs out earns half.
+	MaxPoints = 1000
+
+	// StreakBonus is added for each correct answer in a row
+	// before this one, up to MaxStreakBonus.
+	StreakBonus    = 100
+	MaxStreakBonus = 500
+)
+
+// PredictPoints estimates what a correct answer earns, given
+// the question's points multiplier, how long after the
+// question opened it was given, how long the question was
+// open for, and the player's streak of correct answers
+// including this one. Wrong answers earn nothing and end the
+// streak.
+//
+// The server's rounding and latency compensation make the
+// actual points differ by a few.
+func PredictPoints(multiplier int, elapsed, available time.Duration, streak int) float64 {
+	if multiplier <= 0 {
+		return 0
+	}
+	fraction := 0.0
+	if available > 0 {
+		fraction = math.Min(1, math.Max(0, float64(elapsed)/float64(available)))
+	}
+	points := math.Round(MaxPoints*(1-fraction/2)) * float64(multiplier)
+	if streak > 1 {
+		points += math.Min(float64(StreakBonus*(streak-1)), MaxStreakBonus)
+	}
+	return points
+}
@@ -0,0 +1,64 @@
+package kahoot
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPredictPoints(t *testing.T) {
+	tests := []struct {
+		multiplier int
+		elapsed    time.Duration
+		streak     int
+		expected   float64
+	}{
+		{1, 0, 1, 1000},
+		{1, 10 * time.Second, 1, 750},
+		{1, time.Minute, 1, 500},
+		{2, 10 * time.Second, 1, 1500},
+		{0, 0, 3, 0},
+		{1, 0, 3, 1200},
+		{1, 0, 20, 1500},
+	}
+	for _, test := range tests {
+		actual := PredictPoints(test.multiplier, test.elapsed, 20*time.Second, test.streak)
+		if actual != test.expected {
+			t.Errorf("%+v: got %f", test, actual)
+		}
+	}
+}
+
+func TestPointsModifiers(t *testing.T) {
+	for content, expected := range map[string]int{
+		`{"pointsMultiplier": 2}`: 2,
+		`{"pointsMultiplier": 0}`: 0,
+		`{"points": false}`:       0,
+		`{}`:                      1,
+	} {
+		var m Message
+		if err := json.Unmarshal([]byte(content), &m); err != nil {
+			t.Fatal(err)
+		}
+		if actual := pointsMultiplier(m); actual != expected {
+			t.Errorf("%s: expected %d but got %d", content, expected, actual)
+		}
+	}
+
+	double := 2
+	for i, q := range []QuizQuestion{{Points: true}, {}, {Points: true, PointsMultiplier: &double}} {
+		if actual := q.Multiplier(); actual != []int{1, 0, 2}[i] {
+			t.Errorf("question %d: unexpected multiplier %d", i, actual)
+		}
+	}
+
+	var content Message
+	json.Unmarshal([]byte(`{"points": 900, "pointsData": {"answerStreakPoints": {"streakLevel": 3, "streakBonus": 200}}}`), &content)
+	res, err := parseQuizResult(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Points != 900 || res.Streak != 3 || res.StreakBonus != 200 {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
@@ -2,58 +2,186 @@ package kahoot
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
+	"net"
 	"net/http"
-	"net/url"
-	"regexp"
-)
+	"strconv"
+	"sync"
+	"time"
 
-var (
-	challengeRegexp = regexp.MustCompile(`^decode\.call\(this, '([a-zA-Z0-9]*)'\); ` +
-		`function decode\(message\) \{var offset = ([0-9\+\*\(\)\s]*); ` +
-		`if \(this\.angular\.[a-zA-Z]*\(offset\)\) \{` +
-		`console.log\("Offset derived as: \{", offset, "\}"\);\}` +
-		`return _\.replace\(message, /\./g, function\(char, position\) ` +
-		`\{return String\.fromCharCode\(\(\(\(char\.charCodeAt\(0\) \* position\)` +
-		` \+ offset\) % 77\) \+ 48\);\}\);\}$`)
+	"github.com/unixpickle/kahoot-hack/kahoot/challenge"
 )
 
 func gameSessionToken(gamePin string) (string, error) {
-	return attemptGameSessionToken(gamePin)
+	return sessionToken(context.Background(), &ConnOptions{}, gamePin)
+}
+
+// GameSessionTokenContext reserves a session for a game and
+// returns its deciphered token. Every HTTP request it makes,
+// including remote evaluation of the challenge, is abandoned
+// once ctx is done, and ctx's error is returned.
+func GameSessionTokenContext(ctx context.Context, gamePin string) (string, error) {
+	return sessionToken(ctx, &ConnOptions{}, gamePin)
+}
+
+// GameSessionTokenOptions is like GameSessionTokenContext, but
+// retries failed reservations as opts says.
+func GameSessionTokenOptions(ctx context.Context, gamePin string, opts TokenOptions) (string, error) {
+	return sessionToken(ctx, &ConnOptions{Token: opts}, gamePin)
+}
+
+// DefaultBackoff is the delay before the first retry if
+// TokenOptions.Backoff is not set.
+const DefaultBackoff = 500 * time.Millisecond
+
+// TokenOptions configures how a session is reserved. The zero
+// value makes a single attempt.
+type TokenOptions struct {
+	// Retries is how many more attempts to make after a
+	// failure which may be temporary: a challenge which could
+	// not be solved, a server error, a 429 rate limit, or a
+	// network error. Unknown pins, budget errors and ctx's
+	// errors are never retried.
+	Retries int
+
+	// Backoff is the delay before the first retry, doubled
+	// for each one after it. Each delay is lengthened by a
+	// random amount of up to half, so that bots which failed
+	// together do not retry together, and a 429's
+	// Retry-After is waited out if it is longer.
+	Backoff time.Duration
+}
+
+// delay is how long to wait before a retry, counted from 0.
+func (t TokenOptions) delay(retry int, err error) time.Duration {
+	d := t.Backoff
+	if d <= 0 {
+		d = DefaultBackoff
+	}
+	for i := 0; i < retry && d < time.Hour; i++ {
+		d *= 2
+	}
+	d += time.Duration(rand.Int63n(int64(d/2) + 1))
+	var sessionErr *SessionError
+	if errors.As(err, &sessionErr) && sessionErr.retryAfter > d {
+		d = sessionErr.retryAfter
+	}
+	return d
 }
 
-func attemptGameSessionToken(gamePin string) (string, error) {
-	resp, err := http.Get("https://kahoot.it/reserve/session/" + gamePin)
+// retryableSession reports whether reserving a session again
+// might succeed where err failed.
+func retryableSession(err error) bool {
+	var sessionErr *SessionError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, ErrChallengeUnsolved) || errors.Is(err, ErrRateLimited):
+		return true
+	case errors.As(err, &sessionErr):
+		return sessionErr.Status >= 500
+	case errors.As(err, &netErr):
+		return true
+	}
+	return false
+}
+
+func sessionToken(ctx context.Context, opts *ConnOptions, gamePin string) (string, error) {
+	client := opts.httpConfig()
+	for retry := 0; ; retry++ {
+		if err := currentRateLimit.waitReserve(ctx); err != nil {
+			return "", err
+		}
+		start := time.Now()
+		res, err := reserve(ctx, client, gamePin)
+		opts.Stage.since(StageReserve, start, err)
+		var token string
+		if err == nil {
+			start = time.Now()
+			token, err = decipherToken(ctx, client, res.token, res.Challenge)
+			if errors.Is(err, ErrChallengeUnsolved) {
+				err = &SessionError{Pin: gamePin, Reason: ErrChallengeUnsolved}
+			}
+			opts.Stage.since(StageSolve, start, err)
+		}
+		if err == nil || retry >= opts.Token.Retries || !retryableSession(err) {
+			return token, err
+		}
+		select {
+		case <-time.After(opts.Token.delay(retry, err)):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// reserveResponse is the reserve endpoint's reply.
+type reserveResponse struct {
+	GameInfo
+	Challenge string `json:"challenge"`
+
+	token string
+}
+
+// reserve asks the server for a session. Every successful
+// response also refreshes the pin's cached GameInfo.
+func reserve(ctx context.Context, client *httpConfig, gamePin string) (*reserveResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", protocol().ReserveURL+gamePin, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := currentBudget.takeRequest(); err != nil {
+		return nil, err
+	}
+	resp, err := client.do(req)
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	body, err := ioutil.ReadAll(resp.Body)
+	body, err := readBody(resp.Body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-
-	token := resp.Header.Get("X-Kahoot-Session-Token")
-	var bodyObj struct {
-		Challenge string `json:"challenge"`
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		res := &SessionError{Pin: gamePin, Status: resp.StatusCode}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			res.Reason = ErrRateLimited
+		}
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			res.retryAfter = time.Duration(secs) * time.Second
+		}
+		return nil, res
 	}
-	if err := json.Unmarshal(body, &bodyObj); err != nil {
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, &SessionError{Pin: gamePin, Status: resp.StatusCode, Reason: ErrGameNotFound}
+	} else if resp.StatusCode >= 400 {
+		return nil, &SessionError{Pin: gamePin, Status: resp.StatusCode, Reason: ErrSessionRefused}
+	}
+
+	var res reserveResponse
+	if err := json.Unmarshal(body, &res); err != nil {
 		if string(body) == "Not found" {
-			return "", fmt.Errorf("game pin not found: %s", gamePin)
+			return nil, &SessionError{Pin: gamePin, Status: resp.StatusCode, Reason: ErrGameNotFound}
 		}
-		return "", fmt.Errorf("parse session challenge: %s", err)
+		return nil, fmt.Errorf("parse session challenge: %s", err)
 	}
-
-	return decipherToken(token, bodyObj.Challenge)
+	res.token = resp.Header.Get("X-Kahoot-Session-Token")
+	res.GameInfo.Pin = gamePin
+	res.GameInfo.Fetched = time.Now()
+	gameInfoCache.store(&res.GameInfo)
+	return &res, nil
 }
 
-func decipherToken(xToken, challenge string) (string, error) {
+func decipherToken(ctx context.Context, client *httpConfig, xToken, challenge string) (string, error) {
 	r := bytes.NewReader([]byte(xToken))
 	base64Dec := base64.NewDecoder(base64.StdEncoding, r)
 	rawToken, err := ioutil.ReadAll(base64Dec)
@@ -61,9 +189,11 @@ func decipherToken(xToken, challenge string) (string, error) {
 		return "", fmt.Errorf("parse session token: %s", err)
 	}
 
-	mask, err := computeChallenge(challenge)
-	if err != nil {
-		return "", errors.New("failed to defeat challenge: " + challenge)
+	mask, err := computeChallenge(ctx, client, challenge)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	} else if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrChallengeUnsolved, challenge)
 	}
 
 	for i := range rawToken {
@@ -73,35 +203,60 @@ func decipherToken(xToken, challenge string) (string, error) {
 	return string(rawToken), nil
 }
 
-func computeChallenge(ch string) ([]byte, error) {
-	submatch := challengeRegexp.FindStringSubmatch(ch)
-	if submatch != nil {
-		offset, err := eval(submatch[2])
-		if err == nil {
-			var newRunes []rune
-			for i, x := range submatch[1] {
-				n := (((int64(x) * int64(i)) + offset) % 77) + 48
-				newRunes = append(newRunes, rune(n))
-			}
-			return []byte(string(newRunes)), nil
+// computeChallenge tries each of the current evaluators in
+// turn; an empty mask counts as a failure. A challenge that no local evaluator solves is reported
+// to telemetry, before any remote evaluator sees it.
+func computeChallenge(ctx context.Context, client *httpConfig, ch string) ([]byte, error) {
+	ctx = withHTTPConfig(ctx, client)
+	reported := false
+	for _, e := range currentEvaluators() {
+		if isRemote(e) && !reported {
+			report(&Sample{Kind: "challenge", Content: ch})
+			reported = true
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if mask, err := e.Evaluate(ctx, ch); err == nil && mask != "" {
+			return []byte(mask), nil
 		}
 	}
-
-	evalURL := url.URL{
-		Scheme:   "http",
-		Host:     "safeval.pw",
-		Path:     "/eval",
-		RawQuery: url.Values{"code": []string{ch}}.Encode(),
-	}
-	resp, err := http.Get(evalURL.String())
-	if resp != nil {
-		defer resp.Body.Close()
+	if !reported {
+		report(&Sample{Kind: "challenge", Content: ch})
 	}
-	if err != nil {
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("server failed to evaluate: " + ch)
+	return nil, challenge.ErrUnrecognized
+}
+
+func solveChallengeLocally(ch string) ([]byte, bool) {
+	mask, err := challenge.PatternSolver(protocol().challengeRegexps)(ch)
+	if err != nil {
+		return nil, false
 	}
-	return ioutil.ReadAll(resp.Body)
+	return []byte(mask), true
+}
+
+// jsSolver evaluates challenge scripts in an embedded
+// JavaScript engine. It is only set in builds with the goja
+// tag, which keeps the engine out of default builds.
+var jsSolver func(challenge string) (string, error)
+
+var solverLock sync.Mutex
+var externalSolver func(challenge string) (string, error)
+
+// SetChallengeSolver installs a solver which is tried for
+// challenges that the other evaluators do not solve, but before
+// any RemoteEvaluator. Pass nil to remove it.
+func SetChallengeSolver(solver func(challenge string) (string, error)) {
+	solverLock.Lock()
+	defer solverLock.Unlock()
+	externalSolver = solver
+}
+
+func currentSolver() func(string) (string, error) {
+	solverLock.Lock()
+	defer solverLock.Unlock()
+	return externalSolver
 }
@@ -1,15 +1,23 @@
 package kahoot
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/unixpickle/kahoot-hack/internal/kahoottest"
 )
 
 func TestGameSessionToken(t *testing.T) {
@@ -52,3 +60,268 @@ func TestGameSessionToken(t *testing.T) {
 		t.Fatal("establish WebSocket:", err)
 	}
 }
+
+func TestSessionTokenOffline(t *testing.T) {
+	server := kahoottest.NewServer(kahoottest.Config{Pin: "246810", Token: "offline-token"})
+	defer server.Close()
+	defer useServer(t, server)()
+
+	token, err := GameSessionTokenOptions(context.Background(), "246810", TokenOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "offline-token" {
+		t.Errorf("unexpected token %q", token)
+	}
+	_, err = GameSessionTokenOptions(context.Background(), "135791", TokenOptions{})
+	if !errors.Is(err, ErrGameNotFound) {
+		t.Errorf("expected ErrGameNotFound, got %v", err)
+	}
+}
+
+func TestGameSessionTokenContext(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.URL + "/reserve/session/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProtocol(&old)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := GameSessionTokenContext(ctx, "123456")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline error but got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("request was not abandoned at the deadline")
+	}
+}
+
+func TestCheckSolver(t *testing.T) {
+	if err := checkSolver(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadBodyLimit(t *testing.T) {
+	old := MaxResponseSize
+	MaxResponseSize = 4
+	defer func() { MaxResponseSize = old }()
+	if data, err := readBody(strings.NewReader("abcd")); err != nil || string(data) != "abcd" {
+		t.Errorf("unexpected result %q %v", data, err)
+	}
+	if _, err := readBody(strings.NewReader("abcde")); err != errResponseTooLarge {
+		t.Errorf("expected size error but got %v", err)
+	}
+}
+
+func TestConnOptionsHTTPConfig(t *testing.T) {
+	headers := make(chan http.Header, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers <- r.Header
+		http.Error(w, "no such game", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.URL + "/reserve/session/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProtocol(&old)
+
+	opts := &ConnOptions{
+		HTTPClient: &http.Client{Timeout: time.Second},
+		Header:     http.Header{"X-Test": []string{"yes"}},
+	}
+	reserve(context.Background(), opts.httpConfig(), "123456")
+	if h := <-headers; h.Get("X-Test") != "yes" {
+		t.Errorf("missing custom header: %v", h)
+	}
+	if (&ConnOptions{}).httpConfig() != nil {
+		t.Error("default options should use HTTPClient")
+	}
+}
+
+func TestDialProxy(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	requests := make(chan *http.Request, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		requests <- req
+		conn.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+		conn.Write([]byte("hello"))
+	}()
+
+	proxy := &url.URL{Scheme: "http", Host: listener.Addr().String(), User: url.UserPassword("u", "p")}
+	conn, err := dialProxy(context.Background(), proxy, "kahoot.it:443", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	req := <-requests
+	if req.Method != "CONNECT" || req.Host != "kahoot.it:443" {
+		t.Errorf("unexpected request %s %s", req.Method, req.Host)
+	}
+	if auth := req.Header.Get("Proxy-Authorization"); auth != "Basic dTpw" {
+		t.Errorf("unexpected authorization %q", auth)
+	}
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "hello" {
+		t.Errorf("unexpected tunnel data %q %v", buf, err)
+	}
+}
+
+func TestDialProxyRefused(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusProxyAuthRequired)
+	}))
+	defer server.Close()
+	proxy, _ := url.Parse(server.URL)
+	if _, err := dialProxy(context.Background(), proxy, "kahoot.it:443", time.Second); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestSessionTokenStages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such game", http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.URL + "/reserve/session/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProtocol(&old)
+
+	var stages []string
+	stage := func(stage string, took time.Duration, err error) {
+		if err == nil {
+			t.Errorf("stage %q should have failed", stage)
+		}
+		stages = append(stages, stage)
+	}
+	if _, err := sessionToken(context.Background(), &ConnOptions{Stage: stage}, "123456"); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(stages) != 1 || stages[0] != StageReserve {
+		t.Errorf("unexpected stages %v", stages)
+	}
+}
+
+func TestSessionTokenRetries(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+		} else {
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.URL + "/reserve/session/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProtocol(&old)
+
+	opts := TokenOptions{Retries: 5, Backoff: time.Millisecond}
+	_, err := GameSessionTokenOptions(context.Background(), "123456", opts)
+	if !errors.Is(err, ErrGameNotFound) || requests != 3 {
+		t.Errorf("expected 3 requests and a missing game but got %d and %v", requests, err)
+	}
+
+	requests = 0
+	_, err = GameSessionTokenContext(context.Background(), "123456")
+	if sessionErr, ok := err.(*SessionError); !ok || sessionErr.Status != 503 || requests != 1 {
+		t.Errorf("expected a single failed request but got %d and %v", requests, err)
+	}
+}
+
+func TestReserveStatus(t *testing.T) {
+	var status, requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(status)
+		w.Write([]byte(`{"challenge": "decode.call(this, 'x'); function decode(message) {}"}`))
+	}))
+	defer server.Close()
+
+	old := CurrentProtocol()
+	p := CurrentProtocol()
+	p.ReserveURL = server.URL + "/reserve/session/"
+	if err := SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer SetProtocol(&old)
+
+	opts := TokenOptions{Retries: 5, Backoff: time.Millisecond}
+	for _, c := range []struct {
+		status int
+		reason error
+	}{
+		{http.StatusNotFound, ErrGameNotFound},
+		{http.StatusForbidden, ErrSessionRefused},
+		{http.StatusBadRequest, ErrSessionRefused},
+	} {
+		status, requests = c.status, 0
+		_, err := GameSessionTokenOptions(context.Background(), "123456", opts)
+		sessionErr, ok := err.(*SessionError)
+		if !ok || sessionErr.Status != c.status || !errors.Is(err, c.reason) {
+			t.Errorf("status %d: expected %v but got %v", c.status, c.reason, err)
+		} else if requests != 1 {
+			t.Errorf("status %d: expected no retries but made %d requests", c.status, requests)
+		}
+	}
+}
+
+func TestTokenOptionsDelay(t *testing.T) {
+	opts := TokenOptions{Backoff: time.Second}
+	for retry, min := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second} {
+		if d := opts.delay(retry, nil); d < min || d > min*3/2 {
+			t.Errorf("retry %d: delay %s outside [%s, %s]", retry, d, min, min*3/2)
+		}
+	}
+	limited := &SessionError{Status: http.StatusTooManyRequests, Reason: ErrRateLimited,
+		retryAfter: time.Minute}
+	if d := opts.delay(0, limited); d != time.Minute {
+		t.Errorf("expected Retry-After to be honored but got %s", d)
+	}
+	if !retryableSession(limited) || retryableSession(&SessionError{Status: 404, Reason: ErrGameNotFound}) ||
+		!retryableSession(fmt.Errorf("%w: x", ErrChallengeUnsolved)) {
+		t.Error("unexpected retryability")
+	}
+}
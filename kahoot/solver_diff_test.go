@@ -0,0 +1,119 @@
+//go:build goja
+// +build goja
+
+// These tests compare the challenge solver against a real
+// JavaScript engine on generated challenges. They need goja,
+// so they only run with the goja build tag:
+//
+//	go test -tags goja -run Differential ./kahoot
+//	go test -tags goja -fuzz FuzzSolverDifferential ./kahoot
+
+package kahoot
+
+import (
+	"flag"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+var diffCases = flag.Int("diff.cases", 2000, "number of generated challenges to compare")
+var diffLarge = flag.Bool("diff.large", true, "also generate operands beyond float64's exact integer range")
+
+var angularMethods = []string{"isArray", "isObject", "isString", "isDate", "isNumber", "isElement"}
+
+const messageAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// generateChallenge builds a challenge in the shape the
+// reserve endpoint sends, with a random message and offset
+// expression.
+func generateChallenge(r *rand.Rand, large bool) string {
+	msg := make([]byte, 1+r.Intn(100))
+	for i := range msg {
+		msg[i] = messageAlphabet[r.Intn(len(messageAlphabet))]
+	}
+	return "decode.call(this, '" + string(msg) + "'); function decode(message) {var offset = " +
+		generateExpr(r, 4, large) + "; if (this.angular." + angularMethods[r.Intn(len(angularMethods))] +
+		"(offset)) {console.log(\"Offset derived as: {\", offset, \"}\");}" +
+		"return _.replace(message, /./g, function(char, position) " +
+		"{return String.fromCharCode((((char.charCodeAt(0) * position) + offset) % 77) + 48);});}"
+}
+
+func generateExpr(r *rand.Rand, depth int, large bool) string {
+	if depth == 0 || r.Intn(3) == 0 {
+		if large && r.Intn(4) == 0 {
+			return strconv.FormatInt(r.Int63n(1e12), 10)
+		}
+		return strconv.Itoa(r.Intn(100))
+	}
+	ops := []string{" + ", " * "}
+	n := 2 + r.Intn(2)
+	terms := make([]string, n)
+	for i := range terms {
+		terms[i] = generateExpr(r, depth-1, large)
+	}
+	expr := terms[0]
+	for _, t := range terms[1:] {
+		expr += ops[r.Intn(2)] + t
+	}
+	if r.Intn(2) == 0 {
+		expr = "(" + expr + ")"
+	}
+	return expr
+}
+
+func evalReference(challenge string) (string, error) {
+	vm := goja.New()
+	vm.Set("console", map[string]interface{}{"log": func(...interface{}) {}})
+	// The completion value of the script is the value of its
+	// first statement, decode.call(...).
+	v, err := vm.RunString(challengePrelude + challenge)
+	if err != nil {
+		return "", err
+	}
+	return v.String(), nil
+}
+
+func compareSolver(t *testing.T, challenge string) {
+	expected, err := evalReference(challenge)
+	if err != nil {
+		t.Fatalf("reference failed on %q: %s", challenge, err)
+	}
+	actual, ok := solveChallengeLocally(challenge)
+	if !ok {
+		t.Errorf("solver rejected challenge: %q", challenge)
+	} else if string(actual) != expected {
+		t.Errorf("divergence on %q:\n  go: %q\n  js: %q", challenge, actual, expected)
+	}
+}
+
+func TestSolverDifferential(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < *diffCases && !t.Failed(); i++ {
+		compareSolver(t, generateChallenge(r, *diffLarge))
+	}
+}
+
+func FuzzSolverDifferential(f *testing.F) {
+	for _, seed := range []int64{0, 1, 2, 42} {
+		f.Add(seed, false)
+		f.Add(seed, true)
+	}
+	f.Fuzz(func(t *testing.T, seed int64, large bool) {
+		challenge := generateChallenge(rand.New(rand.NewSource(seed)), large)
+		compareSolver(t, challenge)
+	})
+}
+
+func TestGeneratedChallengesMatch(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 100; i++ {
+		ch := generateChallenge(r, false)
+		if len(protocol().challengeRegexps) == 0 ||
+			protocol().challengeRegexps[0].FindStringSubmatch(ch) == nil {
+			t.Fatal("generated challenge does not match the protocol pattern:", ch)
+		}
+	}
+}
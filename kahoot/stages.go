@@ -0,0 +1,42 @@
+package kahoot
+
+import (
+	"errors"
+	"time"
+)
+
+// Stages of joining a game, in the order they happen. The
+// namerator and two-factor stages only happen in games which
+// use them.
+const (
+	StageReserve   = "reserve"
+	StageSolve     = "solve"
+	StageHandshake = "handshake"
+	StageNamerator = "namerator"
+	StageLogin     = "login"
+	StageTwoFactor = "twoFactor"
+)
+
+// JoinStages lists every stage in order.
+var JoinStages = []string{StageReserve, StageSolve, StageHandshake, StageNamerator,
+	StageLogin, StageTwoFactor}
+
+// ErrTwoFactorRejected is the error of a two-factor stage
+// which the server answered with "wrong sequence".
+var ErrTwoFactorRejected = errors.New("two-factor sequence rejected")
+
+// A StageFunc is told each time a stage of joining finishes,
+// how long it took, and the error if it failed.
+//
+// Reserving and solving happen once per connection, unless a
+// SessionToken was given. Login and namerator are reported for
+// every attempt. A two-factor stage runs from the prompt, or
+// the previous rejection, until the server answers a
+// submission.
+type StageFunc func(stage string, took time.Duration, err error)
+
+func (f StageFunc) since(stage string, start time.Time, err error) {
+	if f != nil {
+		f(stage, time.Since(start), err)
+	}
+}
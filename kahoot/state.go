@@ -0,0 +1,148 @@
+package kahoot
+
+import (
+	"math/rand"
+	"time"
+)
+
+// A GamePhase is how far the current question has come.
+type GamePhase int
+
+const (
+	// PhaseLobby is before the first question.
+	PhaseLobby GamePhase = iota
+
+	// PhaseIntro is while a question is introduced, before
+	// answers may be sent.
+	PhaseIntro
+
+	// PhaseAnswering is while a question accepts answers.
+	PhaseAnswering
+
+	// PhaseResult is once a question's result is revealed.
+	PhaseResult
+
+	// PhaseOver is once the quiz or the game has ended.
+	PhaseOver
+)
+
+func (p GamePhase) String() string {
+	switch p {
+	case PhaseLobby:
+		return "lobby"
+	case PhaseIntro:
+		return "intro"
+	case PhaseAnswering:
+		return "answering"
+	case PhaseResult:
+		return "result"
+	case PhaseOver:
+		return "over"
+	}
+	return "unknown"
+}
+
+// GameState is what a Conn has learned about the game's
+// progress and its player's standing. Quiz.Receive keeps it up
+// to date, and publishes a "stateChanged" connection event
+// with a copy whenever it changes.
+type GameState struct {
+	Phase GamePhase `json:"phase"`
+
+	// Question is the current question's index, or -1 in the
+	// lobby. QuestionType is its kind, such as "quiz" or
+	// "survey", if the server said.
+	Question     int    `json:"question"`
+	QuestionType string `json:"questionType,omitempty"`
+
+	// NumQuestions is the length of the quiz, once a question
+	// has revealed it.
+	NumQuestions int `json:"numQuestions"`
+
+	// Deadline is when the open question closes, if known.
+	Deadline time.Time `json:"deadline"`
+
+	// Score, Rank, and Streak are the player's standing after
+	// the last result, and Correct and Incorrect count its
+	// results so far.
+	Score     float64 `json:"score"`
+	Rank      int     `json:"rank"`
+	Streak    int     `json:"streak"`
+	Correct   int     `json:"correct"`
+	Incorrect int     `json:"incorrect"`
+
+	// Rand, if set, is where strategies draw their random
+	// choices from, so that a seeded player repeats them.
+	// Otherwise they use math/rand's shared source. Conns
+	// never set it; callers of ChooseAnswer do.
+	Rand *rand.Rand `json:"-"`
+}
+
+// TimeLeft returns how long the open question accepts answers,
+// or zero if no question is open or its deadline is unknown.
+func (g GameState) TimeLeft() time.Duration {
+	if g.Phase != PhaseAnswering || g.Deadline.IsZero() {
+		return 0
+	}
+	if left := time.Until(g.Deadline); left > 0 {
+		return left
+	}
+	return 0
+}
+
+// GameState returns a copy of the game's state as far as
+// Quiz.Receive has seen it.
+func (c *Conn) GameState() GameState {
+	c.gameLock.Lock()
+	defer c.gameLock.Unlock()
+	return c.game
+}
+
+// updateGame applies f to the game state and publishes the
+// result.
+func (c *Conn) updateGame(f func(g *GameState)) {
+	c.gameLock.Lock()
+	f(&c.game)
+	state := c.game
+	c.gameLock.Unlock()
+	c.events.Publish(TopicConnection, "stateChanged", &state)
+}
+
+func (g *GameState) observeAction(action *QuizAction) {
+	if action.Type == QuestionIntro || action.Index != g.Question {
+		g.Deadline = time.Time{}
+	}
+	g.Question = action.Index
+	g.QuestionType = action.QuestionType
+	if n := len(action.AnswerCounts); n > 0 {
+		g.NumQuestions = n
+	}
+	if action.Type == QuestionIntro {
+		g.Phase = PhaseIntro
+	} else {
+		g.Phase = PhaseAnswering
+		g.Deadline = action.Deadline()
+	}
+}
+
+func (g *GameState) observeResult(result *QuizResult) {
+	g.Phase = PhaseResult
+	g.Deadline = time.Time{}
+	g.Score = result.TotalScore
+	g.Rank = result.Rank
+	g.Streak = result.Streak
+	if result.IsCorrect {
+		g.Correct++
+	} else {
+		g.Incorrect++
+	}
+}
+
+func (g *GameState) observeEnd(end *QuizEnd) {
+	g.Phase = PhaseOver
+	g.Deadline = time.Time{}
+	g.Score = end.TotalScore
+	g.Rank = end.Rank
+	g.Correct = end.CorrectCount
+	g.Incorrect = end.IncorrectCount
+}
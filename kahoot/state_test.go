@@ -0,0 +1,44 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGameState(t *testing.T) {
+	c := &Conn{events: NewBus(), game: GameState{Question: -1}}
+	sub := c.events.Subscribe(TopicConnection)
+	defer sub.Close()
+
+	intro := &QuizAction{Type: QuestionIntro, Index: 0, AnswerCounts: []int{4, 2}, QuestionType: "quiz"}
+	c.updateGame(func(g *GameState) { g.observeAction(intro) })
+	if s := c.GameState(); s.Phase != PhaseIntro || s.Question != 0 || s.NumQuestions != 2 ||
+		s.QuestionType != "quiz" || s.TimeLeft() != 0 {
+		t.Errorf("unexpected state after intro %+v", s)
+	}
+	if e := <-sub.C; e.Type != "stateChanged" || e.Data.(*GameState).Phase != PhaseIntro {
+		t.Errorf("unexpected event %+v", e)
+	}
+
+	answers := &QuizAction{Type: QuestionAnswers, Index: 0, Received: time.Now(), TimeLeft: time.Minute}
+	c.updateGame(func(g *GameState) { g.observeAction(answers) })
+	if left := c.GameState().TimeLeft(); left <= 50*time.Second || left > time.Minute {
+		t.Errorf("unexpected time left %s", left)
+	}
+
+	c.updateGame(func(g *GameState) {
+		g.observeResult(&QuizResult{IsCorrect: true, TotalScore: 950, Rank: 2, Streak: 1})
+	})
+	c.updateGame(func(g *GameState) { g.observeResult(&QuizResult{TotalScore: 950, Rank: 3}) })
+	if s := c.GameState(); s.Phase != PhaseResult || s.Score != 950 || s.Rank != 3 ||
+		s.Streak != 0 || s.Correct != 1 || s.Incorrect != 1 || s.TimeLeft() != 0 {
+		t.Errorf("unexpected state after results %+v", s)
+	}
+
+	c.updateGame(func(g *GameState) {
+		g.observeEnd(&QuizEnd{Rank: 1, TotalScore: 1900, CorrectCount: 2})
+	})
+	if s := c.GameState(); s.Phase != PhaseOver || s.Rank != 1 || s.Correct != 2 || s.Incorrect != 0 {
+		t.Errorf("unexpected state after the quiz %+v", s)
+	}
+}
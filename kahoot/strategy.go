@@ -0,0 +1,282 @@
+package kahoot
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+)
+
+// An Answer is an AnswerStrategy's decision on a question.
+type Answer struct {
+	// Choice is the on-screen choice to submit, or -1 to leave
+	// the question unanswered.
+	Choice int
+
+	// Delay is how long after the question opened to answer.
+	Delay time.Duration
+}
+
+// Skip is the Answer which leaves a question unanswered.
+var Skip = Answer{Choice: -1}
+
+// At returns when to send the answer: Delay after the action
+// arrived, but early enough to arrive before the question
+// closes, given the connection's round trip.
+func (a Answer) At(action *QuizAction, rtt time.Duration) time.Time {
+	at := action.Received.Add(a.Delay)
+	if deadline := action.Deadline(); !deadline.IsZero() && at.After(deadline.Add(-rtt)) {
+		at = deadline.Add(-rtt)
+	}
+	return at
+}
+
+// An AnswerStrategy decides how a player answers each open
+// question, given what the Conn knows about the game.
+type AnswerStrategy interface {
+	ChooseAnswer(action *QuizAction, state GameState) Answer
+}
+
+// An AnswerFunc is a function used as an AnswerStrategy.
+type AnswerFunc func(action *QuizAction, state GameState) Answer
+
+// ChooseAnswer calls f.
+func (f AnswerFunc) ChooseAnswer(action *QuizAction, state GameState) Answer {
+	return f(action, state)
+}
+
+// RandomStrategy answers every question with a random choice,
+// right away.
+func RandomStrategy() AnswerStrategy {
+	return AnswerFunc(func(action *QuizAction, state GameState) Answer {
+		return Answer{Choice: randomChoice(source(state), action.NumAnswers)}
+	})
+}
+
+// AlwaysIndex always answers the on-screen choice with the
+// given index, or the last one if the question has fewer.
+// Like every built-in strategy, it skips blocks without
+// choices.
+func AlwaysIndex(n int) AnswerStrategy {
+	return AnswerFunc(func(action *QuizAction, state GameState) Answer {
+		if n >= action.NumAnswers {
+			return Answer{Choice: action.NumAnswers - 1}
+		}
+		return Answer{Choice: n}
+	})
+}
+
+// CorrectStrategy answers each question of the quiz correctly,
+// and questions without a correct choice at random.
+func CorrectStrategy(quiz *QuizInfo) AnswerStrategy {
+	return AnswerFunc(func(action *QuizAction, state GameState) Answer {
+		if screen, ok := quiz.CorrectScreenChoice(action); ok {
+			return Answer{Choice: screen}
+		}
+		return Answer{Choice: randomChoice(source(state), action.NumAnswers)}
+	})
+}
+
+// WrongOnPurpose answers each question of the quiz with a
+// random wrong choice. Questions with only one choice, or
+// without a correct one, get a random choice.
+func WrongOnPurpose(quiz *QuizInfo) AnswerStrategy {
+	return AnswerFunc(func(action *QuizAction, state GameState) Answer {
+		return Answer{Choice: wrongChoice(source(state), quiz, action)}
+	})
+}
+
+// HumanLike answers like a person would: after a random delay
+// between MinDelay and MaxDelay, correctly with probability
+// Accuracy if the quiz is known, and otherwise in proportion
+// to Weights.
+type HumanLike struct {
+	// Quiz, if non-nil, tells which choices are correct.
+	Quiz     *QuizInfo
+	Accuracy float64
+
+	// Weights are the relative likelihoods of the on-screen
+	// choices when not answering correctly. Missing weights
+	// count as 1.
+	Weights []float64
+
+	MinDelay time.Duration
+	MaxDelay time.Duration
+}
+
+// DefaultHumanLike returns a HumanLike which gets most
+// questions of the quiz right within a few seconds, slightly
+// favouring the top choices otherwise. The quiz may be nil.
+func DefaultHumanLike(quiz *QuizInfo) *HumanLike {
+	return &HumanLike{
+		Quiz:     quiz,
+		Accuracy: 0.7,
+		Weights:  []float64{1.3, 1.2, 1, 0.9},
+		MinDelay: time.Second,
+		MaxDelay: 6 * time.Second,
+	}
+}
+
+// ChooseAnswer picks a choice and a delay.
+func (h *HumanLike) ChooseAnswer(action *QuizAction, state GameState) Answer {
+	r := source(state)
+	delay := h.MinDelay
+	if h.MaxDelay > h.MinDelay {
+		delay += time.Duration(r.Int63n(int64(h.MaxDelay - h.MinDelay)))
+	}
+	if h.Quiz != nil {
+		if screen, ok := h.Quiz.CorrectScreenChoice(action); ok {
+			if r.Float64() < h.Accuracy {
+				return Answer{Choice: screen, Delay: delay}
+			}
+			return Answer{Choice: h.weighted(r, action.NumAnswers, screen), Delay: delay}
+		}
+	}
+	return Answer{Choice: h.weighted(r, action.NumAnswers, -1), Delay: delay}
+}
+
+// weighted picks a choice by weight, leaving out the excluded
+// one if there is another.
+func (h *HumanLike) weighted(r randSource, numAnswers, exclude int) int {
+	if numAnswers <= 1 {
+		return numAnswers - 1
+	}
+	weights := make([]float64, numAnswers)
+	var total float64
+	for i := range weights {
+		weights[i] = 1
+		if i < len(h.Weights) {
+			weights[i] = h.Weights[i]
+		}
+		if i == exclude || weights[i] < 0 {
+			weights[i] = 0
+		}
+		total += weights[i]
+	}
+	if total == 0 {
+		return randomChoice(r, numAnswers)
+	}
+	x := r.Float64() * total
+	for i, w := range weights {
+		if x < w {
+			return i
+		}
+		x -= w
+	}
+	return numAnswers - 1
+}
+
+// A randSource is where strategies draw random numbers from.
+type randSource interface {
+	Intn(n int) int
+	Int63n(n int64) int64
+	Float64() float64
+}
+
+// globalRand is math/rand's shared source as a randSource.
+type globalRand struct{}
+
+func (globalRand) Intn(n int) int       { return rand.Intn(n) }
+func (globalRand) Int63n(n int64) int64 { return rand.Int63n(n) }
+func (globalRand) Float64() float64     { return rand.Float64() }
+
+// source returns the state's Rand, or the shared source if it
+// has none.
+func source(state GameState) randSource {
+	if state.Rand != nil {
+		return state.Rand
+	}
+	return globalRand{}
+}
+
+// randomChoice picks a choice at random, or -1 if there are
+// none.
+func randomChoice(r randSource, numAnswers int) int {
+	if numAnswers <= 0 {
+		return -1
+	}
+	return r.Intn(numAnswers)
+}
+
+func wrongChoice(r randSource, quiz *QuizInfo, action *QuizAction) int {
+	correct, ok := quiz.CorrectScreenChoice(action)
+	if !ok || action.NumAnswers <= 1 {
+		return randomChoice(r, action.NumAnswers)
+	}
+	choice := r.Intn(action.NumAnswers - 1)
+	if choice >= correct {
+		choice++
+	}
+	return choice
+}
+
+// strategyMakers are the built-in strategies, by name.
+var strategyMakers = map[string]func(quiz *QuizInfo, index int) (AnswerStrategy, error){
+	"random": func(quiz *QuizInfo, index int) (AnswerStrategy, error) {
+		return RandomStrategy(), nil
+	},
+	"fixed": func(quiz *QuizInfo, index int) (AnswerStrategy, error) {
+		return AlwaysIndex(index), nil
+	},
+	"correct": func(quiz *QuizInfo, index int) (AnswerStrategy, error) {
+		if quiz == nil {
+			return nil, errors.New("the correct strategy needs the quiz")
+		}
+		return CorrectStrategy(quiz), nil
+	},
+	"wrong": func(quiz *QuizInfo, index int) (AnswerStrategy, error) {
+		if quiz == nil {
+			return nil, errors.New("the wrong strategy needs the quiz")
+		}
+		return WrongOnPurpose(quiz), nil
+	},
+	"human": func(quiz *QuizInfo, index int) (AnswerStrategy, error) {
+		return DefaultHumanLike(quiz), nil
+	},
+}
+
+// AnswerStrategyNames returns the sorted names NewStrategy
+// accepts.
+func AnswerStrategyNames() []string {
+	var names []string
+	for name := range strategyMakers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewStrategy creates a built-in strategy by name. The
+// "fixed" strategy answers the choice with the given index,
+// "correct" and "wrong" need the quiz, and "human" uses it if
+// it is non-nil.
+func NewStrategy(name string, quiz *QuizInfo, index int) (AnswerStrategy, error) {
+	maker, ok := strategyMakers[name]
+	if !ok {
+		return nil, errors.New("unknown strategy: " + name)
+	}
+	return maker(quiz, index)
+}
+
+// PlayStrategy answers every open question as the strategy
+// decides, until receiving fails, and returns that error.
+func (c *Conn) PlayStrategy(strategy AnswerStrategy) error {
+	quiz := NewQuiz(c)
+	for {
+		action, err := quiz.Receive()
+		if err != nil {
+			return err
+		}
+		if action.Type != QuestionAnswers {
+			continue
+		}
+		answer := strategy.ChooseAnswer(action, c.GameState())
+		if answer.Choice < 0 {
+			continue
+		}
+		time.Sleep(time.Until(answer.At(action, c.RTT())))
+		if err := quiz.Send(action.QuizChoice(answer.Choice)); err != nil {
+			return err
+		}
+	}
+}
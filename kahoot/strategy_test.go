@@ -0,0 +1,68 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStrategies(t *testing.T) {
+	quiz := &QuizInfo{Questions: []QuizQuestion{
+		{Choices: []QuizChoice{{Answer: "a"}, {Answer: "b"}, {Answer: "c", Correct: true}}},
+	}}
+	action := &QuizAction{Type: QuestionAnswers, NumAnswers: 3, AnswerMap: map[int]int{0: 2, 1: 0, 2: 1}}
+	var state GameState
+
+	if a := AlwaysIndex(5).ChooseAnswer(action, state); a.Choice != 2 {
+		t.Errorf("expected the last choice, got %d", a.Choice)
+	}
+	if a := CorrectStrategy(quiz).ChooseAnswer(action, state); a.Choice != 0 {
+		t.Errorf("expected the correct choice 0, got %d", a.Choice)
+	}
+	for i := 0; i < 20; i++ {
+		if a := WrongOnPurpose(quiz).ChooseAnswer(action, state); a.Choice == 0 || a.Choice >= 3 {
+			t.Fatalf("unexpected wrong choice %d", a.Choice)
+		}
+		if a := RandomStrategy().ChooseAnswer(action, state); a.Choice < 0 || a.Choice >= 3 {
+			t.Fatalf("unexpected random choice %d", a.Choice)
+		}
+	}
+
+	human := DefaultHumanLike(quiz)
+	human.Accuracy = 1
+	for i := 0; i < 20; i++ {
+		a := human.ChooseAnswer(action, state)
+		if a.Choice != 0 || a.Delay < human.MinDelay || a.Delay >= human.MaxDelay {
+			t.Fatalf("unexpected answer %+v", a)
+		}
+	}
+	human.Accuracy = 0
+	human.Weights = []float64{0, 0, 1}
+	for i := 0; i < 20; i++ {
+		if a := human.ChooseAnswer(action, state); a.Choice != 2 {
+			t.Fatalf("expected the only weighted wrong choice, got %d", a.Choice)
+		}
+	}
+
+	if _, err := NewStrategy("correct", nil, 0); err == nil {
+		t.Error("expected an error without a quiz")
+	}
+	if _, err := NewStrategy("nope", quiz, 0); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+	for _, name := range AnswerStrategyNames() {
+		if _, err := NewStrategy(name, quiz, 1); err != nil {
+			t.Errorf("strategy %s: %s", name, err)
+		}
+	}
+}
+
+func TestAnswerAt(t *testing.T) {
+	now := time.Now()
+	action := &QuizAction{Received: now, TimeLeft: 10 * time.Second}
+	if at := (Answer{Delay: 2 * time.Second}).At(action, time.Second); !at.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("unexpected time %s", at.Sub(now))
+	}
+	if at := (Answer{Delay: time.Minute}).At(action, time.Second); !at.Equal(now.Add(9 * time.Second)) {
+		t.Errorf("expected the delay to be cut short, got %s", at.Sub(now))
+	}
+}
@@ -0,0 +1,48 @@
+package kahoot
+
+import "encoding/json"
+
+// unrecognized fails a strict Conn on a message it did not
+// understand, returning the *ProtocolError. Other Conns skip
+// the message, so it returns nil for them.
+//
+// It does not close the Conn, since it may be called from the
+// read loop; its callers either end the read loop or Close.
+func (c *Conn) unrecognized(e *ProtocolError, msg interface{}) error {
+	if !c.opts.Strict {
+		return nil
+	}
+	dump, _ := json.Marshal(msg)
+	e.Message = string(dump)
+	c.wsLock.Lock()
+	first := c.strictErr == nil
+	if first {
+		c.strictErr = e
+	} else {
+		e = c.strictErr
+	}
+	c.wsLock.Unlock()
+	if first {
+		c.events.Publish(TopicError, "strict", e)
+	}
+	return e
+}
+
+// strictError returns the message a strict Conn failed on, if
+// any.
+func (c *Conn) strictError() error {
+	c.wsLock.Lock()
+	defer c.wsLock.Unlock()
+	if c.strictErr == nil {
+		return nil
+	}
+	return c.strictErr
+}
+
+// closedErr is the error of receiving from a closed Conn.
+func (c *Conn) closedErr() error {
+	if err := c.strictError(); err != nil {
+		return err
+	}
+	return ErrConnClosed
+}
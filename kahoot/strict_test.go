@@ -0,0 +1,78 @@
+package kahoot
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/internal/kahoottest"
+)
+
+func TestStrict(t *testing.T) {
+	server := kahoottest.NewServer(kahoottest.Config{})
+	defer server.Close()
+	defer useServer(t, server)()
+
+	for _, strict := range []bool{false, true} {
+		c, err := NewConnOptions("123456", &ConnOptions{Strict: strict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+		sub := c.Events().Subscribe(TopicError)
+		defer sub.Close()
+		name := "tolerant"
+		if strict {
+			name = "strict"
+		}
+		if err := c.Login(name); err != nil {
+			t.Fatal(err)
+		}
+		server.Broadcast(99, map[string]interface{}{"surprise": true})
+		server.Broadcast(kickedId, map[string]interface{}{"kickCode": 1.0})
+
+		_, err = NewQuiz(c).Receive()
+		var protoErr *ProtocolError
+		if !strict {
+			if !errors.Is(err, ErrKicked) {
+				t.Errorf("tolerant: expected the unknown message to be skipped, got %v", err)
+			}
+			continue
+		}
+		if !errors.As(err, &protoErr) || !errors.Is(err, ErrUnrecognized) {
+			t.Fatalf("strict: expected a *ProtocolError, got %v", err)
+		}
+		if protoErr.Id != 99 || protoErr.Problem != "unknown message id" || protoErr.Message == "" {
+			t.Errorf("strict: unexpected error %+v", protoErr)
+		}
+		if _, err := c.Receive(protocol().Channels.Player); err != protoErr {
+			t.Errorf("strict: expected the Conn to fail with the error, got %v", err)
+		}
+		for e := range sub.C {
+			if e.Type == "strict" {
+				break
+			}
+		}
+	}
+}
+
+func TestStrictUnknownChannel(t *testing.T) {
+	server := kahoottest.NewServer(kahoottest.Config{})
+	defer server.Close()
+	defer useServer(t, server)()
+
+	c, err := NewConnOptions("123456", &ConnOptions{Strict: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Login("bot"); err != nil {
+		t.Fatal(err)
+	}
+	server.Publish("/service/surprise", map[string]interface{}{"id": 1.0})
+	_, err = NewQuiz(c).Receive()
+	var protoErr *ProtocolError
+	if !errors.As(err, &protoErr) || protoErr.Channel != "/service/surprise" ||
+		protoErr.Problem != "unknown channel" {
+		t.Errorf("expected an unknown channel error, got %v", err)
+	}
+}
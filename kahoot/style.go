@@ -0,0 +1,41 @@
+package kahoot
+
+// A ChoiceStyle is the colour and shape a choice is drawn
+// with, which is how players tell the choices apart.
+type ChoiceStyle struct {
+	Color string `json:"color"`
+	Shape string `json:"shape"`
+}
+
+// choiceStyles are the styles of the on-screen choices, in
+// screen order.
+var choiceStyles = []ChoiceStyle{
+	{"red", "triangle"},
+	{"blue", "diamond"},
+	{"yellow", "circle"},
+	{"green", "square"},
+}
+
+// ChoiceStyleAt returns the style of an on-screen choice.
+// Questions with more than four choices repeat the styles.
+func ChoiceStyleAt(screen int) ChoiceStyle {
+	n := len(choiceStyles)
+	return choiceStyles[(screen%n+n)%n]
+}
+
+// String describes the style the way players would, such as
+// "red triangle".
+func (c ChoiceStyle) String() string {
+	return c.Color + " " + c.Shape
+}
+
+// Styles returns the style of each on-screen choice of the
+// question. Use ScreenChoice to find the style of one of the
+// quiz's choices.
+func (q *QuizAction) Styles() []ChoiceStyle {
+	res := make([]ChoiceStyle, q.NumAnswers)
+	for i := range res {
+		res[i] = ChoiceStyleAt(i)
+	}
+	return res
+}
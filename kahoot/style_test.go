@@ -0,0 +1,20 @@
+package kahoot
+
+import "testing"
+
+func TestChoiceStyles(t *testing.T) {
+	if s := ChoiceStyleAt(0).String(); s != "red triangle" {
+		t.Errorf("unexpected style %q", s)
+	}
+	if ChoiceStyleAt(5) != ChoiceStyleAt(1) || ChoiceStyleAt(-1) != ChoiceStyleAt(3) {
+		t.Error("styles should repeat")
+	}
+	action := &QuizAction{NumAnswers: 2, AnswerMap: map[int]int{0: 1, 1: 0}}
+	styles := action.Styles()
+	if len(styles) != 2 || styles[1].Shape != "diamond" {
+		t.Errorf("unexpected styles %v", styles)
+	}
+	if styles[action.ScreenChoice(0)].Color != "blue" {
+		t.Error("the quiz's first choice should be shown in blue")
+	}
+}
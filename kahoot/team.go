@@ -0,0 +1,65 @@
+package kahoot
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// teamMembersId is the controller message id which names the
+// members of a player's team.
+const teamMembersId = 18
+
+// teamAcceptedId is the player channel message id the server
+// sends once it has accepted a team.
+const teamAcceptedId = 19
+
+// gameModeTeam is the reserve response's gameMode for team
+// games.
+const gameModeTeam = "team"
+
+// TeamMode reports whether the game is played in teams, as far
+// as the reserve response said. In a team game, the server
+// ignores a player's answers until JoinTeam names its members.
+func (c *Conn) TeamMode() bool {
+	return c.teamMode
+}
+
+// JoinTeam names the members of our team. Team games need it
+// after Login and before the first question; if no members are
+// given, the team consists of our nickname alone. The server's
+// acceptance arrives later as a "teamAccepted" connection
+// event, which Quiz.Receive publishes.
+//
+// If the Conn reconnects, it names the same members again.
+func (c *Conn) JoinTeam(members []string) error {
+	if len(members) == 0 {
+		nickname := c.Player().Nickname
+		if nickname == "" {
+			return errors.New("join team: not logged in")
+		}
+		members = []string{nickname}
+	}
+	members = append([]string{}, members...)
+	c.playerLock.Lock()
+	c.team = members
+	c.playerLock.Unlock()
+	return c.sendTeam(members)
+}
+
+func (c *Conn) sendTeam(members []string) error {
+	content, _ := json.Marshal(members)
+	proto := protocol()
+	data := proto.template("message")
+	data["id"] = teamMembersId
+	data["gameid"] = c.gameId
+	data["host"] = proto.Host
+	data["content"] = string(content)
+	return c.send(proto.Channels.Controller, Message{"data": data})
+}
+
+// teamMembers returns the members JoinTeam last named.
+func (c *Conn) teamMembers() []string {
+	c.playerLock.Lock()
+	defer c.playerLock.Unlock()
+	return c.team
+}
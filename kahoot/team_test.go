@@ -0,0 +1,29 @@
+package kahoot
+
+import "testing"
+
+func TestJoinTeam(t *testing.T) {
+	c := &Conn{gameId: "123456", outgoing: make(chan Message, 1), closed: make(chan struct{})}
+	if c.JoinTeam(nil) == nil {
+		t.Error("expected an error before logging in")
+	}
+	c.player = Player{Nickname: "ada"}
+	for _, test := range []struct {
+		members []string
+		content string
+	}{
+		{nil, `["ada"]`},
+		{[]string{"ada", "grace"}, `["ada","grace"]`},
+	} {
+		if err := c.JoinTeam(test.members); err != nil {
+			t.Fatal(err)
+		}
+		data := (<-c.outgoing)["data"].(Message)
+		if data["id"] != teamMembersId || data["gameid"] != "123456" || data["content"] != test.content {
+			t.Errorf("unexpected data %v", data)
+		}
+		if len(c.teamMembers()) == 0 {
+			t.Error("team should be kept for reconnects")
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package kahoot
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+type traceRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"`
+	Messages  []Message `json:"messages"`
+}
+
+// SetTrace starts writing every message sent or received on
+// the connection to w, one JSON object per line. Pass nil to
+// stop tracing.
+//
+// Tracing may be switched on and off at any time; it costs
+// nothing while it is off.
+func (c *Conn) SetTrace(w io.Writer) {
+	c.traceLock.Lock()
+	defer c.traceLock.Unlock()
+	c.traceWriter = w
+	c.events.Publish(TopicConnection, "trace", w != nil)
+}
+
+// Tracing reports whether SetTrace is in effect.
+func (c *Conn) Tracing() bool {
+	c.traceLock.Lock()
+	defer c.traceLock.Unlock()
+	return c.traceWriter != nil
+}
+
+func (c *Conn) trace(direction string, msgs []Message) {
+	c.traceLock.Lock()
+	defer c.traceLock.Unlock()
+	if c.traceWriter == nil {
+		return
+	}
+	data, err := json.Marshal(traceRecord{
+		Time:      time.Now(),
+		Direction: direction,
+		Messages:  msgs,
+	})
+	if err == nil {
+		c.traceWriter.Write(append(data, '\n'))
+	}
+}
@@ -0,0 +1,29 @@
+package kahoot
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestTrace(t *testing.T) {
+	c := &Conn{events: NewBus()}
+	c.trace("in", []Message{{"channel": "/meta/connect"}})
+	if c.Tracing() {
+		t.Fatal("tracing should start off")
+	}
+
+	var buf bytes.Buffer
+	c.SetTrace(&buf)
+	c.trace("out", []Message{{"channel": "/service/controller"}})
+	c.SetTrace(nil)
+	c.trace("in", []Message{{"channel": "/service/player"}})
+
+	var rec traceRecord
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Direction != "out" || rec.Messages[0]["channel"] != "/service/controller" {
+		t.Errorf("unexpected trace: %s", buf.String())
+	}
+}
@@ -0,0 +1,82 @@
+package kahoot
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Player channel message ids of the two-factor exchange.
+const (
+	twoFactorWrongId   = 51
+	twoFactorCorrectId = 52
+	twoFactorPromptId  = 53
+)
+
+const twoFactorSubmitId = 50
+
+// twoFactorEvents are the connection events published for the
+// two-factor messages. A "twoFactorPrompt" means the host's
+// screen shows a new sequence to enter.
+var twoFactorEvents = map[int]string{
+	twoFactorWrongId:   "twoFactorRejected",
+	twoFactorCorrectId: "twoFactorAccepted",
+	twoFactorPromptId:  "twoFactorPrompt",
+}
+
+// SubmitTwoFactor answers a game's two-factor prompt with the
+// sequence of symbols shown on the host's screen: the indices
+// 0 through 3 (red triangle, blue diamond, yellow circle, green
+// square), each exactly once. Whether the sequence was right
+// arrives later as a "twoFactorAccepted" or
+// "twoFactorRejected" connection event, which Quiz.Receive
+// publishes.
+func (c *Conn) SubmitTwoFactor(seq []int) error {
+	m, err := twoFactorMessage(c.gameId, seq)
+	if err != nil {
+		return err
+	}
+	return c.send(protocol().Channels.Controller, m)
+}
+
+func twoFactorMessage(gameId string, seq []int) (Message, error) {
+	if len(seq) != 4 {
+		return nil, errors.New("two-factor sequence needs 4 symbols")
+	}
+	var sequence string
+	var seen [4]bool
+	for _, x := range seq {
+		if x < 0 || x > 3 || seen[x] {
+			return nil, errors.New("two-factor sequence must use each of 0 through 3 once")
+		}
+		seen[x] = true
+		sequence += strconv.Itoa(x)
+	}
+	content, _ := json.Marshal(map[string]string{"sequence": sequence})
+	proto := protocol()
+	data := proto.template("message")
+	data["id"] = twoFactorSubmitId
+	data["gameid"] = gameId
+	data["host"] = proto.Host
+	data["content"] = string(content)
+	return Message{"data": data}, nil
+}
+
+// twoFactorStage times the two-factor stage from the first
+// prompt, or the last rejection, to the server's next verdict.
+func (c *Conn) twoFactorStage(id int) {
+	switch {
+	case id == twoFactorPromptId:
+		if c.twoFactorStart.IsZero() {
+			c.twoFactorStart = time.Now()
+		}
+	case c.twoFactorStart.IsZero():
+	case id == twoFactorWrongId:
+		c.opts.Stage.since(StageTwoFactor, c.twoFactorStart, ErrTwoFactorRejected)
+		c.twoFactorStart = time.Now()
+	case id == twoFactorCorrectId:
+		c.opts.Stage.since(StageTwoFactor, c.twoFactorStart, nil)
+		c.twoFactorStart = time.Time{}
+	}
+}
@@ -0,0 +1,40 @@
+package kahoot
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTwoFactorMessage(t *testing.T) {
+	m, err := twoFactorMessage("123456", []int{2, 0, 3, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := m["data"].(Message)
+	if data["id"] != twoFactorSubmitId || data["gameid"] != "123456" ||
+		data["content"] != `{"sequence":"2031"}` {
+		t.Errorf("unexpected data %v", data)
+	}
+	for _, seq := range [][]int{{0, 1, 2}, {0, 1, 2, 2}, {0, 1, 2, 4}} {
+		if _, err := twoFactorMessage("123456", seq); err == nil {
+			t.Errorf("expected an error for %v", seq)
+		}
+	}
+}
+
+func TestTwoFactorStage(t *testing.T) {
+	var errs []error
+	c := &Conn{opts: ConnOptions{Stage: func(stage string, took time.Duration, err error) {
+		if stage != StageTwoFactor {
+			t.Errorf("unexpected stage %q", stage)
+		}
+		errs = append(errs, err)
+	}}}
+	for _, id := range []int{twoFactorCorrectId, twoFactorPromptId, twoFactorPromptId,
+		twoFactorWrongId, twoFactorCorrectId, twoFactorCorrectId} {
+		c.twoFactorStage(id)
+	}
+	if len(errs) != 2 || errs[0] != ErrTwoFactorRejected || errs[1] != nil {
+		t.Errorf("unexpected stages %v", errs)
+	}
+}
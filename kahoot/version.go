@@ -0,0 +1,64 @@
+package kahoot
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/unixpickle/kahoot-hack/kahoot/challenge"
+)
+
+// Release names the build of the tools. Release builds set it
+// with -ldflags "-X github.com/unixpickle/kahoot-hack/kahoot.Release=v1.2".
+var Release = "dev"
+
+// VersionInfo describes the build and the protocol it speaks,
+// for bug reports.
+type VersionInfo struct {
+	Release   string   `json:"release"`
+	GoVersion string   `json:"goVersion"`
+	Protocol  string   `json:"protocol"`
+	KnownGood string   `json:"knownGood,omitempty"`
+	Solvers   []string `json:"solvers"`
+	Demo      bool     `json:"demo"`
+}
+
+// Version returns the current VersionInfo. The protocol and
+// solvers are those in use, so they reflect SetProtocol and
+// SetSolver.
+func Version() *VersionInfo {
+	proto := protocol()
+	var solvers []string
+	for _, e := range currentEvaluators() {
+		solvers = append(solvers, e.Name())
+		if e == PatternEvaluator {
+			solvers = append(solvers, challenge.Names()...)
+		}
+	}
+	return &VersionInfo{
+		Release:   Release,
+		GoVersion: runtime.Version(),
+		Protocol:  proto.Version,
+		KnownGood: proto.KnownGood,
+		Solvers:   solvers,
+		Demo:      DemoMode,
+	}
+}
+
+// String formats the information as a few lines to paste into
+// an issue.
+func (v *VersionInfo) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "kahoot-hack %s (%s", v.Release, v.GoVersion)
+	if v.Demo {
+		b.WriteString(", demo build")
+	}
+	b.WriteString(")\n")
+	fmt.Fprintf(&b, "protocol: %s", v.Protocol)
+	if v.KnownGood != "" {
+		fmt.Fprintf(&b, ", last known good %s", v.KnownGood)
+	}
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "challenge solvers: %s\n", strings.Join(v.Solvers, ", "))
+	return b.String()
+}
@@ -0,0 +1,29 @@
+package kahoot
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	v := Version()
+	if v.Protocol != DefaultProtocol.Version || v.KnownGood != DefaultProtocol.KnownGood {
+		t.Errorf("unexpected protocol %q (known good %q)", v.Protocol, v.KnownGood)
+	}
+	for _, name := range v.Solvers {
+		if name == "safeval.pw" {
+			t.Errorf("remote evaluation should be off by default: %v", v.Solvers)
+		}
+	}
+	defer SetEvaluators()
+	SetEvaluators(append(DefaultEvaluators(), RemoteEvaluator{})...)
+	if v := Version(); v.Solvers[len(v.Solvers)-1] != "safeval.pw" {
+		t.Errorf("unexpected solvers %v", v.Solvers)
+	}
+	text := v.String()
+	for _, part := range []string{v.Release, "protocol: " + v.Protocol, "challenge solvers: patterns"} {
+		if !strings.Contains(text, part) {
+			t.Errorf("missing %q in %q", part, text)
+		}
+	}
+}
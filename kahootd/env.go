@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const envPrefix = "KAHOOTD_"
+
+// envName returns the environment variable which sets a flag,
+// e.g. KAHOOTD_READY_INTERVAL for -ready-interval.
+func envName(flagName string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(flagName, "-", "_", -1))
+}
+
+// applyEnv sets every flag which was not given on the command
+// line from its environment variable, if present.
+func applyEnv() error {
+	given := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+	var err error
+	flag.VisitAll(func(f *flag.Flag) {
+		if given[f.Name] || err != nil {
+			return
+		}
+		if value, ok := os.LookupEnv(envName(f.Name)); ok {
+			if setErr := f.Value.Set(value); setErr != nil {
+				err = fmt.Errorf("%s: %s", envName(f.Name), setErr)
+			}
+		}
+	})
+	return err
+}
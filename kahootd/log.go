@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A logger writes one line per message, either as plain text
+// or as a JSON object for log collectors.
+type logger struct {
+	lock sync.Mutex
+	w    io.Writer
+	json bool
+}
+
+func newLogger(format string) (*logger, error) {
+	switch format {
+	case "text":
+		return &logger{w: os.Stdout}, nil
+	case "json":
+		return &logger{w: os.Stdout, json: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown log format: %s", format)
+	}
+}
+
+func (l *logger) Log(msg string, fields map[string]interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := time.Now().UTC()
+	if l.json {
+		obj := map[string]interface{}{}
+		for k, v := range fields {
+			if err, ok := v.(error); ok {
+				v = err.Error()
+			}
+			obj[k] = v
+		}
+		obj["time"] = now.Format(time.RFC3339Nano)
+		obj["msg"] = msg
+		data, _ := json.Marshal(obj)
+		l.w.Write(append(data, '\n'))
+		return
+	}
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{now.Format("2006/01/02 15:04:05"), msg}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, fields[k]))
+	}
+	fmt.Fprintln(l.w, strings.Join(parts, " "))
+}
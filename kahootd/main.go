@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/audit"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/server"
+)
+
+const shutdownTimeout = 10 * time.Second
+
+func main() {
+	docker := flag.Bool("docker", false, "container mode: JSON logs and listen on all interfaces")
+	addr := flag.String("addr", "localhost:8080", "address to serve the API on")
+	logFormat := flag.String("log-format", "text", "log format (text or json)")
+	readyInterval := flag.Duration("ready-interval", server.DefaultReadyInterval,
+		"how long to reuse a readiness check")
+	protocolDir := flag.String("protocol-dir", "", "directory with a protocol.json overriding the built-in protocol")
+	maxRequests := flag.Int("max-requests-per-hour", 0, "budget for HTTP requests and connections per hour (0 for none)")
+	maxBots := flag.Int("max-bots", 0, "budget for concurrently connected bots (0 for none)")
+	maxAnswers := flag.Int("max-answers-per-minute", 0, "budget for answer messages per minute (0 for none)")
+	remoteEval := flag.String("remote-eval", "", "send challenges no local solver knows to this evaluation service, e.g. "+kahoot.SafevalURL+" (off by default)")
+	reserveRate := flag.Float64("reserve-rate", kahoot.DefaultRateLimit.ReservesPerSecond, "session reservations per second, shared by every bot (0 for no limit)")
+	loginRate := flag.Float64("login-rate", kahoot.DefaultRateLimit.LoginsPerSecond, "logins per second, shared by every bot (0 for no limit)")
+	rateBurst := flag.Int("rate-burst", kahoot.DefaultRateLimit.Burst, "reservations or logins which may go at once after a quiet spell")
+	httpTimeout := flag.Duration("http-timeout", kahoot.DefaultTimeout, "timeout for each HTTP request and connection attempt")
+	auditPath := flag.String("audit", "", "append-only audit log of swarm starts and stops")
+	verifyAudit := flag.String("verify-audit", "", "verify an audit log's hash chain and exit")
+	tenantsPath := flag.String("tenants", "", "JSON file of tenants and their API keys (open access if unset)")
+	schedulesPath := flag.String("schedules", "", "JSON file of swarms to start on cron schedules")
+	runRetention := flag.Duration("run-retention", server.DefaultRunRetention, "how long finished swarms stay listed before they are forgotten")
+	roleHeader := flag.String("role-header", "", "header from a trusted proxy that can lower the caller's role (viewer, operator, admin)")
+	version := flag.Bool("version", false, "print the version, protocol, and challenge solvers, then exit")
+	flag.Usage = usage
+	flag.Parse()
+	if *version {
+		fmt.Print(kahoot.Version())
+		return
+	}
+	if err := applyEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *docker {
+		given := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) {
+			given[f.Name] = true
+		})
+		if _, ok := os.LookupEnv(envName("addr")); !ok && !given["addr"] {
+			*addr = ":8080"
+		}
+		if _, ok := os.LookupEnv(envName("log-format")); !ok && !given["log-format"] {
+			*logFormat = "json"
+		}
+	}
+
+	if *verifyAudit != "" {
+		last, err := audit.Verify(*verifyAudit)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		if last == nil {
+			fmt.Println("audit log is empty")
+		} else {
+			fmt.Println("audit log is intact:", last.Seq, "entries, last hash", last.Hash)
+		}
+		return
+	}
+
+	log, err := newLogger(*logFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	kahoot.HTTPClient.Timeout = *httpTimeout
+	kahoot.SetBudget(kahoot.Budget{
+		RequestsPerHour:  *maxRequests,
+		MaxConns:         *maxBots,
+		AnswersPerMinute: *maxAnswers,
+	})
+	if *remoteEval != "" {
+		kahoot.SetEvaluators(append(kahoot.DefaultEvaluators(), kahoot.RemoteEvaluator{URL: *remoteEval})...)
+	}
+	kahoot.SetRateLimit(kahoot.RateLimit{
+		ReservesPerSecond: *reserveRate,
+		LoginsPerSecond:   *loginRate,
+		Burst:             *rateBurst,
+	})
+	if *protocolDir != "" {
+		if err := kahoot.LoadProtocolDir(*protocolDir); err != nil {
+			log.Log("failed to load protocol", map[string]interface{}{"error": err})
+			os.Exit(1)
+		}
+	}
+
+	s := server.New()
+	s.ReadyInterval = *readyInterval
+	s.RunRetention = *runRetention
+	s.Log = log.Log
+	s.RoleHeader = *roleHeader
+	s.Ready = func() error {
+		return kahoot.CheckService(5 * time.Second)
+	}
+	if *auditPath != "" {
+		auditLog, err := audit.Open(*auditPath)
+		if err != nil {
+			log.Log("failed to open audit log", map[string]interface{}{"error": err})
+			os.Exit(1)
+		}
+		defer auditLog.Close()
+		s.Audit = auditLog
+	}
+	if *tenantsPath != "" {
+		tenants, err := server.ReadTenants(*tenantsPath)
+		if err == nil {
+			for _, t := range tenants {
+				if err = s.AddTenant(t); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			log.Log("failed to load tenants", map[string]interface{}{"error": err})
+			os.Exit(1)
+		}
+		log.Log("loaded tenants", map[string]interface{}{"count": len(tenants)})
+	}
+	if *schedulesPath != "" {
+		schedules, err := server.ReadSchedules(*schedulesPath)
+		if err == nil {
+			for _, sch := range schedules {
+				if err = s.AddSchedule(sch); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			log.Log("failed to load schedules", map[string]interface{}{"error": err})
+			os.Exit(1)
+		}
+		log.Log("loaded schedules", map[string]interface{}{"count": len(schedules)})
+	}
+
+	httpServer := &http.Server{Addr: *addr, Handler: s}
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	log.Log("serving", map[string]interface{}{"addr": *addr, "pid": os.Getpid()})
+
+	// As PID 1 in a container, the kernel ignores any signal
+	// we do not handle, so SIGTERM must be caught explicitly.
+	sigChan := make(chan os.Signal, 2)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	select {
+	case err := <-serveErr:
+		log.Log("server failed", map[string]interface{}{"error": err})
+		os.Exit(1)
+	case sig := <-sigChan:
+		log.Log("shutting down", map[string]interface{}{"signal": sig.String()})
+	}
+
+	// A second signal skips the graceful shutdown.
+	go func() {
+		sig := <-sigChan
+		log.Log("forced exit", map[string]interface{}{"signal": sig.String()})
+		os.Exit(1)
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	httpServer.Shutdown(ctx)
+	s.Close()
+	log.Log("stopped", nil)
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: kahootd [flags]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Every flag may also be set with an environment variable,")
+	fmt.Fprintln(os.Stderr, "e.g. "+envName("ready-interval")+"=1m for -ready-interval.")
+	fmt.Fprintln(os.Stderr, "Command-line flags take precedence.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Flags:")
+	flag.PrintDefaults()
+}
@@ -12,6 +12,10 @@ echo "Downloading websocket... Please wait"
 go get github.com/gorilla/websocket
 echo "Downloading gopass... Please wait"
 go get github.com/howeyc/gopass
+echo "Downloading crypto... Please wait"
+go get golang.org/x/crypto/scrypt
+echo "Downloading pq... Please wait"
+go get github.com/lib/pq
 mkdir ~/kahoot
 mv /data/data/com.termux/files/usr/src/github.com/unixpickle/kahoot-hack/kahoot-auto/main.go ~/kahoot/auto.go
 mv /data/data/com.termux/files/usr/src/github.com/unixpickle/kahoot-hack/kahoot-crash/main.go ~/kahoot/crash.go
@@ -0,0 +1,134 @@
+// Package manifest loads protocol definitions from a signed
+// remote manifest, so that protocol fixes can be delivered
+// without a new binary.
+//
+// A manifest is an Envelope holding the JSON encoding of a
+// Manifest and an ed25519 signature of that encoding.
+// Manifests are only trusted if they are signed by PinnedKey,
+// have not expired, and are no older than the embedded
+// protocol; otherwise the embedded defaults remain in use.
+package manifest
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// maxManifestSize bounds how much of a response is read.
+const maxManifestSize = 1 << 20
+
+// PinnedKey is the base64-encoded ed25519 public key which
+// manifests must be signed with. It is set when building:
+//
+//	go build -ldflags "-X github.com/unixpickle/kahoot-hack/manifest.PinnedKey=..."
+//
+// If it is empty, remote manifests are refused.
+var PinnedKey string
+
+var (
+	ErrNoPinnedKey = errors.New("manifest: no verification key compiled in")
+	ErrExpired     = errors.New("manifest: expired")
+	ErrDowngrade   = errors.New("manifest: older than the protocol in use")
+)
+
+// An Envelope is a signed Manifest.
+type Envelope struct {
+	Payload   []byte `json:"payload"`
+	Signature []byte `json:"signature"`
+}
+
+// A Manifest is the signed content of an Envelope.
+type Manifest struct {
+	// Version is the version of Protocol. Protocol versions
+	// are dates (YYYY-MM-DD), so they are compared as strings.
+	Version string `json:"version"`
+
+	// Expires is when the manifest stops being trusted.
+	Expires time.Time `json:"expires"`
+
+	Protocol kahoot.Protocol `json:"protocol"`
+}
+
+// Sign creates an Envelope for a protocol which is trusted
+// until expires.
+func Sign(p *kahoot.Protocol, expires time.Time, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(&Manifest{Version: p.Version, Expires: expires, Protocol: *p})
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{Payload: payload, Signature: ed25519.Sign(key, payload)}, nil
+}
+
+// Verify checks an Envelope's signature and decodes its
+// protocol. It fails with ErrExpired if the manifest has
+// expired, and with ErrDowngrade if its protocol is older
+// than the one embedded in the binary.
+func Verify(e *Envelope, key ed25519.PublicKey) (*kahoot.Protocol, error) {
+	if len(key) != ed25519.PublicKeySize {
+		return nil, errors.New("manifest: invalid public key")
+	}
+	if !ed25519.Verify(key, e.Payload, e.Signature) {
+		return nil, errors.New("manifest: bad signature")
+	}
+	var m Manifest
+	if err := json.Unmarshal(e.Payload, &m); err != nil {
+		return nil, errors.New("manifest: " + err.Error())
+	}
+	if m.Version == "" || m.Version != m.Protocol.Version {
+		return nil, errors.New("manifest: version does not match its protocol")
+	}
+	if m.Expires.IsZero() || !time.Now().Before(m.Expires) {
+		return nil, ErrExpired
+	}
+	if m.Version < kahoot.DefaultProtocol.Version {
+		return nil, ErrDowngrade
+	}
+	return &m.Protocol, nil
+}
+
+// Fetch downloads a manifest and verifies it with PinnedKey.
+func Fetch(url string) (*kahoot.Protocol, error) {
+	if PinnedKey == "" {
+		return nil, ErrNoPinnedKey
+	}
+	key, err := base64.StdEncoding.DecodeString(PinnedKey)
+	if err != nil {
+		return nil, errors.New("manifest: invalid pinned key")
+	}
+	client := &http.Client{Timeout: time.Second * 10}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.New("manifest: " + resp.Status)
+	}
+	var e Envelope
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxManifestSize)).Decode(&e); err != nil {
+		return nil, errors.New("manifest: " + err.Error())
+	}
+	return Verify(&e, ed25519.PublicKey(key))
+}
+
+// Load fetches and installs a manifest. On any failure, such
+// as being offline or the manifest being older than the
+// protocol in use, the current protocol is left in place and
+// the error is returned for the caller to log.
+func Load(url string) error {
+	p, err := Fetch(url)
+	if err != nil {
+		return err
+	}
+	if p.Version < kahoot.CurrentProtocol().Version {
+		return ErrDowngrade
+	}
+	return kahoot.SetProtocol(p)
+}
@@ -0,0 +1,67 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := kahoot.DefaultProtocol
+	p.Version = "9999-01-01"
+	env, err := Sign(&p, time.Now().Add(time.Hour), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := Verify(env, pub)
+	if err != nil {
+		t.Fatal(err)
+	} else if verified.Version != "9999-01-01" {
+		t.Errorf("unexpected version: %s", verified.Version)
+	}
+
+	env.Payload[len(env.Payload)-2] ^= 1
+	if _, err := Verify(env, pub); err == nil {
+		t.Error("tampered manifest was accepted")
+	}
+}
+
+func TestVerifyDowngrade(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := kahoot.DefaultProtocol
+	env, err := Sign(&p, time.Now().Add(time.Hour), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(env, pub); err != nil {
+		t.Errorf("manifest of the embedded version was refused: %v", err)
+	}
+
+	p.Version = "2000-01-01"
+	env, err = Sign(&p, time.Now().Add(time.Hour), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(env, pub); err != ErrDowngrade {
+		t.Errorf("expected ErrDowngrade but got %v", err)
+	}
+
+	p = kahoot.DefaultProtocol
+	env, err = Sign(&p, time.Now().Add(-time.Minute), priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(env, pub); err != ErrExpired {
+		t.Errorf("expected ErrExpired but got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package netem
+
+import (
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+)
+
+// minRetransmit is the smallest delay caused by a lost
+// chunk, modeled on TCP's minimum retransmission timeout.
+const minRetransmit = time.Millisecond * 200
+
+const delayQueueSize = 64
+
+type chunk struct {
+	data []byte
+	due  time.Time
+	err  error
+}
+
+// A delayConn delivers data in each direction only after a
+// simulated one-way delay, without blocking the sender.
+// Order is preserved, so a lost chunk holds up the chunks
+// behind it, as it would in TCP.
+type delayConn struct {
+	net.Conn
+	profile Profile
+
+	lock      sync.Mutex
+	lastRead  time.Time
+	lastWrite time.Time
+
+	incoming chan chunk
+	pending  []byte
+	readErr  error
+
+	outgoing  chan chunk
+	writeErr  error
+	closeOnce sync.Once
+	done      chan struct{}
+}
+
+func newDelayConn(c net.Conn, p Profile) *delayConn {
+	d := &delayConn{
+		Conn:     c,
+		profile:  p,
+		incoming: make(chan chunk, delayQueueSize),
+		outgoing: make(chan chunk, delayQueueSize),
+		done:     make(chan struct{}),
+	}
+	go d.readLoop()
+	go d.writeLoop()
+	return d
+}
+
+func (d *delayConn) Read(b []byte) (int, error) {
+	if len(d.pending) == 0 {
+		if d.readErr != nil {
+			return 0, d.readErr
+		}
+		c, ok := <-d.incoming
+		if !ok {
+			return 0, d.readErr
+		}
+		if wait := time.Until(c.due); wait > 0 {
+			time.Sleep(wait)
+		}
+		if c.err != nil {
+			d.readErr = c.err
+			return 0, c.err
+		}
+		d.pending = c.data
+	}
+	n := copy(b, d.pending)
+	d.pending = d.pending[n:]
+	return n, nil
+}
+
+func (d *delayConn) Write(b []byte) (int, error) {
+	d.lock.Lock()
+	err := d.writeErr
+	due := d.nextDue(&d.lastWrite)
+	d.lock.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	data := append([]byte{}, b...)
+	select {
+	case d.outgoing <- chunk{data: data, due: due}:
+		return len(b), nil
+	case <-d.done:
+		return 0, net.ErrClosed
+	}
+}
+
+func (d *delayConn) Close() error {
+	d.closeOnce.Do(func() {
+		close(d.done)
+	})
+	return d.Conn.Close()
+}
+
+func (d *delayConn) readLoop() {
+	defer close(d.incoming)
+	for {
+		buf := make([]byte, 4096)
+		n, err := d.Conn.Read(buf)
+		d.lock.Lock()
+		due := d.nextDue(&d.lastRead)
+		d.lock.Unlock()
+		if n > 0 {
+			d.incoming <- chunk{data: buf[:n], due: due}
+		}
+		if err != nil {
+			d.incoming <- chunk{err: err, due: due}
+			return
+		}
+	}
+}
+
+func (d *delayConn) writeLoop() {
+	for {
+		select {
+		case c := <-d.outgoing:
+			if wait := time.Until(c.due); wait > 0 {
+				time.Sleep(wait)
+			}
+			if _, err := d.Conn.Write(c.data); err != nil {
+				d.lock.Lock()
+				d.writeErr = err
+				d.lock.Unlock()
+				return
+			}
+		case <-d.done:
+			return
+		}
+	}
+}
+
+// nextDue computes when a chunk sent now should arrive,
+// never earlier than the previous chunk in that direction.
+// The caller must hold d.lock.
+func (d *delayConn) nextDue(last *time.Time) time.Time {
+	delay := d.profile.Latency / 2
+	if d.profile.Jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(d.profile.Jitter)))
+	}
+	if d.profile.Loss > 0 && rand.Float64() < d.profile.Loss {
+		retransmit := d.profile.Latency * 3
+		if retransmit < minRetransmit {
+			retransmit = minRetransmit
+		}
+		delay += retransmit
+	}
+	due := time.Now().Add(delay)
+	if due.Before(*last) {
+		due = *last
+	}
+	*last = due
+	return due
+}
@@ -0,0 +1,166 @@
+// Package netem emulates constrained networks by wrapping
+// net.Conns, so that bots can behave like players on slow
+// connections.
+package netem
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Profile describes the network conditions to emulate.
+// Zero fields impose no constraint.
+type Profile struct {
+	// ReadBytesPerSec and WriteBytesPerSec cap the throughput
+	// in each direction.
+	ReadBytesPerSec  int
+	WriteBytesPerSec int
+
+	// Latency is the added round-trip time, half of which is
+	// applied in each direction.
+	Latency time.Duration
+
+	// Jitter is the maximum random variation added to the
+	// delay in each direction.
+	Jitter time.Duration
+
+	// Loss is the probability (0 to 1) that a chunk of data is
+	// "lost" and has to wait for a retransmission.
+	Loss float64
+}
+
+// Profiles are named, commonly useful network profiles.
+var Profiles = map[string]Profile{
+	"3g": {
+		ReadBytesPerSec:  96000,
+		WriteBytesPerSec: 32000,
+		Latency:          time.Millisecond * 200,
+		Jitter:           time.Millisecond * 100,
+		Loss:             0.01,
+	},
+	"edge": {
+		ReadBytesPerSec:  30000,
+		WriteBytesPerSec: 12000,
+		Latency:          time.Millisecond * 600,
+		Jitter:           time.Millisecond * 200,
+		Loss:             0.02,
+	},
+	"hotel-wifi": {
+		ReadBytesPerSec:  250000,
+		WriteBytesPerSec: 125000,
+		Latency:          time.Millisecond * 80,
+		Jitter:           time.Millisecond * 150,
+		Loss:             0.03,
+	},
+}
+
+// LookupProfile finds a named profile.
+func LookupProfile(name string) (Profile, error) {
+	p, ok := Profiles[name]
+	if !ok {
+		var names []string
+		for n := range Profiles {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return Profile{}, fmt.Errorf("unknown network profile %q (available: %v)", name, names)
+	}
+	return p, nil
+}
+
+// Wrap returns a net.Conn which applies the profile to c.
+// Each wrapped connection gets its own budget.
+func (p Profile) Wrap(c net.Conn) net.Conn {
+	if p == (Profile{}) {
+		return c
+	}
+	if p.ReadBytesPerSec > 0 || p.WriteBytesPerSec > 0 {
+		c = &conn{
+			Conn:   c,
+			reads:  newBucket(p.ReadBytesPerSec),
+			writes: newBucket(p.WriteBytesPerSec),
+		}
+	}
+	if p.Latency > 0 || p.Jitter > 0 || p.Loss > 0 {
+		c = newDelayConn(c, p)
+	}
+	return c
+}
+
+type conn struct {
+	net.Conn
+	reads  *bucket
+	writes *bucket
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	if c.reads != nil && len(b) > c.reads.burst {
+		b = b[:c.reads.burst]
+	}
+	n, err := c.Conn.Read(b)
+	if c.reads != nil {
+		c.reads.take(n)
+	}
+	return n, err
+}
+
+func (c *conn) Write(b []byte) (int, error) {
+	if c.writes == nil {
+		return c.Conn.Write(b)
+	}
+	var written int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > c.writes.burst {
+			chunk = chunk[:c.writes.burst]
+		}
+		c.writes.take(len(chunk))
+		n, err := c.Conn.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		b = b[n:]
+	}
+	return written, nil
+}
+
+// A bucket is a token bucket holding up to one second's
+// worth of bytes.
+type bucket struct {
+	lock   sync.Mutex
+	rate   int
+	burst  int
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate int) *bucket {
+	if rate <= 0 {
+		return nil
+	}
+	return &bucket{rate: rate, burst: rate, tokens: float64(rate), last: time.Now()}
+}
+
+// take removes n tokens, sleeping until they are available.
+// The balance may go negative after a large read, in which
+// case later calls wait for it to recover.
+func (b *bucket) take(n int) {
+	b.lock.Lock()
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * float64(b.rate)
+	if b.tokens > float64(b.burst) {
+		b.tokens = float64(b.burst)
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / float64(b.rate) * float64(time.Second))
+	}
+	b.lock.Unlock()
+	time.Sleep(wait)
+}
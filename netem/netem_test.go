@@ -0,0 +1,61 @@
+package netem
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestWriteThrottle(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(ioutil.Discard, server)
+
+	wrapped := Profile{WriteBytesPerSec: 1000}.Wrap(client)
+	defer wrapped.Close()
+
+	start := time.Now()
+	if _, err := wrapped.Write(make([]byte, 1500)); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if elapsed < time.Millisecond*400 || elapsed > time.Second*2 {
+		t.Errorf("1500 bytes at 1000 B/s took %v", elapsed)
+	}
+}
+
+func TestEmptyProfile(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	if (Profile{}).Wrap(client) != client {
+		t.Error("empty profile should not wrap")
+	}
+}
+
+func TestLatency(t *testing.T) {
+	client, server := net.Pipe()
+	defer server.Close()
+	go io.Copy(server, server)
+
+	wrapped := Profile{Latency: time.Millisecond * 200}.Wrap(client)
+	defer wrapped.Close()
+
+	start := time.Now()
+	if _, err := wrapped.Write([]byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 4)
+	if _, err := io.ReadFull(wrapped, buf); err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	if string(buf) != "ping" {
+		t.Errorf("unexpected echo: %q", buf)
+	}
+	if elapsed < time.Millisecond*190 || elapsed > time.Second {
+		t.Errorf("round trip with 200ms latency took %v", elapsed)
+	}
+}
@@ -0,0 +1,187 @@
+// Package overlay serves live question snapshots for stream
+// overlays, such as OBS browser sources.
+package overlay
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// A Snapshot is the compact state of the current question.
+type Snapshot struct {
+	// Question is the 1-based question number, or 0 before the
+	// first question.
+	Question int    `json:"question"`
+	Text     string `json:"text,omitempty"`
+
+	// Translation is Text in the operator's language, if
+	// Server.Translations has it.
+	Translation string `json:"translation,omitempty"`
+
+	// CountdownEnd is when the question closes, in Unix
+	// milliseconds, or 0 if it is not known.
+	CountdownEnd int64 `json:"countdownEnd"`
+
+	// Answers[i] is the number of bots which chose answer i.
+	Answers  []int `json:"answers"`
+	Answered int   `json:"answered"`
+}
+
+// A Server tracks a swarm's events and serves the latest
+// Snapshot, both as plain JSON at /snapshot and as a stream
+// of JSON messages over a WebSocket at /ws.
+type Server struct {
+	// Texts optionally holds the question texts, by index,
+	// for games where they are known ahead of time.
+	Texts []string
+
+	// Translations optionally holds the question texts in the
+	// operator's language, by index.
+	Translations []string
+
+	lock     sync.Mutex
+	snapshot Snapshot
+	watchers map[chan Snapshot]struct{}
+
+	upgrader websocket.Upgrader
+}
+
+// NewServer creates a Server with an empty snapshot.
+func NewServer() *Server {
+	return &Server{
+		snapshot: Snapshot{Answers: []int{}},
+		watchers: map[chan Snapshot]struct{}{},
+		upgrader: websocket.Upgrader{
+			// Browser sources are served from arbitrary origins.
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Watch updates the snapshot from a swarm's events until the
+// swarm's Bus is closed.
+func (s *Server) Watch(b *kahoot.Bus) {
+	s.watch(b.Subscribe(kahoot.TopicQuestion))
+}
+
+func (s *Server) watch(sub *kahoot.Subscription) {
+	for e := range sub.C {
+		s.lock.Lock()
+		switch data := e.Data.(type) {
+		case *kahoot.QuizAction:
+			if data.Index+1 != s.snapshot.Question {
+				s.snapshot = Snapshot{
+					Question: data.Index + 1,
+					Answers:  make([]int, data.NumAnswers),
+				}
+				if data.Index < len(s.Texts) {
+					s.snapshot.Text = s.Texts[data.Index]
+				}
+				if data.Index < len(s.Translations) {
+					s.snapshot.Translation = s.Translations[data.Index]
+				}
+			}
+			if deadline := data.Deadline(); !deadline.IsZero() {
+				s.snapshot.CountdownEnd = deadline.UnixNano() / int64(time.Millisecond)
+			}
+		case *swarm.Answer:
+			if data.Index+1 == s.snapshot.Question && data.Choice < len(s.snapshot.Answers) {
+				s.snapshot.Answers[data.Choice]++
+				s.snapshot.Answered++
+			}
+		default:
+			s.lock.Unlock()
+			continue
+		}
+		s.broadcast()
+		s.lock.Unlock()
+	}
+}
+
+// Snapshot returns a copy of the current snapshot.
+func (s *Server) Snapshot() Snapshot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.copySnapshot()
+}
+
+// ServeHTTP serves /snapshot and /ws.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/snapshot":
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.Snapshot())
+	case "/ws":
+		s.serveWebSocket(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveWebSocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	ch := make(chan Snapshot, 1)
+	s.lock.Lock()
+	ch <- s.copySnapshot()
+	s.watchers[ch] = struct{}{}
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.watchers, ch)
+		s.lock.Unlock()
+	}()
+
+	// Reading lets us notice when the client goes away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := ws.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case snapshot := <-ch:
+			if ws.WriteJSON(snapshot) != nil {
+				return
+			}
+		case <-closed:
+			return
+		}
+	}
+}
+
+// broadcast sends the snapshot to every WebSocket client,
+// replacing any snapshot a slow client has not yet received.
+// The caller must hold s.lock.
+func (s *Server) broadcast() {
+	snapshot := s.copySnapshot()
+	for ch := range s.watchers {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- snapshot
+	}
+}
+
+func (s *Server) copySnapshot() Snapshot {
+	res := s.snapshot
+	res.Answers = append([]int{}, s.snapshot.Answers...)
+	return res
+}
@@ -0,0 +1,47 @@
+package overlay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+func TestWatch(t *testing.T) {
+	bus := kahoot.NewBus()
+	s := NewServer()
+	s.Texts = []string{"What is 2+2?"}
+	s.Translations = []string{"Was ist 2+2?"}
+	sub := bus.Subscribe(kahoot.TopicQuestion)
+	done := make(chan struct{})
+	go func() {
+		s.watch(sub)
+		close(done)
+	}()
+
+	received := time.Unix(1000, 0)
+	bus.Publish(kahoot.TopicQuestion, "answers", &kahoot.QuizAction{
+		Type:       kahoot.QuestionAnswers,
+		NumAnswers: 4,
+		Index:      0,
+		Received:   received,
+		TimeLeft:   time.Second * 20,
+	})
+	bus.Publish(kahoot.TopicQuestion, "answered", &swarm.Answer{Index: 0, Choice: 2})
+	bus.Publish(kahoot.TopicQuestion, "answered", &swarm.Answer{Index: 0, Choice: 2})
+	bus.Publish(kahoot.TopicQuestion, "answered", &swarm.Answer{Index: 0, Choice: 1})
+	bus.Close()
+	<-done
+
+	snap := s.Snapshot()
+	if snap.Question != 1 || snap.Text != "What is 2+2?" || snap.Translation != "Was ist 2+2?" || snap.Answered != 3 {
+		t.Errorf("unexpected snapshot: %+v", snap)
+	}
+	if snap.CountdownEnd != 1020000 {
+		t.Errorf("unexpected countdown end: %d", snap.CountdownEnd)
+	}
+	if len(snap.Answers) != 4 || snap.Answers[1] != 1 || snap.Answers[2] != 2 {
+		t.Errorf("unexpected answers: %v", snap.Answers)
+	}
+}
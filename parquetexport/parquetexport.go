@@ -0,0 +1,143 @@
+// Package parquetexport converts run workspaces into Parquet
+// datasets which pandas, DuckDB and similar tools can read
+// directly.
+//
+// Files are laid out in Hive-style partitions:
+//
+//	OUT/events/run_id=RUN/question_index=N/part-0.parquet
+//	OUT/results/run_id=RUN/question_index=N/part-0.parquet
+//
+// Events which happen before the first question are filed
+// under question_index=-1.
+package parquetexport
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+// An EventRow is one recorded event.
+type EventRow struct {
+	Bot   string    `parquet:"bot"`
+	Seq   int64     `parquet:"seq"`
+	Time  time.Time `parquet:"time,timestamp(millisecond)"`
+	Topic string    `parquet:"topic"`
+	Type  string    `parquet:"type"`
+	Data  string    `parquet:"data,optional"`
+}
+
+// A ResultRow is one bot's result for one question.
+type ResultRow struct {
+	Bot        string  `parquet:"bot"`
+	Choice     int32   `parquet:"choice"`
+	IsCorrect  bool    `parquet:"is_correct"`
+	Points     float64 `parquet:"points"`
+	TotalScore float64 `parquet:"total_score"`
+	Rank       int32   `parquet:"rank"`
+}
+
+type partition struct {
+	events  []EventRow
+	results []ResultRow
+}
+
+// ExportRun writes the recordings of one workspace directory
+// into the dataset at outDir. The run ID is the workspace
+// directory's name.
+func ExportRun(runDir, outDir string) error {
+	recordings, err := workspace.ReadRecordings(runDir)
+	if err != nil {
+		return err
+	}
+	runID := filepath.Base(filepath.Clean(runDir))
+
+	partitions := map[int]*partition{}
+	get := func(index int) *partition {
+		if p, ok := partitions[index]; ok {
+			return p
+		}
+		p := &partition{}
+		partitions[index] = p
+		return p
+	}
+
+	var bots []string
+	for bot := range recordings {
+		bots = append(bots, bot)
+	}
+	sort.Strings(bots)
+	for _, bot := range bots {
+		index := -1
+		for _, e := range recordings[bot] {
+			if e.Topic == kahoot.TopicQuestion {
+				var action struct{ Index *int }
+				if json.Unmarshal(e.Data, &action) == nil && action.Index != nil {
+					index = *action.Index
+				}
+			}
+			p := get(index)
+			row := EventRow{
+				Bot:   bot,
+				Seq:   int64(e.Seq),
+				Time:  e.Time,
+				Topic: string(e.Topic),
+				Type:  e.Type,
+				Data:  string(e.Data),
+			}
+			p.events = append(p.events, row)
+			if e.Topic == kahoot.TopicResult && e.Type == "result" {
+				var result kahoot.QuizResult
+				if json.Unmarshal(e.Data, &result) == nil {
+					p.results = append(p.results, ResultRow{
+						Bot:        bot,
+						Choice:     int32(result.Choice),
+						IsCorrect:  result.IsCorrect,
+						Points:     result.Points,
+						TotalScore: result.TotalScore,
+						Rank:       int32(result.Rank),
+					})
+				}
+			}
+		}
+	}
+
+	for index, p := range partitions {
+		dir := filepath.Join("run_id="+runID, "question_index="+strconv.Itoa(index))
+		path, err := partitionFile(outDir, "events", dir)
+		if err != nil {
+			return err
+		}
+		if err := parquet.WriteFile(path, p.events); err != nil {
+			return err
+		}
+		if len(p.results) == 0 {
+			continue
+		}
+		path, err = partitionFile(outDir, "results", dir)
+		if err != nil {
+			return err
+		}
+		if err := parquet.WriteFile(path, p.results); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// partitionFile creates a partition's directory and returns
+// the path of its data file.
+func partitionFile(outDir, table, partition string) (string, error) {
+	dir := filepath.Join(outDir, table, partition)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "part-0.parquet"), nil
+}
@@ -0,0 +1,64 @@
+package parquetexport
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+func TestExportRun(t *testing.T) {
+	root, err := ioutil.TempDir("", "parquetexport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	ws, err := workspace.Create(root, "flood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	bus := kahoot.NewBus()
+	sub := bus.Subscribe()
+	bus.Publish(kahoot.TopicConnection, "login", kahoot.Player{Nickname: "alex"})
+	bus.Publish(kahoot.TopicQuestion, "intro", &kahoot.QuizAction{Index: 0})
+	bus.Publish(kahoot.TopicResult, "result", &kahoot.QuizResult{IsCorrect: true, Points: 900})
+	bus.Publish(kahoot.TopicQuestion, "intro", &kahoot.QuizAction{Index: 1})
+	bus.Close()
+	if err := ws.Record("alex", sub); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(root, "dataset")
+	if err := ExportRun(ws.Dir, out); err != nil {
+		t.Fatal(err)
+	}
+	runPart := "run_id=" + filepath.Base(ws.Dir)
+
+	events, err := parquet.ReadFile[EventRow](filepath.Join(out, "events", runPart,
+		"question_index=0", "part-0.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Type != "result" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+	results, err := parquet.ReadFile[ResultRow](filepath.Join(out, "results", runPart,
+		"question_index=0", "part-0.parquet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].IsCorrect || results[0].Points != 900 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	for _, part := range []string{"question_index=-1", "question_index=1"} {
+		if _, err := os.Stat(filepath.Join(out, "events", runPart, part)); err != nil {
+			t.Error(err)
+		}
+	}
+}
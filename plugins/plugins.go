@@ -0,0 +1,207 @@
+// Package plugins loads extensions from external binaries
+// using hashicorp/go-plugin, so that strategies, challenge
+// solvers, event sinks and answer providers can be shipped
+// separately from this repository.
+//
+// A plugin is a program which calls Serve with the extensions
+// it implements:
+//
+//	func main() {
+//		plugins.Serve(&plugins.ServeConfig{Strategy: myStrategy{}})
+//	}
+//
+// The host runs it with Load and installs its extensions with
+// Register.
+package plugins
+
+import (
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// A Strategy chooses the on-screen index of the answer a bot
+// should submit.
+type Strategy interface {
+	Choose(bot string, action *kahoot.QuizAction) (int, error)
+}
+
+// A ChallengeSolver computes the mask for a session challenge
+// which the built-in solver does not understand.
+type ChallengeSolver interface {
+	Solve(challenge string) (string, error)
+}
+
+// A Sink receives every event of every bot.
+type Sink interface {
+	Record(bot string, e kahoot.Event) error
+}
+
+// An AnswerProvider knows the correct answers of a game.
+// Answer returns the correct answer's index in the quiz (not
+// on screen), or ok=false if it does not know.
+type AnswerProvider interface {
+	Answer(gamePin string, question int) (index int, ok bool, err error)
+}
+
+// Handshake must match between host and plugin.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "KAHOOT_HACK_PLUGIN",
+	MagicCookieValue: "extension",
+}
+
+const (
+	strategyKind = "strategy"
+	solverKind   = "solver"
+	sinkKind     = "sink"
+	answersKind  = "answers"
+)
+
+// ServeConfig lists the extensions a plugin implements.
+// Unused extensions are left nil.
+type ServeConfig struct {
+	Strategy Strategy
+	Solver   ChallengeSolver
+	Sink     Sink
+	Answers  AnswerProvider
+}
+
+// Serve runs a plugin. It is called from the plugin's main
+// function and does not return.
+func Serve(c *ServeConfig) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         pluginMap(c),
+	})
+}
+
+func pluginMap(c *ServeConfig) map[string]plugin.Plugin {
+	if c == nil {
+		c = &ServeConfig{}
+	}
+	return map[string]plugin.Plugin{
+		strategyKind: &strategyPlugin{impl: c.Strategy},
+		solverKind:   &solverPlugin{impl: c.Solver},
+		sinkKind:     &sinkPlugin{impl: c.Sink},
+		answersKind:  &answersPlugin{impl: c.Answers},
+	}
+}
+
+// A Plugin is a running plugin binary.
+// Extensions it does not implement are nil.
+type Plugin struct {
+	// Name is the binary's name without extension. Register
+	// uses it to name strategies.
+	Name string
+
+	Strategy Strategy
+	Solver   ChallengeSolver
+	Sink     Sink
+	Answers  AnswerProvider
+
+	client *plugin.Client
+}
+
+// Load starts a plugin binary and connects to it.
+func Load(path string) (*Plugin, error) {
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          pluginMap(nil),
+		Cmd:              exec.Command(path),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC},
+	})
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+	name := filepath.Base(path)
+	p, err := dispense(strings.TrimSuffix(name, filepath.Ext(name)), rpcClient)
+	if err != nil {
+		client.Kill()
+		return nil, err
+	}
+	p.client = client
+	return p, nil
+}
+
+func dispense(name string, c plugin.ClientProtocol) (*Plugin, error) {
+	p := &Plugin{Name: name}
+	for _, kind := range []string{strategyKind, solverKind, sinkKind, answersKind} {
+		raw, err := c.Dispense(kind)
+		if err != nil {
+			return nil, err
+		}
+		switch ext := raw.(type) {
+		case *strategyClient:
+			if ext.implemented() {
+				p.Strategy = ext
+			}
+		case *solverClient:
+			if ext.implemented() {
+				p.Solver = ext
+			}
+		case *sinkClient:
+			if ext.implemented() {
+				p.Sink = ext
+			}
+		case *answersClient:
+			if ext.implemented() {
+				p.Answers = ext
+			}
+		}
+	}
+	if p.Strategy == nil && p.Solver == nil && p.Sink == nil && p.Answers == nil {
+		return nil, errors.New("plugin " + name + " implements no extensions")
+	}
+	return p, nil
+}
+
+// Register installs the plugin's strategy as swarm strategy
+// Name, its answer provider as strategy Name+"-answers", and
+// its challenge solver as the kahoot package's solver.
+//
+// It should be called before any swarm starts playing.
+// The sink is not registered anywhere; callers feed it.
+func (p *Plugin) Register(gamePin string) {
+	if p.Strategy != nil {
+		swarm.Strategies[p.Name] = func(bot *swarm.Bot, action *kahoot.QuizAction) int {
+			choice, err := p.Strategy.Choose(bot.Nickname, action)
+			if err != nil || choice < 0 || choice >= action.NumAnswers {
+				if action.NumAnswers <= 0 {
+					return -1
+				}
+				return bot.Intn(action.NumAnswers)
+			}
+			return choice
+		}
+	}
+	if p.Answers != nil {
+		swarm.Strategies[p.Name+"-answers"] = func(bot *swarm.Bot, action *kahoot.QuizAction) int {
+			index, ok, err := p.Answers.Answer(gamePin, action.Index)
+			if err == nil && ok {
+				return action.ScreenChoice(index)
+			}
+			if action.NumAnswers <= 0 {
+				return -1
+			}
+			return bot.Intn(action.NumAnswers)
+		}
+	}
+	if p.Solver != nil {
+		kahoot.SetChallengeSolver(p.Solver.Solve)
+	}
+}
+
+// Close stops the plugin process.
+func (p *Plugin) Close() {
+	if p.client != nil {
+		p.client.Kill()
+	}
+}
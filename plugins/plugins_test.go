@@ -0,0 +1,73 @@
+package plugins
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+type lastStrategy struct{}
+
+func (lastStrategy) Choose(bot string, action *kahoot.QuizAction) (int, error) {
+	return action.NumAnswers - 1, nil
+}
+
+type recordingSink struct {
+	events []kahoot.Event
+}
+
+func (r *recordingSink) Record(bot string, e kahoot.Event) error {
+	if bot != "alex" {
+		return errors.New("unexpected bot " + bot)
+	}
+	r.events = append(r.events, e)
+	return nil
+}
+
+func TestDispense(t *testing.T) {
+	sink := &recordingSink{}
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(&ServeConfig{
+		Strategy: lastStrategy{},
+		Sink:     sink,
+	}), nil)
+	defer client.Close()
+
+	p, err := dispense("test", client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Strategy == nil || p.Sink == nil || p.Solver != nil || p.Answers != nil {
+		t.Fatalf("unexpected extensions: %+v", p)
+	}
+
+	choice, err := p.Strategy.Choose("alex", &kahoot.QuizAction{NumAnswers: 4})
+	if err != nil || choice != 3 {
+		t.Errorf("expected choice 3 but got %d (%v)", choice, err)
+	}
+
+	err = p.Sink.Record("alex", kahoot.Event{
+		Topic: kahoot.TopicResult,
+		Type:  "result",
+		Data:  &kahoot.QuizResult{Points: 500},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sink.events) != 1 {
+		t.Fatal("event was not delivered")
+	}
+	data, _ := sink.events[0].Data.(map[string]interface{})
+	if data["points"] != 500.0 {
+		t.Errorf("unexpected event data: %v", sink.events[0].Data)
+	}
+}
+
+func TestNoExtensions(t *testing.T) {
+	client, _ := plugin.TestPluginRPCConn(t, pluginMap(nil), nil)
+	defer client.Close()
+	if _, err := dispense("empty", client); err == nil {
+		t.Error("expected an error for a plugin without extensions")
+	}
+}
@@ -0,0 +1,227 @@
+package plugins
+
+import (
+	"encoding/json"
+	"errors"
+	"net/rpc"
+	"time"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// errNotImplemented is returned by a plugin for extensions
+// it does not implement.
+var errNotImplemented = errors.New("extension not implemented")
+
+// The argument and reply types below are only exported
+// because net/rpc requires it.
+
+type ChooseArgs struct {
+	Bot    string
+	Action *kahoot.QuizAction
+}
+
+type RecordArgs struct {
+	Bot   string
+	Topic kahoot.Topic
+	Type  string
+	Time  time.Time
+	Seq   uint64
+
+	// Data is JSON, since event data can be of any type.
+	Data []byte
+}
+
+type AnswerArgs struct {
+	GamePin  string
+	Question int
+}
+
+type AnswerReply struct {
+	Index int
+	OK    bool
+}
+
+// Strategy
+
+type strategyPlugin struct{ impl Strategy }
+
+func (p *strategyPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &strategyServer{p.impl}, nil
+}
+
+func (p *strategyPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &strategyClient{c}, nil
+}
+
+type strategyServer struct{ impl Strategy }
+
+func (s *strategyServer) Implemented(args struct{}, reply *bool) error {
+	*reply = s.impl != nil
+	return nil
+}
+
+func (s *strategyServer) Choose(args ChooseArgs, reply *int) error {
+	if s.impl == nil {
+		return errNotImplemented
+	}
+	var err error
+	*reply, err = s.impl.Choose(args.Bot, args.Action)
+	return err
+}
+
+type strategyClient struct{ c *rpc.Client }
+
+func (s *strategyClient) implemented() bool {
+	var res bool
+	return s.c.Call("Plugin.Implemented", struct{}{}, &res) == nil && res
+}
+
+func (s *strategyClient) Choose(bot string, action *kahoot.QuizAction) (int, error) {
+	var res int
+	err := s.c.Call("Plugin.Choose", ChooseArgs{Bot: bot, Action: action}, &res)
+	return res, err
+}
+
+// ChallengeSolver
+
+type solverPlugin struct{ impl ChallengeSolver }
+
+func (p *solverPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &solverServer{p.impl}, nil
+}
+
+func (p *solverPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &solverClient{c}, nil
+}
+
+type solverServer struct{ impl ChallengeSolver }
+
+func (s *solverServer) Implemented(args struct{}, reply *bool) error {
+	*reply = s.impl != nil
+	return nil
+}
+
+func (s *solverServer) Solve(challenge string, reply *string) error {
+	if s.impl == nil {
+		return errNotImplemented
+	}
+	var err error
+	*reply, err = s.impl.Solve(challenge)
+	return err
+}
+
+type solverClient struct{ c *rpc.Client }
+
+func (s *solverClient) implemented() bool {
+	var res bool
+	return s.c.Call("Plugin.Implemented", struct{}{}, &res) == nil && res
+}
+
+func (s *solverClient) Solve(challenge string) (string, error) {
+	var res string
+	err := s.c.Call("Plugin.Solve", challenge, &res)
+	return res, err
+}
+
+// Sink
+
+type sinkPlugin struct{ impl Sink }
+
+func (p *sinkPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &sinkServer{p.impl}, nil
+}
+
+func (p *sinkPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &sinkClient{c}, nil
+}
+
+type sinkServer struct{ impl Sink }
+
+func (s *sinkServer) Implemented(args struct{}, reply *bool) error {
+	*reply = s.impl != nil
+	return nil
+}
+
+func (s *sinkServer) Record(args RecordArgs, reply *struct{}) error {
+	if s.impl == nil {
+		return errNotImplemented
+	}
+	var data interface{}
+	if len(args.Data) > 0 {
+		if err := json.Unmarshal(args.Data, &data); err != nil {
+			return err
+		}
+	}
+	return s.impl.Record(args.Bot, kahoot.Event{
+		Topic: args.Topic,
+		Type:  args.Type,
+		Time:  args.Time,
+		Seq:   args.Seq,
+		Data:  data,
+	})
+}
+
+type sinkClient struct{ c *rpc.Client }
+
+func (s *sinkClient) implemented() bool {
+	var res bool
+	return s.c.Call("Plugin.Implemented", struct{}{}, &res) == nil && res
+}
+
+func (s *sinkClient) Record(bot string, e kahoot.Event) error {
+	data := e.Data
+	if err, ok := data.(error); ok {
+		data = err.Error()
+	}
+	args := RecordArgs{Bot: bot, Topic: e.Topic, Type: e.Type, Time: e.Time, Seq: e.Seq}
+	if data != nil {
+		var err error
+		if args.Data, err = json.Marshal(data); err != nil {
+			return err
+		}
+	}
+	return s.c.Call("Plugin.Record", args, &struct{}{})
+}
+
+// AnswerProvider
+
+type answersPlugin struct{ impl AnswerProvider }
+
+func (p *answersPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &answersServer{p.impl}, nil
+}
+
+func (p *answersPlugin) Client(b *plugin.MuxBroker, c *rpc.Client) (interface{}, error) {
+	return &answersClient{c}, nil
+}
+
+type answersServer struct{ impl AnswerProvider }
+
+func (s *answersServer) Implemented(args struct{}, reply *bool) error {
+	*reply = s.impl != nil
+	return nil
+}
+
+func (s *answersServer) Answer(args AnswerArgs, reply *AnswerReply) error {
+	if s.impl == nil {
+		return errNotImplemented
+	}
+	var err error
+	reply.Index, reply.OK, err = s.impl.Answer(args.GamePin, args.Question)
+	return err
+}
+
+type answersClient struct{ c *rpc.Client }
+
+func (s *answersClient) implemented() bool {
+	var res bool
+	return s.c.Call("Plugin.Implemented", struct{}{}, &res) == nil && res
+}
+
+func (s *answersClient) Answer(gamePin string, question int) (int, bool, error) {
+	var res AnswerReply
+	err := s.c.Call("Plugin.Answer", AnswerArgs{GamePin: gamePin, Question: question}, &res)
+	return res.Index, res.OK, err
+}
@@ -0,0 +1,343 @@
+// Package questionbank accumulates the questions observed in
+// recorded runs into a local bank, so that a quiz which was
+// played but is not owned can be rebuilt and exported.
+//
+// Players never see a question's text, only how many answers
+// it has and, once it ends, which choices were correct and
+// what they said. The bank keeps whatever was observed, merges
+// sightings of the same question across runs, and lets the
+// question text and tags be filled in by hand.
+package questionbank
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+// DefaultPath is where the bank is kept if no other file is
+// specified. It sits next to the run workspaces it is built
+// from.
+var DefaultPath = filepath.Join(workspace.DefaultRoot, "question-bank.json")
+
+// DefaultTime is the time limit given to exported questions,
+// in milliseconds, since players do not see the real one.
+const DefaultTime = 20000
+
+// A Question is everything observed about one question.
+type Question struct {
+	// Key identifies the question across runs. Questions with
+	// the same observed answer texts share a key; see key.
+	Key string `json:"key"`
+
+	// Text is the question itself, which has to be set by hand.
+	Text string `json:"text,omitempty"`
+
+	NumAnswers int `json:"numAnswers"`
+
+	// Choices are the answer texts seen so far, by choice
+	// index. Unseen choices are empty.
+	Choices []string `json:"choices"`
+
+	// Correct lists the choice indices known to be correct.
+	Correct []int `json:"correct,omitempty"`
+
+	Tags []string `json:"tags,omitempty"`
+
+	// Sightings lists the runs and question indices where the
+	// question was seen, as "run#index".
+	Sightings []string `json:"sightings"`
+}
+
+// HasTag reports whether the question has a tag.
+func (q *Question) HasTag(tag string) bool {
+	for _, t := range q.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag tags the question, unless it already has the tag.
+func (q *Question) AddTag(tag string) {
+	q.Tags = addString(q.Tags, tag)
+}
+
+// Known reports whether every choice's text and at least one
+// correct choice have been observed.
+func (q *Question) Known() bool {
+	for _, c := range q.Choices {
+		if c == "" {
+			return false
+		}
+	}
+	return len(q.Correct) > 0
+}
+
+func (q *Question) merge(o *Question) {
+	if q.Text == "" {
+		q.Text = o.Text
+	}
+	for i, c := range o.Choices {
+		if i < len(q.Choices) && q.Choices[i] == "" {
+			q.Choices[i] = c
+		}
+	}
+	for _, c := range o.Correct {
+		q.Correct = addInt(q.Correct, c)
+	}
+	for _, t := range o.Tags {
+		q.AddTag(t)
+	}
+	for _, s := range o.Sightings {
+		q.Sightings = addString(q.Sightings, s)
+	}
+}
+
+// A Bank is a collection of observed questions.
+type Bank struct {
+	Questions []*Question `json:"questions"`
+
+	// Runs lists the run directories already added, by name,
+	// so that adding a run twice does not count it twice.
+	Runs []string `json:"runs"`
+}
+
+// Load reads a bank from a file. A missing file is an empty
+// bank.
+func Load(path string) (*Bank, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Bank{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var b Bank
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse %s: %s", path, err)
+	}
+	return &b, nil
+}
+
+// Save writes the bank to a file.
+func (b *Bank) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Find returns the question with a key, or nil. A unique
+// prefix of the key is enough.
+func (b *Bank) Find(key string) *Question {
+	var res *Question
+	for _, q := range b.Questions {
+		if q.Key == key {
+			return q
+		} else if strings.HasPrefix(q.Key, key) {
+			if res != nil {
+				return nil
+			}
+			res = q
+		}
+	}
+	return res
+}
+
+// Tagged returns the questions with a tag, or every question
+// if the tag is empty.
+func (b *Bank) Tagged(tag string) []*Question {
+	var res []*Question
+	for _, q := range b.Questions {
+		if tag == "" || q.HasTag(tag) {
+			res = append(res, q)
+		}
+	}
+	return res
+}
+
+// AddRun adds the questions recorded in a run workspace. It
+// returns how many questions were new to the bank, and does
+// nothing if the run was added before.
+func (b *Bank) AddRun(dir string) (int, error) {
+	run := filepath.Base(filepath.Clean(dir))
+	for _, r := range b.Runs {
+		if r == run {
+			return 0, nil
+		}
+	}
+	recordings, err := workspace.ReadRecordings(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(recordings) == 0 {
+		return 0, errors.New("no recordings in " + dir)
+	}
+	b.Runs = append(b.Runs, run)
+	var added int
+	for _, q := range observe(run, recordings) {
+		if b.add(q) {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// add merges a question into the bank, reporting whether it
+// was new.
+func (b *Bank) add(q *Question) bool {
+	for _, old := range b.Questions {
+		if old.Key == q.Key {
+			old.merge(q)
+			return false
+		}
+	}
+	b.Questions = append(b.Questions, q)
+	return true
+}
+
+// observe collects the questions of one run from every bot's
+// recording.
+func observe(run string, recordings map[string][]workspace.RecordedEvent) []*Question {
+	byIndex := map[int]*Question{}
+	for _, events := range recordings {
+		index := -1
+		for _, e := range events {
+			switch e.Topic {
+			case kahoot.TopicQuestion:
+				var action struct {
+					Index      *int
+					NumAnswers int
+				}
+				if json.Unmarshal(e.Data, &action) != nil || action.Index == nil {
+					continue
+				}
+				index = *action.Index
+				if _, ok := byIndex[index]; !ok && action.NumAnswers > 0 {
+					byIndex[index] = &Question{
+						NumAnswers: action.NumAnswers,
+						Choices:    make([]string, action.NumAnswers),
+						Sightings:  []string{run + "#" + strconv.Itoa(index)},
+					}
+				}
+			case kahoot.TopicResult:
+				q := byIndex[index]
+				var result kahoot.QuizResult
+				if q == nil || e.Type != "result" || json.Unmarshal(e.Data, &result) != nil {
+					continue
+				}
+				if result.Choice >= 0 && result.Choice < q.NumAnswers && q.Choices[result.Choice] == "" {
+					q.Choices[result.Choice] = result.Text
+				}
+				if result.IsCorrect && result.Choice >= 0 && result.Choice < q.NumAnswers {
+					q.Correct = addInt(q.Correct, result.Choice)
+				}
+			}
+		}
+	}
+
+	var indices []int
+	for index := range byIndex {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	var res []*Question
+	for _, index := range indices {
+		q := byIndex[index]
+		q.Key = key(q)
+		res = append(res, q)
+	}
+	return res
+}
+
+// key identifies a question by its answer texts. Without any
+// texts there is nothing to recognize it by, so it gets a key
+// of its own.
+func key(q *Question) string {
+	var known bool
+	var parts []string
+	for _, c := range q.Choices {
+		c = strings.ToLower(strings.Join(strings.Fields(c), " "))
+		known = known || c != ""
+		parts = append(parts, c)
+	}
+	if !known {
+		parts = q.Sightings
+	}
+	sum := sha256.Sum256([]byte(strconv.Itoa(q.NumAnswers) + "\n" + strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Quiz builds a quiz for the quiz builder API out of
+// questions. Missing question and answer texts get
+// placeholders.
+func Quiz(title string, questions []*Question) *kahoot.QuizInfo {
+	quiz := &kahoot.QuizInfo{Title: title, Type: "quiz", QuizType: "quiz", Language: "English"}
+	for i, q := range questions {
+		text := q.Text
+		if text == "" {
+			text = "Question " + strconv.Itoa(i+1)
+		}
+		question := kahoot.QuizQuestion{
+			NumberOfAnswers: q.NumAnswers,
+			Question:        text,
+			Time:            DefaultTime,
+			Points:          true,
+			Type:            "quiz",
+		}
+		for j, c := range q.Choices {
+			if c == "" {
+				c = "Answer " + strconv.Itoa(j+1)
+			}
+			question.Choices = append(question.Choices, kahoot.QuizChoice{
+				Answer:  c,
+				Correct: hasInt(q.Correct, j),
+			})
+		}
+		quiz.Questions = append(quiz.Questions, question)
+	}
+	return quiz
+}
+
+func addInt(list []int, x int) []int {
+	if hasInt(list, x) {
+		return list
+	}
+	list = append(list, x)
+	sort.Ints(list)
+	return list
+}
+
+func hasInt(list []int, x int) bool {
+	for _, y := range list {
+		if x == y {
+			return true
+		}
+	}
+	return false
+}
+
+func addString(list []string, s string) []string {
+	for _, t := range list {
+		if t == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,134 @@
+package questionbank
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+func recordRun(t *testing.T, root string, results map[string][]*kahoot.QuizResult) string {
+	ws, err := workspace.Create(root, "flood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+	for bot, botResults := range results {
+		bus := kahoot.NewBus()
+		sub := bus.Subscribe()
+		for i, result := range botResults {
+			bus.Publish(kahoot.TopicQuestion, "intro", &kahoot.QuizAction{Index: i, NumAnswers: 4})
+			if result != nil {
+				bus.Publish(kahoot.TopicResult, "result", result)
+			}
+		}
+		bus.Close()
+		if err := ws.Record(bot, sub); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return ws.Dir
+}
+
+func TestAddRun(t *testing.T) {
+	root, err := ioutil.TempDir("", "questionbank")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	first := recordRun(t, root, map[string][]*kahoot.QuizResult{
+		"alex": {{Choice: 0, Text: "Paris", IsCorrect: true}, nil},
+		"bob":  {{Choice: 2, Text: "Rome"}},
+	})
+	second := recordRun(t, root, map[string][]*kahoot.QuizResult{
+		"alex": {{Choice: 0, Text: "Paris", IsCorrect: true}},
+	})
+
+	bank := &Bank{}
+	if added, err := bank.AddRun(first); err != nil {
+		t.Fatal(err)
+	} else if added != 2 {
+		t.Errorf("expected 2 new questions, got %d", added)
+	}
+	if added, err := bank.AddRun(first); err != nil || added != 0 {
+		t.Errorf("adding a run twice should do nothing, got %d %v", added, err)
+	}
+	if _, err := bank.AddRun(second); err != nil {
+		t.Fatal(err)
+	}
+	if len(bank.Questions) != 3 {
+		t.Fatalf("expected 3 questions, got %d", len(bank.Questions))
+	}
+
+	q := bank.Questions[0]
+	if !reflect.DeepEqual(q.Choices, []string{"Paris", "", "Rome", ""}) {
+		t.Errorf("unexpected choices %q", q.Choices)
+	}
+	if !reflect.DeepEqual(q.Correct, []int{0}) {
+		t.Errorf("unexpected correct choices %v", q.Correct)
+	}
+	if bank.Questions[1].Key == bank.Questions[2].Key {
+		t.Error("questions without any texts should not be merged")
+	}
+	if len(bank.Questions[2].Sightings) != 1 {
+		t.Errorf("unexpected sightings %v", bank.Questions[2].Sightings)
+	}
+
+	path := filepath.Join(root, "bank.json")
+	q.AddTag("geography")
+	q.Text = "Capital of France?"
+	if err := bank.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, bank) {
+		t.Error("bank changed after saving and loading")
+	}
+	if tagged := loaded.Tagged("geography"); len(tagged) != 1 || tagged[0].Key != q.Key {
+		t.Errorf("unexpected tagged questions %v", tagged)
+	}
+	if loaded.Find(q.Key[:4]) == nil {
+		t.Error("a key prefix should find the question")
+	}
+}
+
+func TestMergeSightings(t *testing.T) {
+	bank := &Bank{}
+	q := func(run string, choices ...string) *Question {
+		res := &Question{NumAnswers: 2, Choices: choices, Sightings: []string{run + "#0"}}
+		res.Key = key(res)
+		return res
+	}
+	bank.add(q("a", "Yes", "No"))
+	if bank.add(q("b", "yes ", "no")) {
+		t.Error("answer texts differing only in case and spacing should merge")
+	}
+	if len(bank.Questions[0].Sightings) != 2 {
+		t.Errorf("unexpected sightings %v", bank.Questions[0].Sightings)
+	}
+}
+
+func TestQuiz(t *testing.T) {
+	quiz := Quiz("Observed", []*Question{
+		{NumAnswers: 2, Choices: []string{"Yes", ""}, Correct: []int{0}},
+	})
+	if len(quiz.Questions) != 1 {
+		t.Fatal("expected one question")
+	}
+	q := quiz.Questions[0]
+	if q.Question != "Question 1" || q.Time != DefaultTime {
+		t.Errorf("unexpected question %+v", q)
+	}
+	expected := []kahoot.QuizChoice{{Answer: "Yes", Correct: true}, {Answer: "Answer 2"}}
+	if !reflect.DeepEqual(q.Choices, expected) {
+		t.Errorf("unexpected choices %+v", q.Choices)
+	}
+}
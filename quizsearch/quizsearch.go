@@ -0,0 +1,117 @@
+// Package quizsearch finds public quizzes on Kahoot and the
+// correct answers to their questions.
+//
+// A game does not say which quiz it is playing, but every
+// question carries the number of choices of all of the quiz's
+// questions. Find searches by title and keeps the candidates
+// whose questions have the same numbers of choices.
+package quizsearch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// DefaultLimit is the number of search results Find checks.
+const DefaultLimit = 25
+
+// ErrNoMatch is returned by Find when no search result has the
+// expected questions.
+var ErrNoMatch = errors.New("no matching quiz found")
+
+// A Result is a quiz listed by a search.
+type Result struct {
+	Uuid      string `json:"uuid"`
+	Title     string `json:"title"`
+	Creator   string `json:"creator_username"`
+	Questions int    `json:"number_of_questions"`
+}
+
+// Search lists public quizzes matching the query, at most
+// limit of them.
+func Search(query string, limit int) ([]Result, error) {
+	q := url.Values{
+		"query":  {query},
+		"limit":  {strconv.Itoa(limit)},
+		"cursor": {"0"},
+	}
+	var res struct {
+		Entities []struct {
+			Card Result `json:"card"`
+		} `json:"entities"`
+	}
+	if err := getJSON(kahoot.CurrentProtocol().CreatorURL+"kahoots/?"+q.Encode(), &res); err != nil {
+		return nil, fmt.Errorf("search quizzes: %s", err)
+	}
+	var results []Result
+	for _, e := range res.Entities {
+		results = append(results, e.Card)
+	}
+	return results, nil
+}
+
+// Fetch downloads a public quiz, including its answers.
+func Fetch(uuid string) (*kahoot.QuizInfo, error) {
+	return kahoot.PublicQuiz(uuid)
+}
+
+// Find searches for the quiz being played, given its title or
+// other search terms and the AnswerCounts of one of its
+// questions. If answerCounts is empty, the first search result
+// is taken.
+func Find(query string, answerCounts []int) (*kahoot.QuizInfo, error) {
+	results, err := Search(query, DefaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range results {
+		if len(answerCounts) > 0 && r.Questions != 0 && r.Questions != len(answerCounts) {
+			continue
+		}
+		quiz, err := Fetch(r.Uuid)
+		if err != nil {
+			return nil, err
+		}
+		if quiz.Matches(answerCounts) {
+			return quiz, nil
+		}
+	}
+	return nil, ErrNoMatch
+}
+
+// CorrectAnswers returns the indices of the correct choices
+// of each question. Questions without a correct choice, such
+// as polls, get none.
+func CorrectAnswers(quiz *kahoot.QuizInfo) [][]int {
+	res := make([][]int, len(quiz.Questions))
+	for i, q := range quiz.Questions {
+		for j, c := range q.Choices {
+			if c.Correct {
+				res[i] = append(res[i], j)
+			}
+		}
+	}
+	return res
+}
+
+func getJSON(u string, v interface{}) error {
+	if err := kahoot.TakeRequest(); err != nil {
+		return err
+	}
+	resp, err := kahoot.HTTPClient.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(resp.Status)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, kahoot.MaxResponseSize)).Decode(v)
+}
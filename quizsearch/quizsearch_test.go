@@ -0,0 +1,63 @@
+package quizsearch
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestFind(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/create/kahoots/":
+			if r.URL.Query().Get("query") != "capitals" {
+				t.Errorf("unexpected query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"entities": [
+				{"card": {"uuid": "short", "title": "Capitals", "number_of_questions": 1}},
+				{"card": {"uuid": "wrong", "title": "Capitals 2", "number_of_questions": 2}},
+				{"card": {"uuid": "right", "title": "Capitals 3", "number_of_questions": 2}}
+			]}`))
+		case "/play/kahoots/short":
+			t.Error("fetched a quiz with the wrong number of questions")
+		case "/play/kahoots/wrong":
+			w.Write([]byte(`{"title": "Capitals 2", "questions": [
+				{"choices": [{"answer": "a"}, {"answer": "b", "correct": true}]},
+				{"choices": [{"answer": "a"}, {"answer": "b"}]}
+			]}`))
+		case "/play/kahoots/right":
+			w.Write([]byte(`{"title": "Capitals 3", "questions": [
+				{"choices": [{"answer": "a"}, {"answer": "b", "correct": true}]},
+				{"choices": [{"answer": "a", "correct": true}, {"answer": "b"}, {"answer": "c"}]}
+			]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	old := kahoot.CurrentProtocol()
+	defer kahoot.SetProtocol(&old)
+	p := kahoot.CurrentProtocol()
+	p.CreatorURL = server.URL + "/create/"
+	p.PlayURL = server.URL + "/play/"
+	if err := kahoot.SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+
+	quiz, err := Find("capitals", []int{2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quiz.Title != "Capitals 3" {
+		t.Fatalf("found the wrong quiz: %s", quiz.Title)
+	}
+	if answers := CorrectAnswers(quiz); !reflect.DeepEqual(answers, [][]int{{1}, {0}}) {
+		t.Errorf("unexpected answers %v", answers)
+	}
+	if _, err := Find("capitals", []int{4, 4}); err != ErrNoMatch {
+		t.Errorf("expected ErrNoMatch but got %v", err)
+	}
+}
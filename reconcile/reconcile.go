@@ -0,0 +1,355 @@
+// Package reconcile checks a run's own records of the answers
+// its bots sent against the host's report of the same game,
+// to find answers which were lost on the way or arrived much
+// later than the bots think they sent them.
+//
+// Kahoot exports reports as spreadsheets. Save the sheet with
+// every player's answers as CSV; ReadHostReport finds its
+// columns by name.
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+// DefaultTolerance is how far the bots' and the host's answer
+// times may differ before the difference is reported.
+const DefaultTolerance = time.Second
+
+// Statuses of a reconciled answer.
+const (
+	// StatusMatched means both sides agree.
+	StatusMatched = "matched"
+
+	// StatusLost means the server acknowledged the answer but
+	// the host did not record it.
+	StatusLost = "lost"
+
+	// StatusUnacked means the answer was written but not
+	// acknowledged, and the host did not record it either.
+	StatusUnacked = "unacknowledged"
+
+	// StatusRecoveredUnacked means the answer was not
+	// acknowledged, yet the host recorded it.
+	StatusRecoveredUnacked = "recorded without ack"
+
+	// StatusUnexpected means the host recorded an answer
+	// which the bot has no record of sending.
+	StatusUnexpected = "not sent"
+
+	// StatusDifferentAnswer means the host recorded another
+	// answer than the one the bot says it got credit for.
+	StatusDifferentAnswer = "different answer"
+
+	// StatusTiming means the answer times differ by more than
+	// the tolerance.
+	StatusTiming = "timing"
+)
+
+// A HostAnswer is one row of the host's report.
+type HostAnswer struct {
+	Player string
+
+	// Question is zero-based, unlike in the report.
+	Question int
+
+	// Answer is the answer text, if the report has it.
+	Answer string
+
+	// Time is the reported answer time, or -1 if the report
+	// has none.
+	Time time.Duration
+}
+
+// ReadHostReport parses a host report saved as CSV. It needs
+// a player column and a question number column; answer and
+// answer time columns are used if present.
+func ReadHostReport(r io.Reader) ([]HostAnswer, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read header: %s", err)
+	}
+	player := findColumn(header, "player", "nickname", "name")
+	question := findColumn(header, "question number", "question", "question #")
+	answer := findColumn(header, "answer", "answer text")
+	answerTime := findColumn(header, "answer time", "answer time (seconds)", "time")
+	if player < 0 || question < 0 {
+		return nil, errors.New("host report needs player and question columns")
+	}
+
+	var res []HostAnswer
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return res, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if player >= len(record) || question >= len(record) || strings.TrimSpace(record[player]) == "" {
+			continue
+		}
+		num, err := strconv.Atoi(strings.TrimSpace(record[question]))
+		if err != nil || num < 1 {
+			return nil, fmt.Errorf("line %d: invalid question number %q", line, record[question])
+		}
+		a := HostAnswer{Player: strings.TrimSpace(record[player]), Question: num - 1, Time: -1}
+		if answer >= 0 && answer < len(record) {
+			a.Answer = strings.TrimSpace(record[answer])
+		}
+		if answerTime >= 0 && answerTime < len(record) {
+			value := strings.TrimSuffix(strings.TrimSpace(record[answerTime]), "s")
+			if seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				a.Time = time.Duration(seconds * float64(time.Second))
+			}
+		}
+		res = append(res, a)
+	}
+}
+
+func findColumn(header []string, names ...string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// A BotAnswer is a bot's own record of an answer.
+type BotAnswer struct {
+	Bot      string
+	Question int
+	Choice   int
+	Acked    bool
+
+	// Text is the answer text from the question's result, if
+	// the bot saw one.
+	Text string
+
+	// Time is how long after the question opened the answer
+	// was sent, or -1 if the bot did not see it open.
+	Time time.Duration
+}
+
+// ReadBotAnswers collects the answers recorded in a run
+// workspace.
+func ReadBotAnswers(dir string) ([]BotAnswer, error) {
+	recordings, err := workspace.ReadRecordings(dir)
+	if err != nil {
+		return nil, err
+	}
+	var res []BotAnswer
+	for bot, events := range recordings {
+		opened := map[int]time.Time{}
+		byQuestion := map[int]int{}
+		index := -1
+		for _, e := range events {
+			switch {
+			case e.Topic == kahoot.TopicQuestion && e.Type == "answers":
+				var action kahoot.QuizAction
+				if json.Unmarshal(e.Data, &action) == nil {
+					index = action.Index
+					opened[index] = e.Time
+				}
+			case e.Topic == kahoot.TopicQuestion && (e.Type == "sent" || e.Type == "unacked"):
+				var sent kahoot.SentAnswer
+				if json.Unmarshal(e.Data, &sent) != nil {
+					continue
+				}
+				a := BotAnswer{
+					Bot:      bot,
+					Question: sent.Index,
+					Choice:   sent.Choice,
+					Acked:    e.Type == "sent",
+					Time:     -1,
+				}
+				if t, ok := opened[sent.Index]; ok {
+					a.Time = sent.Sent.Sub(t)
+				}
+				byQuestion[sent.Index] = len(res)
+				res = append(res, a)
+			case e.Topic == kahoot.TopicResult && e.Type == "result":
+				var result kahoot.QuizResult
+				if json.Unmarshal(e.Data, &result) != nil {
+					continue
+				}
+				if i, ok := byQuestion[index]; ok {
+					res[i].Text = result.Text
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// A Row is the reconciliation of one bot's answer to one
+// question.
+type Row struct {
+	Bot      string
+	Question int
+	Status   string
+
+	// BotAnswer and HostAnswer are the two sides' records,
+	// either of which may be nil.
+	BotAnswer  *BotAnswer
+	HostAnswer *HostAnswer
+}
+
+// A Report is the outcome of reconciling a run.
+type Report struct {
+	Counts map[string]int
+	Rows   []Row
+}
+
+// Reconcile matches the bots' answers to the host's. Host
+// answers from players which are not bots of the run are
+// ignored.
+func Reconcile(bots []BotAnswer, host []HostAnswer, tolerance time.Duration) *Report {
+	type key struct {
+		player   string
+		question int
+	}
+	botNames := map[string]bool{}
+	botAnswers := map[key]*BotAnswer{}
+	for i := range bots {
+		a := &bots[i]
+		botNames[a.Bot] = true
+		k := key{a.Bot, a.Question}
+		if old, ok := botAnswers[k]; !ok || !old.Acked {
+			botAnswers[k] = a
+		}
+	}
+	hostAnswers := map[key]*HostAnswer{}
+	for i := range host {
+		a := &host[i]
+		if botNames[a.Player] {
+			hostAnswers[key{a.Player, a.Question}] = a
+		}
+	}
+
+	report := &Report{Counts: map[string]int{}}
+	add := func(k key, b *BotAnswer, h *HostAnswer) {
+		row := Row{Bot: k.player, Question: k.question, BotAnswer: b, HostAnswer: h}
+		row.Status = status(b, h, tolerance)
+		report.Counts[row.Status]++
+		report.Rows = append(report.Rows, row)
+	}
+	for k, b := range botAnswers {
+		add(k, b, hostAnswers[k])
+	}
+	for k, h := range hostAnswers {
+		if _, ok := botAnswers[k]; !ok {
+			add(k, nil, h)
+		}
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		r1, r2 := report.Rows[i], report.Rows[j]
+		if r1.Question != r2.Question {
+			return r1.Question < r2.Question
+		}
+		return r1.Bot < r2.Bot
+	})
+	return report
+}
+
+func status(b *BotAnswer, h *HostAnswer, tolerance time.Duration) string {
+	switch {
+	case b == nil:
+		return StatusUnexpected
+	case h == nil && b.Acked:
+		return StatusLost
+	case h == nil:
+		return StatusUnacked
+	case !b.Acked:
+		return StatusRecoveredUnacked
+	case b.Text != "" && h.Answer != "" && !strings.EqualFold(b.Text, h.Answer):
+		return StatusDifferentAnswer
+	case b.Time >= 0 && h.Time >= 0 && absDuration(b.Time-h.Time) > tolerance:
+		return StatusTiming
+	}
+	return StatusMatched
+}
+
+func absDuration(d time.Duration) time.Duration {
+	return time.Duration(math.Abs(float64(d)))
+}
+
+// Discrepancies returns the rows which did not match.
+func (r *Report) Discrepancies() []Row {
+	var res []Row
+	for _, row := range r.Rows {
+		if row.Status != StatusMatched {
+			res = append(res, row)
+		}
+	}
+	return res
+}
+
+// WriteText prints the counts of each status, followed by
+// every discrepancy.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, s := range []string{StatusMatched, StatusLost, StatusUnacked, StatusRecoveredUnacked,
+		StatusUnexpected, StatusDifferentAnswer, StatusTiming} {
+		fmt.Fprintf(tw, "%s\t%d\n", s, r.Counts[s])
+	}
+	discrepancies := r.Discrepancies()
+	if len(discrepancies) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "question\tbot\tstatus\tbot answer\thost answer\tbot time\thost time")
+	}
+	for _, row := range discrepancies {
+		botAnswer, hostAnswer, botTime, hostTime := "-", "-", "-", "-"
+		if b := row.BotAnswer; b != nil {
+			botAnswer = strconv.Itoa(b.Choice)
+			if b.Text != "" {
+				botAnswer += " (" + b.Text + ")"
+			}
+			botTime = formatTime(b.Time)
+		}
+		if h := row.HostAnswer; h != nil {
+			if h.Answer != "" {
+				hostAnswer = h.Answer
+			}
+			hostTime = formatTime(h.Time)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", row.Question+1, row.Bot, row.Status,
+			botAnswer, hostAnswer, botTime, hostTime)
+	}
+	return tw.Flush()
+}
+
+func formatTime(d time.Duration) string {
+	if d < 0 {
+		return "-"
+	}
+	return d.Round(time.Millisecond).String()
+}
+
+// ReadHostReportFile is ReadHostReport for a file.
+func ReadHostReportFile(path string) ([]HostAnswer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadHostReport(f)
+}
@@ -0,0 +1,118 @@
+package reconcile
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/workspace"
+)
+
+func TestReadHostReport(t *testing.T) {
+	csv := "Player,Question Number,Answer,Answer Time (seconds)\n" +
+		"alex,1,Paris,2.5\n" +
+		"bob,2,Rome,\n" +
+		",3,,\n"
+	answers, err := ReadHostReport(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []HostAnswer{
+		{Player: "alex", Question: 0, Answer: "Paris", Time: 2500 * time.Millisecond},
+		{Player: "bob", Question: 1, Answer: "Rome", Time: -1},
+	}
+	if len(answers) != len(expected) {
+		t.Fatalf("expected %d answers, got %+v", len(expected), answers)
+	}
+	for i, a := range answers {
+		if a != expected[i] {
+			t.Errorf("answer %d: expected %+v but got %+v", i, expected[i], a)
+		}
+	}
+	if _, err := ReadHostReport(strings.NewReader("Score,Rank\n")); err == nil {
+		t.Error("expected an error without player and question columns")
+	}
+}
+
+func TestReadBotAnswers(t *testing.T) {
+	root, err := ioutil.TempDir("", "reconcile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	ws, err := workspace.Create(root, "flood")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	bus := kahoot.NewBus()
+	sub := bus.Subscribe()
+	bus.Publish(kahoot.TopicQuestion, "answers", &kahoot.QuizAction{Type: kahoot.QuestionAnswers, Index: 0})
+	bus.Publish(kahoot.TopicQuestion, "sent", &kahoot.SentAnswer{Index: 0, Choice: 2, Sent: time.Now()})
+	bus.Publish(kahoot.TopicResult, "result", &kahoot.QuizResult{Choice: 2, Text: "Paris"})
+	bus.Publish(kahoot.TopicQuestion, "unacked", &kahoot.SentAnswer{Index: 1, Choice: 0})
+	bus.Close()
+	ws.Record("alex", sub)
+
+	answers, err := ReadBotAnswers(ws.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 2 {
+		t.Fatalf("expected 2 answers, got %+v", answers)
+	}
+	if a := answers[0]; !a.Acked || a.Choice != 2 || a.Text != "Paris" || a.Time < 0 {
+		t.Errorf("unexpected first answer %+v", a)
+	}
+	if a := answers[1]; a.Acked || a.Question != 1 || a.Time != -1 {
+		t.Errorf("unexpected second answer %+v", a)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	bots := []BotAnswer{
+		{Bot: "alex", Question: 0, Acked: true, Text: "Paris", Time: time.Second},
+		{Bot: "alex", Question: 1, Acked: true, Time: time.Second},
+		{Bot: "alex", Question: 2, Acked: true, Time: time.Second},
+		{Bot: "alex", Question: 3, Text: "Oslo", Time: -1},
+		{Bot: "bob", Question: 0, Acked: true, Text: "Rome", Time: -1},
+		{Bot: "bob", Question: 1, Time: -1},
+	}
+	host := []HostAnswer{
+		{Player: "alex", Question: 0, Answer: "paris", Time: 1200 * time.Millisecond},
+		{Player: "alex", Question: 2, Time: 4 * time.Second},
+		{Player: "alex", Question: 3, Time: -1},
+		{Player: "bob", Question: 0, Answer: "Paris", Time: -1},
+		{Player: "bob", Question: 2, Time: -1},
+		{Player: "student", Question: 0, Time: -1},
+	}
+	report := Reconcile(bots, host, DefaultTolerance)
+	expected := map[string]int{
+		StatusMatched:          1,
+		StatusLost:             1,
+		StatusTiming:           1,
+		StatusRecoveredUnacked: 1,
+		StatusDifferentAnswer:  1,
+		StatusUnacked:          1,
+		StatusUnexpected:       1,
+	}
+	for status, n := range expected {
+		if report.Counts[status] != n {
+			t.Errorf("expected %d %q, got %d", n, status, report.Counts[status])
+		}
+	}
+	if len(report.Rows) != 7 || len(report.Discrepancies()) != 6 {
+		t.Errorf("unexpected rows %+v", report.Rows)
+	}
+
+	var buf bytes.Buffer
+	report.WriteText(&buf)
+	if !strings.Contains(buf.String(), "different answer") {
+		t.Errorf("unexpected text:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron(t *testing.T) {
+	// 2024-01-01 was a Monday.
+	monday := time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local)
+	cases := []struct {
+		expr  string
+		t     time.Time
+		match bool
+	}{
+		{"* * * * *", monday, true},
+		{"0 2 * * *", monday, true},
+		{"0 2 * * *", monday.Add(time.Minute), false},
+		{"*/15 * * * *", monday.Add(45 * time.Minute), true},
+		{"*/15 * * * *", monday.Add(50 * time.Minute), false},
+		{"0 2 * * 1-5", monday, true},
+		{"0 2 * * 0,6", monday, false},
+		{"0 2 * * 7", monday.AddDate(0, 0, 6), true},
+		{"0 2 15 * 1", monday, true},
+		{"0 2 15 * 2", monday, false},
+		{"@daily", monday.Add(-2 * time.Hour), true},
+	}
+	for _, c := range cases {
+		spec, err := parseCron(c.expr)
+		if err != nil {
+			t.Errorf("%s: %s", c.expr, err)
+			continue
+		}
+		if spec.matches(c.t) != c.match {
+			t.Errorf("%s at %s: expected match=%v", c.expr, c.t, c.match)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "5-2 * * * *", "*/0 * * * *", "a * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	spec, _ := parseCron("30 9 * * 1")
+	from := time.Date(2024, 1, 1, 9, 30, 0, 0, time.Local)
+	expected := time.Date(2024, 1, 8, 9, 30, 0, 0, time.Local)
+	if next := spec.next(from); !next.Equal(expected) {
+		t.Errorf("expected %s but got %s", expected, next)
+	}
+	never, _ := parseCron("0 0 31 2 *")
+	if next := never.next(from); !next.IsZero() {
+		t.Errorf("expected no match but got %s", next)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+// eventKeepalive is how often an idle event stream sends a
+// comment, so that proxies do not time it out.
+const eventKeepalive = 15 * time.Second
+
+// serveDashboard serves the web UI. The page itself needs no
+// API key; it asks for one and sends it with every API call.
+func (s *Server) serveDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardPage)
+}
+
+// servePresets lists the presets and strategies a Request may
+// name.
+func (s *Server) servePresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"presets":    swarm.PresetNames(),
+		"strategies": append(swarm.StrategyNames(), VoteStrategy),
+	})
+}
+
+func (s *Server) serveReport(w http.ResponseWriter, run *Run) {
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="swarm-%s-report.json"`, run.ID))
+	writeJSON(w, http.StatusOK, run.swarm.Report())
+}
+
+// serveEvents streams a swarm's events as server-sent events
+// until the client goes away. It starts with the events the
+// swarm has kept, so a client opened mid-run can show the
+// current state; a reconnecting client gets only those after
+// its Last-Event-ID.
+func (s *Server) serveEvents(w http.ResponseWriter, r *http.Request, run *Run) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	history, sub := run.swarm.Events().Replay()
+	defer sub.Close()
+	if last, err := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64); err == nil {
+		history = history.After(last)
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	for _, e := range history {
+		writeEvent(w, e)
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(eventKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			writeEvent(w, e)
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
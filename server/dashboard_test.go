@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestDashboard(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.AddTenant(Tenant{Name: "qa", Key: "secret"})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "kahootd") {
+		t.Errorf("expected the dashboard without a key but got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/presets", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected /presets to need a key but got %d", rec.Code)
+	}
+}
+
+func TestReportAndEvents(t *testing.T) {
+	s := New()
+	defer s.Close()
+	run, err := s.Start("", &Request{GamePin: "123", Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/swarms/"+run.ID+"/report", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Header().Get("Content-Disposition"), "attachment") {
+		t.Errorf("unexpected report response: %d %v", rec.Code, rec.Header())
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/swarms/"+run.ID+"/bogus", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 but got %d", rec.Code)
+	}
+
+	server := httptest.NewServer(s)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/swarms/" + run.ID + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", resp.Header.Get("Content-Type"))
+	}
+
+	s.lock.Lock()
+	sw := s.runs[run.ID].swarm
+	s.lock.Unlock()
+	sw.Events().Publish(kahoot.TopicError, "test", kahoot.ErrConnClosed)
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+		var e eventJSON
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e); err != nil {
+			t.Fatal(err)
+		}
+		if e.Type == "test" {
+			if e.Data != kahoot.ErrConnClosed.Error() {
+				t.Errorf("unexpected data %v", e.Data)
+			}
+			return
+		}
+	}
+	t.Fatal("event was not streamed")
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// EventSchemaVersion is the major version of the JSON encoding
+// of streamed events, described by the JSON Schema files in
+// schema/. Within a major version, fields and event types may
+// be added, but none are removed, renamed, or given another
+// meaning.
+const EventSchemaVersion = 1
+
+//go:embed schema/*.json
+var schemaFiles embed.FS
+
+type eventJSON struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	Topic         kahoot.Topic `json:"topic"`
+	Type          string       `json:"type"`
+	Time          time.Time    `json:"time"`
+	Seq           uint64       `json:"seq"`
+	Data          interface{}  `json:"data"`
+}
+
+type questionJSON struct {
+	Index            int            `json:"index"`
+	NumAnswers       int            `json:"numAnswers"`
+	QuestionType     string         `json:"questionType,omitempty"`
+	Choices          []string       `json:"choices,omitempty"`
+	AnswerMap        map[string]int `json:"answerMap,omitempty"`
+	AnswerCounts     []int          `json:"answerCounts,omitempty"`
+	PointsMultiplier int            `json:"pointsMultiplier"`
+	TimeLeftMs       int64          `json:"timeLeftMs,omitempty"`
+	Received         time.Time      `json:"received"`
+}
+
+type answerJSON struct {
+	Nickname string `json:"nickname"`
+	Index    int    `json:"index"`
+	Choice   int    `json:"choice"`
+}
+
+type resultJSON struct {
+	*kahoot.QuizResult
+	Index    int    `json:"index"`
+	Nickname string `json:"nickname,omitempty"`
+}
+
+type quizEndJSON struct {
+	*kahoot.QuizEnd
+	Nickname string `json:"nickname,omitempty"`
+}
+
+// encodeEvent prepares an event for JSON, in the shape given
+// by schema/event.schema.json. Errors would otherwise encode
+// as empty objects, and the package's types as their Go field
+// names, which are not meant to be stable.
+func encodeEvent(e kahoot.Event) *eventJSON {
+	res := &eventJSON{
+		SchemaVersion: EventSchemaVersion,
+		Topic:         e.Topic,
+		Type:          e.Type,
+		Time:          e.Time,
+		Seq:           e.Seq,
+		Data:          e.Data,
+	}
+	switch data := e.Data.(type) {
+	case error:
+		res.Data = data.Error()
+	case time.Duration:
+		res.Data = data.Milliseconds()
+	case *kahoot.QuizAction:
+		q := &questionJSON{
+			Index:            data.Index,
+			NumAnswers:       data.NumAnswers,
+			QuestionType:     data.QuestionType,
+			Choices:          data.Choices,
+			AnswerCounts:     data.AnswerCounts,
+			PointsMultiplier: data.PointsMultiplier,
+			TimeLeftMs:       data.TimeLeft.Milliseconds(),
+			Received:         data.Received,
+		}
+		if len(data.AnswerMap) > 0 {
+			q.AnswerMap = map[string]int{}
+			for screen, choice := range data.AnswerMap {
+				q.AnswerMap[fmt.Sprint(screen)] = choice
+			}
+		}
+		res.Data = q
+	case *swarm.Answer:
+		res.Data = &answerJSON{Nickname: data.Nickname, Index: data.Index, Choice: data.Choice}
+	case *kahoot.QuizResult:
+		res.Data = &resultJSON{QuizResult: data, Index: data.Index, Nickname: data.Player.Nickname}
+	case *kahoot.QuizEnd:
+		res.Data = &quizEndJSON{QuizEnd: data, Nickname: data.Player.Nickname}
+	}
+	return res
+}
+
+// writeEvent writes one server-sent event, with the event's
+// sequence number as its id.
+func writeEvent(w io.Writer, e kahoot.Event) {
+	data, err := json.Marshal(encodeEvent(e))
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", e.Seq, data)
+}
+
+// serveSchema serves the JSON Schema files describing events
+// and alerts, such as /schema/event.schema.json.
+func (s *Server) serveSchema(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/schema/")
+	data, err := schemaFiles.ReadFile("schema/" + name)
+	if err != nil || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.Write(data)
+}
@@ -0,0 +1,197 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+func TestEventSchema(t *testing.T) {
+	now := time.Now()
+	events := []kahoot.Event{
+		{Topic: kahoot.TopicConnection, Type: "joining", Data: 20},
+		{Topic: kahoot.TopicConnection, Type: "joined", Data: "alex1"},
+		{Topic: kahoot.TopicConnection, Type: "lobbyTimeout", Data: 20 * time.Minute},
+		{Topic: kahoot.TopicQuestion, Type: "answers", Data: &kahoot.QuizAction{
+			Type:             kahoot.QuestionAnswers,
+			NumAnswers:       4,
+			Index:            2,
+			AnswerMap:        map[int]int{0: 1, 1: 0, 2: 2, 3: 3},
+			Choices:          []string{"a", "b", "c", "d"},
+			AnswerCounts:     []int{4, 2, 4},
+			Received:         now,
+			TimeLeft:         20 * time.Second,
+			PointsMultiplier: 1,
+			QuestionType:     "quiz",
+		}},
+		{Topic: kahoot.TopicQuestion, Type: "answered", Data: &swarm.Answer{Nickname: "alex1", Index: 2, Choice: 3}},
+		{Topic: kahoot.TopicResult, Type: "result", Data: &kahoot.QuizResult{
+			Choice: 1, IsCorrect: true, Points: 950, TotalScore: 1900, Rank: 1, Streak: 2, Index: 2,
+			Player: kahoot.Player{Nickname: "alex1"},
+		}},
+		{Topic: kahoot.TopicResult, Type: "quizEnd", Data: &kahoot.QuizEnd{Rank: 1, TotalScore: 1900, CorrectCount: 2}},
+		{Topic: kahoot.TopicResult, Type: "gameOver"},
+		{Topic: kahoot.TopicError, Type: "join", Data: kahoot.ErrConnClosed},
+	}
+	for i, e := range events {
+		e.Time = now
+		e.Seq = uint64(i + 1)
+		data, err := json.Marshal(encodeEvent(e))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var obj interface{}
+		json.Unmarshal(data, &obj)
+		if err := validate(t, "event.schema.json", nil, obj); err != nil {
+			t.Errorf("%s/%s: %v in %s", e.Topic, e.Type, err, data)
+		}
+	}
+
+	bad := map[string]interface{}{"schemaVersion": 1.0, "topic": "question", "type": "intro",
+		"time": now.Format(time.RFC3339), "seq": 1.0, "data": map[string]interface{}{"Index": 1.0}}
+	if validate(t, "event.schema.json", nil, bad) == nil {
+		t.Error("expected Go field names not to match the schema")
+	}
+}
+
+func TestServeSchema(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.AddTenant(Tenant{Name: "qa", Key: "secret"})
+	for _, name := range []string{"event", "roster", "question", "result", "error", "alert"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("GET", "/schema/"+name+".schema.json", nil))
+		var schema map[string]interface{}
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &schema) != nil {
+			t.Errorf("%s: unexpected response %d", name, rec.Code)
+		}
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/schema/bogus.json", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 but got %d", rec.Code)
+	}
+}
+
+// validate checks obj against the subset of JSON Schema which
+// the schema files use. A nil schema means the file's root.
+// Objects with required fields may not have fields the schema
+// does not list either, so that every field is documented.
+func validate(t *testing.T, file string, schema map[string]interface{}, obj interface{}) error {
+	if schema == nil {
+		data, err := schemaFiles.ReadFile("schema/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal(data, &schema); err != nil {
+			t.Fatal(file, err)
+		}
+	}
+	if ref, ok := schema["$ref"].(string); ok {
+		if strings.HasPrefix(ref, "#/$defs/") {
+			root := map[string]interface{}(nil)
+			data, _ := schemaFiles.ReadFile("schema/" + file)
+			json.Unmarshal(data, &root)
+			def := root["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")]
+			return validate(t, file, def.(map[string]interface{}), obj)
+		}
+		return validate(t, ref, nil, obj)
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, obj) {
+		return fmt.Errorf("%v is not %v", obj, c)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, x := range enum {
+			found = found || reflect.DeepEqual(x, obj)
+		}
+		if !found {
+			return fmt.Errorf("%v is not one of %v", obj, enum)
+		}
+	}
+	if typ, ok := schema["type"].(string); ok && !hasType(typ, obj) {
+		return fmt.Errorf("%v is not of type %s", obj, typ)
+	}
+	if m, ok := obj.(map[string]interface{}); ok {
+		for _, r := range asSlice(schema["required"]) {
+			if _, ok := m[r.(string)]; !ok {
+				return errors.New("missing " + r.(string))
+			}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		extra, _ := schema["additionalProperties"].(map[string]interface{})
+		for k, v := range m {
+			if sub, ok := props[k].(map[string]interface{}); ok {
+				if err := validate(t, file, sub, v); err != nil {
+					return fmt.Errorf("%s: %v", k, err)
+				}
+			} else if extra != nil {
+				if err := validate(t, file, extra, v); err != nil {
+					return fmt.Errorf("%s: %v", k, err)
+				}
+			} else if schema["required"] != nil {
+				return errors.New("unexpected field " + k)
+			}
+		}
+	}
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		for _, x := range asSlice(obj) {
+			if err := validate(t, file, items, x); err != nil {
+				return err
+			}
+		}
+	}
+	for _, sub := range asSlice(schema["allOf"]) {
+		sub := sub.(map[string]interface{})
+		if cond, ok := sub["if"].(map[string]interface{}); ok {
+			if validate(t, file, cond, obj) == nil {
+				if err := validate(t, file, sub["then"].(map[string]interface{}), obj); err != nil {
+					return err
+				}
+			}
+		} else if err := validate(t, file, sub, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func hasType(typ string, obj interface{}) bool {
+	switch typ {
+	case "object":
+		_, ok := obj.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := obj.([]interface{})
+		return ok
+	case "string":
+		_, ok := obj.(string)
+		return ok
+	case "boolean":
+		_, ok := obj.(bool)
+		return ok
+	case "number":
+		_, ok := obj.(float64)
+		return ok
+	case "integer":
+		f, ok := obj.(float64)
+		return ok && f == float64(int64(f))
+	case "null":
+		return obj == nil
+	}
+	return false
+}
+
+func asSlice(x interface{}) []interface{} {
+	s, _ := x.([]interface{})
+	return s
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultReadyInterval is how long a readiness result is
+// reused before Server.Ready is called again, so that frequent
+// probes do not turn into frequent requests to kahoot.it.
+const DefaultReadyInterval = 30 * time.Second
+
+type readiness struct {
+	lock    sync.Mutex
+	checked time.Time
+	err     error
+}
+
+// serveHealth answers liveness probes. Reaching it at all
+// means the process is serving requests.
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+// serveReady answers readiness probes.
+func (s *Server) serveReady(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkReady(); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"ok":    false,
+			"error": err.Error(),
+		})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"ok": true})
+}
+
+func (s *Server) checkReady() error {
+	if s.Ready == nil {
+		return nil
+	}
+	s.ready.lock.Lock()
+	defer s.ready.lock.Unlock()
+	if s.ready.checked.IsZero() || time.Since(s.ready.checked) >= s.ReadyInterval {
+		s.ready.err = s.Ready()
+		s.ready.checked = time.Now()
+	}
+	return s.ready.err
+}
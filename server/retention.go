@@ -0,0 +1,71 @@
+package server
+
+import (
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// DefaultRunRetention is how long a finished run stays listed
+// unless Server.RunRetention says otherwise.
+const DefaultRunRetention = time.Hour
+
+// evictInterval is how often finished runs are looked for.
+var evictInterval = time.Minute
+
+func (s *Server) evictLoop() {
+	ticker := time.NewTicker(evictInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.evictRuns(now)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// evictRuns forgets the runs which finished at least
+// RunRetention before now, closing their swarms. A run has
+// finished once it is stopped, or once none of its bots is
+// still joining or connected.
+func (s *Server) evictRuns(now time.Time) {
+	s.lock.Lock()
+	var running []*Run
+	for _, run := range s.runs {
+		if run.finished.IsZero() {
+			running = append(running, run)
+		}
+	}
+	s.lock.Unlock()
+	for _, run := range running {
+		if runFinished(run) {
+			s.lock.Lock()
+			if run.finished.IsZero() {
+				run.finished = now
+			}
+			s.lock.Unlock()
+		}
+	}
+
+	s.lock.Lock()
+	var swarms []*swarm.Swarm
+	for id, run := range s.runs {
+		if !run.finished.IsZero() && now.Sub(run.finished) >= s.RunRetention {
+			delete(s.runs, id)
+			if !run.Stopped {
+				run.Stopped = true
+				swarms = append(swarms, run.swarm)
+			}
+		}
+	}
+	s.lock.Unlock()
+	for _, sw := range swarms {
+		sw.Close()
+	}
+}
+
+func runFinished(run *Run) bool {
+	return len(run.swarm.Bots()) >= run.count && run.swarm.Connected() == 0
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// A Role limits what an API key may do within its tenant.
+type Role string
+
+const (
+	// RoleViewer may only read: list swarms, watch events,
+	// and download reports.
+	RoleViewer Role = "viewer"
+
+	// RoleOperator may also start and stop swarms. It is the
+	// role of a tenant's key unless the tenant says otherwise.
+	RoleOperator Role = "operator"
+
+	// RoleAdmin may also act on other tenants' swarms by
+	// adding ?tenant=NAME to a request.
+	RoleAdmin Role = "admin"
+)
+
+var roleRanks = map[Role]int{RoleViewer: 1, RoleOperator: 2, RoleAdmin: 3}
+
+// ParseRole checks a role name. The empty name means
+// RoleOperator.
+func ParseRole(name string) (Role, error) {
+	if name == "" {
+		return RoleOperator, nil
+	}
+	if _, ok := roleRanks[Role(name)]; !ok {
+		return "", fmt.Errorf("unknown role %q", name)
+	}
+	return Role(name), nil
+}
+
+// Allows reports whether r includes everything need may do.
+func (r Role) Allows(need Role) bool {
+	return roleRanks[r] >= roleRanks[need]
+}
+
+// A Member is an additional API key for a tenant, usually
+// with a different role than the tenant's own key.
+type Member struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	Role Role   `json:"role,omitempty"`
+}
+
+// authorize checks the caller's role for a request, and
+// switches to another tenant for admins who ask. It writes an
+// error and returns false if the request is not allowed.
+//
+// If RoleHeader is set and the request carries it, its value
+// replaces the key's role, but only if it is no higher.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request,
+	tenant *tenantState, role Role) (*tenantState, Role, bool) {
+	if s.RoleHeader != "" && r.Header.Get(s.RoleHeader) != "" {
+		asked, err := ParseRole(r.Header.Get(s.RoleHeader))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, "", false
+		}
+		if !role.Allows(asked) {
+			http.Error(w, "the "+string(role)+" role cannot act as "+string(asked), http.StatusForbidden)
+			return nil, "", false
+		}
+		role = asked
+	}
+	if r.Method != "GET" && !role.Allows(RoleOperator) {
+		http.Error(w, "the "+string(role)+" role cannot change swarms", http.StatusForbidden)
+		return nil, "", false
+	}
+	if other := r.URL.Query().Get("tenant"); other != "" && other != tenant.Name {
+		if !role.Allows(RoleAdmin) {
+			http.Error(w, "only admins may act for other tenants", http.StatusForbidden)
+			return nil, "", false
+		}
+		s.lock.Lock()
+		known := s.hasTenant(other)
+		s.lock.Unlock()
+		if !known {
+			http.Error(w, "no such tenant: "+other, http.StatusNotFound)
+			return nil, "", false
+		}
+		return s.tenant(other), role, true
+	}
+	return tenant, role, true
+}
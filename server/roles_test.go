@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoles(t *testing.T) {
+	s := New()
+	defer s.Close()
+	err := s.AddTenant(Tenant{Name: "lab", Key: "op", Members: []Member{
+		{Name: "students", Key: "view", Role: RoleViewer},
+		{Name: "teacher", Key: "admin", Role: RoleAdmin},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddTenant(Tenant{Name: "other", Key: "other"})
+	if err := s.AddTenant(Tenant{Name: "bad", Key: "x", Role: "root"}); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+
+	do := func(method, path, key string) int {
+		var body *strings.Reader
+		if method == "POST" {
+			body = strings.NewReader(`{"gamePin": "123", "count": 1}`)
+		} else {
+			body = strings.NewReader("")
+		}
+		req := httptest.NewRequest(method, path, body)
+		req.Header.Set("Authorization", "Bearer "+key)
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do("GET", "/swarms", "view"); code != http.StatusOK {
+		t.Errorf("viewer list: got %d", code)
+	}
+	if code := do("POST", "/swarms", "view"); code != http.StatusForbidden {
+		t.Errorf("viewer start: expected forbidden but got %d", code)
+	}
+	if code := do("POST", "/swarms", "op"); code != http.StatusCreated {
+		t.Errorf("operator start: got %d", code)
+	}
+	if code := do("GET", "/swarms?tenant=other", "op"); code != http.StatusForbidden {
+		t.Errorf("operator cross-tenant: expected forbidden but got %d", code)
+	}
+	if code := do("POST", "/swarms?tenant=other", "admin"); code != http.StatusCreated {
+		t.Errorf("admin cross-tenant start: got %d", code)
+	}
+	if code := do("GET", "/swarms?tenant=nobody", "admin"); code != http.StatusNotFound {
+		t.Errorf("admin unknown tenant: expected not found but got %d", code)
+	}
+	if len(s.Runs("lab")) != 1 || len(s.Runs("other")) != 1 {
+		t.Errorf("unexpected runs: %d and %d", len(s.Runs("lab")), len(s.Runs("other")))
+	}
+
+	s.RoleHeader = "X-Role"
+	req := httptest.NewRequest("DELETE", "/swarms/1", nil)
+	req.Header.Set("Authorization", "Bearer op")
+	req.Header.Set("X-Role", "viewer")
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected the role header to demote the caller but got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("DELETE", "/swarms/1", nil)
+	req.Header.Set("Authorization", "Bearer view")
+	req.Header.Set("X-Role", "admin")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected the role header not to promote the caller but got %d", rec.Code)
+	}
+}
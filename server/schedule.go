@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// maxPinResponse bounds the size of a pin webhook's answer.
+const maxPinResponse = 4096
+
+// A Schedule starts the same swarm whenever its cron
+// expression matches. The game pin is not known in advance:
+// each time the schedule fires, the server asks PinURL for it.
+type Schedule struct {
+	Name   string `json:"name"`
+	Tenant string `json:"tenant,omitempty"`
+
+	// Cron is a five-field cron expression in the server's
+	// local time, such as "0 2 * * 1-5", or one of @hourly,
+	// @daily, @weekly and @monthly.
+	Cron string `json:"cron"`
+
+	// PinURL is fetched with a GET when the schedule fires.
+	// It must respond with the game pin, either as plain text
+	// or as a JSON object with a "gamePin" field.
+	PinURL string `json:"pinURL"`
+
+	// Duration, if set, stops each run after this long, such
+	// as "30m".
+	Duration string `json:"duration,omitempty"`
+
+	// Request describes the swarm. Its GamePin is ignored.
+	Request Request `json:"request"`
+}
+
+// A ScheduleStatus describes a schedule and its last run.
+type ScheduleStatus struct {
+	Schedule
+	Next      time.Time `json:"next"`
+	LastFired time.Time `json:"lastFired,omitempty"`
+	LastRun   string    `json:"lastRun,omitempty"`
+	LastError string    `json:"lastError,omitempty"`
+}
+
+type scheduleState struct {
+	ScheduleStatus
+	cron     *cronSpec
+	duration time.Duration
+}
+
+// ReadSchedules reads a JSON array of schedules from a file.
+func ReadSchedules(path string) ([]Schedule, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var schedules []Schedule
+	if err := json.Unmarshal(data, &schedules); err != nil {
+		return nil, fmt.Errorf("parse schedules: %s", err)
+	}
+	return schedules, nil
+}
+
+// AddSchedule validates a schedule and starts running it.
+// Schedules stop when the server is closed.
+func (s *Server) AddSchedule(sch Schedule) error {
+	if sch.Name == "" || sch.PinURL == "" {
+		return errors.New("schedule needs a name and a pinURL")
+	}
+	cron, err := parseCron(sch.Cron)
+	if err != nil {
+		return err
+	}
+	var duration time.Duration
+	if sch.Duration != "" {
+		if duration, err = time.ParseDuration(sch.Duration); err != nil {
+			return fmt.Errorf("schedule %s: %s", sch.Name, err)
+		}
+	}
+	check := sch.Request
+	check.GamePin = "0"
+	if _, err := check.preset(); err != nil {
+		return fmt.Errorf("schedule %s: %s", sch.Name, err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.tenants) > 0 && !s.hasTenant(sch.Tenant) {
+		return fmt.Errorf("schedule %s: unknown tenant %q", sch.Name, sch.Tenant)
+	}
+	for _, other := range s.schedules {
+		if other.Name == sch.Name {
+			return errors.New("duplicate schedule: " + sch.Name)
+		}
+	}
+	s.schedules = append(s.schedules, &scheduleState{
+		ScheduleStatus: ScheduleStatus{Schedule: sch},
+		cron:           cron,
+		duration:       duration,
+	})
+	if len(s.schedules) == 1 {
+		go s.scheduleLoop()
+	}
+	return nil
+}
+
+// Schedules returns the status of a tenant's schedules.
+func (s *Server) Schedules(tenant string) []*ScheduleStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var res []*ScheduleStatus
+	now := time.Now()
+	for _, sch := range s.schedules {
+		if sch.Tenant == tenant {
+			status := sch.ScheduleStatus
+			status.Next = sch.cron.next(now)
+			res = append(res, &status)
+		}
+	}
+	return res
+}
+
+func (s *Server) scheduleLoop() {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-time.After(next.Sub(now)):
+			s.fireDue(next)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// fireDue starts every schedule which matches a minute.
+func (s *Server) fireDue(minute time.Time) {
+	s.lock.Lock()
+	var due []*scheduleState
+	for _, sch := range s.schedules {
+		if sch.cron.matches(minute) {
+			sch.LastFired = minute
+			due = append(due, sch)
+		}
+	}
+	s.lock.Unlock()
+	for _, sch := range due {
+		go s.fire(sch)
+	}
+}
+
+func (s *Server) fire(sch *scheduleState) {
+	fields := map[string]interface{}{"schedule": sch.Name, "tenant": sch.Tenant}
+	run, err := s.startScheduled(sch)
+	s.lock.Lock()
+	if err != nil {
+		sch.LastError = err.Error()
+	} else {
+		sch.LastError = ""
+		sch.LastRun = run.ID
+	}
+	s.lock.Unlock()
+	if err != nil {
+		fields["error"] = err.Error()
+		s.log("schedule failed", fields)
+		return
+	}
+	fields["id"] = run.ID
+	fields["gamePin"] = run.GamePin
+	s.log("schedule fired", fields)
+	if sch.duration > 0 {
+		time.AfterFunc(sch.duration, func() {
+			s.Stop(sch.Tenant, run.ID)
+		})
+	}
+}
+
+func (s *Server) startScheduled(sch *scheduleState) (*Run, error) {
+	pin, err := fetchPin(sch.PinURL)
+	if err != nil {
+		return nil, fmt.Errorf("fetch pin: %s", err)
+	}
+	req := sch.Request
+	req.GamePin = pin
+	return s.start(sch.Tenant, &req, "")
+}
+
+// fetchPin asks a schedule's webhook for the pin to join.
+func fetchPin(url string) (string, error) {
+	if err := kahoot.TakeRequest(); err != nil {
+		return "", err
+	}
+	resp, err := kahoot.HTTPClient.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("webhook responded " + resp.Status)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxPinResponse))
+	if err != nil {
+		return "", err
+	}
+	pin := strings.TrimSpace(string(data))
+	if strings.HasPrefix(pin, "{") {
+		var obj struct {
+			GamePin string `json:"gamePin"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return "", err
+		}
+		pin = obj.GamePin
+	}
+	if pin == "" {
+		return "", errors.New("webhook returned no pin")
+	}
+	return pin, nil
+}
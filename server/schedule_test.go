@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestAddSchedule(t *testing.T) {
+	s := New()
+	defer s.Close()
+	for _, sch := range []Schedule{
+		{Name: "a", Cron: "* * * *", PinURL: "http://x"},
+		{Name: "a", Cron: "* * * * *"},
+		{Name: "a", Cron: "* * * * *", PinURL: "http://x", Duration: "soon"},
+		{Name: "a", Cron: "* * * * *", PinURL: "http://x", Request: Request{Strategy: "cheat", Count: 1}},
+	} {
+		if err := s.AddSchedule(sch); err == nil {
+			t.Errorf("expected an error for %+v", sch)
+		}
+	}
+	good := Schedule{Name: "a", Cron: "@hourly", PinURL: "http://x", Request: Request{Count: 1}}
+	if err := s.AddSchedule(good); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.AddSchedule(good); err == nil {
+		t.Error("expected an error for a duplicate schedule")
+	}
+
+	s.AddTenant(Tenant{Name: "qa", Key: "k"})
+	good.Name = "b"
+	if err := s.AddSchedule(good); err == nil {
+		t.Error("expected an error for an unknown tenant")
+	}
+}
+
+func TestFetchPin(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	for _, b := range []string{"123456\n", `{"gamePin": "123456"}`} {
+		body = b
+		if pin, err := fetchPin(server.URL); err != nil || pin != "123456" {
+			t.Errorf("body %q: got %q, %v", b, pin, err)
+		}
+	}
+	body = ""
+	if _, err := fetchPin(server.URL); err == nil {
+		t.Error("expected an error for an empty response")
+	}
+}
+
+func TestFireSchedule(t *testing.T) {
+	pins := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("424242"))
+	}))
+	defer pins.Close()
+
+	s := New()
+	defer s.Close()
+	err := s.AddSchedule(Schedule{
+		Name:    "nightly",
+		Cron:    "0 2 * * *",
+		PinURL:  pins.URL,
+		Request: Request{Count: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.fireDue(time.Date(2024, 1, 1, 3, 0, 0, 0, time.Local))
+	s.fireDue(time.Date(2024, 1, 1, 2, 0, 0, 0, time.Local))
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.Schedules("")[0].LastRun == "" && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	runs := s.Runs("")
+	if len(runs) != 1 || runs[0].GamePin != "424242" {
+		t.Fatalf("unexpected runs: %+v", runs)
+	}
+	statuses := s.Schedules("")
+	if statuses[0].LastRun != runs[0].ID || statuses[0].Next.IsZero() {
+		t.Errorf("unexpected status: %+v", statuses)
+	}
+}
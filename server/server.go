@@ -0,0 +1,514 @@
+// Package server implements kahootd, a long-running HTTP
+// service which starts and stops swarms on request.
+//
+// The API is:
+//
+//	GET    /swarms             list every swarm
+//	POST   /swarms             start a swarm described by a JSON Request
+//	GET    /swarms/ID          describe one swarm
+//	DELETE /swarms/ID          disconnect a swarm's bots
+//	GET    /swarms/ID/report   how the swarm's bots fared, as a download
+//	GET    /swarms/ID/events   the swarm's events, as server-sent events
+//	GET    /presets            the presets and strategies requests may name
+//	GET    /whoami             the caller's tenant and Role
+//	GET    /stats              usage counts for the caller's tenant
+//	GET    /games/PIN          a game's settings, without joining it
+//	GET    /schedules          the caller's schedules and their last runs
+//	GET    /vote/ID            the voting page of a swarm using VoteStrategy
+//	GET    /                   a web dashboard for all of the above
+//	GET    /healthz            liveness probe
+//	GET    /readyz             readiness probe
+//
+// If tenants are configured, every endpoint except the probes,
+// the dashboard page, and the voting pages requires an API
+// key, and each tenant only sees its own swarms. Keys have a
+// Role, and only operators and admins may start or stop
+// swarms. Voting pages instead require the token in the
+// swarm's VoteURL.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/audit"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// A Request describes a swarm to start.
+type Request struct {
+	GamePin     string `json:"gamePin"`
+	Preset      string `json:"preset,omitempty"`
+	Prefix      string `json:"prefix,omitempty"`
+	Count       int    `json:"count,omitempty"`
+	Strategy    string `json:"strategy,omitempty"`
+	Concurrency int    `json:"concurrency,omitempty"`
+}
+
+func (r *Request) preset() (swarm.Preset, error) {
+	p := swarm.Preset{Name: "custom", Prefix: "bot", Strategy: "idle"}
+	if r.GamePin == "" {
+		return p, errors.New("missing game pin")
+	}
+	if r.Preset != "" {
+		var err error
+		p, err = swarm.LookupPreset(r.Preset)
+		if err != nil {
+			return p, err
+		}
+	}
+	if r.Prefix != "" {
+		p.Prefix = r.Prefix
+	}
+	if r.Count != 0 {
+		p.Count = r.Count
+		p.Nicknames = nil
+	}
+	if r.Strategy != "" {
+		p.Strategy = r.Strategy
+	}
+	if p.Strategy == VoteStrategy {
+		// start replaces this with the run's ballot box.
+		p.Options.Strategies = map[string]func(*swarm.Bot, *kahoot.QuizAction) int{
+			VoteStrategy: nil,
+		}
+	}
+	if r.Concurrency != 0 {
+		p.Options.Concurrency = r.Concurrency
+	}
+	return p, p.Validate()
+}
+
+// A Run is a swarm started by the server.
+type Run struct {
+	ID      string    `json:"id"`
+	Tenant  string    `json:"tenant,omitempty"`
+	GamePin string    `json:"gamePin"`
+	Preset  string    `json:"preset"`
+	Started time.Time `json:"started"`
+	Joined  int       `json:"joined"`
+	Failed  int       `json:"failed"`
+	Pending int       `json:"pending"`
+
+	// Connected counts the bots which are joining or still in
+	// the game, leaving out those kicked, disconnected, or
+	// gone.
+	Connected int  `json:"connected"`
+	Stopped   bool `json:"stopped"`
+
+	// VoteURL is the path of the page where helpers vote on
+	// answers, if the swarm uses VoteStrategy.
+	VoteURL string `json:"voteURL,omitempty"`
+
+	swarm *swarm.Swarm
+	count int
+	votes *ballotBox
+
+	// finished is when the run was stopped or its last bot
+	// was seen to end; see evictRuns.
+	finished time.Time
+}
+
+// A Server serves the kahootd API.
+type Server struct {
+	// Ready reports whether new swarms could join games.
+	// It backs /readyz and is called at most once per
+	// ReadyInterval.
+	Ready         func() error
+	ReadyInterval time.Duration
+
+	// Log, if set, is called for notable events such as a
+	// swarm starting or stopping.
+	Log func(msg string, fields map[string]interface{})
+
+	// Audit, if set, records who started and stopped which
+	// swarms. A swarm is not started if it cannot be audited.
+	Audit *audit.Log
+
+	// RunRetention is how long a run stays listed after it
+	// finishes, so that its final counts and report can still
+	// be read. A run finishes when it is stopped or none of
+	// its bots is connected any more; its swarm is closed when
+	// it is forgotten.
+	RunRetention time.Duration
+
+	// RoleHeader, if set, names a request header from which
+	// to take the caller's Role, as set by an authenticating
+	// proxy in front of the server. The header can only lower
+	// the role of the caller's key. Only set it if clients
+	// cannot reach the server without passing the proxy.
+	RoleHeader string
+
+	// startLock makes quota checks and starts atomic.
+	startLock sync.Mutex
+
+	lock      sync.Mutex
+	runs      map[string]*Run
+	nextID    int
+	tenants   []*tenantState
+	schedules []*scheduleState
+
+	quit      chan struct{}
+	closeOnce sync.Once
+
+	ready readiness
+}
+
+// New creates a Server with no swarms.
+func New() *Server {
+	return &Server{
+		ReadyInterval: DefaultReadyInterval,
+		RunRetention:  DefaultRunRetention,
+		runs:          map[string]*Run{},
+		quit:          make(chan struct{}),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/healthz":
+		s.serveHealth(w, r)
+	case r.URL.Path == "/readyz":
+		s.serveReady(w, r)
+	case r.URL.Path == "/":
+		s.serveDashboard(w, r)
+	case strings.HasPrefix(r.URL.Path, "/vote/"):
+		s.serveVote(w, r)
+	case strings.HasPrefix(r.URL.Path, "/schema/"):
+		s.serveSchema(w, r)
+	default:
+		s.serveTenant(w, r)
+	}
+}
+
+func (s *Server) serveTenant(w http.ResponseWriter, r *http.Request) {
+	tenant, role, ok := s.authenticate(r)
+	if !ok {
+		http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
+	if tenant, role, ok = s.authorize(w, r, tenant, role); !ok {
+		return
+	}
+	switch {
+	case r.URL.Path == "/whoami":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"tenant": tenant.Name, "role": role})
+	case r.URL.Path == "/stats":
+		writeJSON(w, http.StatusOK, s.Stats(tenant.Name))
+	case r.URL.Path == "/presets":
+		s.servePresets(w, r)
+	case r.URL.Path == "/schedules":
+		writeJSON(w, http.StatusOK, s.Schedules(tenant.Name))
+	case strings.HasPrefix(r.URL.Path, "/games/"):
+		s.serveGame(w, r, strings.TrimPrefix(r.URL.Path, "/games/"))
+	case r.URL.Path == "/swarms":
+		switch r.Method {
+		case "GET":
+			writeJSON(w, http.StatusOK, s.Runs(tenant.Name))
+		case "POST":
+			s.serveStart(w, r, tenant)
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	case strings.HasPrefix(r.URL.Path, "/swarms/") && strings.Count(r.URL.Path, "/") == 3:
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/swarms/"), "/")
+		s.serveRunPart(w, r, tenant, parts[0], parts[1])
+	case strings.HasPrefix(r.URL.Path, "/swarms/"):
+		id := strings.TrimPrefix(r.URL.Path, "/swarms/")
+		switch r.Method {
+		case "GET":
+			if run, ok := s.Run(tenant.Name, id); ok {
+				writeJSON(w, http.StatusOK, run)
+			} else {
+				http.NotFound(w, r)
+			}
+		case "DELETE":
+			if run, ok, err := s.Stop(tenant.Name, id); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+			} else if ok {
+				writeJSON(w, http.StatusOK, run)
+			} else {
+				http.NotFound(w, r)
+			}
+		default:
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveRunPart serves /swarms/ID/report and
+// /swarms/ID/events.
+func (s *Server) serveRunPart(w http.ResponseWriter, r *http.Request, tenant *tenantState, id, part string) {
+	if r.Method != "GET" {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	s.lock.Lock()
+	run, ok := s.runs[id]
+	ok = ok && run.Tenant == tenant.Name
+	s.lock.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch part {
+	case "report":
+		s.serveReport(w, run)
+	case "events":
+		s.serveEvents(w, r, run)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveStart(w http.ResponseWriter, r *http.Request, tenant *tenantState) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	run, err := s.start(tenant.Name, &req, r.RemoteAddr)
+	if err == ErrQuota {
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusCreated, run)
+}
+
+func (s *Server) serveGame(w http.ResponseWriter, r *http.Request, pin string) {
+	info, err := kahoot.LookupGameInfo(pin)
+	if errors.Is(err, kahoot.ErrGameNotFound) {
+		writeJSON(w, http.StatusNotFound, map[string]interface{}{"pin": pin, "exists": false})
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pin":      pin,
+		"exists":   true,
+		"info":     info,
+		"warnings": info.Warnings(),
+	})
+}
+
+// ErrQuota is returned by Start if a swarm would put its
+// tenant over the tenant's bot limit.
+var ErrQuota = errors.New("tenant bot limit exceeded")
+
+// Start validates a Request and starts joining its bots in
+// the background on behalf of a tenant.
+func (s *Server) Start(tenant string, req *Request) (*Run, error) {
+	return s.start(tenant, req, "")
+}
+
+func (s *Server) start(tenant string, req *Request, remoteAddr string) (*Run, error) {
+	preset, err := req.preset()
+	if err != nil {
+		return nil, err
+	}
+	t := s.tenant(tenant)
+	if t.throttle != nil {
+		preset.Options.Throttle = t.throttle.wait
+	}
+	var votes *ballotBox
+	if preset.Strategy == VoteStrategy {
+		votes = newBallotBox()
+		preset.Options.Strategies[VoteStrategy] = votes.choose
+	}
+	nicknames := preset.NicknameList()
+	s.startLock.Lock()
+	defer s.startLock.Unlock()
+	if t.MaxBots > 0 && s.Stats(tenant).ActiveBots+len(nicknames) > t.MaxBots {
+		return nil, ErrQuota
+	}
+	sw := swarm.New(req.GamePin, preset.Options)
+
+	s.lock.Lock()
+	id := strconv.Itoa(s.nextID + 1)
+	s.lock.Unlock()
+	if s.Audit != nil {
+		details := map[string]interface{}{
+			"id":       id,
+			"gamePin":  req.GamePin,
+			"request":  req,
+			"preset":   preset.Name,
+			"bots":     len(nicknames),
+			"strategy": preset.Strategy,
+		}
+		if remoteAddr != "" {
+			details["remoteAddr"] = remoteAddr
+		}
+		if err := s.Audit.Append(actor(tenant), "start", details); err != nil {
+			return nil, fmt.Errorf("audit log: %s", err)
+		}
+	}
+
+	s.lock.Lock()
+	s.nextID++
+	run := &Run{
+		ID:      strconv.Itoa(s.nextID),
+		Tenant:  tenant,
+		GamePin: req.GamePin,
+		Preset:  preset.Name,
+		Started: time.Now(),
+		swarm:   sw,
+		count:   len(nicknames),
+		votes:   votes,
+	}
+	if votes != nil {
+		run.VoteURL = "/vote/" + run.ID + "?token=" + votes.token
+	}
+	s.runs[run.ID] = run
+	if s.nextID == 1 {
+		go s.evictLoop()
+	}
+	s.lock.Unlock()
+
+	s.log("swarm started", map[string]interface{}{
+		"id":      run.ID,
+		"tenant":  tenant,
+		"gamePin": run.GamePin,
+		"preset":  run.Preset,
+		"bots":    run.count,
+	})
+	go func() {
+		sw.Join(nicknames)
+		sw.SetStrategy(preset.Strategy)
+		sw.Play()
+		status := s.status(run)
+		s.log("swarm joined", map[string]interface{}{
+			"id":     run.ID,
+			"joined": status.Joined,
+			"failed": status.Failed,
+		})
+	}()
+	return s.status(run), nil
+}
+
+// Runs returns the status of a tenant's swarms, oldest first.
+func (s *Server) Runs(tenant string) []*Run {
+	s.lock.Lock()
+	var runs []*Run
+	for i := 1; i <= s.nextID; i++ {
+		if run, ok := s.runs[strconv.Itoa(i)]; ok && run.Tenant == tenant {
+			runs = append(runs, run)
+		}
+	}
+	s.lock.Unlock()
+	for i, run := range runs {
+		runs[i] = s.status(run)
+	}
+	return runs
+}
+
+// Run returns the status of one of a tenant's swarms.
+func (s *Server) Run(tenant, id string) (*Run, bool) {
+	s.lock.Lock()
+	run, ok := s.runs[id]
+	ok = ok && run.Tenant == tenant
+	s.lock.Unlock()
+	if !ok {
+		return nil, false
+	}
+	return s.status(run), true
+}
+
+// Stop disconnects the bots of one of a tenant's swarms.
+// The swarm stays listed for RunRetention so that its final
+// counts can still be read. If the stop cannot be written to
+// the audit log, the bots are still disconnected and the
+// error is returned with the run.
+func (s *Server) Stop(tenant, id string) (*Run, bool, error) {
+	s.lock.Lock()
+	run, ok := s.runs[id]
+	ok = ok && run.Tenant == tenant
+	stopping := ok && !run.Stopped
+	if stopping {
+		run.Stopped = true
+		run.finished = time.Now()
+		go run.swarm.Close()
+	}
+	s.lock.Unlock()
+	if !ok {
+		return nil, false, nil
+	} else if !stopping {
+		return s.status(run), true, nil
+	}
+	s.log("swarm stopped", map[string]interface{}{"id": id})
+	if s.Audit != nil {
+		err := s.Audit.Append(actor(tenant), "stop", map[string]interface{}{"id": id})
+		if err != nil {
+			err = fmt.Errorf("audit log: %s", err)
+			s.log("audit failed", map[string]interface{}{"id": id, "error": err.Error()})
+			return s.status(run), true, err
+		}
+	}
+	return s.status(run), true, nil
+}
+
+// Close stops every schedule and disconnects every swarm.
+func (s *Server) Close() {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+	})
+	s.lock.Lock()
+	var swarms []*swarm.Swarm
+	for _, run := range s.runs {
+		run.Stopped = true
+		swarms = append(swarms, run.swarm)
+	}
+	s.lock.Unlock()
+	for _, sw := range swarms {
+		sw.Close()
+	}
+}
+
+// status returns a copy of run with up-to-date counts.
+func (s *Server) status(run *Run) *Run {
+	s.lock.Lock()
+	res := *run
+	s.lock.Unlock()
+	bots := run.swarm.Bots()
+	for _, bot := range bots {
+		if bot.Err != nil {
+			res.Failed++
+		} else {
+			res.Joined++
+		}
+	}
+	res.Pending = run.count - len(bots)
+	res.Connected = run.swarm.Connected()
+	return &res
+}
+
+func actor(tenant string) string {
+	if tenant == "" {
+		return "anonymous"
+	}
+	return tenant
+}
+
+func (s *Server) log(msg string, fields map[string]interface{}) {
+	if s.Log != nil {
+		s.Log(msg, fields)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(obj)
+}
@@ -0,0 +1,259 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/audit"
+	"github.com/unixpickle/kahoot-hack/internal/kahoottest"
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestReadiness(t *testing.T) {
+	s := New()
+	var calls int
+	var readyErr error
+	s.Ready = func() error {
+		calls++
+		return readyErr
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected ready but got %d", rec.Code)
+	}
+
+	readyErr = errors.New("unreachable")
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusOK || calls != 1 {
+		t.Errorf("expected cached result but got %d after %d calls", rec.Code, calls)
+	}
+
+	s.ReadyInterval = 0
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected unavailable but got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected healthy but got %d", rec.Code)
+	}
+}
+
+func TestStartValidation(t *testing.T) {
+	s := New()
+	for _, body := range []string{
+		`{"count": 3}`,
+		`{"gamePin": "123"}`,
+		`{"gamePin": "123", "count": 3, "strategy": "cheat"}`,
+		`{"gamePin": "123", "preset": "demo-5-named", "count": 6}`,
+		`not json`,
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest("POST", "/swarms", strings.NewReader(body)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("expected bad request for %s but got %d", body, rec.Code)
+		}
+	}
+	if len(s.Runs("")) != 0 {
+		t.Error("invalid requests should not start swarms")
+	}
+}
+
+func TestTenants(t *testing.T) {
+	s := New()
+	s.AddTenant(Tenant{Name: "a", Key: "key-a", MaxBots: 5})
+	s.AddTenant(Tenant{Name: "b", Key: "key-b"})
+	if err := s.AddTenant(Tenant{Name: "a", Key: "other"}); err == nil {
+		t.Error("expected duplicate tenant error")
+	}
+
+	get := func(path, key string) int {
+		req := httptest.NewRequest("GET", path, nil)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := get("/swarms", ""); code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized without key but got %d", code)
+	}
+	if code := get("/swarms", "wrong"); code != http.StatusUnauthorized {
+		t.Errorf("expected unauthorized with bad key but got %d", code)
+	}
+	if code := get("/healthz", ""); code != http.StatusOK {
+		t.Errorf("expected probes to skip auth but got %d", code)
+	}
+
+	if _, err := s.Start("a", &Request{GamePin: "123", Count: 6}); err != ErrQuota {
+		t.Errorf("expected quota error but got %v", err)
+	}
+	run, err := s.Start("a", &Request{GamePin: "123", Count: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if _, ok := s.Run("b", run.ID); ok {
+		t.Error("tenant b can see tenant a's swarm")
+	}
+	if code := get("/swarms/"+run.ID, "key-b"); code != http.StatusNotFound {
+		t.Errorf("expected not found for other tenant but got %d", code)
+	}
+	if len(s.Runs("a")) != 1 || len(s.Runs("b")) != 0 {
+		t.Error("unexpected run lists")
+	}
+	// Whether the run still counts as running depends on
+	// whether its bots could reach a game; see
+	// TestQuotaReleasedByKickedBots.
+	if stats := s.Stats("a"); stats.Swarms != 1 || stats.Running > 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	th := &throttle{interval: 20 * time.Millisecond}
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		th.wait()
+	}
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("throttle returned after %s", elapsed)
+	}
+}
+
+func TestAuditStart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "audit.log")
+	s := New()
+	s.Audit, err = audit.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := s.Start("", &Request{GamePin: "123", Count: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop("", run.ID)
+	s.Stop("", run.ID)
+	s.Audit.Close()
+
+	last, err := audit.Verify(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Seq != 2 || last.Action != "stop" || last.Actor != "anonymous" {
+		t.Errorf("unexpected last entry: %+v", last)
+	}
+}
+
+func TestAuditStopFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := New()
+	s.Audit, err = audit.Open(filepath.Join(dir, "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	run, err := s.Start("", &Request{GamePin: "123", Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Audit.Close()
+	stopped, ok, err := s.Stop("", run.ID)
+	if !ok || err == nil {
+		t.Fatalf("expected an audit error, got %v", err)
+	}
+	if !stopped.Stopped {
+		t.Error("the swarm should stop anyway")
+	}
+}
+
+func TestRunRetention(t *testing.T) {
+	s := New()
+	defer s.Close()
+	s.RunRetention = time.Minute
+	run, err := s.Start("", &Request{GamePin: "123", Count: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Stop("", run.ID)
+	s.evictRuns(time.Now())
+	if _, ok := s.Run("", run.ID); !ok {
+		t.Error("stopped run was forgotten before its retention passed")
+	}
+	s.evictRuns(time.Now().Add(2 * time.Minute))
+	if _, ok := s.Run("", run.ID); ok {
+		t.Error("stopped run was still listed after its retention")
+	}
+	if len(s.Runs("")) != 0 {
+		t.Errorf("unexpected runs: %d", len(s.Runs("")))
+	}
+}
+
+func TestQuotaReleasedByKickedBots(t *testing.T) {
+	fake := kahoottest.NewServer(kahoottest.Config{})
+	defer fake.Close()
+	old := kahoot.CurrentProtocol()
+	p := kahoot.CurrentProtocol()
+	p.ReserveURL = fake.ReserveURL()
+	p.DialAddr = fake.DialAddr()
+	p.CometdURL = fake.CometdURL()
+	if err := kahoot.SetProtocol(&p); err != nil {
+		t.Fatal(err)
+	}
+	defer kahoot.SetProtocol(&old)
+
+	s := New()
+	defer s.Close()
+	s.AddTenant(Tenant{Name: "a", Key: "key-a", MaxBots: 2})
+	run, err := s.Start("a", &Request{GamePin: "123456", Count: 2, Prefix: "bot"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor := func(what string, done func() bool) {
+		deadline := time.Now().Add(5 * time.Second)
+		for !done() {
+			if time.Now().After(deadline) {
+				t.Fatal("timed out waiting for " + what)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("logins", func() bool { return len(fake.Logins()) == 2 })
+	if _, err := s.Start("a", &Request{GamePin: "123456", Count: 1}); err != ErrQuota {
+		t.Errorf("expected quota error but got %v", err)
+	}
+	fake.Kick("bot1", 1)
+	fake.Kick("bot2", 1)
+	waitFor("kicks", func() bool {
+		r, _ := s.Run("a", run.ID)
+		return r.Connected == 0
+	})
+	if stats := s.Stats("a"); stats.ActiveBots != 0 || stats.Running != 0 {
+		t.Errorf("kicked bots still count: %+v", stats)
+	}
+	if _, err := s.Start("a", &Request{GamePin: "123456", Count: 2}); err != nil {
+		t.Errorf("expected the kicked bots' quota to be free but got %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Tenant is an API key holder whose swarms and stats are
+// kept separate from every other tenant's.
+type Tenant struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+
+	// MaxBots limits the number of bots in the tenant's
+	// running swarms. Zero means no limit.
+	MaxBots int `json:"maxBots,omitempty"`
+
+	// MaxRate limits how many bots per second the tenant's
+	// swarms may start connecting, across all of them.
+	// Zero means no limit.
+	MaxRate float64 `json:"maxRate,omitempty"`
+
+	// Role is the role of Key; see ParseRole.
+	Role Role `json:"role,omitempty"`
+
+	// Members are further keys for the same tenant, such as
+	// read-only keys for the people watching a shared lab's
+	// runs.
+	Members []Member `json:"members,omitempty"`
+}
+
+// Stats summarizes a tenant's use of the server.
+type Stats struct {
+	Tenant     string `json:"tenant"`
+	Swarms     int    `json:"swarms"`
+	Running    int    `json:"running"`
+	ActiveBots int    `json:"activeBots"`
+	Joined     int    `json:"joined"`
+	Failed     int    `json:"failed"`
+}
+
+// ReadTenants reads a JSON array of tenants from a file.
+func ReadTenants(path string) ([]Tenant, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var tenants []Tenant
+	if err := json.Unmarshal(data, &tenants); err != nil {
+		return nil, fmt.Errorf("parse tenants: %s", err)
+	}
+	return tenants, nil
+}
+
+type tenantState struct {
+	Tenant
+	throttle *throttle
+}
+
+// AddTenant registers a tenant.
+// Once any tenant exists, every API request other than the
+// health probes must present a tenant's key.
+func (s *Server) AddTenant(t Tenant) error {
+	if t.Name == "" || t.Key == "" {
+		return errors.New("tenant needs a name and a key")
+	}
+	var err error
+	if t.Role, err = ParseRole(string(t.Role)); err != nil {
+		return fmt.Errorf("tenant %s: %s", t.Name, err)
+	}
+	t.Members = append([]Member{}, t.Members...)
+	for i, m := range t.Members {
+		if m.Key == "" {
+			return fmt.Errorf("tenant %s: member %q needs a key", t.Name, m.Name)
+		}
+		if t.Members[i].Role, err = ParseRole(string(m.Role)); err != nil {
+			return fmt.Errorf("tenant %s: member %q: %s", t.Name, m.Name, err)
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, other := range s.tenants {
+		if other.Name == t.Name {
+			return errors.New("duplicate tenant: " + t.Name)
+		}
+	}
+	state := &tenantState{Tenant: t}
+	if t.MaxRate > 0 {
+		state.throttle = &throttle{interval: time.Duration(float64(time.Second) / t.MaxRate)}
+	}
+	s.tenants = append(s.tenants, state)
+	return nil
+}
+
+// authenticate finds the tenant and role for a request's API
+// key, given as "Authorization: Bearer KEY" or
+// "X-API-Key: KEY". Without any tenants, every request belongs
+// to the anonymous tenant with RoleAdmin.
+func (s *Server) authenticate(r *http.Request) (*tenantState, Role, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if len(s.tenants) == 0 {
+		return &tenantState{}, RoleAdmin, true
+	}
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		key = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if key == "" {
+		return nil, "", false
+	}
+	for _, t := range s.tenants {
+		if subtle.ConstantTimeCompare([]byte(t.Key), []byte(key)) == 1 {
+			return t, t.Role, true
+		}
+		for _, m := range t.Members {
+			if subtle.ConstantTimeCompare([]byte(m.Key), []byte(key)) == 1 {
+				return t, m.Role, true
+			}
+		}
+	}
+	return nil, "", false
+}
+
+// hasTenant must be called with s.lock held.
+func (s *Server) hasTenant(name string) bool {
+	for _, t := range s.tenants {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) tenant(name string) *tenantState {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, t := range s.tenants {
+		if t.Name == name {
+			return t
+		}
+	}
+	return &tenantState{Tenant: Tenant{Name: name}}
+}
+
+// Stats returns a tenant's stats.
+func (s *Server) Stats(tenant string) *Stats {
+	stats := &Stats{Tenant: tenant}
+	for _, run := range s.Runs(tenant) {
+		stats.Swarms++
+		stats.Joined += run.Joined
+		stats.Failed += run.Failed
+		if !run.Stopped && run.Connected+run.Pending > 0 {
+			stats.Running++
+			stats.ActiveBots += run.Connected + run.Pending
+		}
+	}
+	return stats
+}
+
+// A throttle spaces out calls to wait by a fixed interval.
+type throttle struct {
+	lock     sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (t *throttle) wait() {
+	t.lock.Lock()
+	now := time.Now()
+	start := t.next
+	if start.Before(now) {
+		start = now
+	}
+	t.next = start.Add(t.interval)
+	t.lock.Unlock()
+	time.Sleep(start.Sub(now))
+}
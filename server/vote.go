@@ -0,0 +1,205 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	mathrand "math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/swarm"
+)
+
+// VoteStrategy is the strategy name which makes a swarm's
+// bots submit the answer most human helpers voted for.
+// Helpers vote on the page at the run's VoteURL.
+const VoteStrategy = "vote"
+
+// DefaultVoteTimeout is how long a question's vote stays open
+// if the server did not say when the question closes.
+const DefaultVoteTimeout = 10 * time.Second
+
+// voteMargin is how long before the question closes the vote
+// ends, leaving time to submit the answer.
+const voteMargin = time.Second
+
+//go:embed vote.html
+var votePage []byte
+
+// A Ballot is the vote on one question.
+type Ballot struct {
+	Question   int       `json:"question"`
+	NumAnswers int       `json:"numAnswers"`
+	Closes     time.Time `json:"closes"`
+	Open       bool      `json:"open"`
+	Tally      []int     `json:"tally"`
+
+	// Styles are the choices' colours and shapes, as the
+	// host's screen shows them.
+	Styles []kahoot.ChoiceStyle `json:"styles"`
+
+	// Winner is the choice submitted once the vote closes,
+	// or -1 while it is open.
+	Winner int `json:"winner"`
+
+	votes map[string]int
+	done  chan struct{}
+}
+
+// A ballotBox collects the votes of a run's helpers. Every bot
+// of the run waits for the same ballot on each question.
+type ballotBox struct {
+	token   string
+	timeout time.Duration
+
+	lock    sync.Mutex
+	current *Ballot
+}
+
+func newBallotBox() *ballotBox {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return &ballotBox{token: hex.EncodeToString(buf), timeout: DefaultVoteTimeout}
+}
+
+// choose is the run's VoteStrategy.
+func (b *ballotBox) choose(bot *swarm.Bot, action *kahoot.QuizAction) int {
+	ballot := b.open(action)
+	<-ballot.done
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return ballot.Winner
+}
+
+// open returns the ballot for a question, starting it if this
+// is the first bot to ask.
+func (b *ballotBox) open(action *kahoot.QuizAction) *Ballot {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.current != nil && b.current.Question == action.Index {
+		return b.current
+	}
+	closes := time.Now().Add(b.timeout)
+	if deadline := action.Deadline(); !deadline.IsZero() && deadline.Add(-voteMargin).Before(closes) {
+		closes = deadline.Add(-voteMargin)
+	}
+	ballot := &Ballot{
+		Question:   action.Index,
+		NumAnswers: action.NumAnswers,
+		Closes:     closes,
+		Open:       true,
+		Winner:     -1,
+		Tally:      make([]int, action.NumAnswers),
+		Styles:     action.Styles(),
+		votes:      map[string]int{},
+		done:       make(chan struct{}),
+	}
+	b.current = ballot
+	time.AfterFunc(time.Until(closes), func() {
+		b.close(ballot)
+	})
+	return ballot
+}
+
+func (b *ballotBox) close(ballot *Ballot) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !ballot.Open {
+		return
+	}
+	ballot.Open = false
+	ballot.Winner = -1
+	for choice, n := range ballot.Tally {
+		if n > 0 && (ballot.Winner < 0 || n > ballot.Tally[ballot.Winner]) {
+			ballot.Winner = choice
+		}
+	}
+	if ballot.Winner < 0 && ballot.NumAnswers > 0 {
+		// Nobody voted; a guess beats not answering.
+		ballot.Winner = mathrand.Intn(ballot.NumAnswers)
+	}
+	close(ballot.done)
+}
+
+// vote records or changes a helper's vote on the current
+// question.
+func (b *ballotBox) vote(voter string, question, choice int) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	ballot := b.current
+	if ballot == nil || ballot.Question != question || !ballot.Open {
+		return errors.New("voting on this question is closed")
+	} else if choice < 0 || choice >= ballot.NumAnswers {
+		return errors.New("no such answer")
+	} else if voter == "" {
+		return errors.New("missing voter")
+	}
+	if old, ok := ballot.votes[voter]; ok {
+		ballot.Tally[old]--
+	}
+	ballot.votes[voter] = choice
+	ballot.Tally[choice]++
+	return nil
+}
+
+// ballot returns a copy of the current ballot, or nil before
+// the first question.
+func (b *ballotBox) ballot() *Ballot {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.current == nil {
+		return nil
+	}
+	res := *b.current
+	res.Tally = append([]int{}, b.current.Tally...)
+	return &res
+}
+
+// serveVote serves /vote/ID, the helpers' page, and
+// /vote/ID/ballot, which the page polls and posts votes to.
+// Helpers need the run's vote token rather than an API key.
+func (s *Server) serveVote(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/vote/")
+	id := strings.TrimSuffix(path, "/ballot")
+	s.lock.Lock()
+	run, ok := s.runs[id]
+	s.lock.Unlock()
+	if !ok || run.votes == nil ||
+		subtle.ConstantTimeCompare([]byte(r.URL.Query().Get("token")), []byte(run.votes.token)) != 1 {
+		http.NotFound(w, r)
+		return
+	}
+	if id == path {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(votePage)
+		return
+	}
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, run.votes.ballot())
+	case "POST":
+		var v struct {
+			Voter    string `json:"voter"`
+			Question int    `json:"question"`
+			Choice   int    `json:"choice"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			http.Error(w, "invalid vote: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := run.votes.vote(v.Voter, v.Question, v.Choice); err != nil {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		writeJSON(w, http.StatusOK, run.votes.ballot())
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestBallotBox(t *testing.T) {
+	b := newBallotBox()
+	b.timeout = 50 * time.Millisecond
+	action := &kahoot.QuizAction{Index: 2, NumAnswers: 4}
+	b.open(action)
+	for voter, choice := range map[string]int{"a": 1, "b": 3, "c": 3} {
+		if err := b.vote(voter, 2, choice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Changed votes replace the old ones.
+	b.vote("c", 2, 1)
+	b.vote("d", 2, 1)
+	if err := b.vote("e", 1, 0); err == nil {
+		t.Error("expected an error for the wrong question")
+	}
+	if err := b.vote("e", 2, 4); err == nil {
+		t.Error("expected an error for a missing answer")
+	}
+	if ballot := b.ballot(); !ballot.Open || ballot.Winner != -1 || ballot.Tally[1] != 3 {
+		t.Errorf("unexpected open ballot: %+v", ballot)
+	}
+
+	if choice := b.choose(nil, action); choice != 1 {
+		t.Errorf("expected the majority vote 1 but got %d", choice)
+	}
+	if err := b.vote("e", 2, 0); err == nil {
+		t.Error("expected an error after the vote closed")
+	}
+
+	// Ties go to the earlier answer, and no votes to a guess.
+	action = &kahoot.QuizAction{Index: 3, NumAnswers: 2}
+	b.open(action)
+	b.vote("a", 3, 1)
+	b.vote("b", 3, 0)
+	if choice := b.choose(nil, action); choice != 0 {
+		t.Errorf("expected the tie to go to 0 but got %d", choice)
+	}
+	action = &kahoot.QuizAction{Index: 4, NumAnswers: 2}
+	if choice := b.choose(nil, action); choice < 0 || choice > 1 {
+		t.Errorf("unexpected guess %d", choice)
+	}
+}
+
+func TestBallotDeadline(t *testing.T) {
+	b := newBallotBox()
+	action := &kahoot.QuizAction{
+		Index:      0,
+		NumAnswers: 4,
+		Received:   time.Now(),
+		TimeLeft:   time.Second + 50*time.Millisecond,
+	}
+	start := time.Now()
+	b.choose(nil, action)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("vote should close before the question deadline, took %s", elapsed)
+	}
+}
+
+func TestVotePage(t *testing.T) {
+	s := New()
+	defer s.Close()
+	run, err := s.Start("", &Request{GamePin: "123", Count: 1, Strategy: VoteStrategy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.VoteURL == "" {
+		t.Fatal("missing vote URL")
+	}
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/vote/"+run.ID+"?token=wrong", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a wrong token but got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", run.VoteURL, nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "<html>") {
+		t.Errorf("expected the vote page but got %d", rec.Code)
+	}
+
+	ballotURL := strings.Replace(run.VoteURL, "?", "/ballot?", 1)
+	rec = httptest.NewRecorder()
+	body := strings.NewReader(`{"voter": "a", "question": 0, "choice": 0}`)
+	s.ServeHTTP(rec, httptest.NewRequest("POST", ballotURL, body))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("expected a conflict before any question but got %d", rec.Code)
+	}
+}
@@ -0,0 +1,8 @@
+// Package drivers registers the database/sql drivers which
+// sqlsink supports. Import it for its side effects.
+//
+// SQLite is registered as "sqlite" and Postgres as
+// "postgres"; both are pure Go.
+package drivers
+
+import _ "github.com/lib/pq"
@@ -0,0 +1,3 @@
+package drivers
+
+import _ "modernc.org/sqlite"
@@ -0,0 +1,28 @@
+CREATE TABLE runs (
+	id TEXT PRIMARY KEY,
+	game_pin TEXT NOT NULL,
+	started TIMESTAMP NOT NULL
+);
+
+CREATE TABLE events (
+	run_id TEXT NOT NULL REFERENCES runs (id),
+	bot TEXT NOT NULL,
+	seq BIGINT NOT NULL,
+	time TIMESTAMP NOT NULL,
+	topic TEXT NOT NULL,
+	type TEXT NOT NULL,
+	data TEXT,
+	PRIMARY KEY (run_id, bot, seq)
+);
+
+CREATE TABLE results (
+	run_id TEXT NOT NULL REFERENCES runs (id),
+	bot TEXT NOT NULL,
+	question_index INTEGER NOT NULL,
+	choice INTEGER NOT NULL,
+	is_correct BOOLEAN NOT NULL,
+	points DOUBLE PRECISION NOT NULL,
+	total_score DOUBLE PRECISION NOT NULL,
+	rank INTEGER NOT NULL,
+	PRIMARY KEY (run_id, bot, question_index)
+);
@@ -0,0 +1,201 @@
+// Package sqlsink stores game events and results in a SQL
+// database for ad-hoc analysis of long experiment campaigns.
+//
+// The schema lives in the migrations directory and works on
+// both SQLite and Postgres. Every run gets a row in the runs
+// table; each bot's events go to the events table, and reveal
+// results are also broken out into the results table:
+//
+//	SELECT bot, SUM(points) FROM results
+//	WHERE run_id = 'flood-20170102-150405' GROUP BY bot;
+package sqlsink
+
+import (
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// A Dialect describes the differences between databases that
+// matter to the sink.
+type Dialect struct {
+	Name string
+
+	// Placeholder returns the parameter marker for the n-th
+	// argument of a statement, starting at 1.
+	Placeholder func(n int) string
+}
+
+var (
+	SQLite = Dialect{
+		Name:        "sqlite",
+		Placeholder: func(n int) string { return "?" },
+	}
+	Postgres = Dialect{
+		Name:        "postgres",
+		Placeholder: func(n int) string { return "$" + strconv.Itoa(n) },
+	}
+)
+
+// LookupDialect finds the dialect for a database/sql driver
+// name.
+func LookupDialect(driver string) (Dialect, error) {
+	switch driver {
+	case "sqlite", "sqlite3":
+		return SQLite, nil
+	case "postgres", "pgx":
+		return Postgres, nil
+	}
+	return Dialect{}, fmt.Errorf("unsupported database driver: %s", driver)
+}
+
+// Migrate applies every migration which has not yet been
+// applied, recording the applied versions in the
+// schema_migrations table.
+func Migrate(db *sql.DB, d Dialect) error {
+	_, err := db.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)")
+	if err != nil {
+		return err
+	}
+	applied := map[int]bool{}
+	rows, err := db.Query("SELECT version FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+
+	names, err := migrationNames()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		version, _ := strconv.Atoi(strings.SplitN(name, "_", 2)[0])
+		if applied[version] {
+			continue
+		}
+		script, err := migrations.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return err
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		for _, stmt := range strings.Split(string(script), ";") {
+			if strings.TrimSpace(stmt) == "" {
+				continue
+			}
+			if _, err := tx.Exec(stmt); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("migration %s: %s", name, err)
+			}
+		}
+		_, err = tx.Exec("INSERT INTO schema_migrations (version) VALUES ("+d.Placeholder(1)+")", version)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func migrationNames() ([]string, error) {
+	entries, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// A Sink writes the events of one run to a database.
+type Sink struct {
+	db      *sql.DB
+	dialect Dialect
+	runID   string
+}
+
+// New migrates the database and registers a run.
+func New(db *sql.DB, d Dialect, runID, gamePin string) (*Sink, error) {
+	if err := Migrate(db, d); err != nil {
+		return nil, err
+	}
+	s := &Sink{db: db, dialect: d, runID: runID}
+	_, err := db.Exec("INSERT INTO runs (id, game_pin, started) VALUES "+s.values(3),
+		runID, gamePin, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Record writes every event from sub until it is closed.
+func (s *Sink) Record(bot string, sub *kahoot.Subscription) error {
+	var firstErr error
+	for e := range sub.C {
+		if err := s.insert(bot, e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *Sink) insert(bot string, e kahoot.Event) error {
+	data := e.Data
+	if err, ok := data.(error); ok {
+		data = err.Error()
+	}
+	var dataStr sql.NullString
+	if data != nil {
+		encoded, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		dataStr = sql.NullString{String: string(encoded), Valid: true}
+	}
+	_, err := s.db.Exec("INSERT INTO events (run_id, bot, seq, time, topic, type, data) VALUES "+
+		s.values(7), s.runID, bot, int64(e.Seq), e.Time.UTC(), string(e.Topic), e.Type, dataStr)
+	if err != nil {
+		return err
+	}
+	if result, ok := e.Data.(*kahoot.QuizResult); ok && e.Topic == kahoot.TopicResult {
+		_, err = s.db.Exec("INSERT INTO results (run_id, bot, question_index, choice, is_correct, "+
+			"points, total_score, rank) VALUES "+s.values(8), s.runID, bot, result.Index,
+			result.Choice, result.IsCorrect, result.Points, result.TotalScore, result.Rank)
+	}
+	return err
+}
+
+func (s *Sink) values(n int) string {
+	var markers []string
+	for i := 1; i <= n; i++ {
+		markers = append(markers, s.dialect.Placeholder(i))
+	}
+	return "(" + strings.Join(markers, ", ") + ")"
+}
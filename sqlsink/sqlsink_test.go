@@ -0,0 +1,128 @@
+package sqlsink
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// fakeDriver records statements and remembers applied
+// migration versions, which is all the sink reads back.
+type fakeDriver struct {
+	lock       sync.Mutex
+	statements []string
+	args       [][]driver.Value
+	versions   []int64
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) { return &fakeConn{d}, nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) { return &fakeStmt{c.d, query}, nil }
+func (c *fakeConn) Close() error                              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *fakeConn) Commit() error                             { return nil }
+func (c *fakeConn) Rollback() error                           { return nil }
+
+type fakeStmt struct {
+	d     *fakeDriver
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.lock.Lock()
+	defer s.d.lock.Unlock()
+	s.d.statements = append(s.d.statements, strings.TrimSpace(s.query))
+	s.d.args = append(s.d.args, args)
+	if strings.HasPrefix(s.query, "INSERT INTO schema_migrations") {
+		s.d.versions = append(s.d.versions, args[0].(int64))
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.lock.Lock()
+	defer s.d.lock.Unlock()
+	return &fakeRows{versions: append([]int64{}, s.d.versions...)}, nil
+}
+
+type fakeRows struct{ versions []int64 }
+
+func (r *fakeRows) Columns() []string { return []string{"version"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.versions) == 0 {
+		return io.EOF
+	}
+	dest[0] = r.versions[0]
+	r.versions = r.versions[1:]
+	return nil
+}
+
+func (d *fakeDriver) count(prefix string) int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	var n int
+	for _, s := range d.statements {
+		if strings.HasPrefix(s, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestSink(t *testing.T) {
+	d := &fakeDriver{}
+	sql.Register("sqlsink-fake", d)
+	db, err := sql.Open("sqlsink-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sink, err := New(db, Postgres, "run1", "123456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("CREATE TABLE events"); n != 1 {
+		t.Errorf("expected events table to be created once but got %d", n)
+	}
+	if err := Migrate(db, Postgres); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("CREATE TABLE events"); n != 1 {
+		t.Error("migration was applied twice")
+	}
+
+	bus := kahoot.NewBus()
+	sub := bus.Subscribe()
+	bus.Publish(kahoot.TopicConnection, "login", kahoot.Player{Nickname: "alex"})
+	bus.Publish(kahoot.TopicResult, "result", &kahoot.QuizResult{Index: 2, IsCorrect: true, Points: 950})
+	bus.Close()
+	if err := sink.Record("alex", sub); err != nil {
+		t.Fatal(err)
+	}
+	if n := d.count("INSERT INTO events"); n != 2 {
+		t.Errorf("expected 2 events but got %d", n)
+	}
+	if n := d.count("INSERT INTO results"); n != 1 {
+		t.Errorf("expected 1 result but got %d", n)
+	}
+	for i, s := range d.statements {
+		if strings.HasPrefix(s, "INSERT INTO results") {
+			if !strings.Contains(s, "$8") || d.args[i][2].(int64) != 2 {
+				t.Errorf("unexpected result insert: %s %v", s, d.args[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package swarm
+
+import (
+	"sort"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// How well the bots handled a type of question, as listed in
+// a Report.
+const (
+	// CoverageAnswered types were answered, and the server
+	// confirmed the answers.
+	CoverageAnswered = "answered"
+
+	// CoveragePartial types were parsed, but not answered the
+	// way they ask to be.
+	CoveragePartial = "partially parsed"
+
+	// CoverageUnsupported types are unknown to the kahoot
+	// package, so the bots cannot answer them properly.
+	CoverageUnsupported = "unsupported"
+
+	// CoverageNoAnswer types, such as content slides, take no
+	// answer.
+	CoverageNoAnswer = "nothing to answer"
+)
+
+// unnamedType stands for questions which did not name their
+// type in a Report.
+const unnamedType = "(not given)"
+
+// A TypeCoverage is how well the bots handled one type of
+// question.
+type TypeCoverage struct {
+	Type      string `json:"type"`
+	Questions int    `json:"questions"`
+	Answers   int    `json:"answers"`
+	Coverage  string `json:"coverage"`
+
+	// Note says what is missing, for coverage short of
+	// answered.
+	Note string `json:"note,omitempty"`
+}
+
+// coverage records the type of every question and the answers
+// confirmed for it. It is guarded by the swarm's lock.
+type coverage struct {
+	types   map[int]string
+	answers map[int]int
+}
+
+func (c *coverage) question(action *kahoot.QuizAction) {
+	if c.types == nil {
+		c.types = map[int]string{}
+	}
+	c.types[action.Index] = action.QuestionType
+}
+
+func (c *coverage) answered(index int) {
+	if c.answers == nil {
+		c.answers = map[int]int{}
+	}
+	c.answers[index]++
+}
+
+// report lists the types of question seen, in alphabetical
+// order.
+func (c *coverage) report() []TypeCoverage {
+	byType := map[string]*TypeCoverage{}
+	for index, questionType := range c.types {
+		name := questionType
+		if name == "" {
+			name = unnamedType
+		}
+		entry, ok := byType[name]
+		if !ok {
+			entry = &TypeCoverage{Type: name}
+			byType[name] = entry
+		}
+		entry.Questions++
+		entry.Answers += c.answers[index]
+	}
+	var res []TypeCoverage
+	for _, entry := range byType {
+		questionType := entry.Type
+		if questionType == unnamedType {
+			questionType = ""
+		}
+		entry.Coverage, entry.Note = typeCoverage(questionType, entry.Answers)
+		res = append(res, *entry)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Type < res[j].Type
+	})
+	return res
+}
+
+// typeCoverage judges a question type, given the number of
+// answers confirmed for questions of that type. Bots answer
+// with a choice, so only choice questions can be answered.
+func typeCoverage(questionType string, answers int) (level, note string) {
+	switch kahoot.AnswerKind(questionType) {
+	case "":
+		return CoverageUnsupported, "the kahoot package does not know this type"
+	case kahoot.AnswerNone:
+		return CoverageNoAnswer, ""
+	case kahoot.AnswerChoice:
+		if answers > 0 {
+			return CoverageAnswered, ""
+		}
+		return CoveragePartial, "no answer was confirmed"
+	case kahoot.AnswerText:
+		return CoveragePartial, "bots answer with a choice, not text (see Quiz.SendText)"
+	case kahoot.AnswerOrder:
+		return CoveragePartial, "bots answer with a choice, not an order (see Quiz.SendJumble)"
+	default:
+		return CoveragePartial, "bots answer with a choice, not a value (see Quiz.SendSlider)"
+	}
+}
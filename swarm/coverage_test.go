@@ -0,0 +1,51 @@
+package swarm
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestCoverage(t *testing.T) {
+	s := New("123", Options{})
+	for i, questionType := range []string{"quiz", "jumble", "quiz", "", "drop_pin", "content", "survey"} {
+		s.publishQuestion(&kahoot.QuizAction{Type: kahoot.QuestionIntro, Index: i, QuestionType: questionType})
+	}
+	s.lock.Lock()
+	s.coverage.answered(0)
+	s.coverage.answered(0)
+	s.coverage.answered(1)
+	s.coverage.answered(3)
+	s.lock.Unlock()
+
+	r := s.Report()
+	var got []string
+	for _, c := range r.Coverage {
+		got = append(got, c.Type+"="+c.Coverage)
+	}
+	expected := []string{
+		unnamedType + "=" + CoverageAnswered,
+		"content=" + CoverageNoAnswer,
+		"drop_pin=" + CoverageUnsupported,
+		"jumble=" + CoveragePartial,
+		"quiz=" + CoverageAnswered,
+		"survey=" + CoveragePartial,
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("unexpected coverage %v", got)
+	}
+	if quiz := r.Coverage[4]; quiz.Questions != 2 || quiz.Answers != 2 {
+		t.Errorf("unexpected quiz coverage %+v", quiz)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "partially parsed: bots answer with a choice, not an order") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+}
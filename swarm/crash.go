@@ -0,0 +1,49 @@
+package swarm
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// A Crash records a panic in one of a bot's goroutines.
+type Crash struct {
+	Value interface{}
+	Stack []byte
+	Time  time.Time
+}
+
+func (c *Crash) Error() string {
+	return fmt.Sprintf("bot crashed: %v", c.Value)
+}
+
+// Report formats the crash for a crash report file.
+func (c *Crash) Report(bot *Bot) []byte {
+	return []byte(fmt.Sprintf("bot: %s\ntime: %s\npanic: %v\n\n%s",
+		bot.Nickname, c.Time.Format(time.RFC3339), c.Value, c.Stack))
+}
+
+// recoverBot must be deferred by every goroutine working on
+// behalf of a single bot. If the goroutine panics, the bot is
+// marked failed and disconnected, and the rest of the swarm
+// keeps running.
+func (s *Swarm) recoverBot(bot *Bot) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	crash := &Crash{Value: r, Stack: debug.Stack(), Time: time.Now()}
+	s.lock.Lock()
+	bot.Crash = crash
+	bot.Err = crash
+	s.lock.Unlock()
+	if bot.Conn != nil {
+		go bot.Conn.Close()
+	}
+	s.events.Publish(kahoot.TopicError, "crash", crash)
+	if s.opts.OnCrash != nil {
+		s.opts.OnCrash(bot, crash)
+	}
+}
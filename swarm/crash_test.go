@@ -0,0 +1,25 @@
+package swarm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRecoverBot(t *testing.T) {
+	var reported *Crash
+	s := New("123", Options{OnCrash: func(bot *Bot, c *Crash) {
+		reported = c
+	}})
+	bot := &Bot{Nickname: "alex"}
+	func() {
+		defer s.recoverBot(bot)
+		var m map[string]int
+		m["x"] = 1
+	}()
+	if bot.Crash == nil || bot.Err == nil || reported != bot.Crash {
+		t.Fatal("crash was not recorded")
+	}
+	if !strings.Contains(string(bot.Crash.Report(bot)), "crash_test.go") {
+		t.Error("report should contain a stack trace")
+	}
+}
@@ -0,0 +1,104 @@
+package swarm
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// A Departure makes some of a swarm's bots leave partway
+// through the game, to model an audience drifting away.
+type Departure struct {
+	// AfterQuestion is how many questions must have been
+	// played; the bots leave as the next question starts.
+	AfterQuestion int
+
+	// Fraction is the share of the bots still in the game
+	// which leave, between 0 and 1.
+	Fraction float64
+}
+
+// ParseDepartures parses a comma-separated list of departures
+// such as "20%@3,10%@5": 20% of the bots leave after question
+// 3, and 10% of the rest after question 5.
+func ParseDepartures(s string) ([]Departure, error) {
+	var res []Departure
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		parts := strings.SplitN(term, "@", 2)
+		if len(parts) != 2 || !strings.HasSuffix(parts[0], "%") {
+			return nil, errors.New("invalid departure (want e.g. 20%@3): " + term)
+		}
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(parts[0], "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return nil, errors.New("invalid departure percentage: " + term)
+		}
+		question, err := strconv.Atoi(parts[1])
+		if err != nil || question < 1 {
+			return nil, errors.New("invalid departure question: " + term)
+		}
+		res = append(res, Departure{AfterQuestion: question, Fraction: percent / 100})
+	}
+	return res, nil
+}
+
+// scheduleDepartures starts the departures due once the
+// question with the given index starts. The caller holds the
+// swarm's lock.
+func (s *Swarm) scheduleDepartures(index int) {
+	for s.departed < len(s.departures) && s.departures[s.departed].AfterQuestion <= index {
+		go s.depart(s.departures[s.departed])
+		s.departed++
+	}
+}
+
+// depart makes the departure's share of the playing bots
+// leave, publishing a "departing" connection event with their
+// number.
+func (s *Swarm) depart(d Departure) {
+	s.lock.Lock()
+	var playing []*Bot
+	for _, bot := range s.bots {
+		if bot.Err == nil && bot.Conn != nil && bot.stats.left.IsZero() &&
+			!bot.stats.kicked && !bot.stats.dropped {
+			playing = append(playing, bot)
+		}
+	}
+	leaving := s.markLeaving(chooseDeparting(playing, d.Fraction))
+	s.lock.Unlock()
+	s.events.Publish(kahoot.TopicConnection, "departing", len(leaving))
+	for _, bot := range leaving {
+		bot.Conn.GracefulClose()
+	}
+}
+
+// chooseDeparting picks the given share of the bots at random.
+func chooseDeparting(bots []*Bot, fraction float64) []*Bot {
+	n := int(math.Round(fraction * float64(len(bots))))
+	if n > len(bots) {
+		n = len(bots)
+	}
+	var res []*Bot
+	for _, i := range rand.Perm(len(bots))[:n] {
+		res = append(res, bots[i])
+	}
+	return res
+}
+
+// sortDepartures returns the departures in the order they are
+// due.
+func sortDepartures(departures []Departure) []Departure {
+	res := append([]Departure{}, departures...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].AfterQuestion < res[j].AfterQuestion
+	})
+	return res
+}
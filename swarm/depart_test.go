@@ -0,0 +1,62 @@
+package swarm
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestParseDepartures(t *testing.T) {
+	res, err := ParseDepartures("20%@3, 12.5%@5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Departure{{AfterQuestion: 3, Fraction: 0.2}, {AfterQuestion: 5, Fraction: 0.125}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("unexpected departures %+v", res)
+	}
+	for _, bad := range []string{"20@3", "20%", "0%@3", "120%@3", "20%@0", "x%@2"} {
+		if _, err := ParseDepartures(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestChooseDeparting(t *testing.T) {
+	bots := make([]*Bot, 10)
+	for i := range bots {
+		bots[i] = &Bot{}
+	}
+	chosen := chooseDeparting(bots, 0.25)
+	seen := map[*Bot]bool{}
+	for _, bot := range chosen {
+		seen[bot] = true
+	}
+	if len(chosen) != 3 || len(seen) != 3 {
+		t.Errorf("expected 3 distinct bots, got %d", len(chosen))
+	}
+	if len(chooseDeparting(bots, 1.5)) != 10 {
+		t.Error("a share above 1 should pick every bot")
+	}
+}
+
+func TestDeparturesScheduled(t *testing.T) {
+	s := New("123", Options{Departures: []Departure{{AfterQuestion: 2, Fraction: 0.5},
+		{AfterQuestion: 1, Fraction: 0.5}}})
+	sub := s.Events().Subscribe(kahoot.TopicConnection)
+	defer sub.Close()
+	for i := 0; i < 3; i++ {
+		s.publishQuestion(&kahoot.QuizAction{Type: kahoot.QuestionIntro, Index: i})
+	}
+	for i := 0; i < 2; i++ {
+		if e := <-sub.C; e.Type != "departing" || e.Data != 0 {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.departed != 2 {
+		t.Errorf("expected both departures to start, got %d", s.departed)
+	}
+}
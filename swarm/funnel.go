@@ -0,0 +1,69 @@
+package swarm
+
+import (
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// A StageReport summarizes one stage of joining across the
+// swarm's bots, so that slow or failing stages stand out.
+type StageReport struct {
+	Stage    string `json:"stage"`
+	Attempts int    `json:"attempts"`
+	Failed   int    `json:"failed"`
+
+	// SuccessRate is the fraction of attempts which succeeded.
+	SuccessRate float64 `json:"successRate"`
+
+	// Latency is measured over every attempt, failed or not,
+	// since failures such as timeouts are often the slowest.
+	Latency Latencies `json:"latency"`
+}
+
+// funnel is guarded by the swarm's lock.
+type funnel map[string]*stageSamples
+
+type stageSamples struct {
+	failed int
+	took   []time.Duration
+}
+
+func (f funnel) add(stage string, took time.Duration, err error) {
+	samples := f[stage]
+	if samples == nil {
+		samples = &stageSamples{}
+		f[stage] = samples
+	}
+	samples.took = append(samples.took, took)
+	if err != nil {
+		samples.failed++
+	}
+}
+
+// reports lists the stages bots went through, in the order
+// they happen.
+func (f funnel) reports() []StageReport {
+	var res []StageReport
+	for _, stage := range kahoot.JoinStages {
+		samples := f[stage]
+		if samples == nil {
+			continue
+		}
+		attempts := len(samples.took)
+		res = append(res, StageReport{
+			Stage:       stage,
+			Attempts:    attempts,
+			Failed:      samples.failed,
+			SuccessRate: float64(attempts-samples.failed) / float64(attempts),
+			Latency:     summarize(samples.took),
+		})
+	}
+	return res
+}
+
+func (s *Swarm) recordStage(stage string, took time.Duration, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.funnel.add(stage, took, err)
+}
@@ -0,0 +1,40 @@
+package swarm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestFunnel(t *testing.T) {
+	s := New("123", Options{})
+	s.recordStage(kahoot.StageLogin, 30*time.Millisecond, nil)
+	s.recordStage(kahoot.StageReserve, 10*time.Millisecond, nil)
+	s.recordStage(kahoot.StageReserve, 20*time.Millisecond, errors.New("timeout"))
+	s.recordStage(kahoot.StageLogin, 40*time.Millisecond, nil)
+
+	r := s.Report()
+	if len(r.Funnel) != 2 {
+		t.Fatalf("unexpected funnel %+v", r.Funnel)
+	}
+	reserve, login := r.Funnel[0], r.Funnel[1]
+	if reserve.Stage != kahoot.StageReserve || reserve.Attempts != 2 || reserve.Failed != 1 ||
+		reserve.SuccessRate != 0.5 || reserve.Latency.Max != 20 {
+		t.Errorf("unexpected reserve stage %+v", reserve)
+	}
+	if login.Stage != kahoot.StageLogin || login.SuccessRate != 1 || login.Latency.Min != 30 {
+		t.Errorf("unexpected login stage %+v", login)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "50.0%") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+}
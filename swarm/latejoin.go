@@ -0,0 +1,104 @@
+package swarm
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// A LateJoin adds bots to a swarm after the game has started,
+// to model stragglers. Bots which join late ask the server for
+// the game's state, so they can answer the question in
+// progress if the game lets them.
+type LateJoin struct {
+	// AfterQuestion is how many questions must have been
+	// played; the bots join as the next question starts.
+	AfterQuestion int
+
+	// Count is the number of bots which join.
+	Count int
+
+	// Prefix names the bots, as for AddBots. If it is empty,
+	// DefaultLatePrefix is used.
+	Prefix string
+}
+
+// DefaultLatePrefix names late-joining bots with no prefix of
+// their own.
+const DefaultLatePrefix = "late"
+
+// ParseLateJoins parses a comma-separated list of late joins
+// such as "10@3,5@6": 10 bots join after question 3, and 5
+// more after question 6. A term may name its bots, as in
+// "10@3:straggler".
+func ParseLateJoins(s string) ([]LateJoin, error) {
+	var res []LateJoin
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		var join LateJoin
+		if i := strings.Index(term, ":"); i >= 0 {
+			join.Prefix = term[i+1:]
+			term = term[:i]
+		}
+		parts := strings.SplitN(term, "@", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("invalid late join (want e.g. 10@3): " + term)
+		}
+		count, err := strconv.Atoi(parts[0])
+		if err != nil || count < 1 {
+			return nil, errors.New("invalid late join count: " + term)
+		}
+		question, err := strconv.Atoi(parts[1])
+		if err != nil || question < 1 {
+			return nil, errors.New("invalid late join question: " + term)
+		}
+		join.Count = count
+		join.AfterQuestion = question
+		res = append(res, join)
+	}
+	return res, nil
+}
+
+// scheduleLateJoins starts the late joins due once the question
+// with the given index starts. The caller holds the swarm's
+// lock.
+func (s *Swarm) scheduleLateJoins(index int) {
+	for s.lateJoined < len(s.lateJoins) && s.lateJoins[s.lateJoined].AfterQuestion <= index {
+		go s.joinLate(s.lateJoins[s.lateJoined])
+		s.lateJoined++
+	}
+}
+
+// joinLate adds the late join's bots, publishing a
+// "lateJoining" connection event with their number.
+func (s *Swarm) joinLate(join LateJoin) {
+	prefix := join.Prefix
+	if prefix == "" {
+		prefix = DefaultLatePrefix
+	}
+	s.events.Publish(kahoot.TopicConnection, "lateJoining", join.Count)
+	s.AddBots(join.Count, prefix)
+}
+
+// started reports whether any bot has seen a question.
+func (s *Swarm) started() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.lastQuestion >= 0
+}
+
+// sortLateJoins returns the late joins in the order they are
+// due.
+func sortLateJoins(joins []LateJoin) []LateJoin {
+	res := append([]LateJoin{}, joins...)
+	sort.SliceStable(res, func(i, j int) bool {
+		return res[i].AfterQuestion < res[j].AfterQuestion
+	})
+	return res
+}
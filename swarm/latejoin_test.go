@@ -0,0 +1,33 @@
+package swarm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLateJoins(t *testing.T) {
+	res, err := ParseLateJoins("10@3, 5@6:straggler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []LateJoin{{AfterQuestion: 3, Count: 10}, {AfterQuestion: 6, Count: 5, Prefix: "straggler"}}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("unexpected late joins %+v", res)
+	}
+	for _, bad := range []string{"10", "0@3", "10@0", "x@2", "10%@3"} {
+		if _, err := ParseLateJoins(bad); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestSortLateJoins(t *testing.T) {
+	joins := []LateJoin{{AfterQuestion: 4, Count: 1}, {AfterQuestion: 2, Count: 2}}
+	sorted := sortLateJoins(joins)
+	if sorted[0].AfterQuestion != 2 || sorted[1].AfterQuestion != 4 {
+		t.Errorf("unexpected order %+v", sorted)
+	}
+	if joins[0].AfterQuestion != 4 {
+		t.Error("sortLateJoins changed its argument")
+	}
+}
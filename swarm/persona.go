@@ -0,0 +1,129 @@
+package swarm
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultPersonaDelay is the longest thinking time NewPersona
+// gives a persona unless told otherwise.
+const DefaultPersonaDelay = 3 * time.Second
+
+// A Persona is a virtual participant which behaves the same
+// way in every run it takes part in, so that repeated runs
+// simulate the same class of students.
+type Persona struct {
+	Nickname string
+
+	// Delay is how long the persona thinks before answering,
+	// on top of the swarm's AnswerDelay.
+	Delay time.Duration
+
+	// Seed seeds the random numbers behind the persona's
+	// choices; see Bot.Intn.
+	Seed int64
+}
+
+type personaJSON struct {
+	Nickname string `json:"nickname"`
+	Delay    string `json:"delay"`
+	Seed     int64  `json:"seed"`
+}
+
+// MarshalJSON writes the delay as a string such as "1.5s".
+func (p *Persona) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&personaJSON{Nickname: p.Nickname, Delay: p.Delay.String(), Seed: p.Seed})
+}
+
+// UnmarshalJSON reads what MarshalJSON wrote.
+func (p *Persona) UnmarshalJSON(data []byte) error {
+	var raw personaJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	delay, err := time.ParseDuration(raw.Delay)
+	if err != nil {
+		return err
+	}
+	*p = Persona{Nickname: raw.Nickname, Delay: delay, Seed: raw.Seed}
+	return nil
+}
+
+// NewPersona creates a persona with a random seed and a random
+// delay shorter than maxDelay.
+func NewPersona(nickname string, maxDelay time.Duration) *Persona {
+	p := &Persona{Nickname: nickname, Seed: rand.Int63()}
+	if maxDelay > 0 {
+		p.Delay = time.Duration(rand.Int63n(int64(maxDelay))).Round(time.Millisecond)
+	}
+	return p
+}
+
+func (p *Persona) rand() *rand.Rand {
+	return rand.New(rand.NewSource(p.Seed))
+}
+
+// LoadPersonas returns a persona for each nickname, keyed by
+// nickname. Personas are read from the JSON file at path if
+// it exists; nicknames it does not list get a new persona,
+// which is added to the file, creating it if needed, so that
+// the next run finds the same personas.
+func LoadPersonas(path string, nicknames []string, maxDelay time.Duration) (map[string]*Persona, error) {
+	var all []*Persona
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		if err := json.Unmarshal(data, &all); err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	known := map[string]*Persona{}
+	for _, p := range all {
+		known[p.Nickname] = p
+	}
+	res := map[string]*Persona{}
+	var added bool
+	for _, name := range nicknames {
+		p, ok := known[name]
+		if !ok {
+			p = NewPersona(name, maxDelay)
+			known[name] = p
+			all = append(all, p)
+			added = true
+		}
+		res[name] = p
+	}
+	if added {
+		if err := writePersonas(path, all); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// writePersonas replaces the file at path, so that an
+// interrupted write does not lose the existing personas.
+func writePersonas(path string, personas []*Persona) error {
+	data, err := json.MarshalIndent(personas, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,66 @@
+package swarm
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadPersonas(t *testing.T) {
+	dir, err := ioutil.TempDir("", "personas")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "class.json")
+
+	first, err := LoadPersonas(path, []string{"ada", "grace"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, p := range first {
+		if p.Nickname != name || p.Delay < 0 || p.Delay >= time.Second {
+			t.Errorf("bad persona for %s: %+v", name, p)
+		}
+	}
+
+	second, err := LoadPersonas(path, []string{"grace", "linus"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *second["grace"] != *first["grace"] {
+		t.Errorf("persona changed between runs: %+v then %+v", first["grace"], second["grace"])
+	}
+	if second["linus"] == nil || second["ada"] != nil {
+		t.Errorf("unexpected personas %v", second)
+	}
+
+	third, err := LoadPersonas(path, []string{"ada"}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *third["ada"] != *first["ada"] {
+		t.Error("personas missing from a run should stay in the file")
+	}
+}
+
+func TestPersonaChoices(t *testing.T) {
+	p := NewPersona("ada", 0)
+	var choices [2][]int
+	for i := range choices {
+		bot := &Bot{Persona: p, rand: p.rand()}
+		for j := 0; j < 10; j++ {
+			choices[i] = append(choices[i], bot.Intn(4))
+		}
+	}
+	for j := range choices[0] {
+		if choices[0][j] != choices[1][j] {
+			t.Fatalf("choices differ between runs: %v and %v", choices[0], choices[1])
+		}
+	}
+	if c := (*Bot)(nil).Intn(4); c < 0 || c >= 4 {
+		t.Errorf("unexpected choice %d", c)
+	}
+}
@@ -0,0 +1,332 @@
+package swarm
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// Strategies maps strategy names to functions which return
+// the on-screen index of the answer a bot should submit, or
+// -1 to leave the question unanswered.
+// The "idle" strategy never answers and therefore has no
+// function.
+var Strategies = map[string]func(bot *Bot, action *kahoot.QuizAction) int{
+	"idle":   nil,
+	"random": Adapt(kahoot.RandomStrategy()),
+}
+
+// Adapt turns a kahoot.AnswerStrategy into a strategy for
+// Strategies. The returned function waits out the Answer's
+// Delay, counted from when the question opened, before it
+// returns. The strategy draws its random choices from the
+// bot's source; see Bot.Intn.
+func Adapt(strategy kahoot.AnswerStrategy) func(bot *Bot, action *kahoot.QuizAction) int {
+	return func(bot *Bot, action *kahoot.QuizAction) int {
+		var state kahoot.GameState
+		var rtt time.Duration
+		if bot != nil && bot.Conn != nil {
+			state, rtt = bot.Conn.GameState(), bot.Conn.RTT()
+		}
+		if bot != nil {
+			state.Rand = bot.rand
+		}
+		answer := strategy.ChooseAnswer(action, state)
+		if answer.Choice >= 0 && answer.Delay > 0 {
+			if rtt <= 0 {
+				rtt = unmeasuredRTT
+			}
+			time.Sleep(time.Until(answer.At(action, rtt)))
+		}
+		return answer.Choice
+	}
+}
+
+// Intn returns a random number in [0, n). Strategies should
+// use it rather than math/rand, so that a bot with a Persona,
+// or in a swarm with a Seed, makes the same choices in every
+// run. The bot may be nil.
+func (b *Bot) Intn(n int) int {
+	if b == nil || b.rand == nil {
+		return rand.Intn(n)
+	}
+	return b.rand.Intn(n)
+}
+
+// StrategyNames returns the sorted names of the strategies.
+func StrategyNames() []string {
+	var names []string
+	for name := range Strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupStrategy finds a strategy in the swarm's own
+// strategies or the global ones.
+func (s *Swarm) lookupStrategy(name string) (func(bot *Bot, action *kahoot.QuizAction) int, bool) {
+	if choose, ok := s.opts.Strategies[name]; ok {
+		return choose, true
+	}
+	choose, ok := Strategies[name]
+	return choose, ok
+}
+
+// Play makes every joined bot follow the swarm's strategy
+// until its connection closes. Bots which join later start
+// playing as soon as they join.
+func (s *Swarm) Play() {
+	s.lock.Lock()
+	if s.playing {
+		s.lock.Unlock()
+		return
+	}
+	s.playing = true
+	bots := append([]*Bot{}, s.bots...)
+	s.lock.Unlock()
+	for _, bot := range bots {
+		if bot.Err == nil {
+			go s.play(bot)
+		}
+	}
+	if s.opts.LobbyTimeout > 0 {
+		time.AfterFunc(s.opts.LobbyTimeout, s.checkLobby)
+	}
+}
+
+// checkLobby closes the swarm if the host has not started the
+// game.
+func (s *Swarm) checkLobby() {
+	s.lock.Lock()
+	expired := s.lastQuestion < 0 && s.closing.IsZero()
+	if expired {
+		s.neverStarted = true
+	}
+	s.lock.Unlock()
+	if expired {
+		s.events.Publish(kahoot.TopicConnection, "lobbyTimeout", s.opts.LobbyTimeout)
+		s.Close()
+	}
+}
+
+// SetStrategy changes the strategy used for every question
+// from now on.
+func (s *Swarm) SetStrategy(name string) error {
+	if _, ok := s.lookupStrategy(name); !ok {
+		return errors.New("unknown strategy: " + name)
+	}
+	s.lock.Lock()
+	s.strategy = name
+	s.lock.Unlock()
+	s.events.Publish(kahoot.TopicConnection, "strategy", name)
+	return nil
+}
+
+// Strategy returns the name of the current strategy.
+func (s *Swarm) Strategy() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.strategy
+}
+
+// NextStrategy switches to the next strategy in
+// alphabetical order, wrapping around, and returns its name.
+func (s *Swarm) NextStrategy() string {
+	names := StrategyNames()
+	current := s.Strategy()
+	next := names[0]
+	for i, name := range names {
+		if name == current {
+			next = names[(i+1)%len(names)]
+		}
+	}
+	s.SetStrategy(next)
+	return next
+}
+
+// AnswerNow makes every bot which is waiting out its answer
+// delay answer immediately.
+func (s *Swarm) AnswerNow() {
+	s.AnswerNowSelected(Selector{})
+}
+
+// AddBots joins n more bots, named with the prefix and the
+// lowest numbers not already used by the swarm.
+func (s *Swarm) AddBots(n int, prefix string) error {
+	used := map[string]bool{}
+	for _, bot := range s.Bots() {
+		used[bot.Nickname] = true
+	}
+	var names []string
+	for i := 1; len(names) < n; i++ {
+		name := prefix + strconv.Itoa(i)
+		if !used[name] {
+			names = append(names, name)
+		}
+	}
+	return s.Join(names)
+}
+
+func (s *Swarm) play(bot *Bot) {
+	defer s.recoverBot(bot)
+	quiz := kahoot.NewQuiz(bot.Conn)
+	if s.started() {
+		// The game is under way, so catch up on the question
+		// in progress rather than wait for the next one.
+		quiz.Recover()
+	}
+	for {
+		action, err := quiz.Receive()
+		if err != nil {
+			return
+		}
+		s.publishQuestion(action)
+		if s.opts.Race {
+			quiz.Prepare(action.NumAnswers)
+		}
+		if action.Type != kahoot.QuestionAnswers ||
+			kahoot.AnswerKind(action.QuestionType) == kahoot.AnswerNone {
+			continue
+		}
+
+		s.lock.Lock()
+		choose, _ := s.lookupStrategy(s.strategy)
+		if bot.answerNow == nil {
+			bot.answerNow = make(chan struct{})
+		}
+		answerNow, paused := bot.answerNow, bot.paused
+		s.lock.Unlock()
+		if choose == nil || paused {
+			continue
+		}
+		delay := s.opts.AnswerDelay
+		if bot.Persona != nil {
+			delay += bot.Persona.Delay
+		}
+		if delay > 0 && !s.opts.Race {
+			select {
+			case <-time.After(delay):
+			case <-answerNow:
+			}
+		}
+
+		answer := choose(bot, action)
+		if answer < 0 {
+			continue
+		}
+		if s.opts.LastMoment > 0 && !s.opts.Race {
+			select {
+			case <-time.After(time.Until(lastMoment(action, bot.Conn.RTT(), s.opts.LastMoment))):
+			case <-answerNow:
+			}
+		}
+		sending := time.Now()
+		missed, err := sendWhileOpen(action, bot.Conn.RTT(), func() error {
+			return quiz.Send(action.QuizChoice(answer))
+		})
+		if missed {
+			s.lock.Lock()
+			bot.stats.missed++
+			s.lock.Unlock()
+			s.events.Publish(kahoot.TopicQuestion, "missed", &Answer{
+				Nickname: bot.Nickname,
+				Index:    action.Index,
+				Choice:   answer,
+			})
+		} else if err == nil {
+			acked := time.Now()
+			s.lock.Lock()
+			bot.stats.answers++
+			s.coverage.answered(action.Index)
+			if s.opts.Race {
+				s.race.add(sending.Sub(action.Received), acked.Sub(action.Received))
+			}
+			s.lock.Unlock()
+			s.events.Publish(kahoot.TopicQuestion, "answered", &Answer{
+				Nickname: bot.Nickname,
+				Index:    action.Index,
+				Choice:   answer,
+			})
+		}
+	}
+}
+
+// answerRetryDelay is how long to wait before sending an
+// answer again after a failed attempt.
+const answerRetryDelay = 200 * time.Millisecond
+
+// sendWhileOpen calls send, and calls it again after each
+// failure for as long as the next attempt can still reach the
+// server before the question closes. Once that is no longer
+// the case, it gives up with missed set rather than sending an
+// answer which the server would reject as late. Questions
+// without a known deadline are not retried.
+func sendWhileOpen(action *kahoot.QuizAction, rtt time.Duration, send func() error) (missed bool, err error) {
+	if rtt <= 0 {
+		rtt = unmeasuredRTT
+	}
+	for {
+		err = send()
+		if err == nil || !retryableSend(err) {
+			return false, err
+		}
+		deadline := action.Deadline()
+		if deadline.IsZero() {
+			return false, err
+		} else if time.Now().Add(answerRetryDelay + rtt).After(deadline) {
+			return true, err
+		}
+		time.Sleep(answerRetryDelay)
+	}
+}
+
+// retryableSend reports whether sending an answer again might
+// succeed where it just failed.
+func retryableSend(err error) bool {
+	var budgetErr *kahoot.BudgetError
+	return !errors.Is(err, kahoot.ErrConnClosed) && !errors.Is(err, kahoot.ErrDemoRestricted) &&
+		!errors.As(err, &budgetErr)
+}
+
+// unmeasuredRTT stands in for the round trip of a connection
+// which has not measured one yet.
+const unmeasuredRTT = 250 * time.Millisecond
+
+// lastMoment returns the latest time at which an answer to
+// the action is still expected to arrive before the question
+// closes, or the zero time if the deadline is unknown.
+func lastMoment(action *kahoot.QuizAction, rtt, margin time.Duration) time.Time {
+	deadline := action.Deadline()
+	if deadline.IsZero() {
+		return time.Time{}
+	}
+	if rtt <= 0 {
+		rtt = unmeasuredRTT
+	}
+	return deadline.Add(-rtt - margin)
+}
+
+// publishQuestion publishes the first intro and answers
+// action any bot sees for each question.
+func (s *Swarm) publishQuestion(action *kahoot.QuizAction) {
+	key := action.Index * 2
+	typeStr := "intro"
+	if action.Type == kahoot.QuestionAnswers {
+		key++
+		typeStr = "answers"
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.coverage.question(action)
+	if key > s.lastQuestion {
+		s.lastQuestion = key
+		s.events.Publish(kahoot.TopicQuestion, typeStr, action)
+		s.scheduleDepartures(action.Index)
+		s.scheduleLateJoins(action.Index)
+	}
+}
@@ -0,0 +1,108 @@
+package swarm
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// A Preset is a named, ready-made swarm configuration.
+type Preset struct {
+	Name string
+
+	// Count and Prefix produce nicknames like "bot1", "bot2",
+	// unless Nicknames is set.
+	Count     int
+	Prefix    string
+	Nicknames []string
+
+	Options Options
+
+	// Strategy names the answering behavior; see Strategies.
+	Strategy string
+
+	// MaxBots is a guardrail which overridden counts may not
+	// exceed.
+	MaxBots int
+}
+
+// Presets are the built-in presets, keyed by name.
+var Presets = map[string]Preset{
+	"classroom-30": {
+		Name:     "classroom-30",
+		Count:    30,
+		Prefix:   "student",
+		Options:  Options{Concurrency: 4, JoinDelay: time.Millisecond * 250},
+		Strategy: "random",
+		MaxBots:  60,
+	},
+	"stress-500": {
+		Name:     "stress-500",
+		Count:    500,
+		Prefix:   "load",
+		Options:  Options{Concurrency: 16, JoinDelay: time.Millisecond * 50},
+		Strategy: "random",
+		MaxBots:  1000,
+	},
+	"demo-5-named": {
+		Name:      "demo-5-named",
+		Nicknames: []string{"Ada", "Grace", "Linus", "Alan", "Margaret"},
+		Options:   Options{Concurrency: 1, Ordered: true, JoinDelay: time.Second},
+		Strategy:  "idle",
+		MaxBots:   5,
+	},
+}
+
+// LookupPreset finds a built-in preset by name.
+func LookupPreset(name string) (Preset, error) {
+	p, ok := Presets[name]
+	if !ok {
+		return Preset{}, fmt.Errorf("unknown preset %q (available: %v)", name, PresetNames())
+	}
+	return p, nil
+}
+
+// PresetNames returns the sorted names of the built-in
+// presets.
+func PresetNames() []string {
+	var names []string
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the preset's guardrails and settings.
+func (p *Preset) Validate() error {
+	count := len(p.Nicknames)
+	if count == 0 {
+		count = p.Count
+	}
+	if count <= 0 {
+		return errors.New("preset " + p.Name + ": no bots")
+	}
+	if p.MaxBots > 0 && count > p.MaxBots {
+		return fmt.Errorf("preset %s: %d bots exceeds the limit of %d", p.Name, count, p.MaxBots)
+	}
+	if _, ok := p.Options.Strategies[p.Strategy]; ok {
+		return nil
+	} else if _, ok := Strategies[p.Strategy]; !ok {
+		return errors.New("preset " + p.Name + ": unknown strategy: " + p.Strategy)
+	}
+	return nil
+}
+
+// NicknameList returns the nicknames the preset describes.
+func (p *Preset) NicknameList() []string {
+	if len(p.Nicknames) > 0 {
+		return p.Nicknames
+	}
+	res := make([]string, p.Count)
+	for i := range res {
+		res[i] = p.Prefix + strconv.Itoa(i+1)
+	}
+	return res
+}
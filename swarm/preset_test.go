@@ -0,0 +1,26 @@
+package swarm
+
+import "testing"
+
+func TestPresets(t *testing.T) {
+	for _, name := range PresetNames() {
+		p, err := LookupPreset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Validate(); err != nil {
+			t.Error(err)
+		}
+		if len(p.NicknameList()) == 0 {
+			t.Errorf("preset %s has no nicknames", name)
+		}
+	}
+	p, _ := LookupPreset("demo-5-named")
+	p.Nicknames = append(p.Nicknames, "extra")
+	if p.Validate() == nil {
+		t.Error("expected guardrail to reject extra bot")
+	}
+	if _, err := LookupPreset("nope"); err == nil {
+		t.Error("expected error for unknown preset")
+	}
+}
@@ -0,0 +1,75 @@
+package swarm
+
+import (
+	"sort"
+	"time"
+)
+
+// RaceLatency summarizes how quickly the bots answered in race
+// mode. Latencies are measured from the moment each question
+// opened for a bot.
+type RaceLatency struct {
+	Answers int `json:"answers"`
+
+	// Send is the time until the answer was handed to the
+	// connection, which includes choosing it.
+	Send Latencies `json:"send"`
+
+	// Ack is the time until the server acknowledged the
+	// answer.
+	Ack Latencies `json:"ack"`
+}
+
+// Latencies summarizes a distribution of latencies, in
+// milliseconds.
+type Latencies struct {
+	Min float64 `json:"min"`
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+// raceSamples is guarded by the swarm's lock.
+type raceSamples struct {
+	send []time.Duration
+	ack  []time.Duration
+}
+
+func (r *raceSamples) add(send, ack time.Duration) {
+	r.send = append(r.send, send)
+	r.ack = append(r.ack, ack)
+}
+
+func (r *raceSamples) latency() *RaceLatency {
+	return &RaceLatency{
+		Answers: len(r.ack),
+		Send:    summarize(r.send),
+		Ack:     summarize(r.ack),
+	}
+}
+
+// summarize computes nearest-rank percentiles of samples.
+func summarize(samples []time.Duration) Latencies {
+	if len(samples) == 0 {
+		return Latencies{}
+	}
+	sorted := append([]time.Duration{}, samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	rank := func(p float64) float64 {
+		i := int(p/100*float64(len(sorted))+0.5) - 1
+		if i < 0 {
+			i = 0
+		} else if i >= len(sorted) {
+			i = len(sorted) - 1
+		}
+		return float64(sorted[i]) / float64(time.Millisecond)
+	}
+	return Latencies{
+		Min: rank(0),
+		P50: rank(50),
+		P90: rank(90),
+		P99: rank(99),
+		Max: rank(100),
+	}
+}
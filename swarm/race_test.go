@@ -0,0 +1,39 @@
+package swarm
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRaceLatency(t *testing.T) {
+	s := New("123", Options{Race: true})
+	for i := 1; i <= 10; i++ {
+		s.race.add(time.Duration(i)*time.Millisecond, time.Duration(i)*10*time.Millisecond)
+	}
+	r := s.Report()
+	if r.Race == nil || r.Race.Answers != 10 {
+		t.Fatalf("unexpected race latency: %+v", r.Race)
+	}
+	expected := Latencies{Min: 10, P50: 50, P90: 90, P99: 100, Max: 100}
+	if r.Race.Ack != expected {
+		t.Errorf("expected %+v but got %+v", expected, r.Race.Ack)
+	}
+	if r.Race.Send.P50 != 5 {
+		t.Errorf("unexpected send latencies %+v", r.Race.Send)
+	}
+
+	var buf bytes.Buffer
+	r.WriteText(&buf)
+	if !strings.Contains(buf.String(), "until acknowledged") {
+		t.Errorf("unexpected text:\n%s", buf.String())
+	}
+
+	if New("123", Options{}).Report().Race != nil {
+		t.Error("race latency should only be reported in race mode")
+	}
+	if (summarize(nil) != Latencies{}) {
+		t.Error("expected zero latencies without samples")
+	}
+}
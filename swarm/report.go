@@ -0,0 +1,311 @@
+package swarm
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// maxErrorCategories is the number of error categories listed
+// in a Report.
+const maxErrorCategories = 5
+
+// Reasons a bot's session ended, as listed in a Report.
+const (
+	EndJoinFailed   = "join failed"
+	EndCrashed      = "crashed"
+	EndKicked       = "kicked"
+	EndLeft         = "left"
+	EndNeverStarted = "host never started"
+	EndAbandoned    = "game abandoned"
+	EndDisconnected = "disconnected"
+	EndConnected    = "connected"
+)
+
+// botStats is what the swarm observes about a bot while it is
+// connected. It is guarded by the swarm's lock.
+type botStats struct {
+	questions int
+	lastIndex int
+	answers   int
+	missed    int
+
+	kicked    bool
+	dropped   bool
+	abandoned bool
+	left      time.Time
+	lastErr   string
+	errCount  map[string]int
+}
+
+// A Report summarizes how a swarm's bots fared, usually once
+// the swarm has been closed.
+type Report struct {
+	Bots    int       `json:"bots"`
+	Joined  int       `json:"joined"`
+	Created time.Time `json:"created"`
+
+	// Questions is the number of distinct questions any bot
+	// was asked. Questions are only seen while playing.
+	Questions int `json:"questions"`
+
+	// Answers is the number of answers the server confirmed.
+	Answers int `json:"answers"`
+
+	// Missed is the number of answers which kept failing to
+	// send until their question closed.
+	Missed int `json:"missed"`
+
+	// EndReasons counts the bots by the reason their session
+	// ended. Unprompted disconnects are listed as "error: "
+	// followed by the category of the last error, if any.
+	EndReasons map[string]int `json:"endReasons"`
+
+	// Errors lists the most common error categories across
+	// every bot, most common first.
+	Errors []ErrorCount `json:"topErrors"`
+
+	BotReports []BotReport `json:"botReports"`
+
+	// Funnel breaks joining down into stages, such as
+	// reserving a session and logging in; see kahoot.StageFunc.
+	Funnel []StageReport `json:"funnel"`
+
+	// Coverage lists the types of question the bots were
+	// asked, and how well they handled each.
+	Coverage []TypeCoverage `json:"coverage"`
+
+	// Race is set in race mode; see Options.Race.
+	Race *RaceLatency `json:"race,omitempty"`
+}
+
+// An ErrorCount is the number of times errors of one category
+// were seen.
+type ErrorCount struct {
+	Category string `json:"category"`
+	Count    int    `json:"count"`
+}
+
+// A BotReport is one bot's line in a Report.
+type BotReport struct {
+	Nickname  string `json:"nickname"`
+	End       string `json:"end"`
+	Questions int    `json:"questions"`
+	Answers   int    `json:"answers"`
+	Missed    int    `json:"missed"`
+}
+
+// Report summarizes the swarm's bots so far.
+func (s *Swarm) Report() *Report {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	r := &Report{
+		Bots:       len(s.bots),
+		Created:    time.Now(),
+		EndReasons: map[string]int{},
+	}
+	if s.lastQuestion >= 0 {
+		r.Questions = s.lastQuestion/2 + 1
+	}
+	errorCounts := map[string]int{}
+	for _, bot := range s.bots {
+		end := s.endReason(bot)
+		if end != EndJoinFailed {
+			r.Joined++
+		}
+		r.EndReasons[end]++
+		r.Answers += bot.stats.answers
+		r.Missed += bot.stats.missed
+		for category, n := range bot.stats.errCount {
+			errorCounts[category] += n
+		}
+		r.BotReports = append(r.BotReports, BotReport{
+			Nickname:  bot.Nickname,
+			End:       end,
+			Questions: bot.stats.questions,
+			Answers:   bot.stats.answers,
+			Missed:    bot.stats.missed,
+		})
+	}
+	for category, n := range errorCounts {
+		r.Errors = append(r.Errors, ErrorCount{Category: category, Count: n})
+	}
+	sort.Slice(r.Errors, func(i, j int) bool {
+		if r.Errors[i].Count != r.Errors[j].Count {
+			return r.Errors[i].Count > r.Errors[j].Count
+		}
+		return r.Errors[i].Category < r.Errors[j].Category
+	})
+	if len(r.Errors) > maxErrorCategories {
+		r.Errors = r.Errors[:maxErrorCategories]
+	}
+	r.Funnel = s.funnel.reports()
+	r.Coverage = s.coverage.report()
+	if s.opts.Race {
+		r.Race = s.race.latency()
+	}
+	return r
+}
+
+// WriteText writes the report in a human-readable form.
+func (r *Report) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "bots joined:\t%d of %d\n", r.Joined, r.Bots)
+	fmt.Fprintf(tw, "questions seen:\t%d\n", r.Questions)
+	fmt.Fprintf(tw, "answers confirmed:\t%d\n", r.Answers)
+	if r.Missed > 0 {
+		fmt.Fprintf(tw, "answers missed:\t%d\n", r.Missed)
+	}
+	fmt.Fprintln(tw)
+
+	var reasons []string
+	for reason := range r.EndReasons {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+	fmt.Fprintln(tw, "ended\tbots")
+	for _, reason := range reasons {
+		fmt.Fprintf(tw, "%s\t%d\n", reason, r.EndReasons[reason])
+	}
+	if len(r.Errors) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintln(tw, "error\tcount")
+		for _, e := range r.Errors {
+			fmt.Fprintf(tw, "%s\t%d\n", e.Category, e.Count)
+		}
+	}
+	if len(r.Funnel) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "join stage\tattempts\tsucceeded\tp50 (ms)\tp90 (ms)\tmax (ms)\n")
+		for _, stage := range r.Funnel {
+			fmt.Fprintf(tw, "%s\t%d\t%.1f%%\t%.1f\t%.1f\t%.1f\n", stage.Stage, stage.Attempts,
+				100*stage.SuccessRate, stage.Latency.P50, stage.Latency.P90, stage.Latency.Max)
+		}
+	}
+	if len(r.Coverage) > 0 {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "question type\tquestions\tanswers\tcoverage\n")
+		for _, c := range r.Coverage {
+			level := c.Coverage
+			if c.Note != "" {
+				level += ": " + c.Note
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", c.Type, c.Questions, c.Answers, level)
+		}
+	}
+	if r.Race != nil {
+		fmt.Fprintln(tw)
+		fmt.Fprintf(tw, "race latency (ms)\tmin\tp50\tp90\tp99\tmax\n")
+		for _, row := range []struct {
+			name string
+			l    Latencies
+		}{{"until sent", r.Race.Send}, {"until acknowledged", r.Race.Ack}} {
+			fmt.Fprintf(tw, "%s\t%.1f\t%.1f\t%.1f\t%.1f\t%.1f\n", row.name,
+				row.l.Min, row.l.P50, row.l.P90, row.l.P99, row.l.Max)
+		}
+	}
+	return tw.Flush()
+}
+
+// Connected counts the bots which are still joining or
+// connected: those the Report would list as EndConnected.
+func (s *Swarm) Connected() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var n int
+	for _, bot := range s.bots {
+		if s.endReason(bot) == EndConnected {
+			n++
+		}
+	}
+	return n
+}
+
+// endReason must be called with the swarm's lock held.
+func (s *Swarm) endReason(bot *Bot) string {
+	switch {
+	case bot.Crash != nil:
+		return EndCrashed
+	case bot.Err != nil:
+		return EndJoinFailed
+	case bot.stats.kicked:
+		return EndKicked
+	case bot.stats.abandoned:
+		return EndAbandoned
+	case bot.stats.dropped && bot.stats.lastErr != "":
+		return "error: " + bot.stats.lastErr
+	case bot.stats.dropped:
+		return EndDisconnected
+	case s.neverStarted:
+		return EndNeverStarted
+	case !s.closing.IsZero() || !bot.stats.left.IsZero():
+		return EndLeft
+	default:
+		return EndConnected
+	}
+}
+
+// watch records a joined bot's events in its stats until the
+// subscription is closed.
+func (s *Swarm) watch(bot *Bot, sub *kahoot.Subscription) {
+	defer s.watchers.Done()
+	s.lock.Lock()
+	bot.stats.lastIndex = -1
+	s.lock.Unlock()
+	for e := range sub.C {
+		s.lock.Lock()
+		// Errors caused by Close tearing the connection down
+		// say nothing about how the bot fared.
+		leaving := !s.closing.IsZero() && !e.Time.Before(s.closing) ||
+			!bot.stats.left.IsZero() && !e.Time.Before(bot.stats.left)
+		switch e.Topic {
+		case kahoot.TopicConnection:
+			if e.Type == "kicked" {
+				bot.stats.kicked = true
+			} else if e.Type == "abandoned" {
+				// Nobody will end the game for this bot, so
+				// it leaves on its own.
+				bot.stats.abandoned = true
+				go bot.Conn.GracefulClose()
+			} else if e.Type == "closed" && !leaving {
+				bot.stats.dropped = true
+			}
+		case kahoot.TopicQuestion:
+			action, ok := e.Data.(*kahoot.QuizAction)
+			if ok && action.Index != bot.stats.lastIndex {
+				bot.stats.lastIndex = action.Index
+				bot.stats.questions++
+			}
+		case kahoot.TopicError:
+			if err, ok := e.Data.(error); ok && !leaving && !bot.stats.dropped {
+				category := e.Type + ": " + ErrorCategory(err)
+				if bot.stats.errCount == nil {
+					bot.stats.errCount = map[string]int{}
+				}
+				bot.stats.errCount[category]++
+				bot.stats.lastErr = category
+			}
+			if e.Type == "strict" {
+				s.events.Publish(kahoot.TopicError, "strict", e.Data)
+			}
+		}
+		s.lock.Unlock()
+	}
+}
+
+var numberPattern = regexp.MustCompile(`[0-9]+`)
+
+// ErrorCategory groups errors which differ only in numbers,
+// such as ports, pins or limits, under one name.
+func ErrorCategory(err error) string {
+	msg := numberPattern.ReplaceAllString(err.Error(), "N")
+	if len(msg) > 80 {
+		msg = msg[:77] + "..."
+	}
+	return msg
+}
@@ -0,0 +1,107 @@
+package swarm
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestReport(t *testing.T) {
+	s := New("123", Options{})
+	s.bots = []*Bot{
+		{Nickname: "a", Err: errors.New("dial tcp: i/o timeout")},
+		{Nickname: "b", Crash: &Crash{Value: "boom"}},
+		{Nickname: "c", stats: botStats{kicked: true, dropped: true, questions: 2}},
+		{Nickname: "d", stats: botStats{dropped: true, lastErr: "read: EOF",
+			errCount: map[string]int{"read: EOF": 1}}},
+		{Nickname: "e", stats: botStats{questions: 3, answers: 3}},
+	}
+	s.bots[1].Err = s.bots[1].Crash
+	s.lastQuestion = 5
+	s.closing = time.Now()
+
+	r := s.Report()
+	if r.Bots != 5 || r.Joined != 4 || r.Questions != 3 || r.Answers != 3 {
+		t.Errorf("unexpected totals: %+v", r)
+	}
+	expected := map[string]int{
+		EndJoinFailed:      1,
+		EndCrashed:         1,
+		EndKicked:          1,
+		"error: read: EOF": 1,
+		EndLeft:            1,
+	}
+	for reason, n := range expected {
+		if r.EndReasons[reason] != n {
+			t.Errorf("reason %q: expected %d but got %d", reason, n, r.EndReasons[reason])
+		}
+	}
+	if len(r.Errors) != 1 || r.Errors[0].Count != 1 {
+		t.Errorf("unexpected errors: %v", r.Errors)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "answers confirmed:  3") {
+		t.Errorf("unexpected text report:\n%s", buf.String())
+	}
+}
+
+func TestWatch(t *testing.T) {
+	s := New("123", Options{})
+	bot := &Bot{Nickname: "a"}
+	s.bots = []*Bot{bot}
+	bus := kahoot.NewBus()
+	sub := bus.Subscribe()
+	s.watchers.Add(1)
+	done := make(chan struct{})
+	go func() {
+		s.watch(bot, sub)
+		close(done)
+	}()
+	bus.Publish(kahoot.TopicQuestion, "intro", &kahoot.QuizAction{Index: 0})
+	bus.Publish(kahoot.TopicQuestion, "answers", &kahoot.QuizAction{Index: 0})
+	bus.Publish(kahoot.TopicQuestion, "intro", &kahoot.QuizAction{Index: 1})
+	bus.Publish(kahoot.TopicError, "read", errors.New("read tcp 10.0.0.1:4312: reset"))
+	bus.Publish(kahoot.TopicConnection, "closed", "123")
+	bus.Publish(kahoot.TopicError, "receive", kahoot.ErrConnClosed)
+	bus.Close()
+	<-done
+
+	r := s.Report()
+	if r.BotReports[0].Questions != 2 {
+		t.Errorf("expected 2 questions but got %d", r.BotReports[0].Questions)
+	}
+	want := "error: read: read tcp N.N.N.N:N: reset"
+	if r.BotReports[0].End != want {
+		t.Errorf("expected end %q but got %q", want, r.BotReports[0].End)
+	}
+}
+
+func TestLobbyTimeout(t *testing.T) {
+	s := New("123", Options{LobbyTimeout: time.Millisecond})
+	sub := s.Events().Subscribe(kahoot.TopicConnection)
+	defer sub.Close()
+	s.Play()
+	select {
+	case e := <-sub.C:
+		if e.Type != "lobbyTimeout" {
+			t.Fatalf("unexpected event %s", e.Type)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the lobby did not time out")
+	}
+	if !s.isClosing() {
+		t.Error("swarm should be closed")
+	}
+	s.bots = []*Bot{{Nickname: "a"}}
+	if r := s.Report(); r.EndReasons[EndNeverStarted] != 1 {
+		t.Errorf("unexpected end reasons %v", r.EndReasons)
+	}
+}
@@ -0,0 +1,101 @@
+// Package swarm manages many simultaneous bots in a single
+// game of kahoot.
+package swarm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNicknameLength is the longest nickname kahoot accepts.
+const MaxNicknameLength = 15
+
+// A RosterError lists every problem found in a roster file.
+type RosterError struct {
+	Problems []string
+}
+
+func (r *RosterError) Error() string {
+	return "invalid roster: " + strings.Join(r.Problems, "; ")
+}
+
+// ReadRoster reads a roster file.
+// See ParseRoster for the file format.
+func ReadRoster(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseRoster(f)
+}
+
+// ParseRoster parses a list of nicknames, one per line.
+//
+// Blank lines are ignored, and everything after a '#' is a
+// comment. A line may end with a weight, like "alex 3", in
+// which case the nickname is used for that many bots, with
+// a number appended to each ("alex1", "alex2", "alex3").
+//
+// Every resulting nickname is checked for length and
+// duplicates before anything is returned, so that a bad
+// roster is rejected before any bot joins.
+func ParseRoster(r io.Reader) ([]string, error) {
+	var names []string
+	var problems []string
+	seen := map[string]int{}
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if idx := strings.Index(line, "#"); idx >= 0 {
+			line = line[:idx]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, weight := line, 1
+		if idx := strings.LastIndexAny(line, " \t"); idx >= 0 {
+			if w, err := strconv.Atoi(line[idx+1:]); err == nil {
+				if w < 1 {
+					problems = append(problems, fmt.Sprintf("line %d: bad weight %d", lineNum, w))
+					continue
+				}
+				name, weight = strings.TrimSpace(line[:idx]), w
+			}
+		}
+
+		for i := 0; i < weight; i++ {
+			nickname := name
+			if weight > 1 {
+				nickname += strconv.Itoa(i + 1)
+			}
+			if utf8.RuneCountInString(nickname) > MaxNicknameLength {
+				problems = append(problems, fmt.Sprintf("line %d: nickname too long: %s",
+					lineNum, nickname))
+			} else if prev, ok := seen[nickname]; ok {
+				problems = append(problems, fmt.Sprintf("line %d: duplicate of line %d: %s",
+					lineNum, prev, nickname))
+			} else {
+				seen[nickname] = lineNum
+				names = append(names, nickname)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(problems) > 0 {
+		return nil, &RosterError{Problems: problems}
+	}
+	return names, nil
+}
@@ -0,0 +1,30 @@
+package swarm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseRoster(t *testing.T) {
+	roster := "# period 3\nalex\n\nbob 3  # triplets\n  carol\t\n"
+	names, err := ParseRoster(strings.NewReader(roster))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"alex", "bob1", "bob2", "bob3", "carol"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v but got %v", expected, names)
+	}
+}
+
+func TestParseRosterProblems(t *testing.T) {
+	roster := "alex\nalex\nabcdefghijklmnop\nbob 0\n"
+	_, err := ParseRoster(strings.NewReader(roster))
+	rosterErr, ok := err.(*RosterError)
+	if !ok {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rosterErr.Problems) != 3 {
+		t.Errorf("expected 3 problems but got %v", rosterErr.Problems)
+	}
+}
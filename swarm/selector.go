@@ -0,0 +1,206 @@
+package swarm
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A Selector picks some of a swarm's bots. The zero Selector
+// picks every bot.
+type Selector struct {
+	terms []selectorTerm
+}
+
+type selectorTerm struct {
+	tag        string
+	glob       string
+	start, end int
+}
+
+// ParseSelector parses a comma-separated list of terms, and
+// picks the bots matching any of them:
+//
+//	all         every bot
+//	tag:NAME    bots tagged NAME
+//	N or N-M    bots at roster positions N through M,
+//	            counting from 0
+//	PATTERN     bots whose nickname matches the glob, as in
+//	            path.Match (e.g. alex*)
+//
+// An empty string picks every bot.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+		switch {
+		case term == "" || term == "all":
+			return Selector{}, nil
+		case strings.HasPrefix(term, "tag:"):
+			tag := strings.TrimPrefix(term, "tag:")
+			if tag == "" {
+				return Selector{}, errors.New("empty tag in selector")
+			}
+			sel.terms = append(sel.terms, selectorTerm{tag: tag})
+		case term[0] >= '0' && term[0] <= '9':
+			start, end, err := parseRange(term)
+			if err != nil {
+				return Selector{}, err
+			}
+			sel.terms = append(sel.terms, selectorTerm{start: start, end: end})
+		default:
+			if _, err := path.Match(term, ""); err != nil {
+				return Selector{}, errors.New("invalid nickname pattern: " + term)
+			}
+			sel.terms = append(sel.terms, selectorTerm{glob: term})
+		}
+	}
+	return sel, nil
+}
+
+func parseRange(term string) (start, end int, err error) {
+	parts := strings.SplitN(term, "-", 2)
+	start, err = strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, errors.New("invalid bot index: " + term)
+	}
+	end = start
+	if len(parts) == 2 {
+		end, err = strconv.Atoi(parts[1])
+		if err != nil || end < start {
+			return 0, 0, errors.New("invalid bot range: " + term)
+		}
+	}
+	return start, end, nil
+}
+
+// matches reports whether the selector picks the bot at the
+// given roster position.
+func (sel Selector) matches(bot *Bot, index int) bool {
+	if len(sel.terms) == 0 {
+		return true
+	}
+	for _, t := range sel.terms {
+		switch {
+		case t.tag != "":
+			for _, tag := range bot.tags {
+				if tag == t.tag {
+					return true
+				}
+			}
+		case t.glob != "":
+			if ok, _ := path.Match(t.glob, bot.Nickname); ok {
+				return true
+			}
+		case index >= t.start && index <= t.end:
+			return true
+		}
+	}
+	return false
+}
+
+// Select returns the bots which the selector picks, in roster
+// order.
+func (s *Swarm) Select(sel Selector) []*Bot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.selectLocked(sel)
+}
+
+func (s *Swarm) selectLocked(sel Selector) []*Bot {
+	var res []*Bot
+	for i, bot := range s.bots {
+		if sel.matches(bot, i) {
+			res = append(res, bot)
+		}
+	}
+	return res
+}
+
+// Tag adds a tag to the selected bots, for later selectors to
+// refer to, and returns how many bots were selected.
+func (s *Swarm) Tag(sel Selector, tag string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	bots := s.selectLocked(sel)
+	for _, bot := range bots {
+		if !bot.hasTag(tag) {
+			bot.tags = append(bot.tags, tag)
+		}
+	}
+	return len(bots)
+}
+
+// Tags returns the bot's tags.
+func (s *Swarm) Tags(bot *Bot) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]string{}, bot.tags...)
+}
+
+func (b *Bot) hasTag(tag string) bool {
+	for _, t := range b.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Pause stops the selected bots from answering, or lets them
+// answer again if paused is false, and returns how many bots
+// were selected. Paused bots stay in the game.
+func (s *Swarm) Pause(sel Selector, paused bool) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	bots := s.selectLocked(sel)
+	for _, bot := range bots {
+		bot.paused = paused
+	}
+	return len(bots)
+}
+
+// AnswerNowSelected is like AnswerNow, but only for the
+// selected bots. It returns how many bots were selected.
+func (s *Swarm) AnswerNowSelected(sel Selector) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	bots := s.selectLocked(sel)
+	for _, bot := range bots {
+		if bot.answerNow != nil {
+			close(bot.answerNow)
+			bot.answerNow = make(chan struct{})
+		}
+	}
+	return len(bots)
+}
+
+// Leave gracefully disconnects the selected bots, which end
+// with the reason "left" in the Report, and returns how many
+// connected bots were selected.
+func (s *Swarm) Leave(sel Selector) int {
+	s.lock.Lock()
+	leaving := s.markLeaving(s.selectLocked(sel))
+	s.lock.Unlock()
+	for _, bot := range leaving {
+		bot.Conn.GracefulClose()
+	}
+	return len(leaving)
+}
+
+// markLeaving marks the connected bots among the given ones
+// as having left, and returns them. The caller holds the
+// swarm's lock.
+func (s *Swarm) markLeaving(bots []*Bot) []*Bot {
+	var leaving []*Bot
+	now := time.Now()
+	for _, bot := range bots {
+		if bot.Err == nil && bot.Conn != nil && bot.stats.left.IsZero() {
+			bot.stats.left = now
+			leaving = append(leaving, bot)
+		}
+	}
+	return leaving
+}
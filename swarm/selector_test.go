@@ -0,0 +1,55 @@
+package swarm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelector(t *testing.T) {
+	s := New("123", Options{})
+	for _, name := range []string{"alex1", "alex2", "sam", "kim"} {
+		s.bots = append(s.bots, &Bot{Nickname: name})
+	}
+	if n := s.Tag(mustParseSelector(t, "sam,kim"), "quiet"); n != 2 {
+		t.Errorf("expected to tag 2 bots but tagged %d", n)
+	}
+	cases := map[string][]string{
+		"":                 {"alex1", "alex2", "sam", "kim"},
+		"all":              {"alex1", "alex2", "sam", "kim"},
+		"alex*":            {"alex1", "alex2"},
+		"1-2":              {"alex2", "sam"},
+		"3":                {"kim"},
+		"tag:quiet":        {"sam", "kim"},
+		"tag:quiet,alex1":  {"alex1", "sam", "kim"},
+		"tag:missing,9-12": nil,
+	}
+	for spec, expected := range cases {
+		var names []string
+		for _, bot := range s.Select(mustParseSelector(t, spec)) {
+			names = append(names, bot.Nickname)
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%q: expected %v but got %v", spec, expected, names)
+		}
+	}
+	for _, spec := range []string{"tag:", "3-1", "[a", "1-x"} {
+		if _, err := ParseSelector(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+
+	if n := s.Pause(mustParseSelector(t, "alex*"), true); n != 2 || !s.bots[0].paused || s.bots[2].paused {
+		t.Errorf("unexpected pause result: %d", n)
+	}
+	if n := s.Leave(Selector{}); n != 0 {
+		t.Errorf("bots without connections should not leave, got %d", n)
+	}
+}
+
+func mustParseSelector(t *testing.T, spec string) Selector {
+	sel, err := ParseSelector(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sel
+}
@@ -0,0 +1,457 @@
+package swarm
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+	"github.com/unixpickle/kahoot-hack/netem"
+)
+
+// ErrClosed is returned by Join if the swarm was closed
+// before every bot had joined.
+var ErrClosed = errors.New("swarm closed")
+
+// DefaultConcurrency is the number of bots which connect at
+// once if Options.Concurrency is not set.
+const DefaultConcurrency = 4
+
+// Options configures a Swarm.
+type Options struct {
+	// Concurrency is the maximum number of bots which may be
+	// connecting at once.
+	Concurrency int
+
+	// Ordered makes each bot wait until the previous bot's
+	// login has been confirmed before logging in, so that the
+	// bots appear in the lobby in roster order.
+	// Connections are still established concurrently.
+	Ordered bool
+
+	// JoinDelay is the time to wait between starting each
+	// bot's connection.
+	JoinDelay time.Duration
+
+	// Network is applied separately to each bot's connection.
+	Network netem.Profile
+
+	// Token configures retries of each bot's session
+	// reservation.
+	Token kahoot.TokenOptions
+
+	// Reconnect is how many times in a row each bot tries to
+	// reconnect and log in again after its connection drops.
+	// Bots the host kicked never reconnect.
+	Reconnect int
+
+	// AnswerDelay is how long bots wait before answering a
+	// question, unless AnswerNow is called sooner.
+	AnswerDelay time.Duration
+
+	// LastMoment, if set, makes bots hold each answer until
+	// just before the question closes: the deadline, minus the
+	// connection's measured round trip, minus LastMoment as a
+	// safety margin. Questions without a known deadline are
+	// answered right away. AnswerNow still answers sooner.
+	LastMoment time.Duration
+
+	// Race makes bots answer as fast as they can: answer
+	// messages are encoded when a question is introduced, and
+	// sent as soon as it opens, ignoring AnswerDelay and
+	// LastMoment. The Report then includes the latencies the
+	// bots achieved.
+	Race bool
+
+	// LobbyTimeout, if set, makes the swarm close itself if no
+	// question has started that long after Play was called.
+	// The bots then end with the reason "host never started",
+	// and a "lobbyTimeout" connection event is published.
+	LobbyTimeout time.Duration
+
+	// Throttle, if set, is called before each bot starts to
+	// connect and may block to limit the join rate, e.g.
+	// across several swarms.
+	Throttle func()
+
+	// TraceWriter, if set, opens the destination of a bot's
+	// wire trace when SetTracing turns tracing on.
+	TraceWriter func(bot *Bot) (io.WriteCloser, error)
+
+	// OnCrash, if set, is called after a bot's goroutine
+	// panics and the bot has been marked failed.
+	OnCrash func(bot *Bot, crash *Crash)
+
+	// Strategies adds strategies which only this swarm can
+	// use, alongside the global Strategies. They take
+	// precedence over global strategies of the same name.
+	Strategies map[string]func(bot *Bot, action *kahoot.QuizAction) int
+
+	// Departures make shares of the bots leave after given
+	// questions, in the order they are due.
+	Departures []Departure
+
+	// LateJoins add bots after given questions, once the game
+	// is under way.
+	LateJoins []LateJoin
+
+	// Strict makes every bot's connection fail on the first
+	// message it does not understand; see
+	// kahoot.ConnOptions.Strict. The swarm publishes each
+	// failure as a "strict" error event.
+	Strict bool
+
+	// Personas, if set, gives the bots with these nicknames a
+	// persona, which adds its delay to AnswerDelay and seeds
+	// the bot's random choices. See LoadPersonas.
+	Personas map[string]*Persona
+
+	// Seed, if not zero, seeds the random choices of the bots
+	// without a persona: the n-th bot to join, counting from
+	// 0, draws from its own source seeded with Seed+n, so the
+	// same roster repeats the same choices whatever order the
+	// bots' goroutines run in.
+	Seed int64
+}
+
+// A Bot is a single member of a Swarm.
+type Bot struct {
+	Nickname string
+	Conn     *kahoot.Conn
+
+	// Err is set if the bot failed to join.
+	Err error
+
+	// Crash is set if the bot's goroutine panicked.
+	Crash *Crash
+
+	// JoinTime is how long the bot took to connect and log
+	// in, if it joined.
+	JoinTime time.Duration
+
+	// Persona is the bot's entry in Options.Personas, if any.
+	Persona *Persona
+
+	rand      *rand.Rand
+	joinStart time.Time
+	trace     io.WriteCloser
+	stats     botStats
+
+	// tags, paused, and answerNow are guarded by the swarm's
+	// lock.
+	tags      []string
+	paused    bool
+	answerNow chan struct{}
+}
+
+// A Swarm is a group of bots in the same game.
+type Swarm struct {
+	gamePin string
+	opts    Options
+
+	lock sync.Mutex
+	bots []*Bot
+
+	events       *kahoot.Bus
+	lastQuestion int
+
+	// rostered counts the bots Join has created, for Seed.
+	rostered int
+
+	playing      bool
+	strategy     string
+	neverStarted bool
+
+	closing  time.Time
+	watchers sync.WaitGroup
+	watching []*kahoot.Subscription
+
+	race     raceSamples
+	funnel   funnel
+	coverage coverage
+
+	// departures are due in order; the first departed of them
+	// have started.
+	departures []Departure
+	departed   int
+
+	// lateJoins are due in order; the first lateJoined of them
+	// have started.
+	lateJoins  []LateJoin
+	lateJoined int
+}
+
+// An Answer is published as an "answered" question event on
+// the swarm's Bus whenever a bot submits an answer.
+type Answer struct {
+	Nickname string
+	Index    int
+
+	// Choice is the answer as displayed on screen.
+	Choice int
+}
+
+// New creates an empty Swarm for a game pin.
+func New(gamePin string, opts Options) *Swarm {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	return &Swarm{
+		gamePin:      gamePin,
+		opts:         opts,
+		events:       kahoot.NewBus(),
+		lastQuestion: -1,
+		strategy:     "idle",
+		funnel:       funnel{},
+		departures:   sortDepartures(opts.Departures),
+		lateJoins:    sortLateJoins(opts.LateJoins),
+	}
+}
+
+// Events returns the swarm's Bus.
+//
+// Join publishes a "joining" connection event with the number
+// of bots it is adding, then a "joined" connection event or a
+// "join" error for each of them as soon as it is known.
+//
+// While the swarm is playing, each question's "intro" and
+// "answers" events are published once for the whole swarm,
+// along with an "answered" event for every submission.
+//
+// Close closes the Bus once every bot has disconnected.
+func (s *Swarm) Events() *kahoot.Bus {
+	return s.events
+}
+
+// Join connects and logs in a bot for each nickname.
+// It returns once every bot has either joined or failed.
+//
+// The returned error is the first error encountered, if any.
+// Bots which failed are still listed by Bots, with their Err
+// field set.
+//
+// If the swarm is closed while Join is running, the bots
+// which have not started connecting are dropped, the rest are
+// disconnected as soon as they finish, and Join returns
+// ErrClosed.
+func (s *Swarm) Join(nicknames []string) error {
+	bots := make([]*Bot, len(nicknames))
+	connected := make([]chan struct{}, len(nicknames))
+	s.lock.Lock()
+	first := s.rostered
+	s.rostered += len(nicknames)
+	s.lock.Unlock()
+	for i, name := range nicknames {
+		bots[i] = &Bot{Nickname: name}
+		if p := s.opts.Personas[name]; p != nil {
+			bots[i].Persona = p
+			bots[i].rand = p.rand()
+		} else if s.opts.Seed != 0 {
+			bots[i].rand = rand.New(rand.NewSource(s.opts.Seed + int64(first+i)))
+		}
+		connected[i] = make(chan struct{})
+	}
+	s.events.Publish(kahoot.TopicConnection, "joining", len(nicknames))
+
+	indices := make(chan int)
+	go func() {
+		defer close(indices)
+		for i := range bots {
+			if i > 0 && s.opts.JoinDelay > 0 {
+				time.Sleep(s.opts.JoinDelay)
+			}
+			if s.opts.Throttle != nil {
+				s.opts.Throttle()
+			}
+			if s.isClosing() {
+				for _, bot := range bots[i:] {
+					bot.Err = ErrClosed
+				}
+				if s.opts.Ordered {
+					for _, ch := range connected[i:] {
+						close(ch)
+					}
+				}
+				return
+			}
+			indices <- i
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < s.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range indices {
+				s.connectBot(bots[idx], connected[idx])
+			}
+		}()
+	}
+
+	if s.opts.Ordered {
+		for i, bot := range bots {
+			<-connected[i]
+			if bot.Err == nil && s.isClosing() {
+				bot.Conn.Close()
+				bot.Err = ErrClosed
+			} else if bot.Err == nil {
+				s.loginBot(bot)
+			}
+		}
+	}
+	wg.Wait()
+
+	var started []*Bot
+	for _, bot := range bots {
+		if bot.Err != ErrClosed {
+			started = append(started, bot)
+		}
+	}
+
+	s.lock.Lock()
+	s.bots = append(s.bots, started...)
+	playing := s.playing
+	closing := !s.closing.IsZero()
+	for _, bot := range started {
+		if bot.Err == nil && !closing {
+			sub := bot.Conn.Events().Subscribe(kahoot.TopicConnection,
+				kahoot.TopicQuestion, kahoot.TopicError)
+			s.watching = append(s.watching, sub)
+			s.watchers.Add(1)
+			go s.watch(bot, sub)
+		}
+	}
+	s.lock.Unlock()
+	if closing {
+		// Close ran while these bots were joining, so it
+		// could not disconnect them.
+		for _, bot := range started {
+			if bot.Err == nil {
+				bot.Conn.GracefulClose()
+			}
+		}
+		return ErrClosed
+	}
+	if playing {
+		for _, bot := range bots {
+			if bot.Err == nil {
+				go s.play(bot)
+			}
+		}
+	}
+
+	for _, bot := range bots {
+		if bot.Err != nil {
+			return bot.Err
+		}
+	}
+	return nil
+}
+
+func (s *Swarm) connectBot(bot *Bot, connected chan<- struct{}) {
+	if s.opts.Ordered {
+		defer close(connected)
+	}
+	defer func() {
+		if !s.opts.Ordered || bot.Err != nil {
+			s.publishJoin(bot)
+		}
+	}()
+	defer s.recoverBot(bot)
+	bot.joinStart = time.Now()
+	bot.Conn, bot.Err = kahoot.NewConnOptions(s.gamePin, &kahoot.ConnOptions{
+		WrapConn:  s.opts.Network.Wrap,
+		Stage:     s.recordStage,
+		Token:     s.opts.Token,
+		Reconnect: s.opts.Reconnect,
+		Strict:    s.opts.Strict,
+	})
+	if !s.opts.Ordered && bot.Err == nil {
+		s.login(bot)
+	}
+}
+
+func (s *Swarm) loginBot(bot *Bot) {
+	defer s.publishJoin(bot)
+	defer s.recoverBot(bot)
+	s.login(bot)
+}
+
+// login logs a connected bot in, with a generated nickname if
+// the game only accepts those, and joins a team of one if the
+// game is played in teams.
+func (s *Swarm) login(bot *Bot) {
+	if bot.Conn.Namerator() {
+		var name string
+		name, bot.Err = bot.Conn.LoginGenerated()
+		if bot.Err == nil {
+			bot.Nickname = name
+		}
+	} else {
+		bot.Err = bot.Conn.Login(bot.Nickname)
+	}
+	if bot.Err == nil && bot.Conn.TeamMode() {
+		bot.Err = bot.Conn.JoinTeam(nil)
+	}
+	bot.JoinTime = time.Since(bot.joinStart)
+}
+
+func (s *Swarm) publishJoin(bot *Bot) {
+	if bot.Err != nil {
+		s.events.Publish(kahoot.TopicError, "join", bot.Err)
+	} else {
+		s.events.Publish(kahoot.TopicConnection, "joined", bot.Nickname)
+	}
+}
+
+// Bots returns every bot which has been added to the swarm,
+// in roster order.
+func (s *Swarm) Bots() []*Bot {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*Bot{}, s.bots...)
+}
+
+func (s *Swarm) isClosing() bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return !s.closing.IsZero()
+}
+
+// Close gracefully disconnects every bot, then closes the
+// swarm's Bus, which ends every Subscription to it.
+// Bots which were still connected end with the reason "left"
+// in the swarm's Report.
+func (s *Swarm) Close() {
+	s.lock.Lock()
+	if s.closing.IsZero() {
+		s.closing = time.Now()
+	}
+	s.lock.Unlock()
+
+	var wg sync.WaitGroup
+	for _, bot := range s.Bots() {
+		if bot.Conn == nil {
+			continue
+		}
+		wg.Add(1)
+		go func(c *kahoot.Conn) {
+			defer wg.Done()
+			c.GracefulClose()
+		}(bot.Conn)
+	}
+	wg.Wait()
+
+	s.lock.Lock()
+	watching := s.watching
+	s.watching = nil
+	s.lock.Unlock()
+	for _, sub := range watching {
+		sub.Close()
+	}
+	s.watchers.Wait()
+	s.events.Close()
+}
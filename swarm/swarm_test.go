@@ -0,0 +1,135 @@
+package swarm
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestJoinAfterClose(t *testing.T) {
+	for _, ordered := range []bool{false, true} {
+		s := New("123", Options{Ordered: ordered})
+		sub := s.Events().Subscribe()
+		s.Close()
+		if _, ok := <-sub.C; ok {
+			t.Errorf("ordered=%v: expected Close to end subscriptions", ordered)
+		}
+		if err := s.Join([]string{"a", "b", "c"}); err != ErrClosed {
+			t.Errorf("ordered=%v: expected ErrClosed but got %v", ordered, err)
+		}
+		if n := len(s.Bots()); n != 0 {
+			t.Errorf("ordered=%v: expected no bots but got %d", ordered, n)
+		}
+	}
+}
+
+func TestLastMoment(t *testing.T) {
+	received := time.Now()
+	action := &kahoot.QuizAction{Received: received, TimeLeft: 20 * time.Second}
+	at := lastMoment(action, 100*time.Millisecond, 300*time.Millisecond)
+	if expected := received.Add(20*time.Second - 400*time.Millisecond); !at.Equal(expected) {
+		t.Errorf("expected %v but got %v", expected, at)
+	}
+	at = lastMoment(action, 0, 0)
+	if expected := received.Add(20*time.Second - unmeasuredRTT); !at.Equal(expected) {
+		t.Errorf("unmeasured round trip: expected %v but got %v", expected, at)
+	}
+	if !lastMoment(&kahoot.QuizAction{Received: received}, time.Second, time.Second).IsZero() {
+		t.Error("expected the zero time without a deadline")
+	}
+}
+
+func TestSendWhileOpen(t *testing.T) {
+	blip := errors.New("did not receive successful response")
+	open := &kahoot.QuizAction{Received: time.Now(), TimeLeft: 20 * time.Second}
+	var attempts int
+	missed, err := sendWhileOpen(open, time.Millisecond, func() error {
+		attempts++
+		if attempts < 3 {
+			return blip
+		}
+		return nil
+	})
+	if missed || err != nil || attempts != 3 {
+		t.Errorf("expected success on attempt 3, got missed=%v err=%v after %d", missed, err, attempts)
+	}
+
+	closing := &kahoot.QuizAction{Received: time.Now(), TimeLeft: 100 * time.Millisecond}
+	attempts = 0
+	missed, err = sendWhileOpen(closing, time.Millisecond, func() error {
+		attempts++
+		return blip
+	})
+	if !missed || err != blip || attempts != 1 {
+		t.Errorf("expected a miss after 1 attempt, got missed=%v err=%v after %d", missed, err, attempts)
+	}
+
+	attempts = 0
+	missed, err = sendWhileOpen(open, time.Millisecond, func() error {
+		attempts++
+		return kahoot.ErrConnClosed
+	})
+	if missed || err != kahoot.ErrConnClosed || attempts != 1 {
+		t.Errorf("closed connection should not be retried, got missed=%v after %d", missed, attempts)
+	}
+}
+
+func TestStrategiesWithoutAnswers(t *testing.T) {
+	action := &kahoot.QuizAction{QuestionType: "content", Received: time.Now()}
+	quiz := &kahoot.QuizInfo{}
+	strategies := map[string]func(*Bot, *kahoot.QuizAction) int{
+		"random":  Strategies["random"],
+		"fixed":   Adapt(kahoot.AlwaysIndex(1)),
+		"correct": Adapt(kahoot.CorrectStrategy(quiz)),
+		"wrong":   Adapt(kahoot.WrongOnPurpose(quiz)),
+		"human":   Adapt(kahoot.DefaultHumanLike(nil)),
+	}
+	for name, choose := range strategies {
+		if c := choose(nil, action); c != -1 {
+			t.Errorf("%s: expected -1 but got %d", name, c)
+		}
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	action := &kahoot.QuizAction{NumAnswers: 3, Received: time.Now()}
+	if c := Adapt(kahoot.AlwaysIndex(1))(nil, action); c != 1 {
+		t.Errorf("expected 1 but got %d", c)
+	}
+	skip := kahoot.AnswerFunc(func(*kahoot.QuizAction, kahoot.GameState) kahoot.Answer {
+		return kahoot.Skip
+	})
+	if c := Adapt(skip)(nil, action); c != -1 {
+		t.Errorf("expected a skip but got %d", c)
+	}
+	delayed := kahoot.AnswerFunc(func(*kahoot.QuizAction, kahoot.GameState) kahoot.Answer {
+		return kahoot.Answer{Choice: 2, Delay: 50 * time.Millisecond}
+	})
+	if c := Adapt(delayed)(nil, action); c != 2 || time.Since(action.Received) < 50*time.Millisecond {
+		t.Errorf("expected choice 2 after the delay, got %d", c)
+	}
+}
+
+func TestAdaptSeeded(t *testing.T) {
+	action := &kahoot.QuizAction{NumAnswers: 1000, Received: time.Now()}
+	choices := func(seed int64) []int {
+		bot := &Bot{rand: rand.New(rand.NewSource(seed))}
+		var res []int
+		for i := 0; i < 10; i++ {
+			res = append(res, Adapt(kahoot.RandomStrategy())(bot, action))
+		}
+		return res
+	}
+	a, b, c := choices(3), choices(3), choices(4)
+	same, differs := true, false
+	for i := range a {
+		same = same && a[i] == b[i]
+		differs = differs || a[i] != c[i]
+	}
+	if !same || !differs {
+		t.Errorf("expected seeded bots to repeat their choices: %v, %v, %v", a, b, c)
+	}
+}
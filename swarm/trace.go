@@ -0,0 +1,78 @@
+package swarm
+
+import "errors"
+
+// Bot finds a bot by nickname.
+func (s *Swarm) Bot(nickname string) (*Bot, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, bot := range s.bots {
+		if bot.Nickname == nickname {
+			return bot, true
+		}
+	}
+	return nil, false
+}
+
+// SetTracing turns wire tracing on or off for one bot.
+// Traces are written to writers from Options.TraceWriter,
+// which are closed when tracing stops.
+func (s *Swarm) SetTracing(nickname string, on bool) error {
+	bot, ok := s.Bot(nickname)
+	if !ok {
+		return errors.New("no such bot: " + nickname)
+	} else if bot.Conn == nil {
+		return errors.New("bot is not connected: " + nickname)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if on == (bot.trace != nil) {
+		return nil
+	}
+	if !on {
+		bot.Conn.SetTrace(nil)
+		err := bot.trace.Close()
+		bot.trace = nil
+		return err
+	}
+	if s.opts.TraceWriter == nil {
+		return errors.New("tracing is not configured")
+	}
+	w, err := s.opts.TraceWriter(bot)
+	if err != nil {
+		return err
+	}
+	bot.trace = w
+	bot.Conn.SetTrace(w)
+	return nil
+}
+
+// Tracing reports whether a bot is being traced.
+func (s *Swarm) Tracing(nickname string) bool {
+	bot, ok := s.Bot(nickname)
+	if !ok {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return bot.trace != nil
+}
+
+// ToggleTracing flips tracing for each named bot, or for
+// every joined bot if no names are given.
+func (s *Swarm) ToggleTracing(nicknames ...string) error {
+	if len(nicknames) == 0 {
+		for _, bot := range s.Bots() {
+			if bot.Err == nil {
+				nicknames = append(nicknames, bot.Nickname)
+			}
+		}
+	}
+	var firstErr error
+	for _, name := range nicknames {
+		if err := s.SetTracing(name, !s.Tracing(name)); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,17 @@
+package swarm
+
+import "testing"
+
+func TestSetTracingErrors(t *testing.T) {
+	s := New("123", Options{})
+	s.bots = append(s.bots, &Bot{Nickname: "alex"})
+	if err := s.SetTracing("nobody", true); err == nil {
+		t.Error("expected error for unknown bot")
+	}
+	if err := s.SetTracing("alex", true); err == nil {
+		t.Error("expected error for unconnected bot")
+	}
+	if s.Tracing("alex") {
+		t.Error("bot should not be traced")
+	}
+}
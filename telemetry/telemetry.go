@@ -0,0 +1,191 @@
+// Package telemetry uploads anonymized samples of protocol
+// traffic which the kahoot package did not recognize to a
+// user-configured endpoint, so that protocol changes can be
+// noticed and fixed quickly.
+//
+// Nothing is reported unless a Reporter is created and
+// installed with kahoot.SetReporter.
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+const (
+	queueSize    = 64
+	batchSize    = 16
+	flushTimeout = time.Second * 10
+)
+
+// keptStrings are content keys whose string values describe
+// the protocol rather than the game or its players, so they
+// survive anonymization.
+var keptStrings = map[string]bool{
+	"type":          true,
+	"quizType":      true,
+	"gameBlockType": true,
+	"gameMode":      true,
+	"status":        true,
+}
+
+// A Reporter batches samples and POSTs them as a JSON array
+// to an endpoint. Each distinct kind of sample is only sent
+// once per Reporter.
+type Reporter struct {
+	endpoint string
+	client   *http.Client
+
+	lock sync.Mutex
+	seen map[string]bool
+
+	queue chan *kahoot.Sample
+	done  chan struct{}
+}
+
+// NewReporter creates a Reporter and starts its upload loop.
+func NewReporter(endpoint string) *Reporter {
+	r := &Reporter{
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: time.Second * 30},
+		seen:     map[string]bool{},
+		queue:    make(chan *kahoot.Sample, queueSize),
+		done:     make(chan struct{}),
+	}
+	go r.uploadLoop()
+	return r
+}
+
+// Report anonymizes and queues a sample. If the queue is
+// full, the sample is dropped.
+func (r *Reporter) Report(s *kahoot.Sample) {
+	anon := Anonymize(s)
+	key := anon.Kind + "/" + anon.Channel + "/" + strconv.Itoa(anon.Id) + "/" + shape(anon)
+	r.lock.Lock()
+	if r.seen[key] {
+		r.lock.Unlock()
+		return
+	}
+	r.seen[key] = true
+	r.lock.Unlock()
+	select {
+	case r.queue <- anon:
+	default:
+	}
+}
+
+// Close uploads any queued samples and stops the Reporter.
+func (r *Reporter) Close() {
+	close(r.queue)
+	<-r.done
+}
+
+// Anonymize returns a copy of the sample with identifying
+// information removed.
+//
+// Challenges contain nothing about the game or its players
+// and are kept intact. For messages, every string value is
+// replaced by a placeholder recording its length, except for
+// a few keys which name protocol features; numbers, booleans,
+// and the structure of the message are kept.
+func Anonymize(s *kahoot.Sample) *kahoot.Sample {
+	res := *s
+	if s.Kind == "challenge" {
+		return &res
+	}
+	var content interface{}
+	if json.Unmarshal([]byte(s.Content), &content) != nil {
+		res.Content = "<unparsable len=" + strconv.Itoa(len(s.Content)) + ">"
+		return &res
+	}
+	data, _ := json.Marshal(scrub("", content))
+	res.Content = string(data)
+	return &res
+}
+
+func scrub(key string, value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		if keptStrings[key] {
+			return value
+		}
+		return "<string len=" + strconv.Itoa(len(value)) + ">"
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for k, v := range value {
+			res[k] = scrub(k, v)
+		}
+		return res
+	case []interface{}:
+		res := make([]interface{}, len(value))
+		for i, v := range value {
+			res[i] = scrub(key, v)
+		}
+		return res
+	default:
+		return value
+	}
+}
+
+// shape summarizes the keys of a message, so that messages
+// differing only in their values are reported once.
+func shape(s *kahoot.Sample) string {
+	if s.Kind == "challenge" {
+		return s.Content
+	}
+	var content map[string]interface{}
+	if json.Unmarshal([]byte(s.Content), &content) != nil {
+		return ""
+	}
+	var keys []string
+	for k := range content {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func (r *Reporter) uploadLoop() {
+	defer close(r.done)
+	var batch []*kahoot.Sample
+	timer := time.NewTimer(flushTimeout)
+	for {
+		select {
+		case s, ok := <-r.queue:
+			if !ok {
+				r.upload(batch)
+				return
+			}
+			batch = append(batch, s)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-timer.C:
+		}
+		r.upload(batch)
+		batch = nil
+		timer.Reset(flushTimeout)
+	}
+}
+
+func (r *Reporter) upload(batch []*kahoot.Sample) {
+	if len(batch) == 0 {
+		return
+	}
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return
+	}
+	resp, err := r.client.Post(r.endpoint, "application/json", bytes.NewReader(data))
+	if err == nil {
+		resp.Body.Close()
+	}
+}
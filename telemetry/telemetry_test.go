@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestAnonymize(t *testing.T) {
+	s := &kahoot.Sample{
+		Kind:    "message",
+		Id:      99,
+		Content: `{"playerName":"alex","cid":123,"quizType":"quiz","names":["bob"]}`,
+	}
+	anon := Anonymize(s)
+	if strings.Contains(anon.Content, "alex") || strings.Contains(anon.Content, "bob") {
+		t.Errorf("nicknames were not removed: %s", anon.Content)
+	}
+	if !strings.Contains(anon.Content, `"quizType":"quiz"`) {
+		t.Errorf("protocol values were removed: %s", anon.Content)
+	}
+	if s.Content == anon.Content {
+		t.Error("original sample should be kept intact")
+	}
+}
+
+func TestReporter(t *testing.T) {
+	var received []*kahoot.Sample
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []*kahoot.Sample
+		json.NewDecoder(r.Body).Decode(&batch)
+		received = append(received, batch...)
+	}))
+	defer server.Close()
+
+	r := NewReporter(server.URL)
+	r.Report(&kahoot.Sample{Kind: "message", Id: 99, Content: `{"a":"x"}`})
+	r.Report(&kahoot.Sample{Kind: "message", Id: 99, Content: `{"a":"y"}`})
+	r.Report(&kahoot.Sample{Kind: "challenge", Content: "decode('x')"})
+	r.Close()
+
+	if len(received) != 2 {
+		t.Fatalf("expected 2 samples but got %d", len(received))
+	}
+}
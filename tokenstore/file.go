@@ -0,0 +1,172 @@
+package tokenstore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	saltSize   = 16
+	scryptN    = 1 << 15
+	scryptR    = 8
+	scryptP    = 1
+	aesKeySize = 32
+)
+
+// A FileStore keeps tokens in a JSON file, encrypting each one
+// with AES-GCM under a key derived from a passphrase.
+type FileStore struct {
+	path       string
+	passphrase string
+
+	lock sync.Mutex
+}
+
+type sealedToken struct {
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewFileStore creates a FileStore.
+// The file is created when the first token is saved.
+func NewFileStore(path, passphrase string) *FileStore {
+	return &FileStore{path: path, passphrase: passphrase}
+}
+
+// Load decrypts the token for an account.
+func (f *FileStore) Load(account string) (*Token, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	sealed, ok := entries[account]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	gcm, err := f.cipher(sealed.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, sealed.Nonce, sealed.Ciphertext, []byte(account))
+	if err != nil {
+		return nil, errors.New("decrypt token: wrong passphrase or corrupt file")
+	}
+	var token Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Save encrypts and stores the token for an account.
+func (f *FileStore) Save(account string, token *Token) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+	gcm, err := f.cipher(salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	entries[account] = &sealedToken{
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, []byte(account)),
+	}
+	return f.write(entries)
+}
+
+// Delete removes the token for an account.
+func (f *FileStore) Delete(account string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	entries, err := f.read()
+	if err != nil {
+		return err
+	}
+	delete(entries, account)
+	return f.write(entries)
+}
+
+func (f *FileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(f.passphrase), salt, scryptN, scryptR, scryptP, aesKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (f *FileStore) read() (map[string]*sealedToken, error) {
+	entries := map[string]*sealedToken{}
+	data, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (f *FileStore) write(entries map[string]*sealedToken) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return err
+	}
+	// Write a private temporary file and rename it over the
+	// store, so that several processes sharing the store never
+	// see a partially written file.
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package tokenstore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tokenstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "tokens.json")
+
+	store := NewFileStore(path, "hunter2")
+	if _, err := store.Load("alex"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound but got %v", err)
+	}
+	token := &Token{Value: "secret-token", Expires: time.Now().Add(time.Hour).Round(0)}
+	if err := store.Save("alex", token); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(raw), "secret-token") {
+		t.Error("token stored in plaintext")
+	}
+
+	loaded, err := store.Load("alex")
+	if err != nil {
+		t.Fatal(err)
+	} else if loaded.Value != token.Value || !loaded.Expires.Equal(token.Expires) {
+		t.Errorf("expected %+v but got %+v", token, loaded)
+	}
+	if _, err := NewFileStore(path, "wrong").Load("alex"); err == nil {
+		t.Error("expected error with wrong passphrase")
+	}
+}
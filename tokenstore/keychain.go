@@ -0,0 +1,115 @@
+package tokenstore
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const keychainService = "kahoot-hack"
+
+// A KeychainStore keeps tokens in the operating system's
+// keychain by way of its command-line tool: security(1) on
+// macOS, or secret-tool(1) from libsecret on Linux.
+type KeychainStore struct {
+	tool string
+}
+
+// NewKeychainStore returns a KeychainStore, or an error if no
+// supported keychain tool is installed.
+func NewKeychainStore() (*KeychainStore, error) {
+	var tool string
+	switch runtime.GOOS {
+	case "darwin":
+		tool = "security"
+	case "linux", "freebsd", "openbsd":
+		tool = "secret-tool"
+	default:
+		return nil, errors.New("no keychain support on " + runtime.GOOS)
+	}
+	if _, err := exec.LookPath(tool); err != nil {
+		return nil, err
+	}
+	return &KeychainStore{tool: tool}, nil
+}
+
+// Load reads the token for an account from the keychain.
+func (k *KeychainStore) Load(account string) (*Token, error) {
+	var cmd *exec.Cmd
+	if k.tool == "security" {
+		cmd = exec.Command(k.tool, "find-generic-password", "-s", keychainService,
+			"-a", account, "-w")
+	} else {
+		cmd = exec.Command(k.tool, "lookup", "service", keychainService, "account", account)
+	}
+	output, err := cmd.Output()
+	if err != nil || len(bytes.TrimSpace(output)) == 0 {
+		return nil, ErrNotFound
+	}
+	var token Token
+	if err := json.Unmarshal(bytes.TrimSpace(output), &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// Save writes the token for an account to the keychain.
+func (k *KeychainStore) Save(account string, token *Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if k.tool == "security" {
+		// security -i reads its commands from stdin, which keeps
+		// the token out of the process list.
+		if strings.ContainsAny(account, "\"\\\n") {
+			return errors.New("account name cannot be stored in the keychain: " + account)
+		}
+		cmd = exec.Command(k.tool, "-i")
+		cmd.Stdin = strings.NewReader(fmt.Sprintf("add-generic-password -U -s %s -a \"%s\" -X %s\n",
+			keychainService, account, hex.EncodeToString(data)))
+	} else {
+		cmd = exec.Command(k.tool, "store", "--label", keychainService+" "+account,
+			"service", keychainService, "account", account)
+		cmd.Stdin = strings.NewReader(string(data))
+	}
+	return runTool(cmd, k.tool == "security")
+}
+
+// Delete removes the token for an account from the keychain.
+func (k *KeychainStore) Delete(account string) error {
+	var cmd *exec.Cmd
+	if k.tool == "security" {
+		cmd = exec.Command(k.tool, "delete-generic-password", "-s", keychainService,
+			"-a", account)
+	} else {
+		cmd = exec.Command(k.tool, "clear", "service", keychainService, "account", account)
+	}
+	return runTool(cmd, false)
+}
+
+// runTool runs a keychain command. If interactive, the command
+// reads its subcommands from stdin, as with security -i, and
+// fails by writing to stderr even though it exits with 0.
+func runTool(cmd *exec.Cmd, interactive bool) error {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err == nil && interactive && strings.TrimSpace(stderr.String()) != "" {
+		err = errors.New("command failed")
+	}
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return errors.New(cmd.Args[0] + ": " + msg)
+	}
+	return nil
+}
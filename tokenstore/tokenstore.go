@@ -0,0 +1,45 @@
+// Package tokenstore persists creator API tokens between
+// runs without keeping them in plaintext.
+package tokenstore
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrNotFound = errors.New("no token stored for account")
+
+// A Token is a stored creator API token.
+type Token struct {
+	Value   string    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// Expired reports whether the token has expired or will
+// expire within a minute.
+func (t *Token) Expired() bool {
+	return !t.Expires.IsZero() && time.Now().Add(time.Minute).After(t.Expires)
+}
+
+// A TokenStore saves and loads tokens by account name.
+type TokenStore interface {
+	Load(account string) (*Token, error)
+	Save(account string, token *Token) error
+	Delete(account string) error
+}
+
+// Default returns the OS keychain store if one is available,
+// and otherwise an encrypted file store at path.
+//
+// The passphrase function is only called if the file store
+// is used.
+func Default(path string, passphrase func() (string, error)) (TokenStore, error) {
+	if ks, err := NewKeychainStore(); err == nil {
+		return ks, nil
+	}
+	pass, err := passphrase()
+	if err != nil {
+		return nil, err
+	}
+	return NewFileStore(path, pass), nil
+}
@@ -0,0 +1,199 @@
+// Package translate translates question and choice texts, so
+// that the tools can show a game in the operator's language.
+//
+// A Provider can be the DeepL API or any command which reads
+// texts from its standard input, one per line, and writes
+// their translations in the same order.
+package translate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// DeepLURL is the endpoint of DeepL's free API.
+const DeepLURL = "https://api-free.deepl.com/v2/translate"
+
+// A Provider translates texts into a target language, given
+// as a language code such as "de".
+type Provider interface {
+	Translate(texts []string, target string) ([]string, error)
+}
+
+// New creates a provider from a spec: "deepl", which reads
+// its key from DEEPL_AUTH_KEY, or a command line. Commands get
+// the target language as the TARGET_LANG environment variable.
+func New(spec string) (Provider, error) {
+	if spec == "deepl" {
+		key := os.Getenv("DEEPL_AUTH_KEY")
+		if key == "" {
+			return nil, errors.New("DEEPL_AUTH_KEY is not set")
+		}
+		return NewCached(&DeepL{Key: key}), nil
+	}
+	fields := strings.Fields(spec)
+	if len(fields) == 0 {
+		return nil, errors.New("empty translator command")
+	}
+	return NewCached(&Command{Name: fields[0], Args: fields[1:]}), nil
+}
+
+// DeepL is a Provider backed by the DeepL API.
+type DeepL struct {
+	Key string
+
+	// URL defaults to DeepLURL.
+	URL string
+}
+
+func (d *DeepL) Translate(texts []string, target string) ([]string, error) {
+	endpoint := d.URL
+	if endpoint == "" {
+		endpoint = DeepLURL
+	}
+	form := url.Values{"target_lang": {strings.ToUpper(target)}, "text": texts}
+	req, err := http.NewRequest("POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "DeepL-Auth-Key "+d.Key)
+	if err := kahoot.TakeRequest(); err != nil {
+		return nil, err
+	}
+	resp, err := kahoot.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("translate: %s", resp.Status)
+	}
+	var result struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	body := io.LimitReader(resp.Body, kahoot.MaxResponseSize)
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("translate: %s", err)
+	}
+	if len(result.Translations) != len(texts) {
+		return nil, fmt.Errorf("translate: got %d translations for %d texts",
+			len(result.Translations), len(texts))
+	}
+	res := make([]string, len(texts))
+	for i, t := range result.Translations {
+		res[i] = t.Text
+	}
+	return res, nil
+}
+
+// Command is a Provider which runs a command for every batch
+// of texts.
+type Command struct {
+	Name string
+	Args []string
+}
+
+func (c *Command) Translate(texts []string, target string) ([]string, error) {
+	var input bytes.Buffer
+	for _, t := range texts {
+		input.WriteString(strings.Join(strings.Fields(t), " ") + "\n")
+	}
+	cmd := exec.Command(c.Name, c.Args...)
+	cmd.Env = append(os.Environ(), "TARGET_LANG="+target)
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("translate: %s", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	if len(texts) == 0 {
+		lines = nil
+	}
+	if len(lines) != len(texts) {
+		return nil, fmt.Errorf("translate: got %d lines for %d texts", len(lines), len(texts))
+	}
+	return lines, nil
+}
+
+// NewCached wraps a provider so that each text is only
+// translated once per target language, and empty texts are
+// never sent.
+func NewCached(p Provider) Provider {
+	return &cached{provider: p, results: map[[2]string]string{}}
+}
+
+type cached struct {
+	provider Provider
+
+	lock    sync.Mutex
+	results map[[2]string]string
+}
+
+func (c *cached) Translate(texts []string, target string) ([]string, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var missing []string
+	seen := map[string]bool{}
+	for _, t := range texts {
+		if _, ok := c.results[[2]string{target, t}]; !ok && t != "" && !seen[t] {
+			missing = append(missing, t)
+			seen[t] = true
+		}
+	}
+	if len(missing) > 0 {
+		translated, err := c.provider.Translate(missing, target)
+		if err != nil {
+			return nil, err
+		}
+		for i, t := range missing {
+			c.results[[2]string{target, t}] = translated[i]
+		}
+	}
+	res := make([]string, len(texts))
+	for i, t := range texts {
+		res[i] = c.results[[2]string{target, t}]
+	}
+	return res, nil
+}
+
+// Quiz returns a copy of a quiz with its question and choice
+// texts translated, in one batch.
+func Quiz(p Provider, quiz *kahoot.QuizInfo, target string) (*kahoot.QuizInfo, error) {
+	var texts []string
+	for _, q := range quiz.Questions {
+		texts = append(texts, q.Question)
+		for _, c := range q.Choices {
+			texts = append(texts, c.Answer)
+		}
+	}
+	translated, err := p.Translate(texts, target)
+	if err != nil {
+		return nil, err
+	}
+	res := *quiz
+	res.Questions = make([]kahoot.QuizQuestion, len(quiz.Questions))
+	for i, q := range quiz.Questions {
+		q.Question, translated = translated[0], translated[1:]
+		q.Choices = append([]kahoot.QuizChoice{}, q.Choices...)
+		for j := range q.Choices {
+			q.Choices[j].Answer, translated = translated[0], translated[1:]
+		}
+		res.Questions[i] = q
+	}
+	return &res, nil
+}
@@ -0,0 +1,106 @@
+package translate
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+type upperProvider struct {
+	calls [][]string
+}
+
+func (u *upperProvider) Translate(texts []string, target string) ([]string, error) {
+	u.calls = append(u.calls, texts)
+	var res []string
+	for _, t := range texts {
+		res = append(res, target+":"+strings.ToUpper(t))
+	}
+	return res, nil
+}
+
+func TestCached(t *testing.T) {
+	provider := &upperProvider{}
+	cached := NewCached(provider)
+	res, err := cached.Translate([]string{"yes", "no", "yes", ""}, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, []string{"de:YES", "de:NO", "de:YES", ""}) {
+		t.Errorf("unexpected translations %q", res)
+	}
+	cached.Translate([]string{"no"}, "de")
+	cached.Translate([]string{"no"}, "fr")
+	expected := [][]string{{"yes", "no"}, {"no"}}
+	if !reflect.DeepEqual(provider.calls, expected) {
+		t.Errorf("expected calls %q but got %q", expected, provider.calls)
+	}
+}
+
+func TestQuiz(t *testing.T) {
+	quiz := &kahoot.QuizInfo{Title: "t", Questions: []kahoot.QuizQuestion{
+		{Question: "2+2?", Choices: []kahoot.QuizChoice{{Answer: "four", Correct: true}, {Answer: "five"}}},
+		{Question: "sky?", Choices: []kahoot.QuizChoice{{Answer: "blue"}}},
+	}}
+	res, err := Quiz(&upperProvider{}, quiz, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Questions[0].Question != "de:2+2?" || res.Questions[1].Choices[0].Answer != "de:BLUE" {
+		t.Errorf("unexpected translated quiz %+v", res.Questions)
+	}
+	if !res.Questions[0].Choices[0].Correct || quiz.Questions[0].Choices[0].Answer != "four" {
+		t.Error("translation should keep correctness and leave the original alone")
+	}
+}
+
+func TestDeepL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key secret" || r.Form.Get("target_lang") != "DE" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"translations":[{"text":"Ja"},{"text":"Nein"}]}`))
+	}))
+	defer server.Close()
+	d := &DeepL{Key: "secret", URL: server.URL}
+	res, err := d.Translate([]string{"Yes", "No"}, "de")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, []string{"Ja", "Nein"}) {
+		t.Errorf("unexpected translations %q", res)
+	}
+}
+
+func TestDeepLBudget(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("the request should not be sent")
+	}))
+	defer server.Close()
+	defer kahoot.SetBudget(kahoot.CurrentBudget())
+	kahoot.SetBudget(kahoot.Budget{RequestsPerHour: 1})
+	kahoot.TakeRequest()
+	d := &DeepL{Key: "secret", URL: server.URL}
+	if _, err := d.Translate([]string{"Yes"}, "de"); err == nil {
+		t.Error("expected a budget error")
+	} else if _, ok := err.(*kahoot.BudgetError); !ok {
+		t.Errorf("expected a budget error, got %v", err)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	c := &Command{Name: "sh", Args: []string{"-c", `while read line; do echo "$TARGET_LANG $line"; done`}}
+	res, err := c.Translate([]string{"a b", "c\nd"}, "fr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, []string{"fr a b", "fr c d"}) {
+		t.Errorf("unexpected translations %q", res)
+	}
+}
@@ -0,0 +1,165 @@
+package workspace
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// ManifestFile is the name of a workspace's manifest.
+const ManifestFile = "manifest.json"
+
+// A Manifest records how a run was started, so that it can be
+// started again the same way: the build and protocol, the
+// command line, the random seed, and a hash of every input
+// file. Bots still race each other over the network, so a
+// rerun makes the same choices but not necessarily in the same
+// order.
+type Manifest struct {
+	Command string    `json:"command"`
+	Started time.Time `json:"started"`
+
+	Version *kahoot.VersionInfo `json:"version"`
+
+	// Args are the command-line arguments as given, and Flags
+	// every flag's effective value, defaults included.
+	Args  []string          `json:"args"`
+	Flags map[string]string `json:"flags"`
+
+	// Seed seeded math/rand for the run.
+	Seed int64 `json:"seed"`
+
+	// Files maps each input file to the hex SHA-256 of its
+	// contents when the run started.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// NewManifest starts a manifest for a run of command, using
+// the current protocol and solvers.
+func NewManifest(command string, args []string, seed int64) *Manifest {
+	return &Manifest{
+		Command: command,
+		Started: time.Now(),
+		Version: kahoot.Version(),
+		Args:    append([]string{}, args...),
+		Flags:   map[string]string{},
+		Seed:    seed,
+		Files:   map[string]string{},
+	}
+}
+
+// AddFile records the hash of an input file.
+func (m *Manifest) AddFile(path string) error {
+	sum, err := hashFile(path)
+	if err != nil {
+		return err
+	}
+	m.Files[path] = sum
+	return nil
+}
+
+// RerunArgs returns the arguments which start the run again,
+// with the seed pinned.
+func (m *Manifest) RerunArgs() []string {
+	args := []string{"-seed", strconv.FormatInt(m.Seed, 10)}
+	for i := 0; i < len(m.Args); i++ {
+		arg := m.Args[i]
+		if arg == "-seed" || arg == "--seed" {
+			i++
+			continue
+		} else if strings.HasPrefix(arg, "-seed=") || strings.HasPrefix(arg, "--seed=") {
+			continue
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+// RerunCommand returns RerunArgs as a shell command line.
+func (m *Manifest) RerunCommand() string {
+	words := []string{m.Command}
+	for _, arg := range m.RerunArgs() {
+		words = append(words, shellQuote(arg))
+	}
+	return strings.Join(words, " ")
+}
+
+// Differences lists what is no longer the same as when the
+// run started: the release, the protocol, and the input files.
+// A rerun can only be faithful if it is empty.
+func (m *Manifest) Differences() []string {
+	var res []string
+	now := kahoot.Version()
+	if m.Version != nil && now.Release != m.Version.Release {
+		res = append(res, fmt.Sprintf("release is %s instead of %s", now.Release, m.Version.Release))
+	}
+	if m.Version != nil && now.Protocol != m.Version.Protocol {
+		res = append(res, fmt.Sprintf("protocol is %s instead of %s", now.Protocol, m.Version.Protocol))
+	}
+	var paths []string
+	for path := range m.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if sum, err := hashFile(path); err != nil {
+			res = append(res, path+": "+err.Error())
+		} else if sum != m.Files[path] {
+			res = append(res, path+" has changed")
+		}
+	}
+	return res
+}
+
+// WriteManifest saves the manifest in the workspace.
+func (w *Workspace) WriteManifest(m *Manifest) error {
+	return w.writeJSON(ManifestFile, m)
+}
+
+// ReadManifest reads a manifest file, or the manifest of a
+// workspace directory.
+func ReadManifest(path string) (*Manifest, error) {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, ManifestFile)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %s", err)
+	}
+	return &m, nil
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=.,/:@{}") == "" {
+		return s
+	}
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
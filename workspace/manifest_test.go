@@ -0,0 +1,62 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestManifestRerun(t *testing.T) {
+	m := NewManifest("kahoot-flood", []string{"-seed=3", "-strategy", "random", "123456", "it's", "5"}, 42)
+	expected := []string{"-seed", "42", "-strategy", "random", "123456", "it's", "5"}
+	if args := m.RerunArgs(); !reflect.DeepEqual(args, expected) {
+		t.Errorf("unexpected args %q", args)
+	}
+	if cmd := m.RerunCommand(); cmd != `kahoot-flood -seed 42 -strategy random 123456 'it'\''s' 5` {
+		t.Errorf("unexpected command %s", cmd)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	root, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	ws, err := Create(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	input := filepath.Join(root, "roster.txt")
+	if err := ioutil.WriteFile(input, []byte("alex 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewManifest("kahoot-flood", []string{"123456", input}, 7)
+	if err := m.AddFile(input); err != nil {
+		t.Fatal(err)
+	}
+	if err := ws.WriteManifest(m); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadManifest(ws.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Seed != 7 || !reflect.DeepEqual(read.Args, m.Args) || read.Version.Protocol != m.Version.Protocol {
+		t.Errorf("unexpected manifest %+v", read)
+	}
+	if diffs := read.Differences(); len(diffs) != 0 {
+		t.Errorf("unexpected differences %v", diffs)
+	}
+	if err := ioutil.WriteFile(input, []byte("alex 4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if diffs := read.Differences(); len(diffs) != 1 {
+		t.Errorf("expected the changed roster, got %v", diffs)
+	}
+}
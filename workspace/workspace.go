@@ -0,0 +1,239 @@
+// Package workspace keeps the artifacts of a single run
+// together in one timestamped directory.
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+// DefaultRoot is the directory in which workspaces are
+// created if no other directory is specified.
+const DefaultRoot = "kahoot-runs"
+
+const timeFormat = "20060102-150405"
+
+// A Workspace is a directory holding the artifacts of a run:
+//
+//	config.json   a snapshot of the run's configuration
+//	manifest.json how to start the run again; see Manifest
+//	run.log       the run's log
+//	recordings/   recorded events
+//	exports/      exported data
+//	crashes/      reports of bots that panicked
+//	traces/       wire traces of individual bots
+//	summary.json  a summary written at the end of the run
+//	report.json   how each bot's session ended, and the top errors
+type Workspace struct {
+	Dir string
+
+	logFile *os.File
+	logger  *log.Logger
+}
+
+// Create makes a new workspace inside root, named after the
+// command and the current time.
+func Create(root, command string) (*Workspace, error) {
+	if root == "" {
+		root = DefaultRoot
+	}
+	dir, err := createUnique(filepath.Join(root, command+"-"+time.Now().Format(timeFormat)))
+	if err != nil {
+		return nil, err
+	}
+	for _, sub := range []string{"recordings", "exports"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			return nil, err
+		}
+	}
+	logFile, err := os.OpenFile(filepath.Join(dir, "run.log"),
+		os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Workspace{
+		Dir:     dir,
+		logFile: logFile,
+		logger:  log.New(logFile, "", log.LstdFlags),
+	}, nil
+}
+
+// createUnique creates a new directory named base, or base
+// with the lowest free numeric suffix, so that runs started in
+// the same second by different processes never share a
+// workspace.
+func createUnique(base string) (string, error) {
+	if err := os.MkdirAll(filepath.Dir(base), 0755); err != nil {
+		return "", err
+	}
+	dir := base
+	for i := 2; ; i++ {
+		err := os.Mkdir(dir, 0755)
+		if err == nil {
+			return dir, nil
+		} else if !os.IsExist(err) {
+			return "", err
+		}
+		dir = base + "-" + strconv.Itoa(i)
+	}
+}
+
+// Path returns a path inside the workspace.
+func (w *Workspace) Path(elem ...string) string {
+	return filepath.Join(append([]string{w.Dir}, elem...)...)
+}
+
+// Logger returns the logger for run.log.
+func (w *Workspace) Logger() *log.Logger {
+	return w.logger
+}
+
+// SaveConfig writes a JSON snapshot of the configuration.
+func (w *Workspace) SaveConfig(config interface{}) error {
+	return w.writeJSON("config.json", config)
+}
+
+// WriteSummary writes the run summary as JSON.
+func (w *Workspace) WriteSummary(summary interface{}) error {
+	return w.writeJSON("summary.json", summary)
+}
+
+// WriteReport writes the shutdown report as JSON.
+func (w *Workspace) WriteReport(report interface{}) error {
+	return w.writeJSON("report.json", report)
+}
+
+// CreateExport creates a file in the exports directory.
+func (w *Workspace) CreateExport(name string) (*os.File, error) {
+	return os.Create(w.Path("exports", name))
+}
+
+// WriteCrashReport saves a crash report in the crashes
+// directory.
+func (w *Workspace) WriteCrashReport(name string, report []byte) error {
+	if err := os.MkdirAll(w.Path("crashes"), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(w.Path("crashes", name+".txt"), report, 0644)
+}
+
+// CreateTrace opens a bot's wire trace file in the traces
+// directory, appending if it was traced before.
+func (w *Workspace) CreateTrace(name string) (*os.File, error) {
+	if err := os.MkdirAll(w.Path("traces"), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(w.Path("traces", name+".jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+}
+
+// Record writes every event from a subscription to a JSON
+// lines file in the recordings directory, returning once the
+// subscription is closed.
+func (w *Workspace) Record(name string, sub *kahoot.Subscription) error {
+	f, err := os.Create(w.Path("recordings", name+".jsonl"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeEvents(f, sub)
+}
+
+// Close closes the log file.
+func (w *Workspace) Close() error {
+	return w.logFile.Close()
+}
+
+func (w *Workspace) writeJSON(name string, obj interface{}) error {
+	data, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(w.Path(name), append(data, '\n'), 0644)
+}
+
+type recordedEvent struct {
+	Topic kahoot.Topic `json:"topic"`
+	Type  string       `json:"type"`
+	Time  time.Time    `json:"time"`
+	Seq   uint64       `json:"seq"`
+	Data  interface{}  `json:"data,omitempty"`
+}
+
+// A RecordedEvent is an event read back from a recording.
+// Data holds the event's JSON-encoded data, if any.
+type RecordedEvent struct {
+	Topic kahoot.Topic    `json:"topic"`
+	Type  string          `json:"type"`
+	Time  time.Time       `json:"time"`
+	Seq   uint64          `json:"seq"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// ReadRecordings reads every recording in a workspace
+// directory, keyed by the name it was recorded under.
+func ReadRecordings(dir string) (map[string][]RecordedEvent, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "recordings", "*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+	res := map[string][]RecordedEvent{}
+	for _, path := range paths {
+		events, err := readRecording(path)
+		if err != nil {
+			return nil, err
+		}
+		res[strings.TrimSuffix(filepath.Base(path), ".jsonl")] = events
+	}
+	return res, nil
+}
+
+func readRecording(path string) ([]RecordedEvent, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var events []RecordedEvent
+	dec := json.NewDecoder(f)
+	for {
+		var e RecordedEvent
+		if err := dec.Decode(&e); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("read %s: %s", path, err)
+		}
+		events = append(events, e)
+	}
+}
+
+func writeEvents(w io.Writer, sub *kahoot.Subscription) error {
+	enc := json.NewEncoder(w)
+	for e := range sub.C {
+		data := e.Data
+		if err, ok := data.(error); ok {
+			data = err.Error()
+		}
+		err := enc.Encode(recordedEvent{
+			Topic: e.Topic,
+			Type:  e.Type,
+			Time:  e.Time,
+			Seq:   e.Seq,
+			Data:  data,
+		})
+		if err != nil {
+			return errors.New("record event: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package workspace
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/unixpickle/kahoot-hack/kahoot"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	root, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	ws, err := Create(root, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	bus := kahoot.NewBus()
+	sub := bus.Subscribe()
+	bus.Publish(kahoot.TopicQuestion, "intro", &kahoot.QuizAction{Index: 3})
+	bus.Publish(kahoot.TopicConnection, "closed", nil)
+	bus.Close()
+	if err := ws.Record("alex", sub); err != nil {
+		t.Fatal(err)
+	}
+
+	recordings, err := ReadRecordings(ws.Dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := recordings["alex"]
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events but got %d", len(events))
+	}
+	if events[0].Type != "intro" || events[1].Topic != kahoot.TopicConnection || events[1].Data != nil {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
+
+func TestCreateUnique(t *testing.T) {
+	root, err := ioutil.TempDir("", "workspace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+	dirs := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		ws, err := Create(root, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer ws.Close()
+		dirs[ws.Dir] = true
+	}
+	if len(dirs) != 3 {
+		t.Errorf("expected 3 distinct workspaces but got %v", dirs)
+	}
+}